@@ -2,59 +2,158 @@ package main
 
 import (
 	"context"
-	"log"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"log/slog"
 	"net/http"
 	"os"
-	"os/signal"
-	"syscall"
 	"time"
 
 	"github.com/gin-gonic/gin"
 
-	dbpkg "github.com/huntercenter1/backend-test/order-service/internal/db"
+	platformcfg "github.com/huntercenter1/backend-test/platform/config"
+	dbpkg "github.com/huntercenter1/backend-test/platform/db"
+	"github.com/huntercenter1/backend-test/platform/health"
+	"github.com/huntercenter1/backend-test/platform/logging"
+	"github.com/huntercenter1/backend-test/platform/migrate"
+	"github.com/huntercenter1/backend-test/platform/ratelimit"
+	"github.com/huntercenter1/backend-test/platform/server"
+	"github.com/huntercenter1/backend-test/platform/tracing"
+
 	"github.com/huntercenter1/backend-test/order-service/internal/clients"
+	"github.com/huntercenter1/backend-test/order-service/internal/config"
+	"github.com/huntercenter1/backend-test/order-service/internal/invoice"
+	"github.com/huntercenter1/backend-test/order-service/internal/payments"
+	"github.com/huntercenter1/backend-test/order-service/internal/pricing"
 	"github.com/huntercenter1/backend-test/order-service/internal/repo"
 	"github.com/huntercenter1/backend-test/order-service/internal/service"
 	httpr "github.com/huntercenter1/backend-test/order-service/internal/transport/http"
 )
 
 func main() {
-	if err := dbpkg.Migrate(os.Getenv("DB_DSN"), os.Getenv("MIGRATIONS_DIR")); err != nil {
-		log.Fatalf("migrate: %v", err)
+	if len(os.Args) > 1 && os.Args[1] == "migrate" { os.Exit(runMigrate(os.Args[2:])) }
+
+	cfg, printOnly, err := config.Load(os.Args[1:])
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "invalid configuration:\n%v\n", err)
+		os.Exit(2)
+	}
+	if printOnly { cfg.Print(os.Stdout); return }
+
+	logger := logging.New("order-service", cfg.Log.Level)
+	shutdownTracing, err := tracing.Init(context.Background(), "order-service")
+	if err != nil { fatal("tracing", err) }
+	defer func() { _ = shutdownTracing(context.Background()) }()
+
+	// con migrations.auto=false las migraciones se lanzan aparte (`migrate up`)
+	if cfg.Migrations.Auto {
+		if err := migrate.Up(context.Background(), cfg.DB.DSN, cfg.Migrations); err != nil { fatal("migrate", err) }
 	}
-	db, err := dbpkg.New(context.Background())
-	if err != nil { log.Fatalf("db: %v", err) }
+	db, err := dbpkg.New(context.Background(), cfg.DB)
+	if err != nil { fatal("db", err) }
 	defer db.Close()
+	// no servimos con un esquema más antiguo que el que espera el binario
+	if err := migrate.Check(context.Background(), db.DB, cfg.Migrations.Dir); err != nil { fatal("schema check", err) }
 
 	// clients
-	userAddr := getenv("USER_GRPC_ADDR", "user-service:50051")
-	uc, closeUC, err := clients.NewUserClient(userAddr)
-	if err != nil { log.Fatalf("user client: %v", err) }
+	ucfg := cfg.User.Client.Resilience()
+	ucfg.APIKey = cfg.User.APIKey
+	uc, closeUC, err := clients.NewUserClient(cfg.User.Addr, ucfg)
+	if err != nil { fatal("user client", err) }
 	defer closeUC()
-	pc := clients.NewProductClient(getenv("PRODUCT_BASE_URL", "http://product-service:8081"))
+	pcfg := cfg.Product.Client.Resilience()
+	pcfg.APIKey = cfg.Product.APIKey
+	pc := clients.NewProductClient(cfg.Product.BaseURL, pcfg)
+
+	var limiter *ratelimit.Limiter
+	if cfg.RateLimit.Enabled {
+		policy, _ := cfg.RateLimit.Policy() // ya validada en config.Load
+		policy.Exempt = httpr.ProbeRoutes
+		limiter = ratelimit.New(ratelimit.NewMemoryStore(), policy, logger)
+	}
+
+	// pagos: sin secreto configurado (sólo se admite en local) el fake firma
+	// con uno aleatorio y sólo este proceso puede generar webhooks válidos
+	secret := cfg.Payments.WebhookSecret
+	if secret == "" {
+		secret = randomSecret()
+		logger.Warn("PAYMENTS_WEBHOOK_SECRET not set, using a random secret: webhooks sent to another replica will be rejected")
+	}
+	gateway := payments.NewFake(payments.FakeConfig{WebhookURL: cfg.Payments.FakeWebhookURL, Secret: secret, Delay: 500 * time.Millisecond, Logger: logger})
 
 	// wiring
 	rp := repo.New(db)
-	svc := service.New(rp, uc, pc)
-	rt := httpr.New(svc)
+	promos, rules := repo.NewPromotionRepo(db), repo.NewPricingRuleRepo(db)
+	// promociones antes que impuestos: se tributa por lo que queda tras los descuentos
+	svc := service.New(rp, uc, pc, pricing.Pipeline{service.NewPromotionStage(promos, pc), pricing.Taxes(rules), pricing.Shipping(rules)})
+	pay := service.NewPaymentService(repo.NewPaymentRepo(db), svc, gateway, service.PaymentConfig{
+		Currency: cfg.Payments.Currency, WebhookSecret: secret, WebhookTolerance: cfg.Payments.WebhookTolerance,
+	})
+	servers := []server.Server{}
+	var expiry service.ExpiryService // nil si está desactivado: sin ruta de estado
+	if cfg.Expiry.Enabled {
+		w := service.NewExpiryWorker(repo.NewExpiryRepo(db), pc, service.ExpiryConfig{
+			TTL: cfg.Expiry.TTL, Interval: cfg.Expiry.Interval, BatchSize: cfg.Expiry.BatchSize,
+		})
+		expiry, servers = w, append(servers, w.Server())
+	}
+	rt := httpr.New(svc, httpr.Options{
+		RequestTimeout: cfg.HTTP.RequestTimeout,
+		RateLimit:      limiter,
+		Carts:          service.NewCartService(repo.NewCartRepo(db), svc, uc, pc),
+		Payments:       pay,
+		Shipments:      service.NewShipmentService(repo.NewShipmentRepo(db), svc),
+		Returns:        service.NewReturnService(repo.NewReturnRepo(db), svc, pc, pay),
+		Promotions:     service.NewPromotionService(promos),
+		PricingRules:   service.NewPricingRuleService(rules),
+		Invoices: service.NewInvoiceService(repo.NewInvoiceRepo(db), svc, uc, service.InvoiceConfig{
+			Issuer:   invoice.Party{Name: cfg.Invoices.IssuerName, TaxID: cfg.Invoices.IssuerTaxID, Address: cfg.Invoices.IssuerAddress},
+			Currency: cfg.Payments.Currency,
+		}),
+		Expiry:  expiry,
+		Reports: service.NewReportService(repo.NewReportRepo(db), service.ReportConfig{Timezone: cfg.Reports.Timezone}),
+		Checks: []health.Check{
+			health.DB(db.DB),
+			health.Migrations(db.DB, cfg.Migrations.Dir),
+			health.Ping("user-service", uc.Ping),
+			health.Ping("product-service", pc.Ping),
+		},
+	})
 
 	// http
 	r := gin.New()
+	if err := r.SetTrustedProxies(platformcfg.Proxies(cfg.HTTP.TrustedProxies)); err != nil { fatal("trusted proxies", err) }
 	rt.Register(r)
 
-	srv := &http.Server{ Addr: getenv("APP_PORT", ":8082"), Handler: r }
-	go func() {
-		log.Printf("order-service HTTP listening on %s", srv.Addr)
-		if err := srv.ListenAndServe(); err != nil && err != http.ErrServerClosed {
-			log.Fatalf("listen: %v", err)
-		}
-	}()
-
-	quit := make(chan os.Signal, 1)
-	signal.Notify(quit, os.Interrupt, syscall.SIGTERM)
-	<-quit
-	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second); defer cancel()
-	_ = srv.Shutdown(ctx)
+	srv := &http.Server{Addr: cfg.HTTP.Addr, Handler: r}
+	servers = append(servers, server.HTTP("http", srv))
+	if err := server.Run(context.Background(), logger, cfg.HTTP.ShutdownTimeout, servers...); err != nil {
+		fatal("server", err)
+	}
+}
+
+// runMigrate atiende `order-service migrate <cmd> [flags]`; los flags son
+// los mismos que los del servidor (-config, -db.dsn...).
+func runMigrate(args []string) int {
+	if len(args) == 0 { fmt.Fprintln(os.Stderr, "usage: order-service", migrate.Usage); return 2 }
+	cfg, _, err := config.Load(args[1:])
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "invalid configuration:\n%v\n", err)
+		return 2
+	}
+	logging.New("order-service", cfg.Log.Level)
+	if err := migrate.Command(context.Background(), cfg.DB.DSN, cfg.Migrations, args[0], os.Stdout); err != nil {
+		slog.Error("migrate", "command", args[0], "error", err)
+		return 1
+	}
+	return 0
+}
+
+func randomSecret() string {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil { fatal("webhook secret", err) }
+	return hex.EncodeToString(b)
 }
 
-func getenv(k, d string) string { v := os.Getenv(k); if v == "" { return d }; return v }
+func fatal(msg string, err error) { slog.Error(msg, "error", err); os.Exit(1) }
@@ -0,0 +1,80 @@
+package clients
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"time"
+
+	"github.com/huntercenter1/backend-test/order-service/internal/metrics"
+	"github.com/huntercenter1/backend-test/order-service/internal/resilience"
+)
+
+// Config es la política de resiliencia de un cliente. Los campos a cero
+// toman el valor de DefaultConfig.
+type Config struct {
+	// Timeout se aplica sólo si el contexto de la llamada no trae deadline;
+	// normalmente manda el de la petición entrante.
+	Timeout time.Duration
+	Retry   resilience.RetryConfig
+	Breaker resilience.BreakerConfig
+	// RetryBudgetRatio es la fracción de peticiones que puede reintentarse.
+	RetryBudgetRatio float64
+	RetryBudgetMin   int
+	// APIKey se manda en X-API-Key para que el rate limit del otro servicio
+	// reconozca a order-service (vacío = no se manda).
+	APIKey string
+}
+
+func DefaultConfig() Config {
+	return Config{
+		Timeout:          5 * time.Second,
+		Retry:            resilience.RetryConfig{MaxAttempts: 3, BaseDelay: 50 * time.Millisecond, MaxDelay: time.Second},
+		Breaker:          resilience.BreakerConfig{Failures: 5, Cooldown: 10 * time.Second},
+		RetryBudgetRatio: 0.2,
+		RetryBudgetMin:   10,
+	}
+}
+
+func (c Config) withDefaults() Config {
+	d := DefaultConfig()
+	if c.Timeout <= 0 { c.Timeout = d.Timeout }
+	if c.Retry.MaxAttempts <= 0 { c.Retry.MaxAttempts = d.Retry.MaxAttempts }
+	if c.Retry.BaseDelay <= 0 { c.Retry.BaseDelay = d.Retry.BaseDelay }
+	if c.Retry.MaxDelay <= 0 { c.Retry.MaxDelay = d.Retry.MaxDelay }
+	if c.Breaker.Failures <= 0 { c.Breaker.Failures = d.Breaker.Failures }
+	if c.Breaker.Cooldown <= 0 { c.Breaker.Cooldown = d.Breaker.Cooldown }
+	if c.RetryBudgetRatio <= 0 { c.RetryBudgetRatio = d.RetryBudgetRatio }
+	if c.RetryBudgetMin <= 0 { c.RetryBudgetMin = d.RetryBudgetMin }
+	return c
+}
+
+// policy crea el breaker y el presupuesto de reintentos de una dependencia;
+// se comparten entre todas las operaciones del cliente.
+func (c Config) policy(client string) resilience.Policy {
+	metrics.CircuitState(client, int(resilience.Closed))
+	return resilience.Policy{
+		Retry: c.Retry,
+		Breaker: resilience.NewBreaker(c.Breaker, func(from, to resilience.State) {
+			metrics.CircuitState(client, int(to))
+			slog.Warn("circuit breaker state change", "client", client, "from", from.String(), "to", to.String())
+		}),
+		Budget: resilience.NewBudget(c.RetryBudgetRatio, c.RetryBudgetMin),
+		OnRetry: func(attempt int, err error) {
+			metrics.ClientRetry(client)
+			slog.Warn("retrying call", "client", client, "attempt", attempt, "error", err)
+		},
+	}
+}
+
+// ctxFailure clasifica errores de contexto: un deadline agotado cuenta como
+// fallo de la dependencia (está lenta); una cancelación del llamador no.
+func ctxFailure(err error) (resilience.Outcome, bool) {
+	switch {
+	case errors.Is(err, context.DeadlineExceeded):
+		return resilience.Outcome{Failed: true}, true
+	case errors.Is(err, context.Canceled), errors.Is(err, resilience.ErrCircuitOpen):
+		return resilience.Outcome{}, true
+	}
+	return resilience.Outcome{}, false
+}
@@ -4,57 +4,182 @@ import (
 	"bytes"
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"net/http"
 	"time"
+
+	"go.opentelemetry.io/contrib/instrumentation/net/http/otelhttp"
+
+	"github.com/huntercenter1/backend-test/platform/logging"
+
+	"github.com/huntercenter1/backend-test/order-service/internal/metrics"
+	"github.com/huntercenter1/backend-test/order-service/internal/resilience"
 )
 
 type ProductClient interface {
 	Get(ctx context.Context, id string) (*Product, error)
-	ApplyStockDelta(ctx context.Context, id string, delta int) (*Product, error)
+	ApplyStockDelta(ctx context.Context, id string, ch StockChange) (*Product, error)
+	// Ping comprueba que product-service responde (GET /livez).
+	Ping(ctx context.Context) error
 }
 
+// Motivos de movimiento de stock que entiende product-service.
+const (
+	StockSale    = "sale"
+	StockRestock = "restock"
+	StockReturn  = "return"
+)
+
+// StockChange es un movimiento de stock. ReferenceID identifica la línea que
+// lo origina (StockRef) para cruzarlo con el ledger de product-service, que
+// aplica cada referencia una sola vez por producto y motivo.
+type StockChange struct {
+	Delta       int    `json:"delta"`
+	Reason      string `json:"reason"`
+	ReferenceID string `json:"reference_id,omitempty"`
+	Region      string `json:"region,omitempty"` // región de envío de la venta: product-service sirve desde el almacén más cercano
+}
+
+// StockRef es la referencia de stock de la línea lineID de parent (pedido o
+// devolución).
+func StockRef(parentID, lineID string) string { return parentID + ":" + lineID }
+
+const actor = "order-service"
+
 type productClient struct {
-	base string
-	hc   *http.Client
+	base   string
+	hc     *http.Client
+	cfg    Config
+	policy resilience.Policy
+}
+
+// StatusError es una respuesta no esperada de product-service.
+type StatusError struct {
+	Op   string
+	Code int
+}
+
+func (e *StatusError) Error() string { return fmt.Sprintf("%s status %d", e.Op, e.Code) }
+
+// IsNotFound indica que product-service respondió 404 (el producto no existe).
+func IsNotFound(err error) bool {
+	var se *StatusError
+	return errors.As(err, &se) && se.Code == http.StatusNotFound
 }
 
 type Product struct {
-	ID    string  `json:"id"`
-	Name  string  `json:"name"`
-	Price float64 `json:"price"`
-	Stock int     `json:"stock"`
+	ID             string  `json:"id"`
+	SKU            string  `json:"sku"`
+	Name           string  `json:"name"`
+	Description    string  `json:"description"`
+	Price          float64 `json:"price"`
+	EffectivePrice float64 `json:"effective_price"`
+	Stock          int     `json:"stock"`
+	TaxClass       string  `json:"tax_class"`
+	WeightGrams    int     `json:"weight_grams"`
+	Applied        int     `json:"applied"` // sólo en ApplyStockDelta: el delta que se aplicó de verdad
+}
+
+// UnitPrice es el precio vigente según product-service (incluye precios
+// programados); cae al precio base si el servicio no lo informa.
+func (p *Product) UnitPrice() float64 {
+	if p.EffectivePrice > 0 { return p.EffectivePrice }
+	return p.Price
 }
 
-func NewProductClient(base string) ProductClient {
+func NewProductClient(base string, cfg Config) ProductClient {
+	cfg = cfg.withDefaults()
 	return &productClient{
-		base: base,
-		hc:   &http.Client{ Timeout: 5 * time.Second },
+		base:   base,
+		// sin Timeout fijo: manda el deadline del contexto (ver Config.Timeout)
+		// otelhttp crea un span por llamada e inyecta traceparent
+		hc:     &http.Client{Transport: otelhttp.NewTransport(http.DefaultTransport)},
+		cfg:    cfg,
+		policy: cfg.policy("product"),
 	}
 }
 
+// Get es idempotente, así que se reintenta ante fallos transitorios.
 func (c *productClient) Get(ctx context.Context, id string) (*Product, error) {
-	req, _ := http.NewRequestWithContext(ctx, http.MethodGet, fmt.Sprintf("%s/products/%s", c.base, id), nil)
-	res, err := c.hc.Do(req)
-	if err != nil { return nil, err }
-	defer res.Body.Close()
-	if res.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("product get status %d", res.StatusCode)
-	}
+	ctx, cancel := resilience.WithDefaultTimeout(ctx, c.cfg.Timeout); defer cancel()
 	var p Product
-	return &p, json.NewDecoder(res.Body).Decode(&p)
+	err := c.policy.Do(ctx, func(ctx context.Context) error {
+		req, _ := http.NewRequestWithContext(ctx, http.MethodGet, fmt.Sprintf("%s/products/%s", c.base, id), nil)
+		return c.call("get", "product get", req, &p)
+	}, classifyHTTP(true))
+	if err != nil { return nil, err }
+	return &p, nil
 }
 
-func (c *productClient) ApplyStockDelta(ctx context.Context, id string, delta int) (*Product, error) {
-	body, _ := json.Marshal(map[string]int{"delta": delta})
-	req, _ := http.NewRequestWithContext(ctx, http.MethodPut, fmt.Sprintf("%s/products/%s/stock", c.base, id), bytes.NewReader(body))
-	req.Header.Set("Content-Type", "application/json")
-	res, err := c.hc.Do(req)
+// ApplyStockDelta es idempotente si ch lleva ReferenceID (product-service
+// no aplica dos veces la misma referencia) y entonces se reintenta como
+// Get. Sin referencia un reintento podría descontar dos veces: sólo se
+// reintenta un 429, que el rate limit devuelve sin haber tocado el stock.
+func (c *productClient) ApplyStockDelta(ctx context.Context, id string, ch StockChange) (*Product, error) {
+	ctx, cancel := resilience.WithDefaultTimeout(ctx, c.cfg.Timeout); defer cancel()
+	body, _ := json.Marshal(ch)
+	var p Product
+	err := c.policy.Do(ctx, func(ctx context.Context) error {
+		req, _ := http.NewRequestWithContext(ctx, http.MethodPut, fmt.Sprintf("%s/products/%s/stock", c.base, id), bytes.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("X-Actor", actor)
+		return c.call("apply_stock_delta", "stock update", req, &p)
+	}, classifyHTTP(ch.ReferenceID != ""))
 	if err != nil { return nil, err }
+	return &p, nil
+}
+
+// Ping no usa reintentos ni breaker: readyz debe ver el estado real.
+func (c *productClient) Ping(ctx context.Context) error {
+	ctx, cancel := resilience.WithDefaultTimeout(ctx, c.cfg.Timeout); defer cancel()
+	req, _ := http.NewRequestWithContext(ctx, http.MethodGet, c.base+"/livez", nil)
+	res, err := c.do("ping", req)
+	if err != nil { return err }
+	res.Body.Close()
+	if res.StatusCode != http.StatusOK { return &StatusError{Op: "product-service livez", Code: res.StatusCode} }
+	return nil
+}
+
+// call hace la petición y decodifica una respuesta 200 en out.
+func (c *productClient) call(op, desc string, req *http.Request, out any) error {
+	res, err := c.do(op, req)
+	if err != nil { return err }
 	defer res.Body.Close()
-	if res.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("stock update status %d", res.StatusCode)
+	if res.StatusCode != http.StatusOK { return &StatusError{Op: desc, Code: res.StatusCode} }
+	return json.NewDecoder(res.Body).Decode(out)
+}
+
+// do propaga el id de correlación para poder seguir la petición en los logs
+// de product-service, y mide la llamada bajo la operación op.
+func (c *productClient) do(op string, req *http.Request) (*http.Response, error) {
+	if id := logging.RequestID(req.Context()); id != "" { req.Header.Set("X-Request-Id", id) }
+	if c.cfg.APIKey != "" { req.Header.Set("X-API-Key", c.cfg.APIKey) }
+	start := time.Now()
+	res, err := c.hc.Do(req)
+	metrics.ObserveClient("product", op, start, err != nil || res.StatusCode >= 500)
+	return res, err
+}
+
+// classifyHTTP: 5xx y errores de red son fallos de product-service; 502/503/
+// 504 y errores de red se reintentan si la llamada es idempotente. Un 429 se
+// reintenta siempre: la petición no llegó a procesarse. Otro 4xx (p.ej.
+// producto inexistente) no es fallo ni se reintenta.
+func classifyHTTP(idempotent bool) func(error) resilience.Outcome {
+	return func(err error) resilience.Outcome {
+		if out, ok := ctxFailure(err); ok { return out }
+		var se *StatusError
+		if errors.As(err, &se) {
+			switch se.Code {
+			case http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+				return resilience.Outcome{Failed: true, Retryable: idempotent}
+			case http.StatusTooManyRequests:
+				return resilience.Outcome{Retryable: true}
+			}
+			return resilience.Outcome{Failed: se.Code >= 500}
+		}
+		var syntax *json.SyntaxError
+		if errors.As(err, &syntax) { return resilience.Outcome{} }
+		return resilience.Outcome{Failed: true, Retryable: idempotent}
 	}
-	var p Product
-	return &p, json.NewDecoder(res.Body).Decode(&p)
 }
@@ -0,0 +1,172 @@
+package clients
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/propagation"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+
+	"github.com/huntercenter1/backend-test/platform/logging"
+
+	"github.com/huntercenter1/backend-test/order-service/internal/resilience"
+)
+
+func TestProductClientForwardsRequestID(t *testing.T) {
+	var got string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got = r.Header.Get("X-Request-Id")
+		_ = json.NewEncoder(w).Encode(Product{ID: "p1", Price: 10})
+	}))
+	defer srv.Close()
+
+	ctx := logging.WithRequestID(context.Background(), "req-42")
+	if _, err := NewProductClient(srv.URL, Config{}).Get(ctx, "p1"); err != nil { t.Fatal(err) }
+	if got != "req-42" { t.Fatalf("X-Request-Id = %q, want req-42", got) }
+}
+
+func TestProductClientPropagatesTraceContext(t *testing.T) {
+	otel.SetTextMapPropagator(propagation.TraceContext{})
+	tp := sdktrace.NewTracerProvider()
+	defer tp.Shutdown(context.Background())
+	otel.SetTracerProvider(tp)
+
+	var got string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got = r.Header.Get("traceparent")
+		_ = json.NewEncoder(w).Encode(Product{ID: "p1", Price: 10})
+	}))
+	defer srv.Close()
+
+	ctx, span := tp.Tracer("test").Start(context.Background(), "create order")
+	_, err := NewProductClient(srv.URL, Config{}).Get(ctx, "p1")
+	span.End()
+	if err != nil { t.Fatal(err) }
+	if want := span.SpanContext().TraceID().String(); !strings.Contains(got, want) {
+		t.Fatalf("traceparent = %q, want trace id %s", got, want)
+	}
+}
+
+func TestProductClientPing(t *testing.T) {
+	status := http.StatusOK
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/livez" { t.Errorf("unexpected path %s", r.URL.Path) }
+		w.WriteHeader(status)
+	}))
+	defer srv.Close()
+	pc := NewProductClient(srv.URL, Config{})
+	if err := pc.Ping(context.Background()); err != nil { t.Fatal(err) }
+	status = http.StatusServiceUnavailable
+	if err := pc.Ping(context.Background()); err == nil { t.Fatal("want error on 503") }
+}
+
+func fastConfig() Config {
+	c := DefaultConfig()
+	c.Retry = resilience.RetryConfig{MaxAttempts: 3, BaseDelay: time.Millisecond, MaxDelay: 2 * time.Millisecond}
+	return c
+}
+
+func TestProductClientRetriesIdempotentGet(t *testing.T) {
+	var calls atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if calls.Add(1) < 3 { w.WriteHeader(http.StatusServiceUnavailable); return }
+		_ = json.NewEncoder(w).Encode(Product{ID: "p1", Price: 10})
+	}))
+	defer srv.Close()
+
+	p, err := NewProductClient(srv.URL, fastConfig()).Get(context.Background(), "p1")
+	if err != nil || p.ID != "p1" { t.Fatalf("p=%v err=%v", p, err) }
+	if calls.Load() != 3 { t.Fatalf("calls=%d, want 3", calls.Load()) }
+}
+
+func TestProductClientDoesNotRetryStockDelta(t *testing.T) {
+	var calls atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer srv.Close()
+
+	_, err := NewProductClient(srv.URL, fastConfig()).ApplyStockDelta(context.Background(), "p1", StockChange{Delta: -1, Reason: StockSale})
+	var se *StatusError
+	if !errors.As(err, &se) || se.Code != http.StatusServiceUnavailable { t.Fatalf("err=%v", err) }
+	if calls.Load() != 1 { t.Fatalf("calls=%d, want 1", calls.Load()) }
+
+	// con referencia product-service no lo aplica dos veces: se reintenta
+	calls.Store(0)
+	_, err = NewProductClient(srv.URL, fastConfig()).ApplyStockDelta(context.Background(), "p1", StockChange{Delta: -1, Reason: StockSale, ReferenceID: StockRef("o1", "i1")})
+	if !errors.As(err, &se) || calls.Load() < 2 { t.Fatalf("calls=%d err=%v", calls.Load(), err) }
+}
+
+func TestProductClientRetriesRateLimitedStockDelta(t *testing.T) {
+	var calls atomic.Int32
+	var key string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		key = r.Header.Get("X-API-Key")
+		// el 429 llega antes de aplicar el movimiento: reintentar no descuenta dos veces
+		if calls.Add(1) == 1 { w.WriteHeader(http.StatusTooManyRequests); return }
+		_ = json.NewEncoder(w).Encode(Product{ID: "p1", Stock: 4})
+	}))
+	defer srv.Close()
+
+	cfg := fastConfig()
+	cfg.APIKey = "k1"
+	p, err := NewProductClient(srv.URL, cfg).ApplyStockDelta(context.Background(), "p1", StockChange{Delta: -1, Reason: StockSale})
+	if err != nil || p.Stock != 4 { t.Fatalf("p=%+v err=%v", p, err) }
+	if calls.Load() != 2 || key != "k1" { t.Fatalf("calls=%d key=%q", calls.Load(), key) }
+}
+
+func TestProductClientNotFoundIsNotRetried(t *testing.T) {
+	var calls atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		w.WriteHeader(http.StatusNotFound)
+	}))
+	defer srv.Close()
+
+	pc := NewProductClient(srv.URL, fastConfig())
+	for i := 0; i < 10; i++ {
+		if _, err := pc.Get(context.Background(), "nope"); errors.Is(err, resilience.ErrCircuitOpen) { t.Fatal("404 must not open the circuit") }
+	}
+	if calls.Load() != 10 { t.Fatalf("calls=%d, want 10", calls.Load()) }
+}
+
+func TestProductClientCircuitOpens(t *testing.T) {
+	var calls atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer srv.Close()
+
+	cfg := fastConfig()
+	cfg.Breaker = resilience.BreakerConfig{Failures: 2, Cooldown: time.Hour}
+	pc := NewProductClient(srv.URL, cfg)
+	for i := 0; i < 2; i++ { _, _ = pc.Get(context.Background(), "p1") }
+	if _, err := pc.Get(context.Background(), "p1"); !errors.Is(err, resilience.ErrCircuitOpen) { t.Fatalf("err=%v", err) }
+	if calls.Load() != 2 { t.Fatalf("calls=%d, want 2", calls.Load()) }
+}
+
+func TestProductClientUsesCallerDeadline(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-r.Context().Done():
+		case <-time.After(2 * time.Second):
+		}
+	}))
+	defer srv.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond); defer cancel()
+	start := time.Now()
+	_, err := NewProductClient(srv.URL, fastConfig()).Get(ctx, "p1")
+	if !errors.Is(err, context.DeadlineExceeded) { t.Fatalf("err=%v", err) }
+	if time.Since(start) > time.Second { t.Fatal("client ignored the caller deadline") }
+}
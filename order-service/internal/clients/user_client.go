@@ -2,27 +2,139 @@ package clients
 
 import (
 	"context"
-	"time"
+	"errors"
+	"fmt"
 
+	"go.opentelemetry.io/contrib/instrumentation/google.golang.org/grpc/otelgrpc"
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
 	userpb "github.com/huntercenter1/backend-test/proto"
+
+	"github.com/huntercenter1/backend-test/platform/logging"
+
+	"github.com/huntercenter1/backend-test/order-service/internal/metrics"
+	"github.com/huntercenter1/backend-test/order-service/internal/resilience"
 )
 
+// ErrUserNotFound: user-service no conoce el usuario.
+var ErrUserNotFound = errors.New("user not found")
+
+// User son los datos de user-service que necesita order-service (p. ej.
+// para la factura).
+type User struct {
+	ID       string `json:"id"`
+	Username string `json:"username"`
+	Email    string `json:"email"`
+}
+
 type UserClient interface {
 	Validate(ctx context.Context, userID string) (bool, error)
+	// Get es de sólo lectura y se reintenta como Validate.
+	Get(ctx context.Context, userID string) (*User, error)
+	// Ping consulta grpc.health.v1 de user-service.
+	Ping(ctx context.Context) error
 }
 
-type userClient struct{ cc userpb.UserServiceClient }
+type userClient struct {
+	cc     userpb.UserServiceClient
+	hc     healthpb.HealthClient
+	cfg    Config
+	policy resilience.Policy
+}
 
-func NewUserClient(addr string) (UserClient, func() error, error) {
-	conn, err := grpc.Dial(addr, grpc.WithInsecure())
+func NewUserClient(addr string, cfg Config) (UserClient, func() error, error) {
+	conn, err := grpc.NewClient(addr, userDialOptions(cfg)...)
 	if err != nil { return nil, nil, err }
-	return &userClient{cc: userpb.NewUserServiceClient(conn)}, conn.Close, nil
+	return newUserClient(conn, cfg), conn.Close, nil
+}
+
+// userDialOptions son las opciones de la conexión con user-service; los
+// tests las usan sobre bufconn.
+func userDialOptions(cfg Config) []grpc.DialOption {
+	return []grpc.DialOption{
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+		grpc.WithStatsHandler(otelgrpc.NewClientHandler()),
+		grpc.WithChainUnaryInterceptor(forwardRequestID, sendAPIKey(cfg.APIKey), metrics.UnaryClientInterceptor("user")),
+	}
+}
+
+func newUserClient(conn grpc.ClientConnInterface, cfg Config) *userClient {
+	cfg = cfg.withDefaults()
+	return &userClient{
+		cc:     userpb.NewUserServiceClient(conn),
+		hc:     healthpb.NewHealthClient(conn),
+		cfg:    cfg,
+		policy: cfg.policy("user"),
+	}
 }
 
+// Validate es de sólo lectura y se reintenta ante Unavailable. El deadline
+// del contexto viaja a user-service en la cabecera grpc-timeout.
 func (c *userClient) Validate(ctx context.Context, userID string) (bool, error) {
-	ctx, cancel := context.WithTimeout(ctx, 5*time.Second); defer cancel()
-	resp, err := c.cc.ValidateUser(ctx, &userpb.ValidateUserRequest{UserId: userID})
-	if err != nil { return false, err }
-	return resp.GetValid(), nil
+	ctx, cancel := resilience.WithDefaultTimeout(ctx, c.cfg.Timeout); defer cancel()
+	var valid bool
+	err := c.policy.Do(ctx, func(ctx context.Context) error {
+		resp, err := c.cc.ValidateUser(ctx, &userpb.ValidateUserRequest{UserId: userID})
+		if err != nil { return err }
+		valid = resp.GetValid()
+		return nil
+	}, classifyGRPC)
+	return valid, err
+}
+
+func (c *userClient) Get(ctx context.Context, userID string) (*User, error) {
+	ctx, cancel := resilience.WithDefaultTimeout(ctx, c.cfg.Timeout); defer cancel()
+	var u *User
+	err := c.policy.Do(ctx, func(ctx context.Context) error {
+		resp, err := c.cc.GetUser(ctx, &userpb.GetUserRequest{Id: userID})
+		if err != nil { return err }
+		u = &User{ID: resp.GetId(), Username: resp.GetUsername(), Email: resp.GetEmail()}
+		return nil
+	}, classifyGRPC)
+	if status.Code(err) == codes.NotFound { return nil, ErrUserNotFound }
+	return u, err
+}
+
+// Ping no usa reintentos ni breaker: readyz debe ver el estado real.
+func (c *userClient) Ping(ctx context.Context) error {
+	ctx, cancel := resilience.WithDefaultTimeout(ctx, c.cfg.Timeout); defer cancel()
+	resp, err := c.hc.Check(ctx, &healthpb.HealthCheckRequest{Service: userpb.UserService_ServiceDesc.ServiceName})
+	if err != nil { return err }
+	if resp.GetStatus() != healthpb.HealthCheckResponse_SERVING { return fmt.Errorf("user-service %s", resp.GetStatus()) }
+	return nil
+}
+
+// forwardRequestID manda el id de correlación como metadata x-request-id.
+func forwardRequestID(ctx context.Context, method string, req, reply any, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+	if id := logging.RequestID(ctx); id != "" { ctx = metadata.AppendToOutgoingContext(ctx, "x-request-id", id) }
+	return invoker(ctx, method, req, reply, cc, opts...)
+}
+
+// sendAPIKey manda la clave de order-service como metadata x-api-key, para
+// que el rate limit de user-service no lo cuente por la IP del pod; vacía no
+// se manda.
+func sendAPIKey(key string) grpc.UnaryClientInterceptor {
+	return func(ctx context.Context, method string, req, reply any, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+		if key != "" { ctx = metadata.AppendToOutgoingContext(ctx, "x-api-key", key) }
+		return invoker(ctx, method, req, reply, cc, opts...)
+	}
+}
+
+// classifyGRPC: Unavailable es transitorio y se reintenta; Internal, Unknown
+// y DeadlineExceeded cuentan como fallo de user-service sin reintento.
+func classifyGRPC(err error) resilience.Outcome {
+	if out, ok := ctxFailure(err); ok { return out }
+	switch status.Code(err) {
+	case codes.Unavailable:
+		return resilience.Outcome{Failed: true, Retryable: true}
+	case codes.ResourceExhausted:
+		return resilience.Outcome{Retryable: true}
+	case codes.Internal, codes.Unknown, codes.DeadlineExceeded:
+		return resilience.Outcome{Failed: true}
+	}
+	return resilience.Outcome{}
 }
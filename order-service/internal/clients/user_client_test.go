@@ -0,0 +1,124 @@
+package clients
+
+import (
+	"context"
+	"errors"
+	"net"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+
+	userpb "github.com/huntercenter1/backend-test/proto"
+	"github.com/huntercenter1/backend-test/order-service/internal/resilience"
+)
+
+type fakeUserServer struct {
+	userpb.UnimplementedUserServiceServer
+	calls    atomic.Int32
+	failures int32 // primeras llamadas que devuelven Unavailable
+	code     codes.Code
+	deadline atomic.Int64 // ns restantes vistos por el servidor
+	apiKey   atomic.Value // x-api-key de la última llamada
+}
+
+func (f *fakeUserServer) ValidateUser(ctx context.Context, req *userpb.ValidateUserRequest) (*userpb.ValidateUserResponse, error) {
+	n := f.calls.Add(1)
+	if dl, ok := ctx.Deadline(); ok { f.deadline.Store(int64(time.Until(dl))) }
+	md, _ := metadata.FromIncomingContext(ctx)
+	f.apiKey.Store(strings.Join(md.Get("x-api-key"), ","))
+	if n <= f.failures { return nil, status.Error(f.code, "boom") }
+	return &userpb.ValidateUserResponse{Valid: req.GetUserId() == "u1"}, nil
+}
+
+func (f *fakeUserServer) GetUser(ctx context.Context, req *userpb.GetUserRequest) (*userpb.User, error) {
+	n := f.calls.Add(1)
+	if n <= f.failures { return nil, status.Error(f.code, "boom") }
+	if req.GetId() != "u1" { return nil, status.Error(codes.NotFound, "user not found") }
+	return &userpb.User{Id: "u1", Username: "demo", Email: "demo@example.com"}, nil
+}
+
+func bufUserClient(t *testing.T, srv *fakeUserServer, cfg Config) *userClient {
+	t.Helper()
+	lis := bufconn.Listen(1 << 20)
+	s := grpc.NewServer()
+	userpb.RegisterUserServiceServer(s, srv)
+	go func() { _ = s.Serve(lis) }()
+	t.Cleanup(s.Stop)
+
+	conn, err := grpc.NewClient("passthrough:///bufnet", append(userDialOptions(cfg),
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) { return lis.DialContext(ctx) }),
+	)...)
+	if err != nil { t.Fatal(err) }
+	t.Cleanup(func() { conn.Close() })
+	return newUserClient(conn, cfg)
+}
+
+func TestUserClientRetriesUnavailable(t *testing.T) {
+	srv := &fakeUserServer{failures: 2, code: codes.Unavailable}
+	ok, err := bufUserClient(t, srv, fastConfig()).Validate(context.Background(), "u1")
+	if err != nil || !ok { t.Fatalf("ok=%v err=%v", ok, err) }
+	if srv.calls.Load() != 3 { t.Fatalf("calls=%d, want 3", srv.calls.Load()) }
+}
+
+func TestUserClientGet(t *testing.T) {
+	srv := &fakeUserServer{failures: 1, code: codes.Unavailable}
+	uc := bufUserClient(t, srv, fastConfig())
+	u, err := uc.Get(context.Background(), "u1")
+	if err != nil || u.Username != "demo" || u.Email != "demo@example.com" { t.Fatalf("user=%+v err=%v", u, err) }
+	if srv.calls.Load() != 2 { t.Fatalf("calls=%d, want 2", srv.calls.Load()) }
+
+	if _, err := uc.Get(context.Background(), "u2"); !errors.Is(err, ErrUserNotFound) { t.Fatalf("err=%v", err) }
+}
+
+func TestUserClientDoesNotRetryInvalidArgument(t *testing.T) {
+	srv := &fakeUserServer{failures: 5, code: codes.InvalidArgument}
+	_, err := bufUserClient(t, srv, fastConfig()).Validate(context.Background(), "u1")
+	if status.Code(err) != codes.InvalidArgument { t.Fatalf("err=%v", err) }
+	if srv.calls.Load() != 1 { t.Fatalf("calls=%d, want 1", srv.calls.Load()) }
+}
+
+func TestUserClientCircuitOpens(t *testing.T) {
+	srv := &fakeUserServer{failures: 100, code: codes.Internal}
+	cfg := fastConfig()
+	cfg.Breaker = resilience.BreakerConfig{Failures: 3, Cooldown: time.Hour}
+	uc := bufUserClient(t, srv, cfg)
+	for i := 0; i < 3; i++ { _, _ = uc.Validate(context.Background(), "u1") }
+	if _, err := uc.Validate(context.Background(), "u1"); !errors.Is(err, resilience.ErrCircuitOpen) { t.Fatalf("err=%v", err) }
+	if srv.calls.Load() != 3 { t.Fatalf("calls=%d, want 3", srv.calls.Load()) }
+}
+
+func TestUserClientPropagatesDeadline(t *testing.T) {
+	srv := &fakeUserServer{}
+	uc := bufUserClient(t, srv, fastConfig())
+
+	ctx, cancel := context.WithTimeout(context.Background(), 300*time.Millisecond); defer cancel()
+	if _, err := uc.Validate(ctx, "u1"); err != nil { t.Fatal(err) }
+	if got := time.Duration(srv.deadline.Load()); got <= 0 || got > 300*time.Millisecond {
+		t.Fatalf("server saw deadline %v, want the caller's (<=300ms)", got)
+	}
+
+	// sin deadline del llamador se aplica Config.Timeout
+	if _, err := uc.Validate(context.Background(), "u1"); err != nil { t.Fatal(err) }
+	if got := time.Duration(srv.deadline.Load()); got <= time.Second || got > DefaultConfig().Timeout {
+		t.Fatalf("server saw deadline %v, want ~%v", got, DefaultConfig().Timeout)
+	}
+}
+
+func TestUserClientSendsAPIKey(t *testing.T) {
+	srv := &fakeUserServer{}
+	cfg := DefaultConfig()
+	cfg.APIKey = "uk1"
+	if _, err := bufUserClient(t, srv, cfg).Validate(context.Background(), "u1"); err != nil { t.Fatal(err) }
+	if got := srv.apiKey.Load(); got != "uk1" { t.Fatalf("x-api-key=%v", got) }
+
+	// sin clave no se manda la cabecera
+	if _, err := bufUserClient(t, srv, DefaultConfig()).Validate(context.Background(), "u1"); err != nil { t.Fatal(err) }
+	if got := srv.apiKey.Load(); got != "" { t.Fatalf("x-api-key=%v", got) }
+}
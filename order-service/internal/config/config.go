@@ -0,0 +1,203 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net/url"
+	"time"
+
+	platformcfg "github.com/huntercenter1/backend-test/platform/config"
+	"github.com/huntercenter1/backend-test/platform/db"
+	"github.com/huntercenter1/backend-test/platform/logging"
+	"github.com/huntercenter1/backend-test/platform/migrate"
+	"github.com/huntercenter1/backend-test/platform/ratelimit"
+
+	"github.com/huntercenter1/backend-test/order-service/internal/clients"
+	"github.com/huntercenter1/backend-test/order-service/internal/resilience"
+)
+
+type Config struct {
+	Env string `key:"env" env:"APP_ENV"`
+
+	HTTP struct {
+		Addr            string        `key:"addr" env:"APP_PORT"`
+		RequestTimeout  time.Duration `key:"request_timeout" env:"HTTP_REQUEST_TIMEOUT"`
+		ShutdownTimeout time.Duration `key:"shutdown_timeout" env:"HTTP_SHUTDOWN_TIMEOUT"`
+		// TrustedProxies son las IPs o CIDR de los proxies delante del
+		// servicio, separadas por comas: sólo desde ellos vale X-Forwarded-For
+		// para saber la IP del cliente (rate limit, logs). Vacío = ninguno.
+		TrustedProxies string `key:"trusted_proxies" env:"HTTP_TRUSTED_PROXIES"`
+	} `key:"http"`
+
+	DB db.Config `key:"db"`
+
+	Migrations migrate.Config `key:"migrations"`
+
+	Log logging.Config `key:"log"`
+
+	RateLimit ratelimit.Config `key:"rate_limit"`
+
+	User struct {
+		Addr string `key:"addr" env:"USER_GRPC_ADDR"`
+		// APIKey identifica a order-service ante user-service, que le aplica
+		// RATE_LIMIT_INTERNAL si la clave está en su RATE_LIMIT_CLIENTS.
+		APIKey string `key:"api_key" env:"USER_API_KEY" secret:"true"`
+		Client Client `key:"client" env:"USER_CLIENT_"`
+	} `key:"user_service"`
+
+	Product struct {
+		BaseURL string `key:"base_url" env:"PRODUCT_BASE_URL"`
+		// APIKey identifica a order-service ante product-service, que no le
+		// aplica su rate limit si la clave está en su RATE_LIMIT_CLIENTS.
+		APIKey string `key:"api_key" env:"PRODUCT_API_KEY" secret:"true"`
+		Client Client `key:"client" env:"PRODUCT_CLIENT_"`
+	} `key:"product_service"`
+
+	Payments struct {
+		Provider string `key:"provider" env:"PAYMENTS_PROVIDER"` // de momento sólo "fake"
+		Currency string `key:"currency" env:"PAYMENTS_CURRENCY"`
+		// WebhookSecret firma los webhooks del proveedor. Fuera de local es
+		// obligatorio; en local, sin él, cada proceso genera uno aleatorio y
+		// sólo acepta los webhooks que firma él mismo.
+		WebhookSecret    string        `key:"webhook_secret" env:"PAYMENTS_WEBHOOK_SECRET" secret:"true"`
+		WebhookTolerance time.Duration `key:"webhook_tolerance" env:"PAYMENTS_WEBHOOK_TOLERANCE"`
+		// FakeWebhookURL es adonde envía sus eventos el proveedor fake; vacío = no los envía.
+		FakeWebhookURL string `key:"fake_webhook_url" env:"PAYMENTS_FAKE_WEBHOOK_URL"`
+	} `key:"payments"`
+
+	// Invoices son los datos del emisor que salen en las facturas; la moneda
+	// es la de payments.
+	Invoices struct {
+		IssuerName    string `key:"issuer_name" env:"INVOICES_ISSUER_NAME"`
+		IssuerTaxID   string `key:"issuer_tax_id" env:"INVOICES_ISSUER_TAX_ID"`
+		IssuerAddress string `key:"issuer_address" env:"INVOICES_ISSUER_ADDRESS"`
+	} `key:"invoices"`
+
+	// Expiry es el worker que caduca los pedidos sin pagar (pending o
+	// payment_failed) y repone su stock; puede ir activado en todas las
+	// réplicas.
+	Expiry struct {
+		Enabled   bool          `key:"enabled" env:"ORDER_EXPIRY_ENABLED"`
+		TTL       time.Duration `key:"ttl" env:"ORDER_EXPIRY_TTL"`
+		Interval  time.Duration `key:"interval" env:"ORDER_EXPIRY_INTERVAL"`
+		BatchSize int           `key:"batch_size" env:"ORDER_EXPIRY_BATCH_SIZE"`
+	} `key:"order_expiry"`
+
+	// Reports: zona en la que se cortan los días de los informes si la
+	// petición no trae tz.
+	Reports struct {
+		Timezone string `key:"timezone" env:"REPORTS_TIMEZONE"`
+	} `key:"reports"`
+}
+
+// Client es la resiliencia de un cliente a otro servicio (ver clients.Config).
+type Client struct {
+	Timeout          time.Duration `key:"timeout" env:"TIMEOUT"`
+	MaxAttempts      int           `key:"max_attempts" env:"MAX_ATTEMPTS"`
+	RetryBaseDelay   time.Duration `key:"retry_base_delay" env:"RETRY_BASE_DELAY"`
+	RetryMaxDelay    time.Duration `key:"retry_max_delay" env:"RETRY_MAX_DELAY"`
+	BreakerFailures  int           `key:"breaker_failures" env:"BREAKER_FAILURES"`
+	BreakerCooldown  time.Duration `key:"breaker_cooldown" env:"BREAKER_COOLDOWN"`
+	RetryBudgetRatio float64       `key:"retry_budget_ratio" env:"RETRY_BUDGET_RATIO"`
+}
+
+func (c Client) Resilience() clients.Config {
+	return clients.Config{
+		Timeout:          c.Timeout,
+		Retry:            resilience.RetryConfig{MaxAttempts: c.MaxAttempts, BaseDelay: c.RetryBaseDelay, MaxDelay: c.RetryMaxDelay},
+		Breaker:          resilience.BreakerConfig{Failures: c.BreakerFailures, Cooldown: c.BreakerCooldown},
+		RetryBudgetRatio: c.RetryBudgetRatio,
+	}
+}
+
+func defaultClient() Client {
+	d := clients.DefaultConfig()
+	return Client{
+		Timeout: d.Timeout, MaxAttempts: d.Retry.MaxAttempts, RetryBaseDelay: d.Retry.BaseDelay, RetryMaxDelay: d.Retry.MaxDelay,
+		BreakerFailures: d.Breaker.Failures, BreakerCooldown: d.Breaker.Cooldown, RetryBudgetRatio: d.RetryBudgetRatio,
+	}
+}
+
+func Default() Config {
+	var c Config
+	c.Env = "local"
+	c.HTTP.Addr = ":8082"
+	c.HTTP.RequestTimeout = 10 * time.Second
+	c.HTTP.ShutdownTimeout = 10 * time.Second
+	c.DB = db.DefaultConfig()
+	c.Migrations = migrate.DefaultConfig()
+	c.Log.Level = "info"
+	c.RateLimit = ratelimit.Config{Enabled: true, Default: "300/m", Routes: "POST /orders=30/m,POST /carts/:id/checkout=30/m,POST /payments/webhook=off"}
+	c.User.Addr = "user-service:50051"
+	c.User.Client = defaultClient()
+	c.Product.BaseURL = "http://product-service:8081"
+	c.Product.Client = defaultClient()
+	c.Payments.Provider = "fake"
+	c.Payments.Currency = "EUR"
+	c.Payments.WebhookTolerance = 5 * time.Minute
+	c.Payments.FakeWebhookURL = "http://localhost:8082/payments/webhook"
+	c.Invoices.IssuerName = "Backend Test Store"
+	c.Expiry.Enabled = true
+	c.Expiry.TTL = 30 * time.Minute
+	c.Expiry.Interval = time.Minute
+	c.Expiry.BatchSize = 100
+	c.Reports.Timezone = "UTC"
+	return c
+}
+
+// Load parte de Default y aplica fichero, entorno y flags; después valida.
+func Load(args []string) (Config, bool, error) {
+	c := Default()
+	printOnly, err := platformcfg.Load(&c, args)
+	if err != nil { return c, false, err }
+	return c, printOnly, c.Validate()
+}
+
+// Print escribe la configuración efectiva con los secretos ocultos.
+func (c Config) Print(w io.Writer) { platformcfg.Print(w, &c) }
+
+func (c Config) Validate() error {
+	errs := []error{c.DB.Validate(), c.Migrations.Validate(), c.Log.Validate(), c.RateLimit.Validate()}
+	if c.HTTP.Addr == "" { errs = append(errs, errors.New("http.addr is required (env APP_PORT)")) }
+	if c.HTTP.RequestTimeout <= 0 { errs = append(errs, errors.New("http.request_timeout must be > 0")) }
+	if c.HTTP.ShutdownTimeout <= 0 { errs = append(errs, errors.New("http.shutdown_timeout must be > 0")) }
+	errs = append(errs, platformcfg.ValidateProxies("http.trusted_proxies", c.HTTP.TrustedProxies))
+	if c.User.Addr == "" { errs = append(errs, errors.New("user_service.addr is required (env USER_GRPC_ADDR)")) }
+	if u, err := url.Parse(c.Product.BaseURL); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		errs = append(errs, fmt.Errorf("product_service.base_url must be an http(s) URL, got %q", c.Product.BaseURL))
+	}
+	if c.Payments.Provider != "fake" { errs = append(errs, fmt.Errorf("payments.provider must be fake, got %q", c.Payments.Provider)) }
+	// con varias réplicas un secreto aleatorio por proceso rechaza los
+	// webhooks que llegan a otra réplica; fuera de local no se adivina
+	if c.Payments.WebhookSecret == "" && (c.Env != "local" || c.Payments.Provider != "fake") {
+		errs = append(errs, fmt.Errorf("payments.webhook_secret is required with env %q (env PAYMENTS_WEBHOOK_SECRET)", c.Env))
+	}
+	if len(c.Payments.Currency) != 3 { errs = append(errs, errors.New("payments.currency must be an ISO 4217 code (EUR, USD...)")) }
+	if c.Payments.WebhookTolerance <= 0 { errs = append(errs, errors.New("payments.webhook_tolerance must be > 0")) }
+	if c.Invoices.IssuerName == "" { errs = append(errs, errors.New("invoices.issuer_name is required (env INVOICES_ISSUER_NAME)")) }
+	if c.Expiry.Enabled {
+		if c.Expiry.TTL <= 0 { errs = append(errs, errors.New("order_expiry.ttl must be > 0")) }
+		if c.Expiry.Interval <= 0 { errs = append(errs, errors.New("order_expiry.interval must be > 0")) }
+		if c.Expiry.BatchSize < 1 { errs = append(errs, errors.New("order_expiry.batch_size must be >= 1")) }
+	}
+	if _, err := time.LoadLocation(c.Reports.Timezone); err != nil || c.Reports.Timezone == "" || c.Reports.Timezone == "Local" {
+		errs = append(errs, fmt.Errorf("reports.timezone must be an IANA time zone (Europe/Madrid...), got %q", c.Reports.Timezone))
+	}
+	errs = append(errs, c.User.Client.validate("user_service.client")...)
+	errs = append(errs, c.Product.Client.validate("product_service.client")...)
+	return errors.Join(errs...)
+}
+
+func (c Client) validate(key string) []error {
+	var errs []error
+	if c.Timeout <= 0 { errs = append(errs, fmt.Errorf("%s.timeout must be > 0", key)) }
+	if c.MaxAttempts < 1 { errs = append(errs, fmt.Errorf("%s.max_attempts must be >= 1", key)) }
+	if c.RetryBaseDelay <= 0 || c.RetryMaxDelay < c.RetryBaseDelay {
+		errs = append(errs, fmt.Errorf("%s: retry_base_delay must be > 0 and <= retry_max_delay", key))
+	}
+	if c.BreakerFailures < 1 { errs = append(errs, fmt.Errorf("%s.breaker_failures must be >= 1", key)) }
+	if c.BreakerCooldown <= 0 { errs = append(errs, fmt.Errorf("%s.breaker_cooldown must be > 0", key)) }
+	if c.RetryBudgetRatio <= 0 || c.RetryBudgetRatio > 1 { errs = append(errs, fmt.Errorf("%s.retry_budget_ratio must be in (0, 1]", key)) }
+	return errs
+}
@@ -0,0 +1,62 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func writeFile(t *testing.T, name, data string) string {
+	t.Helper()
+	p := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(p, []byte(data), 0o600); err != nil { t.Fatal(err) }
+	return p
+}
+
+func TestLoadClientSettings(t *testing.T) {
+	file := writeFile(t, "order.yaml", `
+db:
+  dsn: postgres://u:p@db/orders_db
+product_service:
+  base_url: http://localhost:8081
+  client:
+    timeout: 2s
+    max_attempts: 5
+`)
+	t.Setenv("PRODUCT_CLIENT_MAX_ATTEMPTS", "4")
+	t.Setenv("USER_CLIENT_BREAKER_FAILURES", "9")
+	cfg, _, err := Load([]string{"-config", file, "-user_service.addr", "localhost:50051"})
+	if err != nil { t.Fatal(err) }
+
+	pc := cfg.Product.Client.Resilience()
+	switch {
+	case cfg.Product.BaseURL != "http://localhost:8081" || cfg.User.Addr != "localhost:50051":
+		t.Fatalf("downstream addresses: %+v %+v", cfg.Product, cfg.User)
+	case pc.Timeout != 2*time.Second || pc.Retry.MaxAttempts != 4:
+		t.Fatalf("product client: %+v", pc)
+	case cfg.User.Client.BreakerFailures != 9 || cfg.User.Client.MaxAttempts != 3:
+		t.Fatalf("user client: %+v", cfg.User.Client)
+	}
+}
+
+func TestValidateDownstreams(t *testing.T) {
+	t.Setenv("DB_DSN", "postgres://u:p@db/orders_db")
+	t.Setenv("PRODUCT_BASE_URL", "product-service:8081")
+	t.Setenv("USER_CLIENT_RETRY_BUDGET_RATIO", "2")
+	_, _, err := Load(nil)
+	for _, want := range []string{`product_service.base_url must be an http(s) URL`, `user_service.client.retry_budget_ratio must be in (0, 1]`} {
+		if err == nil || !strings.Contains(err.Error(), want) { t.Fatalf("error %v does not mention %q", err, want) }
+	}
+}
+
+func TestWebhookSecretRequiredOutsideLocal(t *testing.T) {
+	t.Setenv("DB_DSN", "postgres://u:p@db/orders_db")
+	if _, _, err := Load(nil); err != nil { t.Fatalf("local without secret: %v", err) }
+	t.Setenv("APP_ENV", "production")
+	_, _, err := Load(nil)
+	if err == nil || !strings.Contains(err.Error(), "payments.webhook_secret is required") { t.Fatalf("err=%v", err) }
+	t.Setenv("PAYMENTS_WEBHOOK_SECRET", "whsec_prod")
+	if _, _, err := Load(nil); err != nil { t.Fatal(err) }
+}
@@ -0,0 +1,88 @@
+// Package invoice compone el documento de una factura a partir del pedido y
+// lo pinta como HTML o PDF con las plantillas de templates/. No accede a la
+// base ni a otros servicios: los datos del cliente y del emisor llegan ya
+// resueltos.
+package invoice
+
+import (
+	"sort"
+	"time"
+
+	"github.com/huntercenter1/backend-test/order-service/internal/models"
+)
+
+// Party es el emisor o el cliente de la factura.
+type Party struct {
+	Name    string
+	TaxID   string
+	Address string
+	Email   string
+}
+
+// Line es una línea facturada; Amount es la base imponible (precio por
+// cantidad menos el descuento).
+type Line struct {
+	Description string
+	SKU         string
+	Quantity    int
+	UnitPrice   float64
+	Discount    float64
+	Amount      float64
+	TaxRate     float64
+	Tax         float64
+	Gift        bool
+}
+
+// TaxLine es el desglose de impuestos de un tipo.
+type TaxLine struct {
+	Rate float64
+	Base float64
+	Tax  float64
+}
+
+type Document struct {
+	Number        string
+	IssuedAt      time.Time
+	OrderID       string
+	Currency      string
+	Issuer        Party
+	Customer      Party
+	Lines         []Line
+	Taxes         []TaxLine // un tipo por fila, de menor a mayor
+	Subtotal      float64
+	DiscountTotal float64
+	TaxTotal      float64
+	ShippingTotal float64
+	Total         float64
+}
+
+// Build arma la factura de o, que debe traer sus líneas (repo.GetOrder).
+// Los importes salen del pedido tal como se cobró; las líneas de pedidos
+// anteriores a las copias del producto se describen con su product_id.
+func Build(inv models.Invoice, o models.Order, issuer, customer Party, currency string) Document {
+	d := Document{
+		Number: inv.Number, IssuedAt: inv.IssuedAt, OrderID: o.ID, Currency: currency,
+		Issuer: issuer, Customer: customer,
+		Subtotal: o.Subtotal, DiscountTotal: o.DiscountTotal, TaxTotal: o.TaxTotal, ShippingTotal: o.ShippingTotal, Total: o.Total,
+	}
+	type sums struct{ base, tax int64 }
+	byRate := map[float64]*sums{}
+	for _, it := range o.Items {
+		desc := it.Name
+		if desc == "" { desc = it.ProductID }
+		base := models.Cents(it.Price)*int64(it.Quantity) - models.Cents(it.Discount)
+		d.Lines = append(d.Lines, Line{
+			Description: desc, SKU: it.SKU, Quantity: it.Quantity, UnitPrice: it.Price, Discount: it.Discount,
+			Amount: float64(base) / 100, TaxRate: it.TaxRate, Tax: it.Tax, Gift: it.Gift,
+		})
+		s := byRate[it.TaxRate]
+		if s == nil { s = &sums{}; byRate[it.TaxRate] = s }
+		s.base += base
+		s.tax += models.Cents(it.Tax)
+	}
+	for rate, s := range byRate {
+		d.Taxes = append(d.Taxes, TaxLine{Rate: rate, Base: float64(s.base) / 100, Tax: float64(s.tax) / 100})
+	}
+	sort.Slice(d.Taxes, func(i, j int) bool { return d.Taxes[i].Rate < d.Taxes[j].Rate })
+	return d
+}
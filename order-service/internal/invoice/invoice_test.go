@@ -0,0 +1,68 @@
+package invoice
+
+import (
+	"bytes"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/huntercenter1/backend-test/order-service/internal/models"
+)
+
+func sampleDocument() Document {
+	o := models.Order{
+		ID: "o1", Subtotal: 145, DiscountTotal: 10, TaxTotal: 24.2, ShippingTotal: 4.95, Total: 164.15,
+		Items: []models.OrderItem{
+			{ProductID: "p1", Quantity: 1, Price: 100, Discount: 10, TaxRate: 21, Tax: 18.9, ProductSnapshot: models.ProductSnapshot{Name: "Portátil <14\">", SKU: "LP-14"}},
+			{ProductID: "p2", Quantity: 3, Price: 10, TaxRate: 10, Tax: 3},
+			{ProductID: "p3", Quantity: 1, Price: 15, TaxRate: 21, Tax: 2.3, ProductSnapshot: models.ProductSnapshot{Name: "Funda (gris)"}},
+		},
+	}
+	inv := models.Invoice{Number: models.InvoiceNumber(2025, 42), IssuedAt: time.Date(2025, 8, 9, 10, 0, 0, 0, time.UTC)}
+	return Build(inv, o, Party{Name: "Tienda S.L.", TaxID: "B12345678"}, Party{Name: "demo", Email: "demo@example.com"}, "EUR")
+}
+
+func TestBuildGroupsTaxesByRate(t *testing.T) {
+	d := sampleDocument()
+	if d.Number != "2025-000042" || len(d.Lines) != 3 { t.Fatalf("doc=%+v", d) }
+	if d.Lines[0].Amount != 90 || d.Lines[1].Description != "p2" { t.Fatalf("lines=%+v", d.Lines) }
+	want := []TaxLine{{Rate: 10, Base: 30, Tax: 3}, {Rate: 21, Base: 105, Tax: 21.2}}
+	if fmt.Sprint(d.Taxes) != fmt.Sprint(want) { t.Fatalf("taxes=%v want %v", d.Taxes, want) }
+}
+
+func TestHTMLEscapesAndShowsTotals(t *testing.T) {
+	var buf bytes.Buffer
+	if err := HTML(&buf, sampleDocument()); err != nil { t.Fatal(err) }
+	out := buf.String()
+	for _, s := range []string{"Factura 2025-000042", "Portátil &lt;14&#34;&gt;", "SKU LP-14", "demo@example.com", "B12345678", "Impuestos 21% s/ 105.00", "164.15"} {
+		if !strings.Contains(out, s) { t.Errorf("html missing %q", s) }
+	}
+}
+
+func TestPDFIsWellFormed(t *testing.T) {
+	var buf bytes.Buffer
+	if err := PDF(&buf, sampleDocument()); err != nil { t.Fatal(err) }
+	out := buf.Bytes()
+	if !bytes.HasPrefix(out, []byte("%PDF-1.4\n")) || !bytes.HasSuffix(out, []byte("%%EOF\n")) { t.Fatalf("not a pdf:\n%s", out) }
+	for _, s := range []string{"(FACTURA 2025-000042)", "Port\xe1til", "Funda \\(gris\\)", "LP-14", "TOTAL EUR"} {
+		if !bytes.Contains(out, []byte(s)) { t.Errorf("pdf missing %q", s) }
+	}
+	// cada entrada de la tabla xref apunta al comienzo de su objeto
+	m := regexp.MustCompile(`startxref\n(\d+)`).FindSubmatch(out)
+	xref, _ := strconv.Atoi(string(m[1]))
+	entries := regexp.MustCompile(`(\d{10}) 00000 n `).FindAllSubmatch(out[xref:], -1)
+	for i, e := range entries {
+		off, _ := strconv.Atoi(string(e[1]))
+		if want := fmt.Sprintf("%d 0 obj", i+1); !bytes.HasPrefix(out[off:], []byte(want)) { t.Fatalf("xref %d points to %q", i+1, out[off:off+10]) }
+	}
+}
+
+func TestPDFPaginates(t *testing.T) {
+	lines := make([]string, 2*linesPerPage+1)
+	var buf bytes.Buffer
+	if err := writePDF(&buf, "x", lines); err != nil { t.Fatal(err) }
+	if !bytes.Contains(buf.Bytes(), []byte("/Count 3")) { t.Fatalf("want 3 pages") }
+}
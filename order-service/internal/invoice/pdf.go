@@ -0,0 +1,85 @@
+package invoice
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// Página A4 en puntos y texto en Courier 10 con interlineado de 12.
+const (
+	pageWidth    = 595
+	pageHeight   = 842
+	margin       = 40
+	fontSize     = 10
+	leading      = 12
+	linesPerPage = (pageHeight - 2*margin) / leading
+)
+
+// writePDF escribe un PDF 1.4 mínimo con lines en Courier, paginando cada
+// linesPerPage. Courier es una de las fuentes estándar de PDF, así que no
+// hace falta incrustarla; el texto va en WinAnsiEncoding.
+func writePDF(w io.Writer, title string, lines []string) error {
+	var pages [][]string
+	for len(lines) > linesPerPage {
+		pages = append(pages, lines[:linesPerPage])
+		lines = lines[linesPerPage:]
+	}
+	pages = append(pages, lines)
+
+	// 1 catálogo, 2 árbol de páginas, 3 fuente, 4 info; después cada página
+	// seguida de su contenido
+	var buf bytes.Buffer
+	offsets := []int{0}
+	obj := func(body string) {
+		offsets = append(offsets, buf.Len())
+		fmt.Fprintf(&buf, "%d 0 obj\n%s\nendobj\n", len(offsets)-1, body)
+	}
+	buf.WriteString("%PDF-1.4\n%\xe2\xe3\xcf\xd3\n") // el comentario binario avisa de que no es texto
+	obj("<< /Type /Catalog /Pages 2 0 R >>")
+	kids := make([]string, len(pages))
+	for i := range pages { kids[i] = fmt.Sprintf("%d 0 R", 5+2*i) }
+	obj(fmt.Sprintf("<< /Type /Pages /Kids [%s] /Count %d >>", strings.Join(kids, " "), len(pages)))
+	obj("<< /Type /Font /Subtype /Type1 /BaseFont /Courier /Encoding /WinAnsiEncoding >>")
+	obj(fmt.Sprintf("<< /Title %s /Producer (order-service) >>", pdfString(title)))
+	for i, page := range pages {
+		obj(fmt.Sprintf("<< /Type /Page /Parent 2 0 R /MediaBox [0 0 %d %d] /Resources << /Font << /F1 3 0 R >> >> /Contents %d 0 R >>",
+			pageWidth, pageHeight, 6+2*i))
+		var content bytes.Buffer
+		fmt.Fprintf(&content, "BT /F1 %d Tf %d TL %d %d Td\n", fontSize, leading, margin, pageHeight-margin-fontSize)
+		for _, l := range page { fmt.Fprintf(&content, "%s Tj T*\n", pdfString(l)) }
+		content.WriteString("ET")
+		obj(fmt.Sprintf("<< /Length %d >>\nstream\n%s\nendstream", content.Len(), content.String()))
+	}
+
+	xref := buf.Len()
+	fmt.Fprintf(&buf, "xref\n0 %d\n0000000000 65535 f \n", len(offsets))
+	for _, off := range offsets[1:] { fmt.Fprintf(&buf, "%010d 00000 n \n", off) }
+	fmt.Fprintf(&buf, "trailer\n<< /Size %d /Root 1 0 R /Info 4 0 R >>\nstartxref\n%d\n%%%%EOF\n", len(offsets), xref)
+	_, err := w.Write(buf.Bytes())
+	return err
+}
+
+// pdfString devuelve s como cadena literal de PDF en WinAnsiEncoding: Latin-1
+// pasa tal cual, el euro tiene su código y lo demás se cambia por '?'.
+func pdfString(s string) string {
+	var b strings.Builder
+	b.WriteByte('(')
+	for _, r := range s {
+		switch {
+		case r == '(' || r == ')' || r == '\\':
+			b.WriteByte('\\'); b.WriteRune(r)
+		case r == '€':
+			b.WriteByte(0x80)
+		case r == '\t':
+			b.WriteByte(' ')
+		case r >= 0x20 && r < 0x7f, r >= 0xa0 && r <= 0xff:
+			b.WriteByte(byte(r))
+		default:
+			b.WriteByte('?')
+		}
+	}
+	b.WriteByte(')')
+	return b.String()
+}
@@ -0,0 +1,45 @@
+package invoice
+
+import (
+	"bytes"
+	"embed"
+	htmltemplate "html/template"
+	"io"
+	"strconv"
+	"strings"
+	"text/template"
+	"time"
+)
+
+//go:embed templates
+var templates embed.FS
+
+// lineWidth son las columnas de invoice.txt; caben en A4 con Courier 10.
+const lineWidth = 84
+
+var funcs = map[string]any{
+	"money": func(v float64) string { return strconv.FormatFloat(v, 'f', 2, 64) },
+	"rate":  func(v float64) string { return strconv.FormatFloat(v, 'f', -1, 64) + "%" },
+	"date":  func(t time.Time) string { return t.Format("2006-01-02") },
+	"rule":  func() string { return strings.Repeat("-", lineWidth) },
+	"gift": func(desc string, gift bool) string {
+		if gift { return desc + " (regalo)" }
+		return desc
+	},
+}
+
+var (
+	htmlTmpl = htmltemplate.Must(htmltemplate.New("invoice.html").Funcs(funcs).ParseFS(templates, "templates/invoice.html"))
+	textTmpl = template.Must(template.New("invoice.txt").Funcs(funcs).ParseFS(templates, "templates/invoice.txt"))
+)
+
+// HTML pinta la factura con templates/invoice.html.
+func HTML(w io.Writer, d Document) error { return htmlTmpl.Execute(w, d) }
+
+// PDF pinta templates/invoice.txt y lo compone en páginas A4 de texto
+// monoespaciado; la plantilla ya trae las columnas alineadas.
+func PDF(w io.Writer, d Document) error {
+	var buf bytes.Buffer
+	if err := textTmpl.Execute(&buf, d); err != nil { return err }
+	return writePDF(w, "Factura "+d.Number, strings.Split(strings.TrimRight(buf.String(), "\n"), "\n"))
+}
@@ -0,0 +1,94 @@
+// Package metrics tiene las métricas propias de order-service (llamadas a
+// otros servicios y pedidos); las de HTTP y del pool de la base están en
+// platform/metrics.
+package metrics
+
+import (
+	"context"
+	"path"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"google.golang.org/grpc"
+)
+
+var (
+	clientRequests = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "client_requests_total",
+		Help: "Llamadas salientes a otros servicios por cliente, operación y resultado.",
+	}, []string{"client", "operation", "outcome"})
+
+	clientDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "client_request_duration_seconds",
+		Help:    "Latencia de las llamadas salientes.",
+		Buckets: prometheus.DefBuckets,
+	}, []string{"client", "operation"})
+
+	clientRetries = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "client_retries_total",
+		Help: "Reintentos de llamadas salientes por cliente.",
+	}, []string{"client"})
+
+	circuitState = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "client_circuit_state",
+		Help: "Estado del circuit breaker por cliente (0 cerrado, 1 abierto, 2 half-open).",
+	}, []string{"client"})
+
+	OrdersCreated = promauto.NewCounter(prometheus.CounterOpts{
+		Name: "orders_created_total",
+		Help: "Pedidos creados.",
+	})
+
+	// OrderValue es el total de cada pedido creado; su _sum es la facturación.
+	OrderValue = promauto.NewHistogram(prometheus.HistogramOpts{
+		Name:    "order_value",
+		Help:    "Importe total de los pedidos creados.",
+		Buckets: prometheus.ExponentialBuckets(5, 2, 12),
+	})
+
+	// OrdersRejected cuenta pedidos rechazados; reason=insufficient_stock
+	// son los eventos de rotura de stock vistos desde pedidos.
+	OrdersRejected = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "orders_rejected_total",
+		Help: "Pedidos rechazados por motivo.",
+	}, []string{"reason"})
+
+	// OrdersExpired cuenta los pedidos pending que caducan sin pagarse.
+	OrdersExpired = promauto.NewCounter(prometheus.CounterOpts{
+		Name: "orders_expired_total",
+		Help: "Pedidos caducados por no pagarse a tiempo.",
+	})
+
+	// PaymentEvents cuenta los webhooks del proveedor de pagos por tipo y
+	// resultado (applied, duplicate, rejected).
+	PaymentEvents = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "payment_events_total",
+		Help: "Webhooks de pagos recibidos por tipo y resultado.",
+	}, []string{"type", "outcome"})
+)
+
+// ObserveClient registra una llamada saliente. failed indica error de red o
+// respuesta 5xx del otro servicio.
+func ObserveClient(client, operation string, start time.Time, failed bool) {
+	outcome := "ok"
+	if failed { outcome = "error" }
+	clientRequests.WithLabelValues(client, operation, outcome).Inc()
+	clientDuration.WithLabelValues(client, operation).Observe(time.Since(start).Seconds())
+}
+
+func ClientRetry(client string) { clientRetries.WithLabelValues(client).Inc() }
+
+// CircuitState publica el estado del breaker (valor de resilience.State).
+func CircuitState(client string, state int) { circuitState.WithLabelValues(client).Set(float64(state)) }
+
+// UnaryClientInterceptor mide las llamadas gRPC salientes; la operación es
+// el nombre del método (ValidateUser).
+func UnaryClientInterceptor(client string) grpc.UnaryClientInterceptor {
+	return func(ctx context.Context, method string, req, reply any, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+		start := time.Now()
+		err := invoker(ctx, method, req, reply, cc, opts...)
+		ObserveClient(client, path.Base(method), start, err != nil)
+		return err
+	}
+}
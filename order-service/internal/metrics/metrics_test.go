@@ -0,0 +1,16 @@
+package metrics
+
+import (
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+func TestObserveClient(t *testing.T) {
+	ObserveClient("product", "get", time.Now(), false)
+	ObserveClient("product", "get", time.Now(), true)
+	if got := testutil.ToFloat64(clientRequests.WithLabelValues("product", "get", "error")); got != 1 {
+		t.Fatalf("errors = %v, want 1", got)
+	}
+}
@@ -0,0 +1,40 @@
+package models
+
+import (
+	"time"
+
+	"github.com/uptrace/bun"
+)
+
+// Estados de un carrito. checking_out bloquea el carrito mientras se crea el
+// pedido para que dos checkouts simultáneos no generen dos pedidos.
+const (
+	CartOpen        = "open"
+	CartCheckingOut = "checking_out"
+	CartCheckedOut  = "checked_out"
+	CartMerged      = "merged"
+)
+
+type Cart struct {
+	bun.BaseModel `bun:"table:carts,alias:c"`
+
+	ID        string     `bun:"id,pk,type:uuid,default:gen_random_uuid()" json:"id"`
+	UserID    *string    `bun:"user_id,type:uuid" json:"user_id"` // nil = carrito de invitado
+	Status    string     `bun:"status,notnull,default:'open'" json:"status"`
+	OrderID   *string    `bun:"order_id,type:uuid" json:"order_id,omitempty"`
+	CreatedAt time.Time  `bun:"created_at,notnull,default:now()" json:"created_at"`
+	UpdatedAt time.Time  `bun:"updated_at,notnull,default:now()" json:"updated_at"`
+	Items     []CartItem `bun:"rel:has-many,join:id=cart_id" json:"items"`
+}
+
+type CartItem struct {
+	bun.BaseModel `bun:"table:cart_items,alias:ci"`
+
+	ID         string    `bun:"id,pk,type:uuid,default:gen_random_uuid()" json:"id"`
+	CartID     string    `bun:"cart_id,notnull" json:"cart_id"`
+	ProductID  string    `bun:"product_id,notnull" json:"product_id"`
+	Quantity   int       `bun:"quantity,notnull" json:"quantity"`
+	AddedPrice float64   `bun:"added_price,notnull" json:"added_price"` // precio cuando se añadió
+	CreatedAt  time.Time `bun:"created_at,notnull,default:now()" json:"created_at"`
+	UpdatedAt  time.Time `bun:"updated_at,notnull,default:now()" json:"updated_at"`
+}
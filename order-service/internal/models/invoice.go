@@ -0,0 +1,28 @@
+package models
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/uptrace/bun"
+)
+
+// Invoice es la factura de un pedido pagado. Se emite una sola vez por
+// pedido, en la transacción que lo deja en paid; Seq es correlativo y sin
+// huecos dentro de cada año.
+type Invoice struct {
+	bun.BaseModel `bun:"table:invoices,alias:inv"`
+
+	ID       string    `bun:"id,pk,type:uuid,default:gen_random_uuid()" json:"id"`
+	OrderID  string    `bun:"order_id,notnull" json:"order_id"`
+	Year     int       `bun:"year,notnull" json:"year"`
+	Seq      int       `bun:"seq,notnull" json:"seq"`
+	Number   string    `bun:"number,notnull" json:"number"` // ver InvoiceNumber
+	UserID   string    `bun:"user_id,notnull" json:"user_id"`
+	TaxTotal float64   `bun:"tax_total,notnull" json:"tax_total"` // copia del pedido al emitirla
+	Total    float64   `bun:"total,notnull" json:"total"`
+	IssuedAt time.Time `bun:"issued_at,notnull,default:now()" json:"issued_at"`
+}
+
+// InvoiceNumber da el número visible de la factura: 2025-000042.
+func InvoiceNumber(year, seq int) string { return fmt.Sprintf("%d-%06d", year, seq) }
@@ -1,28 +1,122 @@
 package models
 
 import (
+	"encoding/json"
 	"time"
 
 	"github.com/uptrace/bun"
 )
 
+// Estados de un pedido. paid, payment_failed y refunded los fija el flujo de
+// pagos (ver SettleOrder); partially_shipped, shipped y delivered, los envíos
+// (ver FulfillOrder); expired, el worker de caducidad; cancelled, a mano con
+// PUT /orders/:id/status (ver ManualStatuses).
+const (
+	OrderPending          = "pending"
+	OrderPaid             = "paid"
+	OrderPaymentFailed    = "payment_failed"
+	OrderRefunded         = "refunded"
+	OrderCancelled        = "cancelled"
+	OrderPartiallyShipped = "partially_shipped"
+	OrderShipped          = "shipped"
+	OrderDelivered        = "delivered"
+	OrderExpired          = "expired" // pending o payment_failed sin pagar pasado el TTL; su stock se repone
+)
+
+// ManualStatuses son los estados que se pueden fijar a mano y desde cuáles:
+// sólo cancelar un pedido que aún no se ha cobrado. El resto los derivan
+// pagos, envíos y el worker de caducidad.
+var ManualStatuses = map[string][]string{
+	OrderCancelled: {OrderPending, OrderPaymentFailed},
+}
+
 type Order struct {
 	bun.BaseModel `bun:"table:orders,alias:o"`
 
-	ID        string    `bun:"id,pk,type:uuid,default:gen_random_uuid()" json:"id"`
-	UserID    string    `bun:"user_id,notnull" json:"user_id"`
-	Status    string    `bun:"status,notnull,default:'pending'" json:"status"`
-	Total     float64   `bun:"total,notnull" json:"total"`
-	CreatedAt time.Time `bun:"created_at,notnull,default:now()" json:"created_at"`
-	UpdatedAt time.Time `bun:"updated_at,notnull,default:now()" json:"updated_at"`
+	ID            string            `bun:"id,pk,type:uuid,default:gen_random_uuid()" json:"id"`
+	UserID        string            `bun:"user_id,notnull" json:"user_id"`
+	Status        string            `bun:"status,notnull,default:'pending'" json:"status"`
+	Region        string            `bun:"region,nullzero" json:"region,omitempty"` // región de envío: decide impuestos y portes
+	CouponCode    string            `bun:"coupon_code,nullzero" json:"coupon_code,omitempty"`
+	Subtotal      float64           `bun:"subtotal,notnull" json:"subtotal"`              // precio de catálogo de las líneas
+	Discounts     []AppliedDiscount `bun:"discounts,type:jsonb,notnull" json:"discounts"` // desglose por promoción
+	DiscountTotal float64           `bun:"discount_total,notnull" json:"discount_total"`
+	TaxTotal      float64           `bun:"tax_total,notnull" json:"tax_total"`
+	ShippingTotal float64           `bun:"shipping_total,notnull" json:"shipping_total"`
+	Total         float64           `bun:"total,notnull" json:"total"`                             // subtotal - discount_total + tax_total + shipping_total
+	RefundedTotal float64           `bun:"refunded_total,notnull,default:0" json:"refunded_total"` // suma de refunds, lo mantienen los pagos
+	CreatedAt     time.Time         `bun:"created_at,notnull,default:now()" json:"created_at"`
+	UpdatedAt     time.Time         `bun:"updated_at,notnull,default:now()" json:"updated_at"`
+
+	// Items sólo se carga al leer un pedido (repo.GetOrder).
+	Items []OrderItem `bun:"rel:has-many,join:id=order_id" json:"items,omitempty"`
+}
+
+// Payable dice si el pedido admite pagos y capturas: sólo mientras está
+// pending o payment_failed. Uno cancelado o caducado ya ha repuesto su stock.
+func (o Order) Payable() bool { return o.Status == OrderPending || o.Status == OrderPaymentFailed }
+
+// NetTotal es el valor neto del pedido: el total menos lo devuelto.
+func (o Order) NetTotal() float64 { return float64(Cents(o.Total)-Cents(o.RefundedTotal)) / 100 }
+
+// MarshalJSON añade net_total a la respuesta.
+func (o Order) MarshalJSON() ([]byte, error) {
+	type plain Order
+	return json.Marshal(struct {
+		plain
+		NetTotal float64 `json:"net_total"`
+	}{plain(o), o.NetTotal()})
+}
+
+// ProductSnapshot es lo que se copia del producto al crear el pedido, para
+// que el pedido siga mostrando lo que se compró aunque el producto cambie o
+// se borre en product-service.
+type ProductSnapshot struct {
+	Name        string `bun:"name,nullzero" json:"name,omitempty"`
+	SKU         string `bun:"sku,nullzero" json:"sku,omitempty"`
+	Description string `bun:"description,nullzero" json:"description,omitempty"`
 }
 
 type OrderItem struct {
 	bun.BaseModel `bun:"table:order_items,alias:oi"`
 
-	ID        string  `bun:"id,pk,type:uuid,default:gen_random_uuid()" json:"id"`
-	OrderID   string  `bun:"order_id,notnull" json:"order_id"`
-	ProductID string  `bun:"product_id,notnull" json:"product_id"`
-	Quantity  int     `bun:"quantity,notnull" json:"quantity"`
-	Price     float64 `bun:"price,notnull" json:"price"`
+	ID           string  `bun:"id,pk,type:uuid,default:gen_random_uuid()" json:"id"`
+	OrderID      string  `bun:"order_id,notnull" json:"order_id"`
+	Position     int     `bun:"position,notnull" json:"-"` // orden de la línea en el pedido
+	ProductID    string  `bun:"product_id,notnull" json:"product_id"`
+	Quantity     int     `bun:"quantity,notnull" json:"quantity"`
+	Price        float64 `bun:"price,notnull" json:"price"`
+	Discount     float64 `bun:"discount,notnull" json:"discount"`   // parte de los descuentos que corresponde a la línea
+	Gift         bool    `bun:"gift,notnull" json:"gift,omitempty"` // regalo de una promoción free_item
+	TaxClass     string  `bun:"tax_class,nullzero" json:"tax_class,omitempty"`
+	TaxRate      float64 `bun:"tax_rate,notnull" json:"tax_rate"` // % aplicado sobre price*quantity - discount
+	Tax          float64 `bun:"tax,notnull" json:"tax"`
+	Shipping     float64 `bun:"shipping,notnull" json:"shipping"` // parte de los portes que corresponde a la línea
+	StockApplied bool    `bun:"stock_applied,notnull" json:"-"`   // la venta ya descontó su stock
+	// AppliedQuantity son las unidades que descontó la venta según
+	// product-service; es lo que se repone, no Quantity.
+	AppliedQuantity int  `bun:"applied_quantity,notnull" json:"-"`
+	Restocked       bool `bun:"restocked,notnull" json:"-"` // stock repuesto al caducar el pedido
+
+	ProductSnapshot // name, sku y description al crear el pedido
+}
+
+// NetUnitPrice es lo que se pagó por unidad una vez descontada la parte de
+// las promociones; es lo que se reembolsa al devolverla.
+func (it OrderItem) NetUnitPrice() float64 {
+	if it.Quantity == 0 { return 0 }
+	return float64(Cents(it.Price)*int64(it.Quantity)-Cents(it.Discount)) / 100 / float64(it.Quantity)
+}
+
+// PaidTotal es lo que se pagó por la línea: el precio menos la parte de las
+// promociones más el impuesto; los portes no cuentan.
+func (it OrderItem) PaidTotal() float64 {
+	return float64(Cents(it.Price)*int64(it.Quantity)-Cents(it.Discount)+Cents(it.Tax)) / 100
+}
+
+// PaidUnitPrice es NetUnitPrice más el impuesto de la unidad; los portes no
+// se reparten por unidad.
+func (it OrderItem) PaidUnitPrice() float64 {
+	if it.Quantity == 0 { return 0 }
+	return it.PaidTotal() / float64(it.Quantity)
 }
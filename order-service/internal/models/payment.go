@@ -0,0 +1,124 @@
+package models
+
+import (
+	"math"
+	"time"
+
+	"github.com/uptrace/bun"
+)
+
+// Estados de un pago (un intento de cobro de un pedido).
+const (
+	PaymentPending           = "pending" // creado, a la espera del proveedor
+	PaymentAuthorized        = "authorized"
+	PaymentPartiallyCaptured = "partially_captured"
+	PaymentCaptured          = "captured"
+	PaymentPartiallyRefunded = "partially_refunded"
+	PaymentRefunded          = "refunded"
+	PaymentFailed            = "failed"
+)
+
+type Payment struct {
+	bun.BaseModel `bun:"table:payments,alias:p"`
+
+	ID             string    `bun:"id,pk,type:uuid,default:gen_random_uuid()" json:"id"`
+	OrderID        string    `bun:"order_id,notnull" json:"order_id"`
+	Provider       string    `bun:"provider,notnull" json:"provider"`
+	ProviderRef    string    `bun:"provider_ref,nullzero" json:"provider_ref,omitempty"`
+	Method         string    `bun:"method,notnull" json:"method"`
+	CaptureMethod  string    `bun:"capture_method,notnull" json:"capture_method"`
+	Status         string    `bun:"status,notnull,default:'pending'" json:"status"`
+	Currency       string    `bun:"currency,notnull" json:"currency"`
+	Amount         float64   `bun:"amount,notnull" json:"amount"`
+	CapturedAmount float64   `bun:"captured_amount,notnull" json:"captured_amount"`
+	RefundedAmount float64   `bun:"refunded_amount,notnull" json:"refunded_amount"`
+	FailureReason  string    `bun:"failure_reason,nullzero" json:"failure_reason,omitempty"`
+	ClientSecret   string    `bun:"-" json:"client_secret,omitempty"` // sólo en la respuesta de creación
+	CreatedAt      time.Time `bun:"created_at,notnull,default:now()" json:"created_at"`
+	UpdatedAt      time.Time `bun:"updated_at,notnull,default:now()" json:"updated_at"`
+}
+
+type Refund struct {
+	bun.BaseModel `bun:"table:refunds,alias:rf"`
+
+	ID          string    `bun:"id,pk,type:uuid,default:gen_random_uuid()" json:"id"`
+	PaymentID   string    `bun:"payment_id,notnull" json:"payment_id"`
+	OrderID     string    `bun:"order_id,notnull" json:"order_id"`
+	ReturnID    *string   `bun:"return_id,type:uuid" json:"return_id,omitempty"` // devolución que lo originó
+	Amount      float64   `bun:"amount,notnull" json:"amount"`
+	ProviderRef string    `bun:"provider_ref,nullzero" json:"provider_ref,omitempty"`
+	Reason      string    `bun:"reason,nullzero" json:"reason,omitempty"`
+	CreatedAt   time.Time `bun:"created_at,notnull,default:now()" json:"created_at"`
+}
+
+// PaymentEvent registra cada webhook procesado; su id (el del proveedor) hace
+// que un webhook repetido no se aplique dos veces.
+type PaymentEvent struct {
+	bun.BaseModel `bun:"table:payment_events,alias:pe"`
+
+	ID          string    `bun:"id,pk" json:"id"`
+	Type        string    `bun:"type,notnull" json:"type"`
+	ProviderRef string    `bun:"provider_ref,notnull" json:"provider_ref"`
+	Payload     string    `bun:"payload,type:jsonb,notnull" json:"-"`
+	ReceivedAt  time.Time `bun:"received_at,notnull,default:now()" json:"received_at"`
+}
+
+// Cents redondea un importe a céntimos para compararlo sin errores de coma flotante.
+func Cents(v float64) int64 { return int64(math.Round(v * 100)) }
+
+// Outstanding es lo que queda por capturar del importe autorizado.
+func (p *Payment) Outstanding() float64 { return float64(Cents(p.Amount)-Cents(p.CapturedAmount)) / 100 }
+
+// Refundable es lo capturado que aún no se ha devuelto.
+func (p *Payment) Refundable() float64 { return float64(Cents(p.CapturedAmount)-Cents(p.RefundedAmount)) / 100 }
+
+// Due es lo que queda por pagar de total, en céntimos: lo que no cubren los
+// pagos que no han fallado, sin contar lo que se devolvió de ellos.
+func Due(total float64, payments []Payment) int64 {
+	due := Cents(total)
+	for _, p := range payments {
+		if p.Status != PaymentFailed { due -= Cents(p.Amount) - Cents(p.RefundedAmount) }
+	}
+	return due
+}
+
+// Recompute deriva Status de los importes; un pago fallido no cambia.
+func (p *Payment) Recompute() {
+	switch {
+	case p.Status == PaymentFailed:
+	case Cents(p.RefundedAmount) > 0 && Cents(p.RefundedAmount) >= Cents(p.CapturedAmount):
+		p.Status = PaymentRefunded
+	case Cents(p.RefundedAmount) > 0:
+		p.Status = PaymentPartiallyRefunded
+	case Cents(p.CapturedAmount) > 0 && Cents(p.CapturedAmount) >= Cents(p.Amount):
+		p.Status = PaymentCaptured
+	case Cents(p.CapturedAmount) > 0:
+		p.Status = PaymentPartiallyCaptured
+	}
+}
+
+// SettleOrder calcula el estado del pedido tras un cambio en sus pagos:
+// paid cuando lo capturado (bruto, sin restar reembolsos) cubre el total,
+// refunded cuando se devolvió todo lo capturado y payment_failed si el
+// último intento falló sin haber cobrado nada. Un reembolso parcial deja el
+// pedido en paid; lo devuelto queda en refunded_total. Sólo toca pedidos en pending, payment_failed o paid; el resto
+// (cancelado, enviado...) no lo cambian los pagos.
+func SettleOrder(current string, total, captured, refunded float64, lastFailed bool) string {
+	switch current {
+	case OrderPending, OrderPaymentFailed, OrderPaid:
+	default:
+		return current
+	}
+	switch {
+	case Cents(captured) > 0 && Cents(refunded) >= Cents(captured):
+		return OrderRefunded
+	// un pedido de total cero no pasa por aquí: se paga al crearlo
+	case Cents(captured) >= Cents(total) && Cents(total) > 0:
+		return OrderPaid
+	case current == OrderPaid:
+		return current
+	case lastFailed && Cents(captured) == 0:
+		return OrderPaymentFailed
+	}
+	return current
+}
@@ -0,0 +1,50 @@
+package models
+
+import (
+	"time"
+
+	"github.com/uptrace/bun"
+)
+
+// AnyRegion y AnyTaxClass son comodines de las reglas: valen para cualquier
+// región o clase fiscal que no tenga una regla propia. DefaultTaxClass es la
+// de los productos que no indican otra (igual que en product-service).
+const (
+	AnyRegion       = "*"
+	AnyTaxClass     = "*"
+	DefaultTaxClass = "standard"
+)
+
+// TaxRule es el tipo (en %) que se aplica a las líneas de una clase fiscal
+// en una región. Gana la regla más específica (ver pricing.MatchTaxRule).
+type TaxRule struct {
+	bun.BaseModel `bun:"table:tax_rules,alias:tr"`
+
+	ID        string    `bun:"id,pk,type:uuid,default:gen_random_uuid()" json:"id"`
+	Region    string    `bun:"region,notnull" json:"region"`       // en mayúsculas (ES, US-CA...) o *
+	TaxClass  string    `bun:"tax_class,notnull" json:"tax_class"` // clase fiscal del producto o *
+	Name      string    `bun:"name,notnull" json:"name"`
+	Rate      float64   `bun:"rate,notnull" json:"rate"`
+	CreatedAt time.Time `bun:"created_at,notnull,default:now()" json:"created_at"`
+}
+
+// Tipos de regla de envío.
+const (
+	ShippingFlat     = "flat"      // Fee fijo
+	ShippingWeight   = "weight"    // Fee + PerKg por cada kilo empezado
+	ShippingFreeOver = "free_over" // gratis si lo comprado (descuentos aplicados) llega a Threshold
+)
+
+// ShippingRule es una tarifa de envío para una región (o * para el resto).
+type ShippingRule struct {
+	bun.BaseModel `bun:"table:shipping_rules,alias:shr"`
+
+	ID        string    `bun:"id,pk,type:uuid,default:gen_random_uuid()" json:"id"`
+	Region    string    `bun:"region,notnull" json:"region"`
+	Name      string    `bun:"name,notnull" json:"name"`
+	Type      string    `bun:"type,notnull" json:"type"`
+	Fee       float64   `bun:"fee,notnull" json:"fee"`
+	PerKg     float64   `bun:"per_kg,notnull" json:"per_kg,omitempty"`
+	Threshold float64   `bun:"threshold,notnull" json:"threshold,omitempty"`
+	CreatedAt time.Time `bun:"created_at,notnull,default:now()" json:"created_at"`
+}
@@ -0,0 +1,62 @@
+package models
+
+import (
+	"time"
+
+	"github.com/uptrace/bun"
+)
+
+// Tipos de promoción.
+const (
+	PromoPercentage = "percentage"  // Value % del subtotal, o sólo de ProductID si está puesto
+	PromoFixed      = "fixed"       // Value de descuento sobre el subtotal
+	PromoBuyXGetY   = "buy_x_get_y" // de cada BuyQuantity+GetQuantity unidades de ProductID, GetQuantity gratis
+	PromoFreeItem   = "free_item"   // regala FreeQuantity unidades de FreeProductID
+)
+
+// Promotion es un cupón (con Code) o una promoción automática (sin Code),
+// que se aplica a todo pedido que cumpla sus condiciones.
+type Promotion struct {
+	bun.BaseModel `bun:"table:promotions,alias:pr"`
+
+	ID             string     `bun:"id,pk,type:uuid,default:gen_random_uuid()" json:"id"`
+	Code           string     `bun:"code,nullzero" json:"code,omitempty"` // en mayúsculas
+	Name           string     `bun:"name,notnull" json:"name"`
+	Type           string     `bun:"type,notnull" json:"type"`
+	Value          float64    `bun:"value,notnull" json:"value,omitempty"`
+	ProductID      string     `bun:"product_id,nullzero,type:uuid" json:"product_id,omitempty"`
+	BuyQuantity    int        `bun:"buy_quantity,notnull" json:"buy_quantity,omitempty"`
+	GetQuantity    int        `bun:"get_quantity,notnull" json:"get_quantity,omitempty"`
+	FreeProductID  string     `bun:"free_product_id,nullzero,type:uuid" json:"free_product_id,omitempty"`
+	FreeQuantity   int        `bun:"free_quantity,notnull" json:"free_quantity,omitempty"`
+	MinSpend       float64    `bun:"min_spend,notnull" json:"min_spend"`
+	StartsAt       *time.Time `bun:"starts_at" json:"starts_at,omitempty"`
+	EndsAt         *time.Time `bun:"ends_at" json:"ends_at,omitempty"`
+	MaxUses        *int       `bun:"max_uses" json:"max_uses,omitempty"` // nil = sin límite
+	MaxUsesPerUser *int       `bun:"max_uses_per_user" json:"max_uses_per_user,omitempty"`
+	Uses           int        `bun:"uses,notnull" json:"uses"`
+	Active         bool       `bun:"active,notnull" json:"active"`
+	CreatedAt      time.Time  `bun:"created_at,notnull,default:now()" json:"created_at"`
+	UpdatedAt      time.Time  `bun:"updated_at,notnull,default:now()" json:"updated_at"`
+}
+
+// PromotionRedemption es un uso de una promoción en un pedido; sirve para
+// el límite por usuario.
+type PromotionRedemption struct {
+	bun.BaseModel `bun:"table:promotion_redemptions,alias:prr"`
+
+	ID          string    `bun:"id,pk,type:uuid,default:gen_random_uuid()" json:"id"`
+	PromotionID string    `bun:"promotion_id,notnull" json:"promotion_id"`
+	OrderID     string    `bun:"order_id,notnull" json:"order_id"`
+	UserID      string    `bun:"user_id,notnull" json:"user_id"`
+	CreatedAt   time.Time `bun:"created_at,notnull,default:now()" json:"created_at"`
+}
+
+// AppliedDiscount es un descuento aplicado a un pedido (desglose por regla).
+type AppliedDiscount struct {
+	PromotionID string  `json:"promotion_id"`
+	Code        string  `json:"code,omitempty"`
+	Name        string  `json:"name"`
+	Type        string  `json:"type"`
+	Amount      float64 `json:"amount"`
+}
@@ -0,0 +1,55 @@
+package models
+
+import (
+	"math"
+	"time"
+)
+
+// SoldStatuses son los estados de un pedido que cuentan como venta en los
+// informes: se llegó a cobrar. refunded entra con su total y lo devuelto se
+// resta en Net.
+var SoldStatuses = []string{OrderPaid, OrderPartiallyShipped, OrderShipped, OrderDelivered, OrderRefunded}
+
+// Sales son las cifras de un conjunto de pedidos vendidos.
+type Sales struct {
+	Orders            int     `json:"orders"`
+	Gross             float64 `json:"gross"`               // suma de total
+	Refunded          float64 `json:"refunded"`            // suma de refunded_total
+	Net               float64 `json:"net"`                 // gross - refunded
+	AverageOrderValue float64 `json:"average_order_value"` // net / orders
+}
+
+// NewSales calcula Net y AverageOrderValue en céntimos.
+func NewSales(orders int, gross, refunded float64) Sales {
+	net := Cents(gross) - Cents(refunded)
+	s := Sales{Orders: orders, Gross: gross, Refunded: refunded, Net: float64(net) / 100}
+	if orders > 0 { s.AverageOrderValue = math.Round(float64(net)/float64(orders)) / 100 }
+	return s
+}
+
+// SalesPeriod son las ventas de un día, semana (de lunes a domingo) o mes.
+type SalesPeriod struct {
+	Period string `json:"period"` // primer día del periodo (YYYY-MM-DD) en la zona del informe
+	Sales
+}
+
+// ProductSales son las unidades e importe vendidos de un producto. Revenue
+// es price*quantity - discount de sus líneas: sin impuestos ni portes y sin
+// restar devoluciones.
+type ProductSales struct {
+	ProductID string  `json:"product_id"`
+	Name      string  `json:"name,omitempty"` // del snapshot más reciente
+	SKU       string  `json:"sku,omitempty"`
+	Quantity  int     `json:"quantity"`
+	Orders    int     `json:"orders"`
+	Revenue   float64 `json:"revenue"`
+}
+
+// CustomerValue es lo que ha comprado un usuario (su lifetime value si el
+// informe no se acota por fechas).
+type CustomerValue struct {
+	UserID string `json:"user_id"`
+	Sales
+	FirstOrderAt time.Time `json:"first_order_at"`
+	LastOrderAt  time.Time `json:"last_order_at"`
+}
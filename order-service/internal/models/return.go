@@ -0,0 +1,114 @@
+package models
+
+import (
+	"time"
+
+	"github.com/uptrace/bun"
+)
+
+// Estados de una devolución (RMA): requested → approved → received, o
+// rejected desde requested o approved.
+const (
+	ReturnRequested = "requested"
+	ReturnApproved  = "approved"
+	ReturnReceived  = "received"
+	ReturnRejected  = "rejected"
+)
+
+// Motivos de devolución admitidos.
+const (
+	ReasonDamaged        = "damaged"
+	ReasonDefective      = "defective"
+	ReasonWrongItem      = "wrong_item"
+	ReasonNotAsDescribed = "not_as_described"
+	ReasonNoLongerNeeded = "no_longer_needed"
+	ReasonOther          = "other"
+)
+
+// ValidReturnReason indica si reason es uno de los motivos admitidos.
+func ValidReturnReason(reason string) bool {
+	switch reason {
+	case ReasonDamaged, ReasonDefective, ReasonWrongItem, ReasonNotAsDescribed, ReasonNoLongerNeeded, ReasonOther:
+		return true
+	}
+	return false
+}
+
+type Return struct {
+	bun.BaseModel `bun:"table:returns,alias:rt"`
+
+	ID             string       `bun:"id,pk,type:uuid,default:gen_random_uuid()" json:"id"`
+	OrderID        string       `bun:"order_id,notnull" json:"order_id"`
+	Status         string       `bun:"status,notnull,default:'requested'" json:"status"`
+	Note           string       `bun:"note,nullzero" json:"note,omitempty"` // comentario del cliente o motivo del rechazo
+	RefundedAmount float64      `bun:"refunded_amount,notnull" json:"refunded_amount"`
+	ApprovedAt     *time.Time   `bun:"approved_at" json:"approved_at,omitempty"`
+	ReceivedAt     *time.Time   `bun:"received_at" json:"received_at,omitempty"`
+	RejectedAt     *time.Time   `bun:"rejected_at" json:"rejected_at,omitempty"`
+	CreatedAt      time.Time    `bun:"created_at,notnull,default:now()" json:"created_at"`
+	UpdatedAt      time.Time    `bun:"updated_at,notnull,default:now()" json:"updated_at"`
+	Items          []ReturnItem `bun:"rel:has-many,join:id=return_id" json:"items"`
+}
+
+// ReturnItem copia producto y precio pagado (descontadas las promociones e
+// impuesto incluido) de la línea del pedido, y guarda lo que vale, para
+// reembolsar y reponer stock sin volver a leerla.
+type ReturnItem struct {
+	bun.BaseModel `bun:"table:return_items,alias:ri"`
+
+	ID          string  `bun:"id,pk,type:uuid,default:gen_random_uuid()" json:"id"`
+	ReturnID    string  `bun:"return_id,notnull" json:"return_id"`
+	OrderItemID string  `bun:"order_item_id,notnull" json:"order_item_id"`
+	ProductID   string  `bun:"product_id,notnull" json:"product_id"`
+	Quantity    int     `bun:"quantity,notnull" json:"quantity"`
+	UnitPrice   float64 `bun:"unit_price,notnull" json:"unit_price"`
+	Amount      float64 `bun:"amount,notnull" json:"amount"` // lo que se reembolsa por estas unidades (ver ReturnAmount)
+	Reason      string  `bun:"reason,notnull" json:"reason"`
+	Restocked   bool    `bun:"restocked,notnull" json:"restocked"` // el stock ya volvió a product-service
+}
+
+// Value es lo que se reembolsa por la devolución.
+func (r *Return) Value() float64 {
+	var cents int64
+	for _, it := range r.Items { cents += Cents(it.Amount) }
+	return float64(cents) / 100
+}
+
+// ReturnAmount es lo que vale devolver quantity unidades de la línea it
+// después de returns (las rechazadas no cuentan). Lo pagado por la línea se
+// reparte en proporción a las unidades, redondeando hacia abajo, y la
+// devolución que se lleva las últimas se queda con los céntimos que sobran:
+// entre todas nunca pasan de lo pagado (10,01 en tres unidades son 3,33,
+// 3,33 y 3,35, no 3×3,34).
+func ReturnAmount(it OrderItem, quantity int, returns []Return) float64 {
+	paid := Cents(it.PaidTotal())
+	left, returned := it.Quantity, int64(0)
+	for _, r := range returns {
+		if r.Status == ReturnRejected { continue }
+		for _, ri := range r.Items {
+			if ri.OrderItemID == it.ID { left -= ri.Quantity; returned += Cents(ri.Amount) }
+		}
+	}
+	if quantity >= left { return float64(max(paid-returned, 0)) / 100 }
+	return float64(paid*int64(quantity)/int64(it.Quantity)) / 100
+}
+
+// Unfinished indica que a una devolución recibida le quedó alguna línea por
+// reponer o parte del valor por reembolsar; Receive puede volver a intentarlo.
+func (r *Return) Unfinished() bool {
+	if r.Status != ReturnReceived { return false }
+	for _, it := range r.Items { if !it.Restocked { return true } }
+	return Cents(r.RefundedAmount) < Cents(r.Value())
+}
+
+// Returnable devuelve, por línea del pedido, las unidades que aún se pueden
+// devolver; las devoluciones rechazadas no cuentan.
+func Returnable(items []OrderItem, returns []Return) map[string]int {
+	left := make(map[string]int, len(items))
+	for _, it := range items { left[it.ID] = it.Quantity }
+	for _, r := range returns {
+		if r.Status == ReturnRejected { continue }
+		for _, ri := range r.Items { left[ri.OrderItemID] -= ri.Quantity }
+	}
+	return left
+}
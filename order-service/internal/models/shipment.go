@@ -0,0 +1,114 @@
+package models
+
+import (
+	"time"
+
+	"github.com/uptrace/bun"
+)
+
+// Estados de un envío. pending es un envío preparado que aún no ha recogido
+// el transportista; sólo los shipped y delivered cuentan para el pedido.
+const (
+	ShipmentPending   = "pending"
+	ShipmentShipped   = "shipped"
+	ShipmentDelivered = "delivered"
+	ShipmentCancelled = "cancelled"
+)
+
+// Shipment es un bulto de un pedido; puede llevar sólo parte de sus líneas
+// (envío parcial).
+type Shipment struct {
+	bun.BaseModel `bun:"table:shipments,alias:sh"`
+
+	ID             string         `bun:"id,pk,type:uuid,default:gen_random_uuid()" json:"id"`
+	OrderID        string         `bun:"order_id,notnull" json:"order_id"`
+	Carrier        string         `bun:"carrier,notnull" json:"carrier"`
+	TrackingNumber string         `bun:"tracking_number,nullzero" json:"tracking_number,omitempty"`
+	Status         string         `bun:"status,notnull,default:'pending'" json:"status"`
+	ShippedAt      *time.Time     `bun:"shipped_at" json:"shipped_at,omitempty"`
+	DeliveredAt    *time.Time     `bun:"delivered_at" json:"delivered_at,omitempty"`
+	CreatedAt      time.Time      `bun:"created_at,notnull,default:now()" json:"created_at"`
+	UpdatedAt      time.Time      `bun:"updated_at,notnull,default:now()" json:"updated_at"`
+	Items          []ShipmentItem `bun:"rel:has-many,join:id=shipment_id" json:"items"`
+}
+
+type ShipmentItem struct {
+	bun.BaseModel `bun:"table:shipment_items,alias:si"`
+
+	ID          string `bun:"id,pk,type:uuid,default:gen_random_uuid()" json:"id"`
+	ShipmentID  string `bun:"shipment_id,notnull" json:"shipment_id"`
+	OrderItemID string `bun:"order_item_id,notnull" json:"order_item_id"`
+	Quantity    int    `bun:"quantity,notnull" json:"quantity"`
+}
+
+// CanTransition indica si un envío puede pasar de from a to: pending a
+// shipped, delivered o cancelled, y shipped a delivered.
+func CanTransition(from, to string) bool {
+	switch from {
+	case ShipmentPending:
+		return to == ShipmentShipped || to == ShipmentDelivered || to == ShipmentCancelled
+	case ShipmentShipped:
+		return to == ShipmentDelivered
+	}
+	return false
+}
+
+// Advance cambia el estado del envío y fija los timestamps; entregar un envío
+// que no constaba como enviado marca también shipped_at.
+func (s *Shipment) Advance(to string, now time.Time) {
+	s.Status = to
+	switch to {
+	case ShipmentShipped:
+		s.ShippedAt = &now
+	case ShipmentDelivered:
+		if s.ShippedAt == nil { s.ShippedAt = &now }
+		s.DeliveredAt = &now
+	}
+}
+
+// Unshipped devuelve, por línea del pedido, la cantidad que aún no está en
+// ningún envío (los cancelados no cuentan).
+func Unshipped(items []OrderItem, shipments []Shipment) map[string]int {
+	left := make(map[string]int, len(items))
+	for _, it := range items { left[it.ID] = it.Quantity }
+	for _, s := range shipments {
+		if s.Status == ShipmentCancelled { continue }
+		for _, si := range s.Items { left[si.OrderItemID] -= si.Quantity }
+	}
+	return left
+}
+
+// FulfillOrder deriva el estado del pedido de sus envíos: delivered cuando
+// todas las unidades se han entregado, shipped cuando todas han salido y
+// partially_shipped si ha salido alguna. Sólo toca pedidos pagados o ya en
+// reparto; un pedido cancelado o devuelto se queda como está.
+func FulfillOrder(current string, items []OrderItem, shipments []Shipment) string {
+	switch current {
+	case OrderPaid, OrderPartiallyShipped, OrderShipped, OrderDelivered:
+	default:
+		return current
+	}
+	shipped, delivered := map[string]int{}, map[string]int{}
+	for _, s := range shipments {
+		if s.Status != ShipmentShipped && s.Status != ShipmentDelivered { continue }
+		for _, si := range s.Items {
+			shipped[si.OrderItemID] += si.Quantity
+			if s.Status == ShipmentDelivered { delivered[si.OrderItemID] += si.Quantity }
+		}
+	}
+	allShipped, allDelivered, some := len(items) > 0, len(items) > 0, false
+	for _, it := range items {
+		if shipped[it.ID] > 0 { some = true }
+		if shipped[it.ID] < it.Quantity { allShipped = false }
+		if delivered[it.ID] < it.Quantity { allDelivered = false }
+	}
+	switch {
+	case allDelivered:
+		return OrderDelivered
+	case allShipped:
+		return OrderShipped
+	case some:
+		return OrderPartiallyShipped
+	}
+	return OrderPaid
+}
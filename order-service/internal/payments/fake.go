@@ -0,0 +1,136 @@
+package payments
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"math"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// MethodDeclined hace que Fake rechace el pago (payment.failed).
+const MethodDeclined = "fake_declined"
+
+type FakeConfig struct {
+	// WebhookURL recibe los eventos firmados con Secret; vacío = no se envían.
+	WebhookURL string
+	Secret     string
+	// Delay antes de enviar cada evento, como haría un proveedor real.
+	Delay  time.Duration
+	Client *http.Client
+	Logger *slog.Logger
+}
+
+// Fake es un proveedor en memoria: acepta cualquier medio de pago salvo
+// MethodDeclined y notifica el resultado al webhook igual que uno real.
+type Fake struct {
+	cfg FakeConfig
+
+	mu      sync.Mutex
+	intents map[string]*fakeIntent
+	wg      sync.WaitGroup
+}
+
+type fakeIntent struct {
+	amount, captured, refunded float64
+	manual, authorized         bool
+}
+
+func NewFake(cfg FakeConfig) *Fake {
+	if cfg.Client == nil { cfg.Client = &http.Client{Timeout: 5 * time.Second} }
+	if cfg.Logger == nil { cfg.Logger = slog.Default() }
+	return &Fake{cfg: cfg, intents: map[string]*fakeIntent{}}
+}
+
+func (f *Fake) Name() string { return "fake" }
+
+func (f *Fake) CreateIntent(ctx context.Context, req IntentRequest) (*Intent, error) {
+	if req.Amount <= 0 { return nil, ErrInvalidAmount }
+	ref := "fake_pi_" + randomHex(12)
+	in := &fakeIntent{amount: req.Amount, manual: req.CaptureMethod == CaptureManual}
+	f.mu.Lock(); f.intents[ref] = in; f.mu.Unlock()
+
+	switch {
+	case req.Method == MethodDeclined:
+		f.send(Event{Type: EventFailed, PaymentRef: ref, FailureReason: "card_declined"})
+	case in.manual:
+		f.mu.Lock(); in.authorized = true; f.mu.Unlock()
+		f.send(Event{Type: EventAuthorized, PaymentRef: ref})
+	default:
+		f.mu.Lock(); in.captured = in.amount; f.mu.Unlock()
+		f.send(Event{Type: EventCaptured, PaymentRef: ref, AmountCaptured: in.amount})
+	}
+	return &Intent{Ref: ref, ClientSecret: ref + "_secret_" + randomHex(8)}, nil
+}
+
+func (f *Fake) Capture(ctx context.Context, ref string, amount float64) (float64, error) {
+	f.mu.Lock()
+	in, ok := f.intents[ref]
+	if !ok { f.mu.Unlock(); return 0, ErrUnknownIntent }
+	if !in.authorized || amount <= 0 || cents(in.captured+amount) > cents(in.amount) { f.mu.Unlock(); return 0, ErrInvalidAmount }
+	in.captured = float64(cents(in.captured+amount)) / 100
+	total := in.captured
+	f.mu.Unlock()
+	f.send(Event{Type: EventCaptured, PaymentRef: ref, AmountCaptured: total})
+	return total, nil
+}
+
+func (f *Fake) Refund(ctx context.Context, ref string, amount float64, reason string) (*RefundResult, error) {
+	f.mu.Lock()
+	in, ok := f.intents[ref]
+	if !ok { f.mu.Unlock(); return nil, ErrUnknownIntent }
+	if amount <= 0 || cents(in.refunded+amount) > cents(in.captured) { f.mu.Unlock(); return nil, ErrInvalidAmount }
+	in.refunded = float64(cents(in.refunded+amount)) / 100
+	total := in.refunded
+	f.mu.Unlock()
+	f.send(Event{Type: EventRefunded, PaymentRef: ref, AmountRefunded: total})
+	return &RefundResult{Ref: "fake_re_" + randomHex(12), Refunded: total}, nil
+}
+
+// Wait espera a que se hayan enviado los webhooks pendientes.
+func (f *Fake) Wait() { f.wg.Wait() }
+
+// send firma y envía el evento en segundo plano, con unos pocos reintentos
+// por si el webhook aún no está escuchando.
+func (f *Fake) send(ev Event) {
+	if f.cfg.WebhookURL == "" { return }
+	ev.ID = "evt_" + randomHex(12)
+	ev.Created = time.Now().UTC()
+	body, _ := json.Marshal(ev)
+	f.wg.Add(1)
+	go func() {
+		defer f.wg.Done()
+		time.Sleep(f.cfg.Delay)
+		var err error
+		for attempt := 0; attempt < 3; attempt++ {
+			if attempt > 0 { time.Sleep(time.Duration(attempt) * 500 * time.Millisecond) }
+			if err = f.post(body); err == nil { return }
+		}
+		f.cfg.Logger.Warn("fake payment webhook", "event", ev.Type, "payment_ref", ev.PaymentRef, "error", err)
+	}()
+}
+
+func (f *Fake) post(body []byte) error {
+	req, _ := http.NewRequest(http.MethodPost, f.cfg.WebhookURL, bytes.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(HeaderSignature, Sign(f.cfg.Secret, body, time.Now()))
+	res, err := f.cfg.Client.Do(req)
+	if err != nil { return err }
+	res.Body.Close()
+	if res.StatusCode >= 300 { return fmt.Errorf("webhook status %d", res.StatusCode) }
+	return nil
+}
+
+func cents(v float64) int64 { return int64(math.Round(v * 100)) }
+
+func randomHex(n int) string {
+	b := make([]byte, n)
+	_, _ = rand.Read(b)
+	return hex.EncodeToString(b)
+}
@@ -0,0 +1,72 @@
+// Package payments abstrae el proveedor de pagos. El servicio sólo habla con
+// PaymentGateway y recibe el resultado asíncrono por webhooks firmados (ver
+// Sign/Verify); Fake es un proveedor local para desarrollo y tests.
+package payments
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+var (
+	ErrInvalidAmount = errors.New("invalid amount")
+	ErrUnknownIntent = errors.New("unknown payment intent")
+)
+
+// Formas de captura de un intent.
+const (
+	CaptureAutomatic = "automatic" // se cobra al confirmar
+	CaptureManual    = "manual"    // se autoriza y se captura después, total o parcialmente
+)
+
+// Tipos de evento que envía el proveedor al webhook. Los importes de
+// captured y refunded son acumulados, así que aplicar un evento dos veces o
+// fuera de orden da el mismo resultado.
+const (
+	EventAuthorized = "payment.authorized"
+	EventCaptured   = "payment.captured"
+	EventFailed     = "payment.failed"
+	EventRefunded   = "payment.refunded"
+)
+
+type PaymentGateway interface {
+	// Name identifica al proveedor en la tabla payments.
+	Name() string
+	CreateIntent(ctx context.Context, req IntentRequest) (*Intent, error)
+	// Capture cobra amount de un intent manual autorizado y devuelve el total
+	// capturado hasta ahora.
+	Capture(ctx context.Context, ref string, amount float64) (float64, error)
+	// Refund devuelve amount de lo capturado.
+	Refund(ctx context.Context, ref string, amount float64, reason string) (*RefundResult, error)
+}
+
+type IntentRequest struct {
+	PaymentID     string // id del pago en orders_db; sirve de clave de idempotencia
+	OrderID       string
+	Amount        float64
+	Currency      string
+	Method        string // medio de pago opaco para el proveedor (token de tarjeta...)
+	CaptureMethod string
+}
+
+type Intent struct {
+	Ref          string `json:"ref"`
+	ClientSecret string `json:"client_secret,omitempty"` // para confirmar desde el cliente
+}
+
+type RefundResult struct {
+	Ref      string
+	Refunded float64 // total devuelto del intent, incluido este reembolso
+}
+
+// Event es el cuerpo JSON de un webhook.
+type Event struct {
+	ID             string    `json:"id"`
+	Type           string    `json:"type"`
+	PaymentRef     string    `json:"payment_ref"`
+	AmountCaptured float64   `json:"amount_captured,omitempty"`
+	AmountRefunded float64   `json:"amount_refunded,omitempty"`
+	FailureReason  string    `json:"failure_reason,omitempty"`
+	Created        time.Time `json:"created"`
+}
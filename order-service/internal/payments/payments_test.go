@@ -0,0 +1,76 @@
+package payments
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestSignatureVerify(t *testing.T) {
+	body := []byte(`{"id":"evt_1"}`)
+	now := time.Unix(1_700_000_000, 0)
+	sig := Sign("s3cret", body, now)
+
+	if err := Verify("s3cret", body, sig, now.Add(time.Minute), 5*time.Minute); err != nil { t.Fatalf("valid signature: %v", err) }
+	cases := map[string]struct {
+		secret, header string
+		body           []byte
+		at             time.Time
+	}{
+		"wrong secret":  {"other", sig, body, now},
+		"tampered body": {"s3cret", sig, []byte(`{"id":"evt_2"}`), now},
+		"too old":       {"s3cret", sig, body, now.Add(6 * time.Minute)},
+		"no timestamp":  {"s3cret", sig[strings.Index(sig, ",")+1:], body, now},
+		"empty":         {"s3cret", "", body, now},
+	}
+	for name, tc := range cases {
+		if err := Verify(tc.secret, tc.body, tc.header, tc.at, 5*time.Minute); !errors.Is(err, ErrInvalidSignature) { t.Errorf("%s: err=%v", name, err) }
+	}
+
+	// durante una rotación llegan dos firmas y basta con que una sea válida
+	rotated := Sign("old", body, now) + ",v1=" + sig[strings.Index(sig, "v1=")+3:]
+	if err := Verify("s3cret", body, rotated, now, time.Minute); err != nil { t.Fatalf("rotated: %v", err) }
+}
+
+func TestFakeSendsSignedWebhooks(t *testing.T) {
+	var mu sync.Mutex
+	var events []Event
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		if err := Verify("whsec", body, r.Header.Get(HeaderSignature), time.Now(), time.Minute); err != nil { w.WriteHeader(http.StatusUnauthorized); return }
+		var ev Event
+		_ = json.Unmarshal(body, &ev)
+		mu.Lock(); events = append(events, ev); mu.Unlock()
+	}))
+	defer srv.Close()
+	f := NewFake(FakeConfig{WebhookURL: srv.URL, Secret: "whsec"})
+	ctx := context.Background()
+
+	auto, err := f.CreateIntent(ctx, IntentRequest{Amount: 50, Method: "card"})
+	if err != nil { t.Fatal(err) }
+	manual, _ := f.CreateIntent(ctx, IntentRequest{Amount: 100, Method: "card", CaptureMethod: CaptureManual})
+	declined, _ := f.CreateIntent(ctx, IntentRequest{Amount: 10, Method: MethodDeclined})
+	f.Wait()
+
+	if total, err := f.Capture(ctx, manual.Ref, 30); err != nil || total != 30 { t.Fatalf("partial capture: %v %v", total, err) }
+	if total, err := f.Capture(ctx, manual.Ref, 70); err != nil || total != 100 { t.Fatalf("second capture: %v %v", total, err) }
+	if _, err := f.Capture(ctx, manual.Ref, 0.01); !errors.Is(err, ErrInvalidAmount) { t.Fatalf("over-capture: %v", err) }
+	if _, err := f.Refund(ctx, auto.Ref, 60, ""); !errors.Is(err, ErrInvalidAmount) { t.Fatalf("over-refund: %v", err) }
+	if res, err := f.Refund(ctx, auto.Ref, 20, "damaged"); err != nil || res.Refunded != 20 || res.Ref == "" { t.Fatalf("refund: %+v %v", res, err) }
+	f.Wait()
+
+	got := map[string][]string{}
+	mu.Lock()
+	for _, ev := range events { got[ev.PaymentRef] = append(got[ev.PaymentRef], ev.Type) }
+	mu.Unlock()
+	if len(got[auto.Ref]) != 2 || got[auto.Ref][0] != EventCaptured { t.Fatalf("automatic intent events: %v", got[auto.Ref]) }
+	if len(got[manual.Ref]) != 3 || got[manual.Ref][0] != EventAuthorized { t.Fatalf("manual intent events: %v", got[manual.Ref]) }
+	if len(got[declined.Ref]) != 1 || got[declined.Ref][0] != EventFailed { t.Fatalf("declined intent events: %v", got[declined.Ref]) }
+}
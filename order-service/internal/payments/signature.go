@@ -0,0 +1,54 @@
+package payments
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// HeaderSignature lleva "t=<unix>,v1=<hex>", con v1 = HMAC-SHA256(secret,
+// "<t>.<cuerpo>"). El timestamp firmado evita que se reenvíe un webhook
+// capturado pasado el margen de tolerancia.
+const HeaderSignature = "Payment-Signature"
+
+var ErrInvalidSignature = errors.New("invalid webhook signature")
+
+func Sign(secret string, payload []byte, t time.Time) string {
+	ts := strconv.FormatInt(t.Unix(), 10)
+	return "t=" + ts + ",v1=" + mac(secret, ts, payload)
+}
+
+// Verify comprueba la firma y que el timestamp esté a menos de tolerance de now.
+func Verify(secret string, payload []byte, header string, now time.Time, tolerance time.Duration) error {
+	var ts string
+	var sigs []string
+	for _, part := range strings.Split(header, ",") {
+		k, v, _ := strings.Cut(strings.TrimSpace(part), "=")
+		switch k {
+		case "t":
+			ts = v
+		case "v1":
+			sigs = append(sigs, v) // puede haber varias durante una rotación del secreto
+		}
+	}
+	sec, err := strconv.ParseInt(ts, 10, 64)
+	if err != nil || len(sigs) == 0 { return ErrInvalidSignature }
+	if d := now.Sub(time.Unix(sec, 0)); d > tolerance || d < -tolerance { return ErrInvalidSignature }
+	want := mac(secret, ts, payload)
+	for _, s := range sigs {
+		if hmac.Equal([]byte(s), []byte(want)) { return nil }
+	}
+	return ErrInvalidSignature
+}
+
+func mac(secret, ts string, payload []byte) string {
+	h := hmac.New(sha256.New, []byte(secret))
+	h.Write([]byte(ts))
+	h.Write([]byte("."))
+	h.Write(payload)
+	return hex.EncodeToString(h.Sum(nil))
+}
@@ -0,0 +1,28 @@
+package pricing
+
+import "context"
+
+// Stage es un paso del cálculo. Modifica las líneas de q (descuentos,
+// impuestos, portes); Pipeline recalcula los totales después de cada paso.
+type Stage interface {
+	Apply(ctx context.Context, q *Quote) error
+}
+
+// StageFunc adapta una función a Stage.
+type StageFunc func(ctx context.Context, q *Quote) error
+
+func (f StageFunc) Apply(ctx context.Context, q *Quote) error { return f(ctx, q) }
+
+// Pipeline ejecuta los pasos en orden. El orden importa: los impuestos se
+// calculan sobre lo que queda tras los descuentos y el envío gratis mira el
+// importe ya descontado, así que lo habitual es promociones, impuestos y
+// portes.
+type Pipeline []Stage
+
+func (p Pipeline) Run(ctx context.Context, q *Quote) error {
+	for _, st := range p {
+		if err := st.Apply(ctx, q); err != nil { return err }
+		q.sum()
+	}
+	return nil
+}
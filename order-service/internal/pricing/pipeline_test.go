@@ -0,0 +1,109 @@
+package pricing
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/huntercenter1/backend-test/order-service/internal/models"
+)
+
+// rules sirve las mismas reglas para cualquier región, como hace el
+// repositorio con las de la región pedida y las comodín.
+type rules struct {
+	tax  []models.TaxRule
+	ship []models.ShippingRule
+}
+
+func (r rules) TaxRules(context.Context, string) ([]models.TaxRule, error) { return r.tax, nil }
+func (r rules) ShippingRules(context.Context, string) ([]models.ShippingRule, error) { return r.ship, nil }
+
+func TestMatchTaxRule(t *testing.T) {
+	list := []models.TaxRule{
+		{Name: "any", Region: "*", TaxClass: "*", Rate: 1},
+		{Name: "any reduced", Region: "*", TaxClass: "reduced", Rate: 2},
+		{Name: "ES", Region: "ES", TaxClass: "*", Rate: 21},
+		{Name: "ES reduced", Region: "ES", TaxClass: "reduced", Rate: 10},
+		{Name: "FR", Region: "FR", TaxClass: "standard", Rate: 20},
+	}
+	for _, c := range []struct{ region, class, want string }{
+		{"ES", "reduced", "ES reduced"},
+		{"ES", "standard", "ES"},
+		{"PT", "reduced", "any reduced"},
+		{"PT", "standard", "any"},
+		{"", "standard", "any"},
+		{"FR", "reduced", "any reduced"},
+	} {
+		if r := MatchTaxRule(list, c.region, c.class); r == nil || r.Name != c.want { t.Errorf("%s/%s: got %+v want %s", c.region, c.class, r, c.want) }
+	}
+	if r := MatchTaxRule(list[4:], "ES", "standard"); r != nil { t.Fatalf("want no rule, got %+v", r) }
+}
+
+func TestShippingFee(t *testing.T) {
+	list := []models.ShippingRule{
+		{Region: "*", Type: models.ShippingFlat, Fee: 15},
+		{Region: "ES", Type: models.ShippingFlat, Fee: 4.95},
+		{Region: "ES", Type: models.ShippingWeight, Fee: 2, PerKg: 1.5},
+		{Region: "ES", Type: models.ShippingFreeOver, Threshold: 50},
+	}
+	for _, c := range []struct {
+		name   string
+		region string
+		net    float64
+		grams  int
+		want   float64
+	}{
+		{"light parcel: weight is cheaper", "ES", 20, 900, 3.5},
+		{"heavy parcel: flat is cheaper", "ES", 20, 2500, 4.95},
+		{"free over threshold", "ES", 50, 2500, 0},
+		{"region without rules uses *", "PT", 80, 0, 15},
+	} {
+		if got := ShippingFee(list, c.region, c.net, c.grams); got != c.want { t.Errorf("%s: got %v want %v", c.name, got, c.want) }
+	}
+	if got := ShippingFee(nil, "ES", 10, 100); got != 0 { t.Fatalf("no rules: got %v", got) }
+}
+
+// Las promociones van antes: el impuesto se calcula sobre el neto y el
+// envío gratis mira lo que queda tras el descuento.
+func TestPipelineBreakdown(t *testing.T) {
+	q := NewQuote("u1", []Line{
+		{ProductID: "book", Quantity: 2, UnitPrice: 20, TaxClass: "reduced", WeightGrams: 500},
+		{ProductID: "lamp", Quantity: 1, UnitPrice: 20, TaxClass: "standard", WeightGrams: 1000},
+	})
+	q.Region = "ES"
+	promos := StageFunc(func(_ context.Context, q *Quote) error {
+		ApplyPromotions(q, []models.Promotion{{Active: true, Type: models.PromoFixed, Value: 15}}, nil, now)
+		return nil
+	})
+	src := rules{
+		tax:  []models.TaxRule{{Region: "ES", TaxClass: "*", Rate: 21}, {Region: "ES", TaxClass: "reduced", Rate: 4}},
+		ship: []models.ShippingRule{{Region: "ES", Type: models.ShippingFlat, Fee: 5}, {Region: "ES", Type: models.ShippingFreeOver, Threshold: 50}},
+	}
+	if err := (Pipeline{promos, Taxes(src), Shipping(src)}).Run(context.Background(), q); err != nil { t.Fatal(err) }
+
+	// 60 - 15 = 45 (< 50): se cobran portes. Descuento 10 / 5 por importe.
+	book, lamp := q.Lines[0], q.Lines[1]
+	if book.Discount != 10 || lamp.Discount != 5 { t.Fatalf("discounts %+v", q.Lines) }
+	if book.TaxRate != 4 || book.Tax != 1.2 || lamp.TaxRate != 21 || lamp.Tax != 3.15 { t.Fatalf("taxes %+v", q.Lines) }
+	// 5 de portes repartidos por peso: 1000 g cada línea
+	if book.Shipping != 2.5 || lamp.Shipping != 2.5 { t.Fatalf("shipping %+v", q.Lines) }
+	if q.Subtotal != 60 || q.DiscountTotal != 15 || q.TaxTotal != 4.35 || q.ShippingTotal != 5 || q.Total != 54.35 {
+		t.Fatalf("quote %+v", q)
+	}
+}
+
+func TestPipelineStopsOnError(t *testing.T) {
+	boom := errors.New("boom")
+	var ran bool
+	p := Pipeline{
+		StageFunc(func(context.Context, *Quote) error { return boom }),
+		StageFunc(func(context.Context, *Quote) error { ran = true; return nil }),
+	}
+	if err := p.Run(context.Background(), NewQuote("u1", nil)); !errors.Is(err, boom) || ran { t.Fatalf("err=%v ran=%v", err, ran) }
+}
+
+func TestApplyShippingSpreadsCents(t *testing.T) {
+	q := NewQuote("u1", []Line{{ProductID: "a", Quantity: 1, UnitPrice: 1}, {ProductID: "b", Quantity: 1, UnitPrice: 1}, {ProductID: "c", Quantity: 1, UnitPrice: 1}})
+	ApplyShipping(q, []models.ShippingRule{{Region: "*", Type: models.ShippingFlat, Fee: 1}})
+	if q.ShippingTotal != 1 || q.Lines[0].Shipping != 0.34 || q.Lines[2].Shipping != 0.33 || q.Total != 4 { t.Fatalf("lines=%+v total=%v", q.Lines, q.Total) }
+}
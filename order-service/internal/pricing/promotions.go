@@ -0,0 +1,127 @@
+package pricing
+
+import (
+	"errors"
+	"sort"
+	"time"
+
+	"github.com/huntercenter1/backend-test/order-service/internal/models"
+)
+
+var (
+	ErrPromotionInactive   = errors.New("promotion is not active")
+	ErrPromotionNotStarted = errors.New("promotion has not started yet")
+	ErrPromotionExpired    = errors.New("promotion has expired")
+	ErrMinSpend            = errors.New("order does not reach the promotion's minimum spend")
+)
+
+// Eligible comprueba estado, ventana de validez y gasto mínimo. Los límites
+// de uso no se miran aquí: los aplica el repositorio al crear el pedido.
+func Eligible(p *models.Promotion, subtotal float64, now time.Time) error {
+	switch {
+	case !p.Active:
+		return ErrPromotionInactive
+	case p.StartsAt != nil && now.Before(*p.StartsAt):
+		return ErrPromotionNotStarted
+	case p.EndsAt != nil && !now.Before(*p.EndsAt):
+		return ErrPromotionExpired
+	case models.Cents(subtotal) < models.Cents(p.MinSpend):
+		return ErrMinSpend
+	}
+	return nil
+}
+
+// ApplyPromotions aplica las promociones elegibles a q. El gasto mínimo se
+// mide sobre el subtotal de lo comprado (sin regalos). Primero van las de
+// producto (buy_x_get_y, percentage de un producto, free_item) y después las
+// de pedido, que se calculan sobre lo que queda y se reparten entre las
+// líneas en proporción a su importe. gifts tiene, por producto, la línea a
+// precio de catálogo de lo que regalan las free_item (precio, clase fiscal y
+// peso); un regalo que no está (producto inexistente o sin stock) no se
+// añade.
+func ApplyPromotions(q *Quote, promos []models.Promotion, gifts map[string]Line, now time.Time) {
+	subtotal := q.Subtotal
+	ordered := make([]models.Promotion, len(promos))
+	copy(ordered, promos)
+	sort.SliceStable(ordered, func(i, j int) bool { return lineLevel(&ordered[i]) && !lineLevel(&ordered[j]) })
+
+	for i := range ordered {
+		p := &ordered[i]
+		if Eligible(p, subtotal, now) != nil { continue }
+		var amount int64
+		switch p.Type {
+		case models.PromoBuyXGetY:
+			amount = q.discountLines(p.ProductID, func(l Line) int64 {
+				group := p.BuyQuantity + p.GetQuantity
+				if group <= 0 { return 0 }
+				return int64(l.Quantity/group*p.GetQuantity) * models.Cents(l.UnitPrice)
+			})
+		case models.PromoPercentage:
+			if p.ProductID != "" {
+				amount = q.discountLines(p.ProductID, func(l Line) int64 { return percent(models.Cents(l.Gross()), p.Value) })
+			} else {
+				amount = q.discountOrder(percent(models.Cents(q.Net()), p.Value))
+			}
+		case models.PromoFixed:
+			amount = q.discountOrder(models.Cents(p.Value))
+		case models.PromoFreeItem:
+			gift, ok := gifts[p.FreeProductID]
+			if !ok { continue }
+			gift.ProductID, gift.Quantity, gift.Gift = p.FreeProductID, max(p.FreeQuantity, 1), true
+			gift.Discount = gift.Gross()
+			q.Lines = append(q.Lines, gift)
+			amount = models.Cents(gift.Discount)
+		}
+		q.sum()
+		if amount <= 0 { continue }
+		q.Discounts = append(q.Discounts, models.AppliedDiscount{PromotionID: p.ID, Code: p.Code, Name: p.Name, Type: p.Type, Amount: float64(amount) / 100})
+	}
+}
+
+func lineLevel(p *models.Promotion) bool {
+	return p.Type == models.PromoBuyXGetY || p.Type == models.PromoFreeItem || (p.Type == models.PromoPercentage && p.ProductID != "")
+}
+
+func percent(cents int64, pct float64) int64 { return int64(float64(cents)*pct/100 + 0.5) }
+
+// discountLines descuenta de cada línea (no regalo) de productID lo que diga
+// fn, sin pasar de lo que le queda; devuelve el total descontado en céntimos.
+func (q *Quote) discountLines(productID string, fn func(Line) int64) int64 {
+	var total int64
+	for i, l := range q.Lines {
+		if l.Gift || l.ProductID != productID { continue }
+		d := min(fn(l), models.Cents(l.Gross())-models.Cents(l.Discount))
+		if d <= 0 { continue }
+		q.Lines[i].Discount = float64(models.Cents(l.Discount)+d) / 100
+		total += d
+	}
+	return total
+}
+
+// discountOrder reparte amount céntimos entre las líneas en proporción a lo
+// que le queda a cada una; los céntimos del redondeo van a las primeras
+// líneas que aún tengan margen.
+func (q *Quote) discountOrder(amount int64) int64 {
+	left := make([]int64, len(q.Lines))
+	var sum int64
+	for i, l := range q.Lines {
+		left[i] = models.Cents(l.Gross()) - models.Cents(l.Discount)
+		sum += left[i]
+	}
+	amount = min(amount, sum)
+	if amount <= 0 { return 0 }
+	share := make([]int64, len(q.Lines))
+	rest := amount
+	for i := range q.Lines {
+		if left[i] <= 0 { continue }
+		share[i] = amount * left[i] / sum
+		rest -= share[i]
+	}
+	for i := 0; rest > 0; i = (i + 1) % len(q.Lines) {
+		if share[i] < left[i] { share[i]++; rest-- }
+	}
+	for i := range q.Lines {
+		q.Lines[i].Discount = float64(models.Cents(q.Lines[i].Discount)+share[i]) / 100
+	}
+	return amount
+}
@@ -0,0 +1,94 @@
+package pricing
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/huntercenter1/backend-test/order-service/internal/models"
+)
+
+var now = time.Date(2025, 8, 9, 12, 0, 0, 0, time.UTC)
+
+func quote() *Quote {
+	return NewQuote("u1", []Line{
+		{ProductID: "p1", Quantity: 5, UnitPrice: 10},
+		{ProductID: "p2", Quantity: 1, UnitPrice: 50},
+	})
+}
+
+func TestEligible(t *testing.T) {
+	before, after := now.Add(-time.Hour), now.Add(time.Hour)
+	cases := []struct {
+		name string
+		p    models.Promotion
+		want error
+	}{
+		{"active", models.Promotion{Active: true, StartsAt: &before, EndsAt: &after, MinSpend: 100}, nil},
+		{"inactive", models.Promotion{}, ErrPromotionInactive},
+		{"not started", models.Promotion{Active: true, StartsAt: &after}, ErrPromotionNotStarted},
+		{"expired", models.Promotion{Active: true, EndsAt: &now}, ErrPromotionExpired},
+		{"min spend", models.Promotion{Active: true, MinSpend: 100.01}, ErrMinSpend},
+	}
+	for _, c := range cases {
+		if err := Eligible(&c.p, 100, now); !errors.Is(err, c.want) { t.Errorf("%s: got %v want %v", c.name, err, c.want) }
+	}
+}
+
+func TestApplyPromotionTypes(t *testing.T) {
+	cases := []struct {
+		name  string
+		promo models.Promotion
+		want  float64
+	}{
+		{"percentage", models.Promotion{Type: models.PromoPercentage, Value: 10}, 10},
+		{"percentage of product", models.Promotion{Type: models.PromoPercentage, Value: 50, ProductID: "p2"}, 25},
+		{"fixed", models.Promotion{Type: models.PromoFixed, Value: 15}, 15},
+		{"fixed over total", models.Promotion{Type: models.PromoFixed, Value: 500}, 100},
+		// 5 unidades: un grupo de 2+1, la quinta no completa otro
+		{"buy 2 get 1", models.Promotion{Type: models.PromoBuyXGetY, ProductID: "p1", BuyQuantity: 2, GetQuantity: 1}, 10},
+		{"free item", models.Promotion{Type: models.PromoFreeItem, FreeProductID: "g1", FreeQuantity: 2}, 8},
+		{"below min spend", models.Promotion{Type: models.PromoFixed, Value: 5, MinSpend: 200}, 0},
+	}
+	for _, c := range cases {
+		q := quote()
+		c.promo.Active = true
+		ApplyPromotions(q, []models.Promotion{c.promo}, map[string]Line{"g1": {UnitPrice: 4}}, now)
+		if q.DiscountTotal != c.want { t.Errorf("%s: discount=%v want %v", c.name, q.DiscountTotal, c.want) }
+		if q.Total != q.Subtotal-q.DiscountTotal { t.Errorf("%s: total=%v subtotal=%v", c.name, q.Total, q.Subtotal) }
+		if c.want == 0 && len(q.Discounts) != 0 { t.Errorf("%s: unexpected breakdown %+v", c.name, q.Discounts) }
+	}
+}
+
+func TestFreeItemAddsGiftLine(t *testing.T) {
+	q := quote()
+	ApplyPromotions(q, []models.Promotion{{Active: true, Type: models.PromoFreeItem, FreeProductID: "g1"}, {Active: true, Type: models.PromoFreeItem, FreeProductID: "missing"}}, map[string]Line{"g1": {UnitPrice: 4, WeightGrams: 100}}, now)
+	if len(q.Lines) != 3 { t.Fatalf("lines=%+v", q.Lines) }
+	g := q.Lines[2]
+	if !g.Gift || g.Quantity != 1 || g.Discount != 4 || g.WeightGrams != 100 { t.Fatalf("gift=%+v", g) }
+	// el regalo no cuenta como compra: el total no cambia
+	if q.Subtotal != 104 || q.Total != 100 { t.Fatalf("subtotal=%v total=%v", q.Subtotal, q.Total) }
+}
+
+// Las de producto van antes que las de pedido aunque lleguen después, y el
+// porcentaje de pedido se calcula sobre lo que queda.
+func TestApplyPromotionsOrderAndAllocation(t *testing.T) {
+	q := quote()
+	ApplyPromotions(q, []models.Promotion{
+		{ID: "pct", Active: true, Type: models.PromoPercentage, Value: 10},
+		{ID: "bxgy", Active: true, Type: models.PromoBuyXGetY, ProductID: "p1", BuyQuantity: 1, GetQuantity: 1},
+	}, nil, now)
+	if len(q.Discounts) != 2 || q.Discounts[0].PromotionID != "bxgy" || q.Discounts[0].Amount != 20 || q.Discounts[1].Amount != 8 {
+		t.Fatalf("breakdown=%+v", q.Discounts)
+	}
+	// p1 queda en 30 y p2 en 50: el 10% (8) se reparte 3 y 5
+	if q.Lines[0].Discount != 23 || q.Lines[1].Discount != 5 || q.Total != 72 { t.Fatalf("lines=%+v total=%v", q.Lines, q.Total) }
+}
+
+func TestDiscountOrderSpreadsCents(t *testing.T) {
+	q := NewQuote("u1", []Line{{ProductID: "a", Quantity: 1, UnitPrice: 1}, {ProductID: "b", Quantity: 1, UnitPrice: 1}, {ProductID: "c", Quantity: 1, UnitPrice: 1}})
+	ApplyPromotions(q, []models.Promotion{{Active: true, Type: models.PromoFixed, Value: 1}}, nil, now)
+	var sum float64
+	for _, l := range q.Lines { sum += l.Discount }
+	if q.DiscountTotal != 1 || models.Cents(sum) != 100 || q.Lines[0].Discount != 0.34 { t.Fatalf("lines=%+v", q.Lines) }
+}
@@ -0,0 +1,75 @@
+// Package pricing calcula el importe de un pedido a partir de sus líneas a
+// precio de catálogo: subtotal, descuentos de promociones (con su reparto
+// por línea), impuestos, portes y total. El cálculo es un Pipeline de pasos
+// (Stage) que se ejecutan en orden sobre el mismo Quote; las reglas llegan
+// por interfaces, el paquete no accede a la base ni a otros servicios.
+package pricing
+
+import "github.com/huntercenter1/backend-test/order-service/internal/models"
+
+// Line es una línea del pedido a precio de catálogo. Discount es la parte de
+// los descuentos que le corresponde; Gift marca los regalos de free_item.
+// Tax y Shipping los rellenan los pasos de impuestos y portes. Product no
+// interviene en el cálculo: viaja hasta la línea del pedido.
+type Line struct {
+	ProductID   string
+	Product     models.ProductSnapshot
+	Quantity    int
+	UnitPrice   float64
+	TaxClass    string
+	WeightGrams int // por unidad
+	Discount    float64
+	Gift        bool
+	TaxRate     float64
+	Tax         float64
+	Shipping    float64
+}
+
+// Gross es el importe de la línea sin descuentos.
+func (l Line) Gross() float64 { return float64(models.Cents(l.UnitPrice)*int64(l.Quantity)) / 100 }
+
+// Net es el importe de la línea con su parte de los descuentos; es la base
+// de los impuestos.
+func (l Line) Net() float64 { return float64(models.Cents(l.Gross())-models.Cents(l.Discount)) / 100 }
+
+// Quote es el cálculo de un pedido. Region y CouponCode son los datos del
+// pedido que usan los pasos; CouponApplied lo marca el paso que acepta el
+// cupón.
+type Quote struct {
+	UserID        string
+	Region        string
+	CouponCode    string
+	CouponApplied bool
+	Lines         []Line
+	Subtotal      float64
+	Discounts     []models.AppliedDiscount
+	DiscountTotal float64
+	TaxTotal      float64
+	ShippingTotal float64
+	Total         float64
+}
+
+// NewQuote prepara el cálculo de las líneas sin ningún descuento.
+func NewQuote(userID string, lines []Line) *Quote {
+	q := &Quote{UserID: userID, Lines: lines, Discounts: []models.AppliedDiscount{}}
+	q.sum()
+	return q
+}
+
+// Net es lo comprado una vez aplicados los descuentos, sin impuestos ni
+// portes.
+func (q *Quote) Net() float64 { return float64(models.Cents(q.Subtotal)-models.Cents(q.DiscountTotal)) / 100 }
+
+// sum recalcula subtotal, descuento, impuestos, portes y total a partir de
+// las líneas.
+func (q *Quote) sum() {
+	var sub, disc, tax, ship int64
+	for _, l := range q.Lines {
+		sub += models.Cents(l.Gross())
+		disc += models.Cents(l.Discount)
+		tax += models.Cents(l.Tax)
+		ship += models.Cents(l.Shipping)
+	}
+	q.Subtotal, q.DiscountTotal, q.TaxTotal, q.ShippingTotal = float64(sub)/100, float64(disc)/100, float64(tax)/100, float64(ship)/100
+	q.Total = float64(sub-disc+tax+ship) / 100
+}
@@ -0,0 +1,88 @@
+package pricing
+
+import (
+	"context"
+	"math"
+
+	"github.com/huntercenter1/backend-test/order-service/internal/models"
+)
+
+// ShippingRules da las tarifas de una región y las de models.AnyRegion.
+type ShippingRules interface {
+	ShippingRules(ctx context.Context, region string) ([]models.ShippingRule, error)
+}
+
+// Shipping es el paso de portes: calcula la tarifa con ShippingFee y la
+// reparte entre las líneas según su peso.
+func Shipping(rules ShippingRules) Stage {
+	return StageFunc(func(ctx context.Context, q *Quote) error {
+		list, err := rules.ShippingRules(ctx, q.Region)
+		if err != nil { return err }
+		ApplyShipping(q, list)
+		return nil
+	})
+}
+
+// ApplyShipping fija Shipping en cada línea. El reparto es proporcional al
+// peso (a las unidades si nada pesa); los céntimos del redondeo van a las
+// primeras líneas.
+func ApplyShipping(q *Quote, rules []models.ShippingRule) {
+	var grams int
+	for _, l := range q.Lines { grams += l.WeightGrams * l.Quantity }
+	fee := ShippingFee(rules, q.Region, q.Net(), grams)
+
+	weights := make([]int64, len(q.Lines))
+	var sum int64
+	for i, l := range q.Lines {
+		weights[i] = int64(l.WeightGrams * l.Quantity)
+		if grams == 0 { weights[i] = int64(l.Quantity) }
+		sum += weights[i]
+	}
+	amount, rest := models.Cents(fee), models.Cents(fee)
+	for i := range q.Lines {
+		var share int64
+		if sum > 0 { share = amount * weights[i] / sum }
+		q.Lines[i].Shipping = float64(share) / 100
+		rest -= share
+	}
+	for i := 0; rest > 0 && len(q.Lines) > 0; i = (i + 1) % len(q.Lines) {
+		q.Lines[i].Shipping = float64(models.Cents(q.Lines[i].Shipping)+1) / 100
+		rest--
+	}
+	q.sum()
+}
+
+// ShippingFee usa las tarifas de la región o, si no tiene ninguna, las de
+// models.AnyRegion. El envío es gratis si se cumple alguna free_over; si no,
+// se cobra la más barata de las flat y weight. Sin tarifas no hay portes.
+func ShippingFee(rules []models.ShippingRule, region string, net float64, grams int) float64 {
+	var own, fallback []models.ShippingRule
+	for _, r := range rules {
+		switch r.Region {
+		case region:
+			own = append(own, r)
+		case models.AnyRegion:
+			fallback = append(fallback, r)
+		}
+	}
+	if len(own) == 0 { own = fallback }
+
+	fee := -1.0
+	for _, r := range own {
+		var f float64
+		switch r.Type {
+		case models.ShippingFreeOver:
+			if models.Cents(net) >= models.Cents(r.Threshold) { return 0 }
+			continue
+		case models.ShippingFlat:
+			f = r.Fee
+		case models.ShippingWeight:
+			f = r.Fee + r.PerKg*math.Ceil(float64(grams)/1000)
+		default:
+			continue
+		}
+		if fee < 0 || f < fee { fee = f }
+	}
+	if fee < 0 { return 0 }
+	return float64(models.Cents(fee)) / 100
+}
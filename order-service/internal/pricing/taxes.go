@@ -0,0 +1,65 @@
+package pricing
+
+import (
+	"context"
+
+	"github.com/huntercenter1/backend-test/order-service/internal/models"
+)
+
+// TaxRules da las reglas que pueden aplicar en una región: las suyas y las
+// de models.AnyRegion.
+type TaxRules interface {
+	TaxRules(ctx context.Context, region string) ([]models.TaxRule, error)
+}
+
+// Taxes es el paso de impuestos: cada línea paga el tipo de su regla sobre
+// su importe neto (Line.Net), redondeado a céntimos por línea.
+func Taxes(rules TaxRules) Stage {
+	return StageFunc(func(ctx context.Context, q *Quote) error {
+		list, err := rules.TaxRules(ctx, q.Region)
+		if err != nil { return err }
+		ApplyTaxes(q, list)
+		return nil
+	})
+}
+
+// ApplyTaxes fija TaxRate y Tax de cada línea; las que no tienen regla no
+// pagan impuestos.
+func ApplyTaxes(q *Quote, rules []models.TaxRule) {
+	for i, l := range q.Lines {
+		q.Lines[i].TaxRate, q.Lines[i].Tax = 0, 0
+		r := MatchTaxRule(rules, q.Region, l.TaxClass)
+		if r == nil { continue }
+		q.Lines[i].TaxRate = r.Rate
+		q.Lines[i].Tax = float64(percent(models.Cents(l.Net()), r.Rate)) / 100
+	}
+	q.sum()
+}
+
+// MatchTaxRule elige la regla más específica para la región y la clase:
+// región y clase exactas, región exacta y cualquier clase, cualquier región
+// y la clase, y por último la comodín de ambas. nil si ninguna aplica.
+func MatchTaxRule(rules []models.TaxRule, region, taxClass string) *models.TaxRule {
+	var best *models.TaxRule
+	score := -1
+	for i := range rules {
+		r := &rules[i]
+		s := 0
+		switch r.Region {
+		case region:
+			s += 2
+		case models.AnyRegion:
+		default:
+			continue
+		}
+		switch r.TaxClass {
+		case taxClass:
+			s++
+		case models.AnyTaxClass:
+		default:
+			continue
+		}
+		if s > score { best, score = r, s }
+	}
+	return best
+}
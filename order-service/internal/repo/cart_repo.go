@@ -0,0 +1,199 @@
+package repo
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/uptrace/bun"
+
+	"github.com/huntercenter1/backend-test/order-service/internal/models"
+)
+
+var (
+	// ErrCartNotOpen: el carrito ya se pagó, se fusionó con otro o está en checkout.
+	ErrCartNotOpen = errors.New("cart is not open")
+	// ErrCartOwned: sólo se fusionan carritos de invitado.
+	ErrCartOwned = errors.New("cart already belongs to a user")
+)
+
+// CartRepo guarda los carritos en orders_db. Todas las escrituras sobre
+// los items bloquean la fila del carrito y comprueban que sigue abierto.
+type CartRepo interface {
+	Create(ctx context.Context, c *models.Cart) (*models.Cart, error)
+	Get(ctx context.Context, id string) (*models.Cart, error)
+	OpenByUser(ctx context.Context, userID string) (*models.Cart, error)
+	AddItem(ctx context.Context, cartID, productID string, qty int, price float64) error
+	SetQuantity(ctx context.Context, cartID, productID string, qty int) error
+	RemoveItem(ctx context.Context, cartID, productID string) error
+	Merge(ctx context.Context, guestID, userID string) (string, error)
+	BeginCheckout(ctx context.Context, id string) (*models.Cart, error)
+	FinishCheckout(ctx context.Context, id, orderID string) error
+	AbortCheckout(ctx context.Context, id string) error
+}
+
+type cartRepo struct{ db *bun.DB }
+
+func NewCartRepo(db *bun.DB) CartRepo { return &cartRepo{db: db} }
+
+func (r *cartRepo) Create(ctx context.Context, c *models.Cart) (*models.Cart, error) {
+	ctx, cancel := context.WithTimeout(ctx, timeout); defer cancel()
+	c.Status = models.CartOpen
+	if _, err := r.db.NewInsert().Model(c).Returning("*").Exec(ctx); err != nil { return nil, err }
+	c.Items = []models.CartItem{}
+	return c, nil
+}
+
+func (r *cartRepo) Get(ctx context.Context, id string) (*models.Cart, error) {
+	ctx, cancel := context.WithTimeout(ctx, timeout); defer cancel()
+	return r.get(ctx, r.db.NewSelect().Where("c.id = ?", id))
+}
+
+func (r *cartRepo) OpenByUser(ctx context.Context, userID string) (*models.Cart, error) {
+	ctx, cancel := context.WithTimeout(ctx, timeout); defer cancel()
+	return r.get(ctx, r.db.NewSelect().Where("c.user_id = ? AND c.status = ?", userID, models.CartOpen))
+}
+
+func (r *cartRepo) get(ctx context.Context, q *bun.SelectQuery) (*models.Cart, error) {
+	var c models.Cart
+	err := q.Model(&c).Relation("Items", func(q *bun.SelectQuery) *bun.SelectQuery { return q.Order("ci.created_at", "ci.product_id") }).Scan(ctx)
+	if errors.Is(err, sql.ErrNoRows) { return nil, ErrNotFound }
+	if err != nil { return nil, err }
+	if c.Items == nil { c.Items = []models.CartItem{} }
+	return &c, nil
+}
+
+// AddItem suma qty a la línea del producto (o la crea) y guarda el precio
+// vigente como precio de referencia.
+func (r *cartRepo) AddItem(ctx context.Context, cartID, productID string, qty int, price float64) error {
+	return r.inOpenCart(ctx, cartID, func(ctx context.Context, tx bun.Tx) error {
+		return upsertItem(ctx, tx, cartID, productID, qty, price)
+	})
+}
+
+func (r *cartRepo) SetQuantity(ctx context.Context, cartID, productID string, qty int) error {
+	return r.inOpenCart(ctx, cartID, func(ctx context.Context, tx bun.Tx) error {
+		res, err := tx.NewUpdate().Model((*models.CartItem)(nil)).
+			Set("quantity = ?", qty).Set("updated_at = ?", time.Now()).
+			Where("cart_id = ? AND product_id = ?", cartID, productID).Exec(ctx)
+		return affected(res, err)
+	})
+}
+
+func (r *cartRepo) RemoveItem(ctx context.Context, cartID, productID string) error {
+	return r.inOpenCart(ctx, cartID, func(ctx context.Context, tx bun.Tx) error {
+		res, err := tx.NewDelete().Model((*models.CartItem)(nil)).Where("cart_id = ? AND product_id = ?", cartID, productID).Exec(ctx)
+		return affected(res, err)
+	})
+}
+
+// Merge pasa el carrito de invitado guestID al usuario. Si el usuario no
+// tiene carrito abierto, el de invitado pasa a ser suyo; si lo tiene, se
+// suman las cantidades en el suyo y el de invitado queda como merged.
+// Devuelve el id del carrito resultante.
+func (r *cartRepo) Merge(ctx context.Context, guestID, userID string) (string, error) {
+	ctx, cancel := context.WithTimeout(ctx, timeout); defer cancel()
+	target := guestID
+	err := r.db.RunInTx(ctx, nil, func(ctx context.Context, tx bun.Tx) error {
+		guest, err := lockCart(ctx, tx, "id = ?", guestID)
+		if err != nil { return err }
+		if guest.Status != models.CartOpen { return ErrCartNotOpen }
+		if guest.UserID != nil {
+			if *guest.UserID == userID { return nil } // ya fusionado: idempotente
+			return ErrCartOwned
+		}
+
+		own, err := lockCart(ctx, tx, "user_id = ? AND status = ?", userID, models.CartOpen)
+		if errors.Is(err, ErrNotFound) {
+			_, err = tx.NewUpdate().Model((*models.Cart)(nil)).Set("user_id = ?", userID).Set("updated_at = ?", time.Now()).Where("id = ?", guestID).Exec(ctx)
+			return err
+		}
+		if err != nil { return err }
+
+		var items []models.CartItem
+		if err := tx.NewSelect().Model(&items).Where("cart_id = ?", guestID).Scan(ctx); err != nil { return err }
+		for _, it := range items {
+			if err := upsertItem(ctx, tx, own.ID, it.ProductID, it.Quantity, it.AddedPrice); err != nil { return err }
+		}
+		now := time.Now()
+		if _, err := tx.NewUpdate().Model((*models.Cart)(nil)).Set("status = ?", models.CartMerged).Set("updated_at = ?", now).Where("id = ?", guestID).Exec(ctx); err != nil { return err }
+		if _, err := tx.NewUpdate().Model((*models.Cart)(nil)).Set("updated_at = ?", now).Where("id = ?", own.ID).Exec(ctx); err != nil { return err }
+		target = own.ID
+		return nil
+	})
+	return target, err
+}
+
+// BeginCheckout pasa el carrito de open a checking_out de forma atómica: si
+// dos peticiones hacen checkout a la vez sólo una lo consigue.
+func (r *cartRepo) BeginCheckout(ctx context.Context, id string) (*models.Cart, error) {
+	ctx, cancel := context.WithTimeout(ctx, timeout); defer cancel()
+	res, err := r.db.NewUpdate().Model((*models.Cart)(nil)).
+		Set("status = ?", models.CartCheckingOut).Set("updated_at = ?", time.Now()).
+		Where("id = ? AND status = ?", id, models.CartOpen).Exec(ctx)
+	if err := affected(res, err); err != nil {
+		if errors.Is(err, ErrNotFound) {
+			if _, gerr := r.get(ctx, r.db.NewSelect().Where("c.id = ?", id)); gerr == nil { return nil, ErrCartNotOpen }
+		}
+		return nil, err
+	}
+	return r.get(ctx, r.db.NewSelect().Where("c.id = ?", id))
+}
+
+func (r *cartRepo) FinishCheckout(ctx context.Context, id, orderID string) error {
+	return r.setStatus(ctx, id, models.CartCheckedOut, &orderID)
+}
+
+// AbortCheckout devuelve el carrito a open si no se pudo crear el pedido.
+func (r *cartRepo) AbortCheckout(ctx context.Context, id string) error {
+	return r.setStatus(ctx, id, models.CartOpen, nil)
+}
+
+func (r *cartRepo) setStatus(ctx context.Context, id, status string, orderID *string) error {
+	ctx, cancel := context.WithTimeout(ctx, timeout); defer cancel()
+	res, err := r.db.NewUpdate().Model((*models.Cart)(nil)).
+		Set("status = ?", status).Set("order_id = ?", orderID).Set("updated_at = ?", time.Now()).
+		Where("id = ? AND status = ?", id, models.CartCheckingOut).Exec(ctx)
+	return affected(res, err)
+}
+
+// inOpenCart ejecuta fn en una transacción con la fila del carrito
+// bloqueada, después de comprobar que está abierto.
+func (r *cartRepo) inOpenCart(ctx context.Context, cartID string, fn func(ctx context.Context, tx bun.Tx) error) error {
+	ctx, cancel := context.WithTimeout(ctx, timeout); defer cancel()
+	return r.db.RunInTx(ctx, nil, func(ctx context.Context, tx bun.Tx) error {
+		c, err := lockCart(ctx, tx, "id = ?", cartID)
+		if err != nil { return err }
+		if c.Status != models.CartOpen { return ErrCartNotOpen }
+		if err := fn(ctx, tx); err != nil { return err }
+		_, err = tx.NewUpdate().Model((*models.Cart)(nil)).Set("updated_at = ?", time.Now()).Where("id = ?", cartID).Exec(ctx)
+		return err
+	})
+}
+
+func lockCart(ctx context.Context, tx bun.Tx, where string, args ...any) (*models.Cart, error) {
+	var c models.Cart
+	err := tx.NewSelect().Model(&c).Where(where, args...).For("UPDATE").Scan(ctx)
+	if errors.Is(err, sql.ErrNoRows) { return nil, ErrNotFound }
+	if err != nil { return nil, err }
+	return &c, nil
+}
+
+func upsertItem(ctx context.Context, tx bun.Tx, cartID, productID string, qty int, price float64) error {
+	now := time.Now()
+	_, err := tx.NewInsert().Model(&models.CartItem{CartID: cartID, ProductID: productID, Quantity: qty, AddedPrice: price, CreatedAt: now, UpdatedAt: now}).
+		ExcludeColumn("id").
+		On("CONFLICT (cart_id, product_id) DO UPDATE").
+		Set("quantity = ci.quantity + EXCLUDED.quantity").
+		Set("added_price = EXCLUDED.added_price").
+		Set("updated_at = EXCLUDED.updated_at").
+		Exec(ctx)
+	return err
+}
+
+func affected(res sql.Result, err error) error {
+	if err != nil { return err }
+	if n, _ := res.RowsAffected(); n == 0 { return ErrNotFound }
+	return nil
+}
@@ -0,0 +1,110 @@
+package repo
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"slices"
+	"time"
+
+	"github.com/uptrace/bun"
+
+	"github.com/huntercenter1/backend-test/order-service/internal/models"
+)
+
+// RestockFunc devuelve el stock de items a product-service y dice qué líneas
+// (por id) se han repuesto; las que falten se reintentan en otra pasada.
+type RestockFunc func(ctx context.Context, o *models.Order, items []models.OrderItem) []string
+
+// ExpiryRepo caduca los pedidos sin pagar (pending o payment_failed) y
+// repone el stock de las líneas cuya venta lo descontó (stock_applied).
+// Cada pedido se trata en
+// su propia transacción con la fila bloqueada con FOR UPDATE SKIP LOCKED:
+// varias réplicas pueden buscar a la vez sin tocar el mismo pedido ni
+// esperarse unas a otras.
+type ExpiryRepo interface {
+	// ExpireNext toma el siguiente pedido pendiente de tratar: uno sin pagar
+	// creado antes de before sin pagos en curso, o un expired o cancelled con
+	// líneas descontadas aún sin reponer que no se haya intentado desde
+	// retryBefore. Llama a restock con esas líneas y, en la misma
+	// transacción, deja el pedido sin pagar en expired, marca las repuestas
+	// y devuelve los usos de sus promociones. Devuelve nil cuando no queda
+	// ninguno; expired indica si el pedido acaba de caducar (false si sólo se
+	// reintentaba el stock).
+	ExpireNext(ctx context.Context, before, retryBefore time.Time, restock RestockFunc) (o *models.Order, expired bool, err error)
+	// Backlog cuenta los pedidos que ExpireNext trataría con los mismos
+	// before y retryBefore.
+	Backlog(ctx context.Context, before, retryBefore time.Time) (int, error)
+}
+
+type expiryRepo struct{ db *bun.DB }
+
+func NewExpiryRepo(db *bun.DB) ExpiryRepo { return &expiryRepo{db: db} }
+
+// inFlight son los pagos que aún pueden cobrar: un pedido con alguno ni
+// caduca ni se puede cancelar.
+var inFlight = []string{models.PaymentPending, models.PaymentAuthorized, models.PaymentPartiallyCaptured}
+
+// unpaid son los estados que caducan: payment_failed aún admite otro pago,
+// así que retiene el stock igual que pending.
+var unpaid = []string{models.OrderPending, models.OrderPaymentFailed}
+
+// closed son los estados en que acaba un pedido sin pagar (ver closeOrder):
+// sus líneas que no se pudieron reponer se reintentan.
+var closed = []string{models.OrderExpired, models.OrderCancelled}
+
+func expirable(q *bun.SelectQuery, before, retryBefore time.Time) *bun.SelectQuery {
+	return q.WhereGroup(" AND ", func(q *bun.SelectQuery) *bun.SelectQuery {
+		return q.
+			WhereGroup(" OR ", func(q *bun.SelectQuery) *bun.SelectQuery {
+				return q.Where("o.status IN (?) AND o.created_at < ?", bun.In(unpaid), before).
+					Where("NOT EXISTS (SELECT 1 FROM payments AS p WHERE p.order_id = o.id AND p.status IN (?))", bun.In(inFlight))
+			}).
+			WhereGroup(" OR ", func(q *bun.SelectQuery) *bun.SelectQuery {
+				return q.Where("o.status IN (?) AND o.updated_at < ?", bun.In(closed), retryBefore).
+					Where("EXISTS (SELECT 1 FROM order_items AS oi WHERE oi.order_id = o.id AND oi.stock_applied AND NOT oi.restocked)")
+			})
+	})
+}
+
+// ExpireNext no lleva el timeout del resto del repo: dentro van las llamadas
+// a product-service y lo acota quien llama.
+func (r *expiryRepo) ExpireNext(ctx context.Context, before, retryBefore time.Time, restock RestockFunc) (*models.Order, bool, error) {
+	var o models.Order
+	var expired bool
+	err := r.db.RunInTx(ctx, nil, func(ctx context.Context, tx bun.Tx) error {
+		// por updated_at: un pedido cuyo stock falla pasa al final de la cola
+		err := expirable(tx.NewSelect().Model(&o), before, retryBefore).Order("o.updated_at").Limit(1).For("UPDATE OF o SKIP LOCKED").Scan(ctx)
+		if err != nil { return err }
+		expired = slices.Contains(unpaid, o.Status)
+		to := o.Status
+		if expired { to = models.OrderExpired }
+		return closeOrder(ctx, tx, &o, to, restock)
+	})
+	if errors.Is(err, sql.ErrNoRows) { return nil, false, nil }
+	if err != nil { return nil, false, err }
+	return &o, expired, nil
+}
+
+// closeOrder es el camino común de la caducidad y la cancelación, con la
+// fila del pedido bloqueada: llama a restock con sus líneas descontadas sin
+// reponer, marca las repuestas, deja el pedido en to y, si sale de un estado
+// sin pagar, devuelve los usos de sus promociones (en los reintentos ya están
+// libres).
+func closeOrder(ctx context.Context, tx bun.Tx, o *models.Order, to string, restock RestockFunc) error {
+	var items []models.OrderItem
+	if err := orderItems(tx.NewSelect().Model(&items)).Where("order_id = ? AND stock_applied AND NOT restocked", o.ID).Scan(ctx); err != nil { return err }
+	if done := restock(ctx, o, items); len(done) > 0 {
+		if _, err := tx.NewUpdate().Model((*models.OrderItem)(nil)).Set("restocked = true").Where("id IN (?)", bun.In(done)).Exec(ctx); err != nil { return err }
+	}
+	release := slices.Contains(unpaid, o.Status)
+	o.Status, o.UpdatedAt = to, time.Now()
+	if _, err := tx.NewUpdate().Model(o).Column("status", "updated_at").WherePK().Exec(ctx); err != nil { return err }
+	if release { return releaseRedemptions(ctx, tx, o.ID) }
+	return nil
+}
+
+func (r *expiryRepo) Backlog(ctx context.Context, before, retryBefore time.Time) (int, error) {
+	ctx, cancel := context.WithTimeout(ctx, timeout); defer cancel()
+	return expirable(r.db.NewSelect().Model((*models.Order)(nil)), before, retryBefore).Count(ctx)
+}
@@ -0,0 +1,50 @@
+package repo
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/uptrace/bun"
+
+	"github.com/huntercenter1/backend-test/order-service/internal/models"
+)
+
+// InvoiceRepo lee las facturas. No hay Create: se emiten en la transacción
+// que deja el pedido en paid (ver issueInvoice).
+type InvoiceRepo interface {
+	ByOrder(ctx context.Context, orderID string) (*models.Invoice, error)
+}
+
+type invoiceRepo struct{ db *bun.DB }
+
+func NewInvoiceRepo(db *bun.DB) InvoiceRepo { return &invoiceRepo{db: db} }
+
+func (r *invoiceRepo) ByOrder(ctx context.Context, orderID string) (*models.Invoice, error) {
+	ctx, cancel := context.WithTimeout(ctx, timeout); defer cancel()
+	var inv models.Invoice
+	err := r.db.NewSelect().Model(&inv).Where("order_id = ?", orderID).Scan(ctx)
+	if errors.Is(err, sql.ErrNoRows) { return nil, ErrNotFound }
+	if err != nil { return nil, err }
+	return &inv, nil
+}
+
+const nextInvoiceSeq = `INSERT INTO invoice_sequences (year, last_number) VALUES (?, 1)
+	ON CONFLICT (year) DO UPDATE SET last_number = invoice_sequences.last_number + 1
+	RETURNING last_number`
+
+// issueInvoice emite la factura del pedido si aún no tiene; hay que llamarla
+// con la fila del pedido bloqueada. El upsert sobre invoice_sequences bloquea
+// el contador del año hasta el commit: dos emisiones simultáneas no se llevan
+// el mismo número y, si la transacción se deshace, el número no se pierde.
+func issueInvoice(ctx context.Context, tx bun.Tx, o *models.Order) error {
+	exists, err := tx.NewSelect().Model((*models.Invoice)(nil)).Where("order_id = ?", o.ID).Exists(ctx)
+	if err != nil || exists { return err }
+	now := time.Now().UTC()
+	inv := &models.Invoice{OrderID: o.ID, Year: now.Year(), UserID: o.UserID, TaxTotal: o.TaxTotal, Total: o.Total, IssuedAt: now}
+	if err := tx.NewRaw(nextInvoiceSeq, inv.Year).Scan(ctx, &inv.Seq); err != nil { return err }
+	inv.Number = models.InvoiceNumber(inv.Year, inv.Seq)
+	_, err = tx.NewInsert().Model(inv).Exec(ctx)
+	return err
+}
@@ -2,7 +2,10 @@ package repo
 
 import (
 	"context"
+	"database/sql"
 	"errors"
+	"fmt"
+	"slices"
 	"time"
 
 	"github.com/uptrace/bun"
@@ -12,7 +15,9 @@ import (
 
 var (
 	ErrNotFound = errors.New("not found")
-	timeout     = 5 * time.Second
+	// ErrOrderState: el pedido no está en un estado desde el que se permita el cambio.
+	ErrOrderState = errors.New("status change not allowed from the order's current status")
+	timeout       = 5 * time.Second
 )
 
 type Repo interface {
@@ -20,7 +25,22 @@ type Repo interface {
 	GetOrder(ctx context.Context, id string) (*models.Order, error)
 	GetItems(ctx context.Context, orderID string) ([]models.OrderItem, error)
 	ListByUser(ctx context.Context, userID string) ([]models.Order, error)
-	UpdateStatus(ctx context.Context, id, status string) (*models.Order, error)
+	// UpdateStatus pasa el pedido a to si está en alguno de from; si no,
+	// devuelve ErrOrderState.
+	UpdateStatus(ctx context.Context, id string, from []string, to string) (*models.Order, error)
+	// Cancel cancela el pedido si está en alguno de from y no tiene pagos en
+	// curso (si no, ErrOrderState) por el mismo camino que la caducidad: llama a restock
+	// con sus líneas descontadas y, en la misma transacción, marca las
+	// repuestas y devuelve los usos de sus promociones. Las que no se
+	// repongan las reintenta el worker de caducidad.
+	Cancel(ctx context.Context, id string, from []string, restock RestockFunc) (*models.Order, error)
+	// MarkStockApplied anota que la venta de la línea ya descontó quantity
+	// unidades de stock.
+	MarkStockApplied(ctx context.Context, itemID string, quantity int) error
+	// MarkPaid pasa a paid un pedido pending de total cero y emite su
+	// factura en la misma transacción; desde otro estado devuelve
+	// ErrOrderState.
+	MarkPaid(ctx context.Context, id string) (*models.Order, error)
 }
 
 type repo struct{ db *bun.DB }
@@ -35,11 +55,16 @@ func (r *repo) CreateOrder(ctx context.Context, o *models.Order, items []models.
 			return err
 		}
 		for i := range items {
-			items[i].OrderID = o.ID
+			items[i].OrderID, items[i].Position = o.ID, i
 		}
 		if _, err := tx.NewInsert().Model(&items).Exec(ctx); err != nil {
 			return err
 		}
+		// canjear las promociones aplicadas; si alguna ya no tiene usos no
+		// se crea el pedido
+		for _, d := range o.Discounts {
+			if err := redeem(ctx, tx, d.PromotionID, o); err != nil { return err }
+		}
 		return nil
 	})
 	return o, items, err
@@ -48,7 +73,8 @@ func (r *repo) CreateOrder(ctx context.Context, o *models.Order, items []models.
 func (r *repo) GetOrder(ctx context.Context, id string) (*models.Order, error) {
 	ctx, cancel := context.WithTimeout(ctx, timeout); defer cancel()
 	var o models.Order
-	if err := r.db.NewSelect().Model(&o).Where("id = ?", id).Scan(ctx); err != nil {
+	// las líneas van embebidas: has-many hace una segunda consulta por order_id
+	if err := r.db.NewSelect().Model(&o).Relation("Items", orderItems).Where("o.id = ?", id).Scan(ctx); err != nil {
 		return nil, ErrNotFound
 	}
 	return &o, nil
@@ -57,7 +83,7 @@ func (r *repo) GetOrder(ctx context.Context, id string) (*models.Order, error) {
 func (r *repo) GetItems(ctx context.Context, orderID string) ([]models.OrderItem, error) {
 	ctx, cancel := context.WithTimeout(ctx, timeout); defer cancel()
 	var items []models.OrderItem
-	if err := r.db.NewSelect().Model(&items).Where("order_id = ?", orderID).Scan(ctx); err != nil {
+	if err := orderItems(r.db.NewSelect().Model(&items)).Where("order_id = ?", orderID).Scan(ctx); err != nil {
 		return nil, err
 	}
 	return items, nil
@@ -72,14 +98,66 @@ func (r *repo) ListByUser(ctx context.Context, userID string) ([]models.Order, e
 	return orders, nil
 }
 
-func (r *repo) UpdateStatus(ctx context.Context, id, status string) (*models.Order, error) {
+func (r *repo) UpdateStatus(ctx context.Context, id string, from []string, to string) (*models.Order, error) {
 	ctx, cancel := context.WithTimeout(ctx, timeout); defer cancel()
-	o, err := r.GetOrder(ctx, id)
+	var o models.Order
+	err := r.db.RunInTx(ctx, nil, func(ctx context.Context, tx bun.Tx) error {
+		err := tx.NewSelect().Model(&o).Where("id = ?", id).For("UPDATE").Scan(ctx)
+		if errors.Is(err, sql.ErrNoRows) { return ErrNotFound }
+		if err != nil { return err }
+		if !slices.Contains(from, o.Status) { return fmt.Errorf("%w: %s to %s", ErrOrderState, o.Status, to) }
+		o.Status = to
+		o.UpdatedAt = time.Now()
+		_, err = tx.NewUpdate().Model(&o).Column("status", "updated_at").WherePK().Exec(ctx)
+		return err
+	})
 	if err != nil { return nil, err }
-	o.Status = status
-	o.UpdatedAt = time.Now()
-	if _, err := r.db.NewUpdate().Model(o).Column("status", "updated_at").WherePK().Exec(ctx); err != nil {
-		return nil, err
-	}
-	return o, nil
+	return &o, nil
+}
+
+// Cancel no lleva el timeout del resto del repo: dentro van las llamadas a
+// product-service y lo acota quien llama.
+func (r *repo) Cancel(ctx context.Context, id string, from []string, restock RestockFunc) (*models.Order, error) {
+	var o models.Order
+	err := r.db.RunInTx(ctx, nil, func(ctx context.Context, tx bun.Tx) error {
+		err := tx.NewSelect().Model(&o).Where("id = ?", id).For("UPDATE").Scan(ctx)
+		if errors.Is(err, sql.ErrNoRows) { return ErrNotFound }
+		if err != nil { return err }
+		if !slices.Contains(from, o.Status) { return fmt.Errorf("%w: %s to %s", ErrOrderState, o.Status, models.OrderCancelled) }
+		// como en la caducidad: un pago pendiente o autorizado aún puede cobrar
+		paying, err := tx.NewSelect().Model((*models.Payment)(nil)).
+			Where("order_id = ? AND status IN (?)", o.ID, bun.In(inFlight)).Exists(ctx)
+		if err != nil { return err }
+		if paying { return fmt.Errorf("%w: payment in progress", ErrOrderState) }
+		return closeOrder(ctx, tx, &o, models.OrderCancelled, restock)
+	})
+	if err != nil { return nil, err }
+	return &o, nil
+}
+
+func (r *repo) MarkStockApplied(ctx context.Context, itemID string, quantity int) error {
+	ctx, cancel := context.WithTimeout(ctx, timeout); defer cancel()
+	_, err := r.db.NewUpdate().Model((*models.OrderItem)(nil)).
+		Set("stock_applied = true").Set("applied_quantity = ?", quantity).Where("id = ?", itemID).Exec(ctx)
+	return err
 }
+
+func (r *repo) MarkPaid(ctx context.Context, id string) (*models.Order, error) {
+	ctx, cancel := context.WithTimeout(ctx, timeout); defer cancel()
+	var o models.Order
+	err := r.db.RunInTx(ctx, nil, func(ctx context.Context, tx bun.Tx) error {
+		err := tx.NewSelect().Model(&o).Where("id = ?", id).For("UPDATE").Scan(ctx)
+		if errors.Is(err, sql.ErrNoRows) { return ErrNotFound }
+		if err != nil { return err }
+		if o.Status != models.OrderPending { return fmt.Errorf("%w: %s to %s", ErrOrderState, o.Status, models.OrderPaid) }
+		o.Status = models.OrderPaid
+		o.UpdatedAt = time.Now()
+		if _, err := tx.NewUpdate().Model(&o).Column("status", "updated_at").WherePK().Exec(ctx); err != nil { return err }
+		return issueInvoice(ctx, tx, &o)
+	})
+	if err != nil { return nil, err }
+	return &o, nil
+}
+
+// orderItems devuelve las líneas en el orden en que se crearon.
+func orderItems(q *bun.SelectQuery) *bun.SelectQuery { return q.Order("oi.position") }
@@ -0,0 +1,188 @@
+package repo
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/uptrace/bun"
+
+	"github.com/huntercenter1/backend-test/order-service/internal/models"
+	"github.com/huntercenter1/backend-test/order-service/internal/payments"
+)
+
+var (
+	// ErrDuplicateEvent: el webhook ya se había procesado.
+	ErrDuplicateEvent = errors.New("payment event already processed")
+	// ErrOrderNotPayable: el pedido no admite pagos (p. ej. caducó) o ya no
+	// le queda nada por pagar.
+	ErrOrderNotPayable = errors.New("order cannot be paid in its current status")
+	// ErrAmountExceeds: el importe del pago supera lo que queda por pagar.
+	ErrAmountExceeds = errors.New("amount exceeds what is due")
+)
+
+// PaymentRepo guarda pagos, reembolsos y eventos del proveedor. Cada cambio
+// en un pago recalcula en la misma transacción el estado del pedido (ver
+// models.SettleOrder) y, si queda pagado, emite su factura.
+type PaymentRepo interface {
+	// Create bloquea el pedido y sólo da de alta el pago si sigue pending o
+	// payment_failed y su importe cabe en lo que queda por pagar (ver
+	// models.Due); con Amount 0 paga todo lo pendiente. Con el pedido
+	// bloqueado, dos pagos a la vez no pueden cubrirlo dos veces ni cruzarse
+	// con el worker de caducidad.
+	Create(ctx context.Context, p *models.Payment) (*models.Payment, error)
+	// SaveIntent guarda la referencia del proveedor o el fallo al crear el intent.
+	SaveIntent(ctx context.Context, p *models.Payment) (*models.Payment, error)
+	Get(ctx context.Context, id string) (*models.Payment, error)
+	ByOrder(ctx context.Context, orderID string) ([]models.Payment, error)
+	Refunds(ctx context.Context, orderID string) ([]models.Refund, error)
+	ApplyEvent(ctx context.Context, ev payments.Event, payload []byte) (*models.Payment, error)
+	// Capture y Refund reciben el total acumulado que informa el proveedor,
+	// así da igual si el webhook equivalente llega antes o después.
+	Capture(ctx context.Context, id string, captured float64) (*models.Payment, error)
+	Refund(ctx context.Context, rf *models.Refund, refunded float64) (*models.Payment, error)
+}
+
+type paymentRepo struct{ db *bun.DB }
+
+func NewPaymentRepo(db *bun.DB) PaymentRepo { return &paymentRepo{db: db} }
+
+func (r *paymentRepo) Create(ctx context.Context, p *models.Payment) (*models.Payment, error) {
+	ctx, cancel := context.WithTimeout(ctx, timeout); defer cancel()
+	p.Status = models.PaymentPending
+	err := r.db.RunInTx(ctx, nil, func(ctx context.Context, tx bun.Tx) error {
+		var o models.Order
+		err := tx.NewSelect().Model(&o).Column("status", "total").Where("id = ?", p.OrderID).For("UPDATE").Scan(ctx)
+		if errors.Is(err, sql.ErrNoRows) { return ErrNotFound }
+		if err != nil { return err }
+		if !o.Payable() { return ErrOrderNotPayable }
+		var existing []models.Payment
+		if err := tx.NewSelect().Model(&existing).Where("order_id = ?", p.OrderID).Scan(ctx); err != nil { return err }
+		due := models.Due(o.Total, existing)
+		if due <= 0 { return ErrOrderNotPayable }
+		amount := models.Cents(p.Amount)
+		if amount == 0 { amount = due }
+		if amount > due { return ErrAmountExceeds }
+		p.Amount = float64(amount) / 100
+		_, err = tx.NewInsert().Model(p).Returning("*").Exec(ctx)
+		return err
+	})
+	if err != nil { return nil, err }
+	return p, nil
+}
+
+func (r *paymentRepo) SaveIntent(ctx context.Context, in *models.Payment) (*models.Payment, error) {
+	return r.update(ctx, "id = ?", in.ID, func(ctx context.Context, tx bun.Tx, p *models.Payment) error {
+		p.ProviderRef, p.Status, p.FailureReason = in.ProviderRef, in.Status, in.FailureReason
+		return nil
+	})
+}
+
+func (r *paymentRepo) Get(ctx context.Context, id string) (*models.Payment, error) {
+	ctx, cancel := context.WithTimeout(ctx, timeout); defer cancel()
+	var p models.Payment
+	err := r.db.NewSelect().Model(&p).Where("id = ?", id).Scan(ctx)
+	if errors.Is(err, sql.ErrNoRows) { return nil, ErrNotFound }
+	if err != nil { return nil, err }
+	return &p, nil
+}
+
+func (r *paymentRepo) ByOrder(ctx context.Context, orderID string) ([]models.Payment, error) {
+	ctx, cancel := context.WithTimeout(ctx, timeout); defer cancel()
+	list := []models.Payment{}
+	err := r.db.NewSelect().Model(&list).Where("order_id = ?", orderID).Order("created_at").Scan(ctx)
+	return list, err
+}
+
+func (r *paymentRepo) Refunds(ctx context.Context, orderID string) ([]models.Refund, error) {
+	ctx, cancel := context.WithTimeout(ctx, timeout); defer cancel()
+	list := []models.Refund{}
+	err := r.db.NewSelect().Model(&list).Where("order_id = ?", orderID).Order("created_at").Scan(ctx)
+	return list, err
+}
+
+// ApplyEvent registra el evento en payment_events y lo aplica al pago; si
+// el id ya estaba devuelve ErrDuplicateEvent sin tocar nada.
+func (r *paymentRepo) ApplyEvent(ctx context.Context, ev payments.Event, payload []byte) (*models.Payment, error) {
+	return r.update(ctx, "provider_ref = ?", ev.PaymentRef, func(ctx context.Context, tx bun.Tx, p *models.Payment) error {
+		res, err := tx.NewInsert().Model(&models.PaymentEvent{ID: ev.ID, Type: ev.Type, ProviderRef: ev.PaymentRef, Payload: string(payload), ReceivedAt: time.Now()}).
+			On("CONFLICT (id) DO NOTHING").Exec(ctx)
+		if err != nil { return err }
+		if n, _ := res.RowsAffected(); n == 0 { return ErrDuplicateEvent }
+
+		switch ev.Type {
+		case payments.EventAuthorized:
+			if p.Status == models.PaymentPending { p.Status = models.PaymentAuthorized }
+		case payments.EventCaptured:
+			p.CapturedAmount = max(p.CapturedAmount, ev.AmountCaptured)
+		case payments.EventRefunded:
+			p.RefundedAmount = max(p.RefundedAmount, ev.AmountRefunded)
+		case payments.EventFailed:
+			// un pago que ya cobró algo no pasa a fallido
+			if models.Cents(p.CapturedAmount) == 0 { p.Status, p.FailureReason = models.PaymentFailed, ev.FailureReason }
+		}
+		return nil
+	})
+}
+
+func (r *paymentRepo) Capture(ctx context.Context, id string, captured float64) (*models.Payment, error) {
+	return r.update(ctx, "id = ?", id, func(ctx context.Context, tx bun.Tx, p *models.Payment) error {
+		p.CapturedAmount = max(p.CapturedAmount, captured)
+		return nil
+	})
+}
+
+func (r *paymentRepo) Refund(ctx context.Context, rf *models.Refund, refunded float64) (*models.Payment, error) {
+	return r.update(ctx, "id = ?", rf.PaymentID, func(ctx context.Context, tx bun.Tx, p *models.Payment) error {
+		rf.OrderID = p.OrderID
+		rf.CreatedAt = time.Now()
+		if _, err := tx.NewInsert().Model(rf).Returning("*").Exec(ctx); err != nil { return err }
+		p.RefundedAmount = max(p.RefundedAmount, refunded)
+		return nil
+	})
+}
+
+// update bloquea el pago, aplica fn, recalcula su estado y el del pedido.
+func (r *paymentRepo) update(ctx context.Context, where string, arg any, fn func(ctx context.Context, tx bun.Tx, p *models.Payment) error) (*models.Payment, error) {
+	ctx, cancel := context.WithTimeout(ctx, timeout); defer cancel()
+	var p models.Payment
+	err := r.db.RunInTx(ctx, nil, func(ctx context.Context, tx bun.Tx) error {
+		err := tx.NewSelect().Model(&p).Where(where, arg).For("UPDATE").Scan(ctx)
+		if errors.Is(err, sql.ErrNoRows) { return ErrNotFound }
+		if err != nil { return err }
+		if err := fn(ctx, tx, &p); err != nil { return err }
+		p.Recompute()
+		p.UpdatedAt = time.Now()
+		if _, err := tx.NewUpdate().Model(&p).
+			Column("provider_ref", "status", "captured_amount", "refunded_amount", "failure_reason", "updated_at").
+			WherePK().Exec(ctx); err != nil { return err }
+		return settleOrder(ctx, tx, p.OrderID)
+	})
+	if err != nil { return nil, err }
+	return &p, nil
+}
+
+// settleOrder recalcula el estado y lo devuelto del pedido a partir de todos
+// sus pagos.
+func settleOrder(ctx context.Context, tx bun.Tx, orderID string) error {
+	var o models.Order
+	if err := tx.NewSelect().Model(&o).Where("id = ?", orderID).For("UPDATE").Scan(ctx); err != nil { return err }
+	var captured, refunded float64
+	if err := tx.NewSelect().Model((*models.Payment)(nil)).
+		ColumnExpr("COALESCE(SUM(captured_amount), 0), COALESCE(SUM(refunded_amount), 0)").
+		Where("order_id = ?", orderID).Scan(ctx, &captured, &refunded); err != nil { return err }
+	var last string
+	if err := tx.NewSelect().Model((*models.Payment)(nil)).Column("status").
+		Where("order_id = ?", orderID).Order("created_at DESC").Limit(1).Scan(ctx, &last); err != nil { return err }
+
+	next := models.SettleOrder(o.Status, o.Total, captured, refunded, last == models.PaymentFailed)
+	if next == o.Status && models.Cents(refunded) == models.Cents(o.RefundedTotal) { return nil }
+	if next == models.OrderPaid && o.Status != models.OrderPaid {
+		if err := issueInvoice(ctx, tx, &o); err != nil { return err }
+	}
+	_, err := tx.NewUpdate().Model((*models.Order)(nil)).
+		Set("status = ?", next).Set("refunded_total = ?", refunded).Set("updated_at = ?", time.Now()).
+		Where("id = ?", orderID).Exec(ctx)
+	return err
+}
@@ -0,0 +1,87 @@
+package repo
+
+import (
+	"context"
+	"errors"
+
+	"github.com/uptrace/bun"
+
+	"github.com/huntercenter1/backend-test/order-service/internal/models"
+)
+
+// ErrDuplicateRule: ya hay una regla de impuestos para esa región y clase.
+var ErrDuplicateRule = errors.New("tax rule already exists for region and tax class")
+
+// PricingRuleRepo guarda las reglas de impuestos y de portes. TaxRules y
+// ShippingRules son las que usa el pipeline de precios (pricing.TaxRules y
+// pricing.ShippingRules).
+type PricingRuleRepo interface {
+	CreateTaxRule(ctx context.Context, r *models.TaxRule) (*models.TaxRule, error)
+	ListTaxRules(ctx context.Context) ([]models.TaxRule, error)
+	DeleteTaxRule(ctx context.Context, id string) error
+	TaxRules(ctx context.Context, region string) ([]models.TaxRule, error)
+
+	CreateShippingRule(ctx context.Context, r *models.ShippingRule) (*models.ShippingRule, error)
+	ListShippingRules(ctx context.Context) ([]models.ShippingRule, error)
+	DeleteShippingRule(ctx context.Context, id string) error
+	ShippingRules(ctx context.Context, region string) ([]models.ShippingRule, error)
+}
+
+type pricingRuleRepo struct{ db *bun.DB }
+
+func NewPricingRuleRepo(db *bun.DB) PricingRuleRepo { return &pricingRuleRepo{db: db} }
+
+func (r *pricingRuleRepo) CreateTaxRule(ctx context.Context, t *models.TaxRule) (*models.TaxRule, error) {
+	ctx, cancel := context.WithTimeout(ctx, timeout); defer cancel()
+	if _, err := r.db.NewInsert().Model(t).Returning("*").Exec(ctx); err != nil {
+		// unique (region, tax_class) → tratamos como duplicado
+		return nil, ErrDuplicateRule
+	}
+	return t, nil
+}
+
+func (r *pricingRuleRepo) ListTaxRules(ctx context.Context) ([]models.TaxRule, error) {
+	ctx, cancel := context.WithTimeout(ctx, timeout); defer cancel()
+	list := []models.TaxRule{}
+	err := r.db.NewSelect().Model(&list).Order("region", "tax_class").Scan(ctx)
+	return list, err
+}
+
+func (r *pricingRuleRepo) DeleteTaxRule(ctx context.Context, id string) error {
+	ctx, cancel := context.WithTimeout(ctx, timeout); defer cancel()
+	res, err := r.db.NewDelete().Model((*models.TaxRule)(nil)).Where("id = ?", id).Exec(ctx)
+	return affected(res, err)
+}
+
+func (r *pricingRuleRepo) TaxRules(ctx context.Context, region string) ([]models.TaxRule, error) {
+	ctx, cancel := context.WithTimeout(ctx, timeout); defer cancel()
+	list := []models.TaxRule{}
+	err := r.db.NewSelect().Model(&list).Where("region IN (?, ?)", region, models.AnyRegion).Scan(ctx)
+	return list, err
+}
+
+func (r *pricingRuleRepo) CreateShippingRule(ctx context.Context, s *models.ShippingRule) (*models.ShippingRule, error) {
+	ctx, cancel := context.WithTimeout(ctx, timeout); defer cancel()
+	if _, err := r.db.NewInsert().Model(s).Returning("*").Exec(ctx); err != nil { return nil, err }
+	return s, nil
+}
+
+func (r *pricingRuleRepo) ListShippingRules(ctx context.Context) ([]models.ShippingRule, error) {
+	ctx, cancel := context.WithTimeout(ctx, timeout); defer cancel()
+	list := []models.ShippingRule{}
+	err := r.db.NewSelect().Model(&list).Order("region", "created_at").Scan(ctx)
+	return list, err
+}
+
+func (r *pricingRuleRepo) DeleteShippingRule(ctx context.Context, id string) error {
+	ctx, cancel := context.WithTimeout(ctx, timeout); defer cancel()
+	res, err := r.db.NewDelete().Model((*models.ShippingRule)(nil)).Where("id = ?", id).Exec(ctx)
+	return affected(res, err)
+}
+
+func (r *pricingRuleRepo) ShippingRules(ctx context.Context, region string) ([]models.ShippingRule, error) {
+	ctx, cancel := context.WithTimeout(ctx, timeout); defer cancel()
+	list := []models.ShippingRule{}
+	err := r.db.NewSelect().Model(&list).Where("region IN (?, ?)", region, models.AnyRegion).Scan(ctx)
+	return list, err
+}
@@ -0,0 +1,128 @@
+package repo
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/uptrace/bun"
+
+	"github.com/huntercenter1/backend-test/order-service/internal/models"
+)
+
+var (
+	ErrDuplicateCode = errors.New("promotion code already exists")
+	// ErrPromotionExhausted: se alcanzó el límite global o por usuario al
+	// canjear la promoción.
+	ErrPromotionExhausted = errors.New("promotion usage limit reached")
+)
+
+// PromotionRepo guarda cupones y promociones automáticas. Los usos se
+// canjean en la transacción que crea el pedido (ver repo.CreateOrder) y se
+// devuelven en la que lo caduca o lo cancela (ver releaseRedemptions).
+type PromotionRepo interface {
+	Create(ctx context.Context, p *models.Promotion) (*models.Promotion, error)
+	List(ctx context.Context) ([]models.Promotion, error)
+	SetActive(ctx context.Context, id string, active bool) (*models.Promotion, error)
+	// Automatic devuelve las promociones sin código activas en now que aún
+	// tienen usos para userID.
+	Automatic(ctx context.Context, userID string, now time.Time) ([]models.Promotion, error)
+	ByCode(ctx context.Context, code string) (*models.Promotion, error)
+	// Redemptions cuenta los usos de la promoción por el usuario.
+	Redemptions(ctx context.Context, promotionID, userID string) (int, error)
+}
+
+type promotionRepo struct{ db *bun.DB }
+
+func NewPromotionRepo(db *bun.DB) PromotionRepo { return &promotionRepo{db: db} }
+
+func (r *promotionRepo) Create(ctx context.Context, p *models.Promotion) (*models.Promotion, error) {
+	ctx, cancel := context.WithTimeout(ctx, timeout); defer cancel()
+	if _, err := r.db.NewInsert().Model(p).Returning("*").Exec(ctx); err != nil {
+		// índice unique en code → tratamos como duplicado
+		return nil, ErrDuplicateCode
+	}
+	return p, nil
+}
+
+func (r *promotionRepo) List(ctx context.Context) ([]models.Promotion, error) {
+	ctx, cancel := context.WithTimeout(ctx, timeout); defer cancel()
+	list := []models.Promotion{}
+	err := r.db.NewSelect().Model(&list).Order("created_at DESC").Scan(ctx)
+	return list, err
+}
+
+func (r *promotionRepo) SetActive(ctx context.Context, id string, active bool) (*models.Promotion, error) {
+	ctx, cancel := context.WithTimeout(ctx, timeout); defer cancel()
+	var p models.Promotion
+	res, err := r.db.NewUpdate().Model(&p).Set("active = ?", active).Set("updated_at = ?", time.Now()).
+		Where("id = ?", id).Returning("*").Exec(ctx)
+	if err := affected(res, err); err != nil { return nil, err }
+	return &p, nil
+}
+
+func (r *promotionRepo) Automatic(ctx context.Context, userID string, now time.Time) ([]models.Promotion, error) {
+	ctx, cancel := context.WithTimeout(ctx, timeout); defer cancel()
+	list := []models.Promotion{}
+	err := r.db.NewSelect().Model(&list).
+		Where("pr.code IS NULL AND pr.active").
+		Where("pr.starts_at IS NULL OR pr.starts_at <= ?", now).
+		Where("pr.ends_at IS NULL OR pr.ends_at > ?", now).
+		Where("pr.max_uses IS NULL OR pr.uses < pr.max_uses").
+		Where("pr.max_uses_per_user IS NULL OR (SELECT count(*) FROM promotion_redemptions AS prr WHERE prr.promotion_id = pr.id AND prr.user_id = ?) < pr.max_uses_per_user", userID).
+		Order("pr.created_at").Scan(ctx)
+	return list, err
+}
+
+func (r *promotionRepo) ByCode(ctx context.Context, code string) (*models.Promotion, error) {
+	ctx, cancel := context.WithTimeout(ctx, timeout); defer cancel()
+	var p models.Promotion
+	err := r.db.NewSelect().Model(&p).Where("code = ?", code).Scan(ctx)
+	if errors.Is(err, sql.ErrNoRows) { return nil, ErrNotFound }
+	if err != nil { return nil, err }
+	return &p, nil
+}
+
+func (r *promotionRepo) Redemptions(ctx context.Context, promotionID, userID string) (int, error) {
+	ctx, cancel := context.WithTimeout(ctx, timeout); defer cancel()
+	return r.db.NewSelect().Model((*models.PromotionRedemption)(nil)).
+		Where("promotion_id = ? AND user_id = ?", promotionID, userID).Count(ctx)
+}
+
+// redeem suma un uso a la promoción y lo registra para el pedido. El UPDATE
+// condicionado bloquea la fila de la promoción hasta el final de la
+// transacción, así dos pedidos simultáneos no pueden pasar del límite global
+// ni del límite por usuario.
+func redeem(ctx context.Context, tx bun.Tx, promotionID string, o *models.Order) error {
+	var perUser sql.NullInt64
+	err := tx.NewUpdate().Model((*models.Promotion)(nil)).
+		Set("uses = uses + 1").Set("updated_at = ?", time.Now()).
+		Where("id = ? AND active", promotionID).
+		Where("max_uses IS NULL OR uses < max_uses").
+		Returning("max_uses_per_user").Scan(ctx, &perUser)
+	if errors.Is(err, sql.ErrNoRows) { return ErrPromotionExhausted }
+	if err != nil { return err }
+	if perUser.Valid {
+		n, err := tx.NewSelect().Model((*models.PromotionRedemption)(nil)).
+			Where("promotion_id = ? AND user_id = ?", promotionID, o.UserID).Count(ctx)
+		if err != nil { return err }
+		if int64(n) >= perUser.Int64 { return ErrPromotionExhausted }
+	}
+	_, err = tx.NewInsert().Model(&models.PromotionRedemption{PromotionID: promotionID, OrderID: o.ID, UserID: o.UserID}).Exec(ctx)
+	return err
+}
+
+// releaseRedemptions devuelve los usos que canjeó el pedido: borra sus
+// promotion_redemptions y resta un uso a cada promoción. Se llama en la
+// transacción que deja el pedido en expired o cancelled, para que un pedido
+// que nunca se paga no agote un cupón limitado.
+func releaseRedemptions(ctx context.Context, tx bun.Tx, orderID string) error {
+	var ids []string
+	err := tx.NewDelete().Model((*models.PromotionRedemption)(nil)).Where("order_id = ?", orderID).Returning("promotion_id").Scan(ctx, &ids)
+	if err != nil || len(ids) == 0 { return err }
+	_, err = tx.NewUpdate().Model((*models.Promotion)(nil)).
+		Set("uses = uses - 1").Set("updated_at = ?", time.Now()).
+		Where("id IN (?)", bun.In(ids)).Exec(ctx)
+	return err
+}
@@ -0,0 +1,115 @@
+package repo
+
+import (
+	"context"
+	"time"
+
+	"github.com/uptrace/bun"
+
+	"github.com/huntercenter1/backend-test/order-service/internal/models"
+)
+
+// ReportRange acota un informe a los pedidos creados en [From, To); un
+// extremo a cero no acota. TZ es la zona IANA en la que se cortan los
+// periodos.
+type ReportRange struct {
+	From, To time.Time
+	TZ       string
+}
+
+// ReportRepo agrega los pedidos vendidos (models.SoldStatuses) al vuelo:
+// los filtros van por los índices de created_at, user_id y product_id.
+type ReportRepo interface {
+	// Sales agrupa por day, week o month (los de date_trunc); sólo devuelve
+	// los periodos con ventas.
+	Sales(ctx context.Context, rg ReportRange, interval string) ([]models.SalesPeriod, error)
+	// TopProducts ordena por quantity o revenue, de más a menos.
+	TopProducts(ctx context.Context, rg ReportRange, by string, limit int) ([]models.ProductSales, error)
+	// Customers ordena por neto, de más a menos; userID lo limita a ese usuario.
+	Customers(ctx context.Context, rg ReportRange, userID string, limit int) ([]models.CustomerValue, error)
+}
+
+type reportRepo struct{ db *bun.DB }
+
+func NewReportRepo(db *bun.DB) ReportRepo { return &reportRepo{db: db} }
+
+func sold(q *bun.SelectQuery, rg ReportRange) *bun.SelectQuery {
+	q = q.Where("o.status IN (?)", bun.In(models.SoldStatuses))
+	if !rg.From.IsZero() { q = q.Where("o.created_at >= ?", rg.From) }
+	if !rg.To.IsZero() { q = q.Where("o.created_at < ?", rg.To) }
+	return q
+}
+
+func (r *reportRepo) Sales(ctx context.Context, rg ReportRange, interval string) ([]models.SalesPeriod, error) {
+	ctx, cancel := context.WithTimeout(ctx, timeout); defer cancel()
+	var rows []struct {
+		Period   time.Time `bun:"period"` // hora local de la zona, sin zona
+		Orders   int       `bun:"orders"`
+		Gross    float64   `bun:"gross"`
+		Refunded float64   `bun:"refunded"`
+	}
+	err := sold(r.db.NewSelect().Model((*models.Order)(nil)), rg).
+		ColumnExpr("date_trunc(?, o.created_at AT TIME ZONE ?) AS period", interval, rg.TZ).
+		ColumnExpr("count(*) AS orders").
+		ColumnExpr("COALESCE(SUM(o.total), 0) AS gross").
+		ColumnExpr("COALESCE(SUM(o.refunded_total), 0) AS refunded").
+		GroupExpr("period").OrderExpr("period").Scan(ctx, &rows)
+	if err != nil { return nil, err }
+	out := make([]models.SalesPeriod, 0, len(rows))
+	for _, row := range rows {
+		out = append(out, models.SalesPeriod{Period: row.Period.Format(time.DateOnly), Sales: models.NewSales(row.Orders, row.Gross, row.Refunded)})
+	}
+	return out, nil
+}
+
+// productOrder son los órdenes admitidos por TopProducts; el empate se
+// deshace por el otro criterio y por producto.
+var productOrder = map[string]string{
+	"quantity": "quantity DESC, revenue DESC, oi.product_id",
+	"revenue":  "revenue DESC, quantity DESC, oi.product_id",
+}
+
+func (r *reportRepo) TopProducts(ctx context.Context, rg ReportRange, by string, limit int) ([]models.ProductSales, error) {
+	ctx, cancel := context.WithTimeout(ctx, timeout); defer cancel()
+	order, ok := productOrder[by]
+	if !ok { order = productOrder["quantity"] }
+	out := []models.ProductSales{}
+	err := sold(r.db.NewSelect().Model((*models.OrderItem)(nil)).Join("JOIN orders AS o ON o.id = oi.order_id"), rg).
+		ColumnExpr("oi.product_id").
+		// el nombre y el sku del pedido más reciente que los guardó
+		ColumnExpr("COALESCE((array_agg(oi.name ORDER BY o.created_at DESC) FILTER (WHERE oi.name IS NOT NULL))[1], '') AS name").
+		ColumnExpr("COALESCE((array_agg(oi.sku ORDER BY o.created_at DESC) FILTER (WHERE oi.sku IS NOT NULL))[1], '') AS sku").
+		ColumnExpr("SUM(oi.quantity) AS quantity").
+		ColumnExpr("count(DISTINCT oi.order_id) AS orders").
+		ColumnExpr("SUM(oi.price * oi.quantity - oi.discount) AS revenue").
+		GroupExpr("oi.product_id").OrderExpr(order).Limit(limit).Scan(ctx, &out)
+	if err != nil { return nil, err }
+	return out, nil
+}
+
+func (r *reportRepo) Customers(ctx context.Context, rg ReportRange, userID string, limit int) ([]models.CustomerValue, error) {
+	ctx, cancel := context.WithTimeout(ctx, timeout); defer cancel()
+	var rows []struct {
+		UserID       string    `bun:"user_id"`
+		Orders       int       `bun:"orders"`
+		Gross        float64   `bun:"gross"`
+		Refunded     float64   `bun:"refunded"`
+		FirstOrderAt time.Time `bun:"first_order_at"`
+		LastOrderAt  time.Time `bun:"last_order_at"`
+	}
+	q := sold(r.db.NewSelect().Model((*models.Order)(nil)), rg)
+	if userID != "" { q = q.Where("o.user_id = ?", userID) }
+	err := q.ColumnExpr("o.user_id").
+		ColumnExpr("count(*) AS orders").
+		ColumnExpr("SUM(o.total) AS gross").
+		ColumnExpr("SUM(o.refunded_total) AS refunded").
+		ColumnExpr("min(o.created_at) AS first_order_at").
+		ColumnExpr("max(o.created_at) AS last_order_at").
+		GroupExpr("o.user_id").OrderExpr("SUM(o.total - o.refunded_total) DESC, o.user_id").Limit(limit).Scan(ctx, &rows)
+	if err != nil { return nil, err }
+	out := make([]models.CustomerValue, 0, len(rows))
+	for _, row := range rows {
+		out = append(out, models.CustomerValue{UserID: row.UserID, Sales: models.NewSales(row.Orders, row.Gross, row.Refunded), FirstOrderAt: row.FirstOrderAt, LastOrderAt: row.LastOrderAt})
+	}
+	return out, nil
+}
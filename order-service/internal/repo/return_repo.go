@@ -0,0 +1,155 @@
+package repo
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/uptrace/bun"
+
+	"github.com/huntercenter1/backend-test/order-service/internal/models"
+)
+
+var (
+	// ErrOrderNotReturnable: sólo se devuelven pedidos entregados.
+	ErrOrderNotReturnable = errors.New("only delivered orders can be returned")
+	// ErrReturnQuantity: una línea no es del pedido o supera lo que queda por devolver.
+	ErrReturnQuantity = errors.New("return items must belong to the order and not exceed the quantity left to return")
+	ErrReturnState    = errors.New("operation not allowed in the return's current status")
+)
+
+// ReturnRepo guarda las devoluciones (RMA). Create bloquea el pedido para que
+// dos devoluciones simultáneas no cuenten la misma unidad; los cambios de
+// estado son un UPDATE condicionado al estado de origen.
+type ReturnRepo interface {
+	// Create completa producto, precio y valor de cada línea desde
+	// order_items (ver models.ReturnAmount).
+	Create(ctx context.Context, r *models.Return) (*models.Return, error)
+	Get(ctx context.Context, id string) (*models.Return, error)
+	ByOrder(ctx context.Context, orderID string) ([]models.Return, error)
+	// Transition pasa la devolución a to si está en alguno de from; si no,
+	// devuelve ErrReturnState.
+	Transition(ctx context.Context, id string, from []string, to, note string) (*models.Return, error)
+	// ClaimRestock marca la línea como repuesta si aún no lo estaba; false si
+	// otra llamada ya la reclamó. ReleaseRestock la devuelve a pendiente
+	// cuando la reposición falla.
+	ClaimRestock(ctx context.Context, itemID string) (bool, error)
+	ReleaseRestock(ctx context.Context, itemID string) error
+	// ReserveRefund suma amount a refunded_amount sólo si sigue valiendo
+	// seen, para que dos llamadas no reembolsen lo mismo; lo que no llegue a
+	// reembolsarse se devuelve con AddRefunded en negativo.
+	ReserveRefund(ctx context.Context, id string, seen, amount float64) (bool, error)
+	AddRefunded(ctx context.Context, id string, amount float64) error
+}
+
+type returnRepo struct{ db *bun.DB }
+
+func NewReturnRepo(db *bun.DB) ReturnRepo { return &returnRepo{db: db} }
+
+func (r *returnRepo) Create(ctx context.Context, rt *models.Return) (*models.Return, error) {
+	ctx, cancel := context.WithTimeout(ctx, timeout); defer cancel()
+	err := r.db.RunInTx(ctx, nil, func(ctx context.Context, tx bun.Tx) error {
+		o, err := lockOrder(ctx, tx, rt.OrderID)
+		if err != nil { return err }
+		if o.Status != models.OrderDelivered { return ErrOrderNotReturnable }
+		var items []models.OrderItem
+		if err := tx.NewSelect().Model(&items).Where("order_id = ?", o.ID).Scan(ctx); err != nil { return err }
+		var returns []models.Return
+		if err := tx.NewSelect().Model(&returns).Relation("Items").Where("rt.order_id = ?", o.ID).Scan(ctx); err != nil { return err }
+
+		byID := make(map[string]models.OrderItem, len(items))
+		for _, it := range items { byID[it.ID] = it }
+		left := models.Returnable(items, returns)
+		for i, ri := range rt.Items {
+			it, ok := byID[ri.OrderItemID]
+			if !ok || ri.Quantity > left[ri.OrderItemID] { return ErrReturnQuantity }
+			left[ri.OrderItemID] -= ri.Quantity
+			// se reembolsa lo pagado: el precio menos la parte de las promociones
+			// más el impuesto (los portes no se devuelven)
+			rt.Items[i].ProductID, rt.Items[i].UnitPrice = it.ProductID, it.PaidUnitPrice()
+			rt.Items[i].Amount = models.ReturnAmount(it, ri.Quantity, returns)
+		}
+
+		rt.Status = models.ReturnRequested
+		if _, err := tx.NewInsert().Model(rt).Returning("*").Exec(ctx); err != nil { return err }
+		for i := range rt.Items { rt.Items[i].ReturnID = rt.ID }
+		_, err = tx.NewInsert().Model(&rt.Items).Returning("*").Exec(ctx)
+		return err
+	})
+	if err != nil { return nil, err }
+	return rt, nil
+}
+
+func (r *returnRepo) Get(ctx context.Context, id string) (*models.Return, error) {
+	ctx, cancel := context.WithTimeout(ctx, timeout); defer cancel()
+	var rt models.Return
+	err := r.db.NewSelect().Model(&rt).Relation("Items").Where("rt.id = ?", id).Scan(ctx)
+	if errors.Is(err, sql.ErrNoRows) { return nil, ErrNotFound }
+	if err != nil { return nil, err }
+	return &rt, nil
+}
+
+func (r *returnRepo) ByOrder(ctx context.Context, orderID string) ([]models.Return, error) {
+	ctx, cancel := context.WithTimeout(ctx, timeout); defer cancel()
+	list := []models.Return{}
+	err := r.db.NewSelect().Model(&list).Relation("Items").Where("rt.order_id = ?", orderID).Order("rt.created_at").Scan(ctx)
+	return list, err
+}
+
+func (r *returnRepo) Transition(ctx context.Context, id string, from []string, to, note string) (*models.Return, error) {
+	ctx, cancel := context.WithTimeout(ctx, timeout); defer cancel()
+	now := time.Now()
+	q := r.db.NewUpdate().Model((*models.Return)(nil)).
+		Set("status = ?", to).Set("updated_at = ?", now).
+		Where("id = ?", id).Where("status IN (?)", bun.In(from))
+	switch to {
+	case models.ReturnApproved:
+		q = q.Set("approved_at = ?", now)
+	case models.ReturnReceived:
+		q = q.Set("received_at = ?", now)
+	case models.ReturnRejected:
+		q = q.Set("rejected_at = ?", now)
+	}
+	if note != "" { q = q.Set("note = ?", note) }
+	res, err := q.Exec(ctx)
+	if err != nil { return nil, err }
+	if n, _ := res.RowsAffected(); n == 0 {
+		// no existe o no estaba en un estado de origen válido
+		if _, err := r.Get(ctx, id); err != nil { return nil, err }
+		return nil, ErrReturnState
+	}
+	return r.Get(ctx, id)
+}
+
+func (r *returnRepo) ClaimRestock(ctx context.Context, itemID string) (bool, error) {
+	ctx, cancel := context.WithTimeout(ctx, timeout); defer cancel()
+	res, err := r.db.NewUpdate().Model((*models.ReturnItem)(nil)).Set("restocked = true").Where("id = ?", itemID).Where("NOT restocked").Exec(ctx)
+	if err != nil { return false, err }
+	n, _ := res.RowsAffected()
+	return n == 1, nil
+}
+
+func (r *returnRepo) ReleaseRestock(ctx context.Context, itemID string) error {
+	ctx, cancel := context.WithTimeout(ctx, timeout); defer cancel()
+	res, err := r.db.NewUpdate().Model((*models.ReturnItem)(nil)).Set("restocked = false").Where("id = ?", itemID).Exec(ctx)
+	return affected(res, err)
+}
+
+func (r *returnRepo) ReserveRefund(ctx context.Context, id string, seen, amount float64) (bool, error) {
+	ctx, cancel := context.WithTimeout(ctx, timeout); defer cancel()
+	res, err := r.db.NewUpdate().Model((*models.Return)(nil)).
+		Set("refunded_amount = refunded_amount + ?", amount).Set("updated_at = ?", time.Now()).
+		Where("id = ?", id).Where("refunded_amount = ?", seen).Exec(ctx)
+	if err != nil { return false, err }
+	n, _ := res.RowsAffected()
+	return n == 1, nil
+}
+
+func (r *returnRepo) AddRefunded(ctx context.Context, id string, amount float64) error {
+	ctx, cancel := context.WithTimeout(ctx, timeout); defer cancel()
+	res, err := r.db.NewUpdate().Model((*models.Return)(nil)).
+		Set("refunded_amount = refunded_amount + ?", amount).Set("updated_at = ?", time.Now()).
+		Where("id = ?", id).Exec(ctx)
+	return affected(res, err)
+}
@@ -0,0 +1,134 @@
+package repo
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/uptrace/bun"
+
+	"github.com/huntercenter1/backend-test/order-service/internal/models"
+)
+
+var (
+	// ErrOrderNotShippable: sólo se preparan envíos de pedidos pagados o
+	// parcialmente enviados.
+	ErrOrderNotShippable = errors.New("order cannot be shipped in its current status")
+	ErrNothingToShip     = errors.New("all order items are already in a shipment")
+	// ErrShipmentQuantity: una línea no es del pedido o supera lo que queda por enviar.
+	ErrShipmentQuantity = errors.New("shipment items must belong to the order and not exceed the quantity left to ship")
+)
+
+// ShipmentRepo guarda los envíos de los pedidos. Create y Update bloquean la
+// fila del pedido, así dos envíos simultáneos no reparten la misma unidad y
+// el estado del pedido se recalcula con todos los envíos a la vista (ver
+// models.FulfillOrder).
+type ShipmentRepo interface {
+	// Create reparte sh.Items entre lo que queda por enviar; sin items, el
+	// envío lleva todo lo pendiente.
+	Create(ctx context.Context, sh *models.Shipment) (*models.Shipment, error)
+	Get(ctx context.Context, id string) (*models.Shipment, error)
+	ByOrder(ctx context.Context, orderID string) ([]models.Shipment, error)
+	// Update aplica fn al envío bloqueado y recalcula el estado del pedido.
+	Update(ctx context.Context, id string, fn func(sh *models.Shipment) error) (*models.Shipment, error)
+}
+
+type shipmentRepo struct{ db *bun.DB }
+
+func NewShipmentRepo(db *bun.DB) ShipmentRepo { return &shipmentRepo{db: db} }
+
+func (r *shipmentRepo) Create(ctx context.Context, sh *models.Shipment) (*models.Shipment, error) {
+	ctx, cancel := context.WithTimeout(ctx, timeout); defer cancel()
+	err := r.db.RunInTx(ctx, nil, func(ctx context.Context, tx bun.Tx) error {
+		o, err := lockOrder(ctx, tx, sh.OrderID)
+		if err != nil { return err }
+		if o.Status != models.OrderPaid && o.Status != models.OrderPartiallyShipped { return ErrOrderNotShippable }
+		items, shipments, err := fulfillment(ctx, tx, o.ID)
+		if err != nil { return err }
+
+		left := models.Unshipped(items, shipments)
+		if len(sh.Items) == 0 {
+			for _, it := range items {
+				if left[it.ID] > 0 { sh.Items = append(sh.Items, models.ShipmentItem{OrderItemID: it.ID, Quantity: left[it.ID]}) }
+			}
+			if len(sh.Items) == 0 { return ErrNothingToShip }
+		}
+		for _, si := range sh.Items {
+			n, ok := left[si.OrderItemID]
+			if !ok || si.Quantity > n { return ErrShipmentQuantity }
+			left[si.OrderItemID] = n - si.Quantity
+		}
+
+		sh.Status = models.ShipmentPending
+		if _, err := tx.NewInsert().Model(sh).Returning("*").Exec(ctx); err != nil { return err }
+		for i := range sh.Items { sh.Items[i].ShipmentID = sh.ID }
+		_, err = tx.NewInsert().Model(&sh.Items).Returning("*").Exec(ctx)
+		return err
+	})
+	if err != nil { return nil, err }
+	return sh, nil
+}
+
+func (r *shipmentRepo) Get(ctx context.Context, id string) (*models.Shipment, error) {
+	ctx, cancel := context.WithTimeout(ctx, timeout); defer cancel()
+	var sh models.Shipment
+	err := r.db.NewSelect().Model(&sh).Relation("Items").Where("sh.id = ?", id).Scan(ctx)
+	if errors.Is(err, sql.ErrNoRows) { return nil, ErrNotFound }
+	if err != nil { return nil, err }
+	return &sh, nil
+}
+
+func (r *shipmentRepo) ByOrder(ctx context.Context, orderID string) ([]models.Shipment, error) {
+	ctx, cancel := context.WithTimeout(ctx, timeout); defer cancel()
+	list := []models.Shipment{}
+	err := r.db.NewSelect().Model(&list).Relation("Items").Where("sh.order_id = ?", orderID).Order("sh.created_at").Scan(ctx)
+	return list, err
+}
+
+func (r *shipmentRepo) Update(ctx context.Context, id string, fn func(sh *models.Shipment) error) (*models.Shipment, error) {
+	ctx, cancel := context.WithTimeout(ctx, timeout); defer cancel()
+	var sh models.Shipment
+	err := r.db.RunInTx(ctx, nil, func(ctx context.Context, tx bun.Tx) error {
+		// primero el pedido y luego el envío, en el mismo orden que Create
+		var orderID string
+		err := tx.NewSelect().Model((*models.Shipment)(nil)).Column("order_id").Where("id = ?", id).Scan(ctx, &orderID)
+		if errors.Is(err, sql.ErrNoRows) { return ErrNotFound }
+		if err != nil { return err }
+		o, err := lockOrder(ctx, tx, orderID)
+		if err != nil { return err }
+		if err := tx.NewSelect().Model(&sh).Relation("Items").Where("sh.id = ?", id).For("UPDATE OF sh").Scan(ctx); err != nil { return err }
+
+		if err := fn(&sh); err != nil { return err }
+		sh.UpdatedAt = time.Now()
+		if _, err := tx.NewUpdate().Model(&sh).
+			Column("carrier", "tracking_number", "status", "shipped_at", "delivered_at", "updated_at").
+			WherePK().Exec(ctx); err != nil { return err }
+
+		items, shipments, err := fulfillment(ctx, tx, o.ID)
+		if err != nil { return err }
+		next := models.FulfillOrder(o.Status, items, shipments)
+		if next == o.Status { return nil }
+		_, err = tx.NewUpdate().Model((*models.Order)(nil)).Set("status = ?", next).Set("updated_at = ?", time.Now()).Where("id = ?", o.ID).Exec(ctx)
+		return err
+	})
+	if err != nil { return nil, err }
+	return &sh, nil
+}
+
+func lockOrder(ctx context.Context, tx bun.Tx, id string) (*models.Order, error) {
+	var o models.Order
+	err := tx.NewSelect().Model(&o).Where("id = ?", id).For("UPDATE").Scan(ctx)
+	if errors.Is(err, sql.ErrNoRows) { return nil, ErrNotFound }
+	if err != nil { return nil, err }
+	return &o, nil
+}
+
+// fulfillment carga las líneas del pedido y todos sus envíos con sus líneas.
+func fulfillment(ctx context.Context, tx bun.Tx, orderID string) ([]models.OrderItem, []models.Shipment, error) {
+	var items []models.OrderItem
+	if err := tx.NewSelect().Model(&items).Where("order_id = ?", orderID).Scan(ctx); err != nil { return nil, nil, err }
+	var shipments []models.Shipment
+	if err := tx.NewSelect().Model(&shipments).Relation("Items").Where("sh.order_id = ?", orderID).Scan(ctx); err != nil { return nil, nil, err }
+	return items, shipments, nil
+}
@@ -0,0 +1,102 @@
+package resilience
+
+import (
+	"errors"
+	"sync"
+	"time"
+)
+
+var ErrCircuitOpen = errors.New("circuit breaker open")
+
+type State int
+
+const (
+	Closed State = iota
+	Open
+	HalfOpen
+)
+
+func (s State) String() string {
+	switch s {
+	case Open:
+		return "open"
+	case HalfOpen:
+		return "half-open"
+	}
+	return "closed"
+}
+
+type BreakerConfig struct {
+	// Failures consecutivos que abren el circuito.
+	Failures int
+	// Cooldown es el tiempo que el circuito queda abierto antes de dejar
+	// pasar una llamada de prueba.
+	Cooldown time.Duration
+}
+
+// Breaker es un circuit breaker por dependencia: tras Failures fallos
+// seguidos rechaza llamadas durante Cooldown; después deja pasar una sola
+// de prueba (half-open) que lo cierra si va bien o lo reabre si falla.
+type Breaker struct {
+	cfg      BreakerConfig
+	now      func() time.Time
+	onChange func(from, to State)
+
+	mu       sync.Mutex
+	state    State
+	failures int
+	openedAt time.Time
+	probing  bool
+}
+
+func NewBreaker(cfg BreakerConfig, onChange func(from, to State)) *Breaker {
+	if cfg.Failures <= 0 { cfg.Failures = 5 }
+	if cfg.Cooldown <= 0 { cfg.Cooldown = 10 * time.Second }
+	return &Breaker{cfg: cfg, now: time.Now, onChange: onChange}
+}
+
+func (b *Breaker) State() State {
+	b.mu.Lock(); defer b.mu.Unlock()
+	return b.state
+}
+
+// Allow devuelve ErrCircuitOpen si la llamada no debe hacerse. Cada Allow
+// que devuelve nil debe ir seguido de un Record.
+func (b *Breaker) Allow() error {
+	b.mu.Lock(); defer b.mu.Unlock()
+	switch b.state {
+	case Open:
+		if b.now().Sub(b.openedAt) < b.cfg.Cooldown { return ErrCircuitOpen }
+		b.setState(HalfOpen)
+		b.probing = true
+		return nil
+	case HalfOpen:
+		if b.probing { return ErrCircuitOpen }
+		b.probing = true
+	}
+	return nil
+}
+
+// Record registra el resultado de una llamada permitida. failed debe ser
+// true sólo para fallos de la dependencia (red, 5xx, Unavailable), no para
+// errores de negocio como un 404.
+func (b *Breaker) Record(failed bool) {
+	b.mu.Lock(); defer b.mu.Unlock()
+	b.probing = false
+	if !failed {
+		b.failures = 0
+		if b.state != Closed { b.setState(Closed) }
+		return
+	}
+	b.failures++
+	if b.state == HalfOpen || b.failures >= b.cfg.Failures {
+		b.openedAt = b.now()
+		if b.state != Open { b.setState(Open) }
+	}
+}
+
+func (b *Breaker) setState(s State) {
+	from := b.state
+	b.state = s
+	if b.onChange != nil { b.onChange(from, s) }
+}
@@ -0,0 +1,77 @@
+package resilience
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+var errDown = errors.New("down")
+
+func retryable(err error) Outcome { return Outcome{Failed: true, Retryable: true} }
+
+func TestDoRetriesUntilSuccess(t *testing.T) {
+	calls := 0
+	p := Policy{Retry: RetryConfig{MaxAttempts: 3, BaseDelay: time.Millisecond, MaxDelay: time.Millisecond}}
+	err := p.Do(context.Background(), func(context.Context) error {
+		calls++
+		if calls < 3 { return errDown }
+		return nil
+	}, retryable)
+	if err != nil || calls != 3 { t.Fatalf("err=%v calls=%d", err, calls) }
+}
+
+func TestDoStopsOnNonRetryable(t *testing.T) {
+	calls := 0
+	p := Policy{Retry: RetryConfig{MaxAttempts: 5}}
+	err := p.Do(context.Background(), func(context.Context) error { calls++; return errDown },
+		func(error) Outcome { return Outcome{} })
+	if !errors.Is(err, errDown) || calls != 1 { t.Fatalf("err=%v calls=%d", err, calls) }
+}
+
+func TestDoRespectsDeadline(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond); defer cancel()
+	calls := 0
+	p := Policy{Retry: RetryConfig{MaxAttempts: 10, BaseDelay: time.Second, MaxDelay: time.Second}}
+	start := time.Now()
+	err := p.Do(ctx, func(context.Context) error { calls++; return errDown }, retryable)
+	if !errors.Is(err, errDown) { t.Fatalf("err=%v", err) }
+	if time.Since(start) > 500*time.Millisecond { t.Fatal("should not sleep past the deadline") }
+}
+
+func TestBudgetLimitsRetries(t *testing.T) {
+	b := NewBudget(0.1, 2)
+	calls := 0
+	p := Policy{Retry: RetryConfig{MaxAttempts: 10}, Budget: b}
+	err := p.Do(context.Background(), func(context.Context) error { calls++; return errDown }, retryable)
+	if !errors.Is(err, ErrBudgetExhausted) { t.Fatalf("err=%v", err) }
+	if calls != 3 { t.Fatalf("calls=%d, want 1 + 2 retries", calls) }
+}
+
+func TestBreakerOpensAndRecovers(t *testing.T) {
+	now := time.Unix(0, 0)
+	var transitions []string
+	b := NewBreaker(BreakerConfig{Failures: 2, Cooldown: time.Minute}, func(from, to State) { transitions = append(transitions, to.String()) })
+	b.now = func() time.Time { return now }
+
+	for i := 0; i < 2; i++ {
+		if err := b.Allow(); err != nil { t.Fatal(err) }
+		b.Record(true)
+	}
+	if !errors.Is(b.Allow(), ErrCircuitOpen) { t.Fatal("breaker should be open") }
+
+	now = now.Add(time.Minute)
+	if err := b.Allow(); err != nil { t.Fatalf("probe should pass: %v", err) }
+	if !errors.Is(b.Allow(), ErrCircuitOpen) { t.Fatal("only one probe while half-open") }
+	b.Record(false)
+	if b.State() != Closed { t.Fatalf("state=%s", b.State()) }
+	if got := len(transitions); got != 3 { t.Fatalf("transitions=%v", transitions) }
+}
+
+func TestBackoffBounds(t *testing.T) {
+	c := RetryConfig{BaseDelay: 10 * time.Millisecond, MaxDelay: 50 * time.Millisecond}
+	for n := 1; n < 10; n++ {
+		if d := c.Backoff(n); d < 0 || d > 50*time.Millisecond { t.Fatalf("backoff(%d)=%v", n, d) }
+	}
+}
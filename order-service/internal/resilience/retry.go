@@ -0,0 +1,120 @@
+package resilience
+
+import (
+	"context"
+	"errors"
+	"math/rand/v2"
+	"sync"
+	"time"
+)
+
+var ErrBudgetExhausted = errors.New("retry budget exhausted")
+
+type RetryConfig struct {
+	// MaxAttempts cuenta también el primer intento; 1 = sin reintentos.
+	MaxAttempts int
+	BaseDelay   time.Duration
+	MaxDelay    time.Duration
+}
+
+// Backoff devuelve la espera antes del reintento n (1, 2, ...) con "full
+// jitter": un valor aleatorio entre 0 y min(MaxDelay, BaseDelay*2^(n-1)),
+// para que los clientes no reintenten todos a la vez.
+func (c RetryConfig) Backoff(n int) time.Duration {
+	d := c.BaseDelay << (n - 1)
+	if d <= 0 || d > c.MaxDelay { d = c.MaxDelay }
+	if d <= 0 { return 0 }
+	return rand.N(d + 1)
+}
+
+// Budget limita los reintentos a una fracción de las peticiones para que,
+// si una dependencia cae, los reintentos no multipliquen la carga. Cada
+// petición deposita Ratio tokens y cada reintento gasta uno; Min tokens
+// están siempre disponibles al arrancar.
+type Budget struct {
+	mu     sync.Mutex
+	ratio  float64
+	max    float64
+	tokens float64
+}
+
+func NewBudget(ratio float64, min int) *Budget {
+	if ratio <= 0 { ratio = 0.1 }
+	if min <= 0 { min = 10 }
+	return &Budget{ratio: ratio, max: float64(min), tokens: float64(min)}
+}
+
+func (b *Budget) deposit() {
+	b.mu.Lock(); defer b.mu.Unlock()
+	b.tokens += b.ratio
+	if b.tokens > b.max { b.tokens = b.max }
+}
+
+func (b *Budget) withdraw() bool {
+	b.mu.Lock(); defer b.mu.Unlock()
+	if b.tokens < 1 { return false }
+	b.tokens--
+	return true
+}
+
+// Policy agrupa la resiliencia de una dependencia. Todos los campos son
+// opcionales: sin Breaker no hay circuito y sin Budget no hay límite.
+type Policy struct {
+	Retry   RetryConfig
+	Breaker *Breaker
+	Budget  *Budget
+	// OnRetry se llama antes de cada reintento (métricas, logs).
+	OnRetry func(attempt int, err error)
+}
+
+// Outcome clasifica el resultado de un intento.
+type Outcome struct {
+	// Failed marca un fallo de la dependencia (cuenta para el breaker).
+	Failed bool
+	// Retryable indica que repetir la llamada puede tener éxito y es seguro.
+	Retryable bool
+}
+
+// Do ejecuta fn aplicando breaker, reintentos con backoff y presupuesto.
+// classify decide a partir del error si el intento falló y si se puede
+// reintentar; sólo debe marcar como reintentables llamadas idempotentes.
+// Nunca espera más allá del deadline de ctx.
+func (p Policy) Do(ctx context.Context, fn func(ctx context.Context) error, classify func(error) Outcome) error {
+	attempts := p.Retry.MaxAttempts
+	if attempts <= 0 { attempts = 1 }
+	if p.Budget != nil { p.Budget.deposit() }
+	var err error
+	for n := 1; ; n++ {
+		if p.Breaker != nil {
+			if berr := p.Breaker.Allow(); berr != nil {
+				if err != nil { return errors.Join(err, berr) }
+				return berr
+			}
+		}
+		err = fn(ctx)
+		out := Outcome{}
+		if err != nil { out = classify(err) }
+		if p.Breaker != nil { p.Breaker.Record(out.Failed) }
+		if err == nil || !out.Retryable || n >= attempts || ctx.Err() != nil { return err }
+		if p.Budget != nil && !p.Budget.withdraw() { return errors.Join(err, ErrBudgetExhausted) }
+
+		wait := p.Retry.Backoff(n)
+		if dl, ok := ctx.Deadline(); ok && time.Until(dl) <= wait { return err }
+		if p.OnRetry != nil { p.OnRetry(n, err) }
+		t := time.NewTimer(wait)
+		select {
+		case <-ctx.Done():
+			t.Stop()
+			return err
+		case <-t.C:
+		}
+	}
+}
+
+// WithDefaultTimeout aplica d sólo si ctx no trae ya un deadline: así se
+// respeta el de la petición entrante y se evita esperar indefinidamente
+// en llamadas sin él (jobs, arranque).
+func WithDefaultTimeout(ctx context.Context, d time.Duration) (context.Context, context.CancelFunc) {
+	if _, ok := ctx.Deadline(); ok || d <= 0 { return context.WithCancel(ctx) }
+	return context.WithTimeout(ctx, d)
+}
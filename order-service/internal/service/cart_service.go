@@ -0,0 +1,200 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+
+	"github.com/huntercenter1/backend-test/order-service/internal/clients"
+	"github.com/huntercenter1/backend-test/order-service/internal/models"
+	"github.com/huntercenter1/backend-test/order-service/internal/repo"
+)
+
+var (
+	ErrInvalidQuantity = errors.New("quantity must be > 0")
+	ErrProductNotFound = errors.New("product not found")
+	ErrEmptyCart       = errors.New("cart is empty")
+	// ErrGuestCart: un carrito de invitado no se puede pagar; antes hay que
+	// iniciar sesión y fusionarlo (POST /carts/merge).
+	ErrGuestCart = errors.New("cart has no user; merge it into the user's cart first")
+)
+
+// Disponibilidad de una línea del carrito, calculada al leerlo.
+const (
+	LineOK                = "ok"
+	LineInsufficientStock = "insufficient_stock"
+	LineUnavailable       = "unavailable" // el producto ya no existe
+	LineUnknown           = "unknown"     // product-service no respondió
+)
+
+// CartView es el carrito con precio y stock actuales de product-service.
+type CartView struct {
+	*models.Cart
+	Items     []CartLine `json:"items"`
+	Subtotal  float64    `json:"subtotal"`
+	Available bool       `json:"available"` // todas las líneas en estado ok
+}
+
+type CartLine struct {
+	models.CartItem
+	Name         string  `json:"name,omitempty"`
+	UnitPrice    float64 `json:"unit_price"`
+	LineTotal    float64 `json:"line_total"`
+	Stock        int     `json:"stock"`
+	Availability string  `json:"availability"`
+	PriceChanged bool    `json:"price_changed"` // el precio no es el de cuando se añadió
+}
+
+// CheckoutRequest son los datos del pedido que no están en el carrito; los
+// dos son opcionales.
+type CheckoutRequest struct {
+	CouponCode string `json:"coupon_code"`
+	Region     string `json:"region"`
+}
+
+type CartService interface {
+	// Create devuelve el carrito abierto del usuario si ya tiene uno
+	// (created=false); con userID vacío crea un carrito de invitado.
+	Create(ctx context.Context, userID string) (cart *CartView, created bool, err error)
+	Get(ctx context.Context, id string) (*CartView, error)
+	ByUser(ctx context.Context, userID string) (*CartView, error)
+	AddItem(ctx context.Context, id, productID string, qty int) (*CartView, error)
+	UpdateItem(ctx context.Context, id, productID string, qty int) (*CartView, error)
+	RemoveItem(ctx context.Context, id, productID string) (*CartView, error)
+	// Merge se llama al iniciar sesión con el carrito de invitado.
+	Merge(ctx context.Context, guestID, userID string) (*CartView, error)
+	// Checkout crea el pedido con el cupón y la región de req.
+	Checkout(ctx context.Context, id string, req CheckoutRequest) (*models.Order, []models.OrderItem, error)
+}
+
+type cartService struct {
+	repo   repo.CartRepo
+	orders Service
+	uc     clients.UserClient
+	pc     clients.ProductClient
+}
+
+// NewCartService recibe el servicio de pedidos porque el checkout crea el
+// pedido por el mismo camino que POST /orders (validación, stock y precios).
+func NewCartService(r repo.CartRepo, orders Service, uc clients.UserClient, pc clients.ProductClient) CartService {
+	return &cartService{repo: r, orders: orders, uc: uc, pc: pc}
+}
+
+func (s *cartService) Create(ctx context.Context, userID string) (*CartView, bool, error) {
+	if userID == "" {
+		c, err := s.repo.Create(ctx, &models.Cart{})
+		if err != nil { return nil, false, err }
+		return s.view(ctx, c), true, nil
+	}
+	if err := s.validateUser(ctx, userID); err != nil { return nil, false, err }
+	if c, err := s.repo.OpenByUser(ctx, userID); err == nil { return s.view(ctx, c), false, nil }
+	c, err := s.repo.Create(ctx, &models.Cart{UserID: &userID})
+	if err != nil {
+		// otra petición creó el carrito a la vez (índice único de carrito abierto)
+		if c, gerr := s.repo.OpenByUser(ctx, userID); gerr == nil { return s.view(ctx, c), false, nil }
+		return nil, false, err
+	}
+	return s.view(ctx, c), true, nil
+}
+
+func (s *cartService) Get(ctx context.Context, id string) (*CartView, error) {
+	c, err := s.repo.Get(ctx, id)
+	if err != nil { return nil, err }
+	return s.view(ctx, c), nil
+}
+
+func (s *cartService) ByUser(ctx context.Context, userID string) (*CartView, error) {
+	c, err := s.repo.OpenByUser(ctx, userID)
+	if err != nil { return nil, err }
+	return s.view(ctx, c), nil
+}
+
+// AddItem comprueba que el producto existe; el stock no se reserva, se
+// muestra al leer el carrito y se exige en el checkout.
+func (s *cartService) AddItem(ctx context.Context, id, productID string, qty int) (*CartView, error) {
+	if qty <= 0 { return nil, ErrInvalidQuantity }
+	p, err := s.pc.Get(ctx, productID)
+	if clients.IsNotFound(err) { return nil, ErrProductNotFound }
+	if err != nil { return nil, err }
+	if err := s.repo.AddItem(ctx, id, productID, qty, p.UnitPrice()); err != nil { return nil, err }
+	return s.Get(ctx, id)
+}
+
+// UpdateItem fija la cantidad de una línea; 0 la elimina.
+func (s *cartService) UpdateItem(ctx context.Context, id, productID string, qty int) (*CartView, error) {
+	if qty < 0 { return nil, ErrInvalidQuantity }
+	if qty == 0 { return s.RemoveItem(ctx, id, productID) }
+	if err := s.repo.SetQuantity(ctx, id, productID, qty); err != nil { return nil, err }
+	return s.Get(ctx, id)
+}
+
+func (s *cartService) RemoveItem(ctx context.Context, id, productID string) (*CartView, error) {
+	if err := s.repo.RemoveItem(ctx, id, productID); err != nil { return nil, err }
+	return s.Get(ctx, id)
+}
+
+func (s *cartService) Merge(ctx context.Context, guestID, userID string) (*CartView, error) {
+	if err := s.validateUser(ctx, userID); err != nil { return nil, err }
+	id, err := s.repo.Merge(ctx, guestID, userID)
+	if err != nil { return nil, err }
+	return s.Get(ctx, id)
+}
+
+// Checkout bloquea el carrito (checking_out), crea el pedido con
+// Service.Create y lo deja como checked_out con el id del pedido. Si el
+// pedido no se crea el carrito vuelve a open para poder corregirlo.
+func (s *cartService) Checkout(ctx context.Context, id string, req CheckoutRequest) (*models.Order, []models.OrderItem, error) {
+	c, err := s.repo.BeginCheckout(ctx, id)
+	if err != nil { return nil, nil, err }
+	abort := func(err error) (*models.Order, []models.OrderItem, error) {
+		if aerr := s.repo.AbortCheckout(context.WithoutCancel(ctx), id); aerr != nil {
+			slog.ErrorContext(ctx, "cart checkout abort", "cart_id", id, "error", aerr)
+		}
+		return nil, nil, err
+	}
+	if c.UserID == nil { return abort(ErrGuestCart) }
+	if len(c.Items) == 0 { return abort(ErrEmptyCart) }
+
+	items := make([]CreateItem, len(c.Items))
+	for i, it := range c.Items { items[i] = CreateItem{ProductID: it.ProductID, Quantity: it.Quantity} }
+	o, orderItems, err := s.orders.Create(ctx, CreateRequest{UserID: *c.UserID, Items: items, CouponCode: req.CouponCode, Region: req.Region})
+	if err != nil { return abort(err) }
+
+	// el pedido ya existe: un fallo aquí sólo deja el carrito en checking_out
+	if err := s.repo.FinishCheckout(context.WithoutCancel(ctx), id, o.ID); err != nil {
+		slog.ErrorContext(ctx, "cart checkout finish", "cart_id", id, "order_id", o.ID, "error", err)
+	}
+	return o, orderItems, nil
+}
+
+func (s *cartService) validateUser(ctx context.Context, userID string) error {
+	ok, err := s.uc.Validate(ctx, userID)
+	if err != nil || !ok { return errors.New("invalid user") }
+	return nil
+}
+
+// view consulta cada producto en product-service para mostrar precio y
+// disponibilidad actuales. Si product-service falla el carrito se devuelve
+// igualmente, con esas líneas como unknown y el precio de cuando se añadieron.
+func (s *cartService) view(ctx context.Context, c *models.Cart) *CartView {
+	v := &CartView{Cart: c, Items: make([]CartLine, 0, len(c.Items)), Available: true}
+	for _, it := range c.Items {
+		line := CartLine{CartItem: it, UnitPrice: it.AddedPrice, Availability: LineOK}
+		p, err := s.pc.Get(ctx, it.ProductID)
+		switch {
+		case clients.IsNotFound(err):
+			line.Availability = LineUnavailable
+		case err != nil:
+			line.Availability = LineUnknown
+		default:
+			line.Name, line.Stock, line.UnitPrice = p.Name, p.Stock, p.UnitPrice()
+			line.PriceChanged = line.UnitPrice != it.AddedPrice
+			if p.Stock < it.Quantity { line.Availability = LineInsufficientStock }
+		}
+		if line.Availability != LineOK { v.Available = false }
+		if line.Availability != LineUnavailable { line.LineTotal = line.UnitPrice * float64(it.Quantity) }
+		v.Subtotal += line.LineTotal
+		v.Items = append(v.Items, line)
+	}
+	return v
+}
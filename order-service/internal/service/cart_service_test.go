@@ -0,0 +1,219 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+
+	"github.com/huntercenter1/backend-test/order-service/internal/clients"
+	"github.com/huntercenter1/backend-test/order-service/internal/models"
+	"github.com/huntercenter1/backend-test/order-service/internal/repo"
+)
+
+// memCarts es un CartRepo en memoria con las mismas reglas de estado.
+type memCarts struct{ carts map[string]*models.Cart }
+
+func newMemCarts() *memCarts { return &memCarts{carts: map[string]*models.Cart{}} }
+
+func (m *memCarts) Create(_ context.Context, c *models.Cart) (*models.Cart, error) {
+	c.ID = "c" + string(rune('0'+len(m.carts))); c.Status = models.CartOpen; c.Items = []models.CartItem{}
+	m.carts[c.ID] = c
+	return c, nil
+}
+func (m *memCarts) Get(_ context.Context, id string) (*models.Cart, error) {
+	c, ok := m.carts[id]
+	if !ok { return nil, repo.ErrNotFound }
+	cp := *c; cp.Items = append([]models.CartItem{}, c.Items...)
+	return &cp, nil
+}
+func (m *memCarts) OpenByUser(ctx context.Context, userID string) (*models.Cart, error) {
+	for id, c := range m.carts {
+		if c.UserID != nil && *c.UserID == userID && c.Status == models.CartOpen { return m.Get(ctx, id) }
+	}
+	return nil, repo.ErrNotFound
+}
+func (m *memCarts) open(id string) (*models.Cart, error) {
+	c, ok := m.carts[id]
+	if !ok { return nil, repo.ErrNotFound }
+	if c.Status != models.CartOpen { return nil, repo.ErrCartNotOpen }
+	return c, nil
+}
+func (m *memCarts) AddItem(_ context.Context, cartID, productID string, qty int, price float64) error {
+	c, err := m.open(cartID)
+	if err != nil { return err }
+	for i := range c.Items {
+		if c.Items[i].ProductID == productID { c.Items[i].Quantity += qty; c.Items[i].AddedPrice = price; return nil }
+	}
+	c.Items = append(c.Items, models.CartItem{CartID: cartID, ProductID: productID, Quantity: qty, AddedPrice: price})
+	return nil
+}
+func (m *memCarts) SetQuantity(_ context.Context, cartID, productID string, qty int) error {
+	c, err := m.open(cartID)
+	if err != nil { return err }
+	for i := range c.Items {
+		if c.Items[i].ProductID == productID { c.Items[i].Quantity = qty; return nil }
+	}
+	return repo.ErrNotFound
+}
+func (m *memCarts) RemoveItem(_ context.Context, cartID, productID string) error {
+	c, err := m.open(cartID)
+	if err != nil { return err }
+	for i := range c.Items {
+		if c.Items[i].ProductID == productID { c.Items = append(c.Items[:i], c.Items[i+1:]...); return nil }
+	}
+	return repo.ErrNotFound
+}
+func (m *memCarts) Merge(ctx context.Context, guestID, userID string) (string, error) {
+	g, err := m.open(guestID)
+	if err != nil { return "", err }
+	if g.UserID != nil { return "", repo.ErrCartOwned }
+	own, err := m.OpenByUser(ctx, userID)
+	if err != nil { g.UserID = &userID; return guestID, nil }
+	for _, it := range g.Items { _ = m.AddItem(ctx, own.ID, it.ProductID, it.Quantity, it.AddedPrice) }
+	g.Status = models.CartMerged
+	return own.ID, nil
+}
+func (m *memCarts) BeginCheckout(ctx context.Context, id string) (*models.Cart, error) {
+	c, err := m.open(id)
+	if err != nil { return nil, err }
+	c.Status = models.CartCheckingOut
+	return m.Get(ctx, id)
+}
+func (m *memCarts) FinishCheckout(_ context.Context, id, orderID string) error {
+	m.carts[id].Status = models.CartCheckedOut; m.carts[id].OrderID = &orderID
+	return nil
+}
+func (m *memCarts) AbortCheckout(_ context.Context, id string) error { m.carts[id].Status = models.CartOpen; return nil }
+
+// catalogPC responde según el mapa; los productos que faltan dan 404 y
+// down simula product-service caído.
+type catalogPC struct {
+	fakePC
+	products map[string]clients.Product
+	down     bool
+}
+
+func (c *catalogPC) Get(_ context.Context, id string) (*clients.Product, error) {
+	if c.down { return nil, errors.New("connection refused") }
+	p, ok := c.products[id]
+	if !ok { return nil, &clients.StatusError{Op: "product get", Code: http.StatusNotFound} }
+	return &p, nil
+}
+
+type orderRepo struct{ fakeRepo }
+
+func (orderRepo) CreateOrder(_ context.Context, o *models.Order, items []models.OrderItem) (*models.Order, []models.OrderItem, error) {
+	o.ID = "o1"
+	return o, items, nil
+}
+
+func newCartSvc(pc *catalogPC) (CartService, *memCarts) {
+	carts := newMemCarts()
+	return NewCartService(carts, New(orderRepo{}, fakeUC{ok: true}, pc, nil), fakeUC{ok: true}, pc), carts
+}
+
+func TestCartRefreshesPriceAndAvailability(t *testing.T) {
+	pc := &catalogPC{products: map[string]clients.Product{
+		"p1": {ID: "p1", Name: "Laptop", Price: 100, Stock: 5},
+		"p2": {ID: "p2", Name: "Mouse", Price: 10, Stock: 1},
+		"p3": {ID: "p3", Name: "Gone", Price: 7, Stock: 9},
+	}}
+	svc, _ := newCartSvc(pc)
+	ctx := context.Background()
+	cart, created, err := svc.Create(ctx, "u1")
+	if err != nil || !created { t.Fatalf("create: %v created=%v", err, created) }
+	for id, qty := range map[string]int{"p1": 2, "p2": 3, "p3": 1} {
+		if _, err := svc.AddItem(ctx, cart.ID, id, qty); err != nil { t.Fatal(err) }
+	}
+
+	// cambia el precio efectivo de p1 y desaparece p3
+	pc.products["p1"] = clients.Product{ID: "p1", Name: "Laptop", Price: 100, EffectivePrice: 90, Stock: 5}
+	delete(pc.products, "p3")
+	v, err := svc.Get(ctx, cart.ID)
+	if err != nil { t.Fatal(err) }
+	lines := map[string]CartLine{}
+	for _, l := range v.Items { lines[l.ProductID] = l }
+	if l := lines["p1"]; l.UnitPrice != 90 || !l.PriceChanged || l.Availability != LineOK || l.LineTotal != 180 { t.Fatalf("p1: %+v", l) }
+	if l := lines["p2"]; l.Availability != LineInsufficientStock || l.LineTotal != 30 { t.Fatalf("p2: %+v", l) }
+	if l := lines["p3"]; l.Availability != LineUnavailable || l.LineTotal != 0 { t.Fatalf("p3: %+v", l) }
+	if v.Available || v.Subtotal != 210 { t.Fatalf("available=%v subtotal=%v", v.Available, v.Subtotal) }
+
+	// con product-service caído el carrito se sigue leyendo
+	pc.down = true
+	v, err = svc.Get(ctx, cart.ID)
+	if err != nil { t.Fatal(err) }
+	if v.Items[0].Availability != LineUnknown || v.Available { t.Fatalf("down: %+v", v.Items[0]) }
+
+	// un segundo Create del mismo usuario devuelve el mismo carrito
+	pc.down = false
+	again, created, err := svc.Create(ctx, "u1")
+	if err != nil || created || again.ID != cart.ID { t.Fatalf("second create: %v created=%v id=%s", err, created, again.ID) }
+}
+
+func TestCartItemsValidation(t *testing.T) {
+	svc, _ := newCartSvc(&catalogPC{products: map[string]clients.Product{"p1": {ID: "p1", Price: 5, Stock: 5}}})
+	ctx := context.Background()
+	cart, _, _ := svc.Create(ctx, "")
+	if _, err := svc.AddItem(ctx, cart.ID, "p1", 0); !errors.Is(err, ErrInvalidQuantity) { t.Fatalf("qty 0: %v", err) }
+	if _, err := svc.AddItem(ctx, cart.ID, "nope", 1); !errors.Is(err, ErrProductNotFound) { t.Fatalf("missing product: %v", err) }
+	if _, err := svc.AddItem(ctx, "missing", "p1", 1); !errors.Is(err, repo.ErrNotFound) { t.Fatalf("missing cart: %v", err) }
+
+	if _, err := svc.AddItem(ctx, cart.ID, "p1", 1); err != nil { t.Fatal(err) }
+	v, err := svc.AddItem(ctx, cart.ID, "p1", 2)
+	if err != nil || len(v.Items) != 1 || v.Items[0].Quantity != 3 { t.Fatalf("add twice: %v %+v", err, v) }
+	v, err = svc.UpdateItem(ctx, cart.ID, "p1", 0) // 0 elimina la línea
+	if err != nil || len(v.Items) != 0 { t.Fatalf("update to 0: %v %+v", err, v) }
+}
+
+func TestCartMergeOnLogin(t *testing.T) {
+	svc, carts := newCartSvc(&catalogPC{products: map[string]clients.Product{
+		"p1": {ID: "p1", Price: 5, Stock: 50}, "p2": {ID: "p2", Price: 8, Stock: 50},
+	}})
+	ctx := context.Background()
+	own, _, _ := svc.Create(ctx, "u1")
+	_, _ = svc.AddItem(ctx, own.ID, "p1", 1)
+	guest, _, _ := svc.Create(ctx, "")
+	_, _ = svc.AddItem(ctx, guest.ID, "p1", 2)
+	_, _ = svc.AddItem(ctx, guest.ID, "p2", 1)
+
+	v, err := svc.Merge(ctx, guest.ID, "u1")
+	if err != nil { t.Fatal(err) }
+	if v.ID != own.ID || len(v.Items) != 2 || v.Items[0].Quantity != 3 { t.Fatalf("merged cart: %+v", v) }
+	if carts.carts[guest.ID].Status != models.CartMerged { t.Fatalf("guest cart status=%s", carts.carts[guest.ID].Status) }
+	if _, err := svc.AddItem(ctx, guest.ID, "p1", 1); !errors.Is(err, repo.ErrCartNotOpen) { t.Fatalf("merged cart must be closed: %v", err) }
+
+	// sin carrito propio el de invitado pasa a ser del usuario
+	guest2, _, _ := svc.Create(ctx, "")
+	v, err = svc.Merge(ctx, guest2.ID, "u2")
+	if err != nil || v.ID != guest2.ID || v.UserID == nil || *v.UserID != "u2" { t.Fatalf("adopt: %v %+v", err, v) }
+
+	bad := NewCartService(carts, nil, fakeUC{ok: false}, &catalogPC{})
+	if _, err := bad.Merge(ctx, guest2.ID, "ghost"); err == nil { t.Fatal("merge with invalid user must fail") }
+}
+
+func TestCartCheckoutCreatesOrder(t *testing.T) {
+	pc := &catalogPC{products: map[string]clients.Product{"p1": {ID: "p1", Price: 100, EffectivePrice: 80, Stock: 1}}}
+	svc, carts := newCartSvc(pc)
+	ctx := context.Background()
+
+	guest, _, _ := svc.Create(ctx, "")
+	_, _ = svc.AddItem(ctx, guest.ID, "p1", 1)
+	if _, _, err := svc.Checkout(ctx, guest.ID, CheckoutRequest{}); !errors.Is(err, ErrGuestCart) { t.Fatalf("guest checkout: %v", err) }
+	if carts.carts[guest.ID].Status != models.CartOpen { t.Fatalf("guest cart must stay open") }
+
+	cart, _, _ := svc.Create(ctx, "u1")
+	if _, _, err := svc.Checkout(ctx, cart.ID, CheckoutRequest{}); !errors.Is(err, ErrEmptyCart) { t.Fatalf("empty checkout: %v", err) }
+
+	// sin stock suficiente falla en Service.Create y el carrito sigue abierto
+	_, _ = svc.AddItem(ctx, cart.ID, "p1", 2)
+	if _, _, err := svc.Checkout(ctx, cart.ID, CheckoutRequest{}); err == nil || err.Error() != "insufficient stock" { t.Fatalf("checkout without stock: %v", err) }
+	if carts.carts[cart.ID].Status != models.CartOpen { t.Fatalf("cart must be reopened, status=%s", carts.carts[cart.ID].Status) }
+
+	_, _ = svc.UpdateItem(ctx, cart.ID, "p1", 1)
+	o, items, err := svc.Checkout(ctx, cart.ID, CheckoutRequest{})
+	if err != nil { t.Fatal(err) }
+	if o.UserID != "u1" || o.Total != 80 || len(items) != 1 { t.Fatalf("order: %+v items=%+v", o, items) }
+	if c := carts.carts[cart.ID]; c.Status != models.CartCheckedOut || c.OrderID == nil || *c.OrderID != o.ID { t.Fatalf("cart after checkout: %+v", c) }
+	if _, _, err := svc.Checkout(ctx, cart.ID, CheckoutRequest{}); !errors.Is(err, repo.ErrCartNotOpen) { t.Fatalf("second checkout: %v", err) }
+}
@@ -0,0 +1,172 @@
+package service
+
+import (
+	"context"
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/huntercenter1/backend-test/platform/server"
+
+	"github.com/huntercenter1/backend-test/order-service/internal/clients"
+	"github.com/huntercenter1/backend-test/order-service/internal/metrics"
+	"github.com/huntercenter1/backend-test/order-service/internal/models"
+	"github.com/huntercenter1/backend-test/order-service/internal/repo"
+)
+
+// orderExpiryTimeout acota un pedido: su transacción y las llamadas a
+// product-service para reponer cada línea.
+const orderExpiryTimeout = 30 * time.Second
+
+type ExpiryConfig struct {
+	TTL       time.Duration // un pedido sin pagar más antiguo que esto caduca
+	Interval  time.Duration // entre pasadas
+	BatchSize int           // pedidos como mucho por pasada
+}
+
+// ExpiryStatus es el estado del worker en esta réplica; Backlog es global.
+type ExpiryStatus struct {
+	TTL          string     `json:"ttl"`
+	Interval     string     `json:"interval"`
+	BatchSize    int        `json:"batch_size"`
+	Running      bool       `json:"running"` // hay una pasada en curso
+	LastRunAt    *time.Time `json:"last_run_at,omitempty"`
+	LastDuration string     `json:"last_duration,omitempty"`
+	LastExpired  int        `json:"last_expired"`
+	LastError    string     `json:"last_error,omitempty"`
+	TotalExpired int        `json:"total_expired"` // desde que arrancó el proceso
+	Backlog      int        `json:"backlog"`       // pedidos por caducar o por reponer ahora mismo
+}
+
+// ExpiryService es lo que expone el worker por HTTP.
+type ExpiryService interface {
+	Status(ctx context.Context) (ExpiryStatus, error)
+}
+
+// ExpiryWorker caduca los pedidos pending o payment_failed que nadie paga y
+// devuelve su stock a product-service; también reintenta el de los
+// cancelados que no se pudo reponer. Se puede ejecutar en varias réplicas
+// a la vez (ver repo.ExpiryRepo).
+type ExpiryWorker struct {
+	repo repo.ExpiryRepo
+	pc   clients.ProductClient
+	cfg  ExpiryConfig
+	now  func() time.Time
+
+	mu     sync.Mutex
+	status ExpiryStatus
+}
+
+func NewExpiryWorker(r repo.ExpiryRepo, pc clients.ProductClient, cfg ExpiryConfig) *ExpiryWorker {
+	return &ExpiryWorker{repo: r, pc: pc, cfg: cfg, now: time.Now, status: ExpiryStatus{
+		TTL: cfg.TTL.String(), Interval: cfg.Interval.String(), BatchSize: cfg.BatchSize,
+	}}
+}
+
+// RunOnce hace una pasada y devuelve cuántos pedidos han caducado.
+func (w *ExpiryWorker) RunOnce(ctx context.Context) (int, error) {
+	start := w.now()
+	w.mu.Lock(); w.status.Running = true; w.mu.Unlock()
+
+	before, n := start.Add(-w.cfg.TTL), 0
+	var err error
+	for i := 0; i < w.cfg.BatchSize && ctx.Err() == nil; i++ {
+		o, expired, e := w.expireNext(ctx, before, start)
+		if e != nil { err = e; break }
+		if o == nil { break }
+		if expired {
+			n++
+			metrics.OrdersExpired.Inc()
+			slog.InfoContext(ctx, "order expired", "order_id", o.ID, "user_id", o.UserID, "created_at", o.CreatedAt)
+		}
+	}
+	if err != nil { slog.ErrorContext(ctx, "order expiry pass failed", "expired", n, "error", err) }
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.status.Running, w.status.LastRunAt, w.status.LastDuration = false, &start, w.now().Sub(start).String()
+	w.status.LastExpired, w.status.LastError = n, ""
+	if err != nil { w.status.LastError = err.Error() }
+	w.status.TotalExpired += n
+	return n, err
+}
+
+// expireNext trata un pedido; los que ya se intentaron en esta pasada
+// (desde start) no se repiten. Cancelar ctx no corta el pedido en curso:
+// deshacer la transacción después de reponer parte del stock lo repondría
+// dos veces en la siguiente pasada.
+func (w *ExpiryWorker) expireNext(ctx context.Context, before, start time.Time) (*models.Order, bool, error) {
+	ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), orderExpiryTimeout); defer cancel()
+	return w.repo.ExpireNext(ctx, before, start, restockFunc(w.pc))
+}
+
+// restockFunc devuelve a cada línea las unidades que descontó su venta
+// (applied_quantity). Un producto que ya no existe en product-service (un
+// 404) se da por repuesto para no reintentarlo siempre; cualquier otro error
+// deja la línea pendiente para la siguiente pasada. Lo usan la caducidad y la
+// cancelación; la referencia por línea hace que un reintento tras un fallo
+// de red no reponga dos veces.
+func restockFunc(pc clients.ProductClient) repo.RestockFunc {
+	return func(ctx context.Context, o *models.Order, items []models.OrderItem) []string {
+		var done []string
+		for _, it := range items {
+			if it.AppliedQuantity == 0 { done = append(done, it.ID); continue }
+			_, err := pc.ApplyStockDelta(ctx, it.ProductID, clients.StockChange{Delta: it.AppliedQuantity, Reason: clients.StockRestock, ReferenceID: clients.StockRef(o.ID, it.ID)})
+			switch {
+			case err == nil:
+			case clients.IsNotFound(err):
+				slog.WarnContext(ctx, "order product gone, not restocked", "order_id", o.ID, "status", o.Status, "product_id", it.ProductID, "quantity", it.AppliedQuantity)
+			default:
+				slog.ErrorContext(ctx, "order restock failed", "order_id", o.ID, "status", o.Status, "product_id", it.ProductID, "quantity", it.AppliedQuantity, "error", err)
+				continue
+			}
+			done = append(done, it.ID)
+		}
+		return done
+	}
+}
+
+func (w *ExpiryWorker) Status(ctx context.Context) (ExpiryStatus, error) {
+	now := w.now()
+	backlog, err := w.repo.Backlog(ctx, now.Add(-w.cfg.TTL), now)
+	if err != nil { return ExpiryStatus{}, err }
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	st := w.status
+	st.Backlog = backlog
+	return st, nil
+}
+
+// Run hace una pasada cada Interval hasta que ctx se cancela.
+func (w *ExpiryWorker) Run(ctx context.Context) {
+	t := time.NewTicker(w.cfg.Interval)
+	defer t.Stop()
+	for {
+		_, _ = w.RunOnce(ctx)
+		select {
+		case <-ctx.Done():
+			return
+		case <-t.C:
+		}
+	}
+}
+
+// Server ejecuta Run dentro del ciclo de vida de server.Run; al pararse
+// espera a que acabe el pedido en curso.
+func (w *ExpiryWorker) Server() server.Server {
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	return server.Server{
+		Name:  "order-expiry",
+		Serve: func() error { defer close(done); w.Run(ctx); return nil },
+		Shutdown: func(sctx context.Context) error {
+			cancel()
+			select {
+			case <-done:
+				return nil
+			case <-sctx.Done():
+				return sctx.Err()
+			}
+		},
+	}
+}
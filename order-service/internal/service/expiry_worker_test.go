@@ -0,0 +1,174 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"slices"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/huntercenter1/backend-test/order-service/internal/clients"
+	"github.com/huntercenter1/backend-test/order-service/internal/models"
+	"github.com/huntercenter1/backend-test/order-service/internal/repo"
+)
+
+// memExpiry es un ExpiryRepo en memoria con la misma selección que el de
+// Postgres (pending o payment_failed antiguos sin pagos en curso, o expired
+// o cancelled con líneas descontadas sin reponer) y el mismo orden por
+// updated_at.
+type memExpiry struct {
+	orders   map[string]*models.Order
+	items    []*models.OrderItem
+	inFlight map[string]bool // pedidos con un pago en curso
+}
+
+func (m *memExpiry) candidates(before, retryBefore time.Time) []*models.Order {
+	var out []*models.Order
+	for _, o := range m.orders {
+		switch {
+		case (o.Status == models.OrderPending || o.Status == models.OrderPaymentFailed) && o.CreatedAt.Before(before) && !m.inFlight[o.ID]:
+		case (o.Status == models.OrderExpired || o.Status == models.OrderCancelled) && o.UpdatedAt.Before(retryBefore) && len(m.pending(o.ID)) > 0:
+		default:
+			continue
+		}
+		out = append(out, o)
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].UpdatedAt.Before(out[j].UpdatedAt) })
+	return out
+}
+func (m *memExpiry) pending(orderID string) []models.OrderItem {
+	var out []models.OrderItem
+	for _, it := range m.items { if it.OrderID == orderID && it.StockApplied && !it.Restocked { out = append(out, *it) } }
+	return out
+}
+func (m *memExpiry) ExpireNext(ctx context.Context, before, retryBefore time.Time, restock repo.RestockFunc) (*models.Order, bool, error) {
+	c := m.candidates(before, retryBefore)
+	if len(c) == 0 { return nil, false, nil }
+	o := c[0]
+	done := map[string]bool{}
+	for _, id := range restock(ctx, o, m.pending(o.ID)) { done[id] = true }
+	for _, it := range m.items { if done[it.ID] { it.Restocked = true } }
+	expired := o.Status == models.OrderPending || o.Status == models.OrderPaymentFailed
+	if expired { o.Status = models.OrderExpired }
+	o.UpdatedAt = time.Now()
+	cp := *o
+	return &cp, expired, nil
+}
+func (m *memExpiry) Backlog(_ context.Context, before, retryBefore time.Time) (int, error) { return len(m.candidates(before, retryBefore)), nil }
+
+// restockPC suma las reposiciones por producto y anota sus referencias;
+// down hace fallar las de esos productos, status las responde con ese
+// código y "gone" no existe en product-service.
+type restockPC struct {
+	fakePC
+	deltas map[string]int
+	refs   []string
+	down   map[string]bool
+	status map[string]int
+}
+
+func (s *restockPC) ApplyStockDelta(_ context.Context, id string, ch clients.StockChange) (*clients.Product, error) {
+	if id == "gone" { return nil, &clients.StatusError{Op: "stock update", Code: http.StatusNotFound} }
+	if s.down[id] { return nil, errors.New("connection refused") }
+	if code := s.status[id]; code != 0 { return nil, &clients.StatusError{Op: "stock update", Code: code} }
+	if ch.Reason != clients.StockRestock || ch.ReferenceID == "" { return nil, errors.New("unexpected stock change") }
+	s.deltas[id] += ch.Delta
+	s.refs = append(s.refs, ch.ReferenceID)
+	return &clients.Product{ID: id}, nil
+}
+
+func newExpiryFixture() (*ExpiryWorker, *memExpiry, *restockPC) {
+	now := time.Now()
+	at := func(ago time.Duration) time.Time { return now.Add(-ago) }
+	m := &memExpiry{
+		orders: map[string]*models.Order{
+			"old":    {ID: "old", Status: models.OrderPending, CreatedAt: at(2 * time.Hour), UpdatedAt: at(2 * time.Hour)},
+			"older":  {ID: "older", Status: models.OrderPending, CreatedAt: at(3 * time.Hour), UpdatedAt: at(3 * time.Hour)},
+			"fresh":  {ID: "fresh", Status: models.OrderPending, CreatedAt: at(time.Minute), UpdatedAt: at(time.Minute)},
+			"paying": {ID: "paying", Status: models.OrderPending, CreatedAt: at(2 * time.Hour), UpdatedAt: at(2 * time.Hour)},
+			"paid":   {ID: "paid", Status: models.OrderPaid, CreatedAt: at(5 * time.Hour), UpdatedAt: at(5 * time.Hour)},
+			"failed": {ID: "failed", Status: models.OrderPaymentFailed, CreatedAt: at(90 * time.Minute), UpdatedAt: at(80 * time.Minute)},
+		},
+		items: []*models.OrderItem{
+			{ID: "i1", OrderID: "old", ProductID: "p1", Quantity: 2, StockApplied: true, AppliedQuantity: 2},
+			{ID: "i2", OrderID: "old", ProductID: "p2", Quantity: 1, StockApplied: true, AppliedQuantity: 1},
+			{ID: "i3", OrderID: "older", ProductID: "p1", Quantity: 1, StockApplied: true, AppliedQuantity: 1},
+			{ID: "i4", OrderID: "older", ProductID: "gone", Quantity: 1, StockApplied: true, AppliedQuantity: 1},
+			{ID: "i5", OrderID: "fresh", ProductID: "p1", Quantity: 5, StockApplied: true, AppliedQuantity: 5},
+			{ID: "i6", OrderID: "paying", ProductID: "p1", Quantity: 5, StockApplied: true, AppliedQuantity: 5},
+			{ID: "i7", OrderID: "paid", ProductID: "p1", Quantity: 5, StockApplied: true, AppliedQuantity: 5},
+			{ID: "i8", OrderID: "failed", ProductID: "p2", Quantity: 4, StockApplied: true, AppliedQuantity: 3}, // la venta descontó 3 de 4
+			{ID: "i9", OrderID: "failed", ProductID: "p3", Quantity: 7}, // su venta no llegó a descontar
+		},
+		inFlight: map[string]bool{"paying": true},
+	}
+	pc := &restockPC{deltas: map[string]int{}, down: map[string]bool{}, status: map[string]int{}}
+	return NewExpiryWorker(m, pc, ExpiryConfig{TTL: time.Hour, Interval: time.Minute, BatchSize: 10}), m, pc
+}
+
+func TestExpiryExpiresOldPendingAndRestoresStock(t *testing.T) {
+	w, m, pc := newExpiryFixture()
+	n, err := w.RunOnce(context.Background())
+	if err != nil || n != 3 { t.Fatalf("expired=%d err=%v", n, err) }
+	for id, want := range map[string]string{"old": models.OrderExpired, "older": models.OrderExpired, "failed": models.OrderExpired, "fresh": models.OrderPending, "paying": models.OrderPending, "paid": models.OrderPaid} {
+		if got := m.orders[id].Status; got != want { t.Errorf("%s status=%s want %s", id, got, want) }
+	}
+	// el producto borrado se da por repuesto; lo que no se descontó no se
+	// repone, ni entero ni en parte
+	if pc.deltas["p1"] != 3 || pc.deltas["p2"] != 4 || pc.deltas["p3"] != 0 || len(m.pending("older")) != 0 { t.Fatalf("deltas=%v", pc.deltas) }
+	// una referencia por línea: product-service no repite una reposición reintentada
+	if !slices.Contains(pc.refs, "old:i1") || !slices.Contains(pc.refs, "old:i2") { t.Fatalf("refs=%v", pc.refs) }
+
+	if n, _ := w.RunOnce(context.Background()); n != 0 || pc.deltas["p1"] != 3 { t.Fatalf("second pass expired=%d deltas=%v", n, pc.deltas) }
+}
+
+func TestExpiryRetriesFailedRestock(t *testing.T) {
+	w, m, pc := newExpiryFixture()
+	ctx := context.Background()
+	pc.down["p2"] = true
+	if n, _ := w.RunOnce(ctx); n != 3 { t.Fatalf("expired=%d", n) }
+	if left := m.pending("old"); len(left) != 1 || left[0].ProductID != "p2" { t.Fatalf("left=%+v", left) }
+	st, err := w.Status(ctx)
+	if err != nil || st.Backlog != 2 || st.LastExpired != 3 || st.TotalExpired != 3 || st.LastRunAt == nil { t.Fatalf("status=%+v err=%v", st, err) }
+
+	// el reintento repone lo que faltaba sin volver a contar el pedido
+	pc.down["p2"] = false
+	if n, _ := w.RunOnce(ctx); n != 0 { t.Fatalf("retry expired=%d", n) }
+	if pc.deltas["p1"] != 3 || pc.deltas["p2"] != 4 { t.Fatalf("deltas=%v", pc.deltas) }
+	if st, _ := w.Status(ctx); st.Backlog != 0 || st.TotalExpired != 3 { t.Fatalf("status=%+v", st) }
+}
+
+func TestExpiryRetriesRestockRejectedByProductService(t *testing.T) {
+	w, m, pc := newExpiryFixture()
+	ctx := context.Background()
+	// sólo un 404 es un producto borrado: un 500 o un almacén inexistente no
+	// dan la línea por repuesta
+	pc.status["p1"] = http.StatusInternalServerError
+	pc.status["p2"] = http.StatusUnprocessableEntity
+	if n, _ := w.RunOnce(ctx); n != 3 { t.Fatalf("expired=%d", n) }
+	if left := m.pending("old"); len(left) != 2 { t.Fatalf("left=%+v", left) }
+	if left := m.pending("older"); len(left) != 1 || left[0].ProductID != "p1" { t.Fatalf("left=%+v", left) }
+
+	pc.status = map[string]int{}
+	if n, _ := w.RunOnce(ctx); n != 0 { t.Fatalf("retry expired=%d", n) }
+	if pc.deltas["p1"] != 3 || pc.deltas["p2"] != 4 || len(m.pending("old")) != 0 || len(m.pending("older")) != 0 { t.Fatalf("deltas=%v", pc.deltas) }
+}
+
+func TestExpiryRetriesCancelledRestock(t *testing.T) {
+	w, m, pc := newExpiryFixture()
+	m.orders["cancelled"] = &models.Order{ID: "cancelled", Status: models.OrderCancelled, CreatedAt: time.Now(), UpdatedAt: time.Now().Add(-time.Minute)}
+	m.items = append(m.items, &models.OrderItem{ID: "i10", OrderID: "cancelled", ProductID: "p4", Quantity: 2, StockApplied: true, AppliedQuantity: 2})
+	// la cancelación no pudo reponer p4: el worker lo repone y el pedido sigue cancelado
+	if n, _ := w.RunOnce(context.Background()); n != 3 { t.Fatalf("expired=%d", n) }
+	if pc.deltas["p4"] != 2 || m.orders["cancelled"].Status != models.OrderCancelled || len(m.pending("cancelled")) != 0 { t.Fatalf("deltas=%v status=%s", pc.deltas, m.orders["cancelled"].Status) }
+}
+
+func TestExpiryBatchSize(t *testing.T) {
+	w, m, _ := newExpiryFixture()
+	w.cfg.BatchSize = 1
+	if n, _ := w.RunOnce(context.Background()); n != 1 { t.Fatalf("expired=%d", n) }
+	// el más antiguo primero
+	if m.orders["older"].Status != models.OrderExpired || m.orders["old"].Status != models.OrderPending { t.Fatalf("wrong order expired first") }
+}
@@ -0,0 +1,53 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/huntercenter1/backend-test/order-service/internal/clients"
+	"github.com/huntercenter1/backend-test/order-service/internal/invoice"
+	"github.com/huntercenter1/backend-test/order-service/internal/repo"
+)
+
+var (
+	// ErrNoInvoice: la factura se emite al pagarse el pedido.
+	ErrNoInvoice = errors.New("order has no invoice until it is paid")
+	// ErrCustomer envuelve los fallos al pedir el cliente a user-service.
+	ErrCustomer = errors.New("customer details unavailable")
+)
+
+// InvoiceConfig son los datos del emisor y la moneda de las facturas.
+type InvoiceConfig struct {
+	Issuer   invoice.Party
+	Currency string
+}
+
+type InvoiceService interface {
+	// Get devuelve la factura del pedido lista para pintar; el cliente se
+	// pide a user-service en cada llamada.
+	Get(ctx context.Context, orderID string) (*invoice.Document, error)
+}
+
+type invoiceService struct {
+	repo   repo.InvoiceRepo
+	orders Service
+	uc     clients.UserClient
+	cfg    InvoiceConfig
+}
+
+func NewInvoiceService(r repo.InvoiceRepo, orders Service, uc clients.UserClient, cfg InvoiceConfig) InvoiceService {
+	return &invoiceService{repo: r, orders: orders, uc: uc, cfg: cfg}
+}
+
+func (s *invoiceService) Get(ctx context.Context, orderID string) (*invoice.Document, error) {
+	o, err := s.orders.Get(ctx, orderID)
+	if err != nil { return nil, err }
+	inv, err := s.repo.ByOrder(ctx, orderID)
+	if errors.Is(err, repo.ErrNotFound) { return nil, ErrNoInvoice }
+	if err != nil { return nil, err }
+	u, err := s.uc.Get(ctx, o.UserID)
+	if err != nil { return nil, fmt.Errorf("%w: %v", ErrCustomer, err) }
+	d := invoice.Build(*inv, *o, s.cfg.Issuer, invoice.Party{Name: u.Username, Email: u.Email}, s.cfg.Currency)
+	return &d, nil
+}
@@ -0,0 +1,49 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/huntercenter1/backend-test/order-service/internal/invoice"
+	"github.com/huntercenter1/backend-test/order-service/internal/models"
+	"github.com/huntercenter1/backend-test/order-service/internal/repo"
+)
+
+type memInvoices map[string]models.Invoice
+
+func (m memInvoices) ByOrder(_ context.Context, orderID string) (*models.Invoice, error) {
+	inv, ok := m[orderID]
+	if !ok { return nil, repo.ErrNotFound }
+	return &inv, nil
+}
+
+func newInvoiceSvc(uc fakeUC) InvoiceService {
+	orders := map[string]*models.Order{
+		"o1": {ID: "o1", UserID: "u1", Status: models.OrderPaid, Subtotal: 20, TaxTotal: 4.2, Total: 24.2,
+			Items: []models.OrderItem{{ProductID: "p1", Quantity: 2, Price: 10, TaxRate: 21, Tax: 4.2, ProductSnapshot: models.ProductSnapshot{Name: "Taza"}}}},
+		"o2": {ID: "o2", UserID: "u1", Status: models.OrderPending, Total: 10},
+	}
+	invoices := memInvoices{"o1": {OrderID: "o1", Year: 2025, Seq: 7, Number: models.InvoiceNumber(2025, 7), IssuedAt: time.Now()}}
+	cfg := InvoiceConfig{Issuer: invoice.Party{Name: "Tienda S.L."}, Currency: "EUR"}
+	return NewInvoiceService(invoices, New(ordersFrom{orders: orders}, uc, fakePC{}, nil), uc, cfg)
+}
+
+func TestInvoiceIncludesCustomerAndLines(t *testing.T) {
+	d, err := newInvoiceSvc(fakeUC{ok: true}).Get(context.Background(), "o1")
+	if err != nil { t.Fatal(err) }
+	if d.Number != "2025-000007" || d.Customer.Email != "demo@example.com" || d.Issuer.Name != "Tienda S.L." || d.Currency != "EUR" {
+		t.Fatalf("doc=%+v", d)
+	}
+	if len(d.Lines) != 1 || d.Lines[0].Description != "Taza" || d.Total != 24.2 { t.Fatalf("lines=%+v total=%v", d.Lines, d.Total) }
+}
+
+func TestInvoiceErrors(t *testing.T) {
+	ctx := context.Background()
+	svc := newInvoiceSvc(fakeUC{ok: true})
+	if _, err := svc.Get(ctx, "missing"); !errors.Is(err, repo.ErrNotFound) { t.Fatalf("missing order: %v", err) }
+	if _, err := svc.Get(ctx, "o2"); !errors.Is(err, ErrNoInvoice) { t.Fatalf("unpaid order: %v", err) }
+	down := newInvoiceSvc(fakeUC{err: errors.New("connection refused")})
+	if _, err := down.Get(ctx, "o1"); !errors.Is(err, ErrCustomer) { t.Fatalf("user-service down: %v", err) }
+}
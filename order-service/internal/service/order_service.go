@@ -3,19 +3,41 @@ package service
 import (
 	"context"
 	"errors"
+	"fmt"
+	"log/slog"
+	"strings"
 
 	"github.com/huntercenter1/backend-test/order-service/internal/clients"
+	"github.com/huntercenter1/backend-test/order-service/internal/metrics"
 	"github.com/huntercenter1/backend-test/order-service/internal/models"
+	"github.com/huntercenter1/backend-test/order-service/internal/pricing"
 	"github.com/huntercenter1/backend-test/order-service/internal/repo"
 )
 
+// ErrCoupon: el cupón no existe o no se puede aplicar a este pedido.
+var ErrCoupon = errors.New("coupon not applicable")
+
+// ErrInvalidStatus: el estado no se puede fijar a mano (ver models.ManualStatuses).
+var ErrInvalidStatus = errors.New("status cannot be set manually")
+
+// ErrStockUpdate: product-service no aceptó el descuento de stock de alguna
+// línea; el pedido se ha cancelado y puede reintentarse.
+var ErrStockUpdate = errors.New("stock could not be updated")
+
 type CreateItem struct {
 	ProductID string  `json:"product_id"`
 	Quantity  int     `json:"quantity"`
 }
 
+type CreateRequest struct {
+	UserID     string       `json:"user_id"`
+	Items      []CreateItem `json:"items"`
+	CouponCode string       `json:"coupon_code"`
+	Region     string       `json:"region"` // región de envío (ES, US-CA...): impuestos y portes
+}
+
 type Service interface {
-	Create(ctx context.Context, userID string, items []CreateItem) (*models.Order, []models.OrderItem, error)
+	Create(ctx context.Context, req CreateRequest) (*models.Order, []models.OrderItem, error)
 	Get(ctx context.Context, id string) (*models.Order, error)
 	Items(ctx context.Context, id string) ([]models.OrderItem, error)
 	ByUser(ctx context.Context, userID string) ([]models.Order, error)
@@ -23,55 +45,132 @@ type Service interface {
 }
 
 type service struct {
-	repo repo.Repo
-	uc   clients.UserClient
-	pc   clients.ProductClient
+	repo    repo.Repo
+	uc      clients.UserClient
+	pc      clients.ProductClient
+	pricing pricing.Pipeline
 }
 
-func New(r repo.Repo, uc clients.UserClient, pc clients.ProductClient) Service {
-	return &service{repo: r, uc: uc, pc: pc}
+// New: pipeline son los pasos del cálculo del pedido (promociones,
+// impuestos, portes...); con pipeline vacío el total es el subtotal y no se
+// aceptan cupones.
+func New(r repo.Repo, uc clients.UserClient, pc clients.ProductClient, pipeline pricing.Pipeline) Service {
+	return &service{repo: r, uc: uc, pc: pc, pricing: pipeline}
 }
 
-func (s *service) Create(ctx context.Context, userID string, items []CreateItem) (*models.Order, []models.OrderItem, error) {
+func (s *service) Create(ctx context.Context, req CreateRequest) (*models.Order, []models.OrderItem, error) {
+	userID, items := req.UserID, req.Items
 	if userID == "" || len(items) == 0 { return nil, nil, errors.New("invalid payload") }
 
 	// 1) validar usuario
 	ok, err := s.uc.Validate(ctx, userID)
-	if err != nil || !ok { return nil, nil, errors.New("invalid user") }
+	if err != nil || !ok { metrics.OrdersRejected.WithLabelValues("invalid_user").Inc(); return nil, nil, errors.New("invalid user") }
 
-	// 2) verificar stock y total
-	var orderItems []models.OrderItem
-	var total float64
+	// 2) verificar stock y precio
+	var lines []pricing.Line
 	for _, it := range items {
 		if it.Quantity <= 0 { return nil, nil, errors.New("quantity must be > 0") }
 		p, err := s.pc.Get(ctx, it.ProductID)
-		if err != nil { return nil, nil, err }
-		if p.Stock < it.Quantity { return nil, nil, errors.New("insufficient stock") }
-		line := models.OrderItem{
-			ProductID: it.ProductID,
-			Quantity:  it.Quantity,
-			Price:     p.Price,
-		}
-		total += p.Price * float64(it.Quantity)
-		orderItems = append(orderItems, line)
+		if err != nil { metrics.OrdersRejected.WithLabelValues("product_unavailable").Inc(); return nil, nil, err }
+		if p.Stock < it.Quantity { metrics.OrdersRejected.WithLabelValues("insufficient_stock").Inc(); return nil, nil, errors.New("insufficient stock") }
+		lines = append(lines, productLine(it.ProductID, p, it.Quantity))
+	}
+
+	// 3) promociones, impuestos, portes y total
+	q := pricing.NewQuote(userID, lines)
+	q.Region = strings.ToUpper(strings.TrimSpace(req.Region))
+	q.CouponCode = strings.ToUpper(strings.TrimSpace(req.CouponCode))
+	if err := s.pricing.Run(ctx, q); err != nil { return nil, nil, err }
+	if q.CouponCode != "" && !q.CouponApplied { return nil, nil, fmt.Errorf("%w: coupons are not enabled", ErrCoupon) }
+	var orderItems []models.OrderItem
+	for _, l := range q.Lines {
+		orderItems = append(orderItems, models.OrderItem{
+			ProductID: l.ProductID, ProductSnapshot: l.Product, Quantity: l.Quantity, Price: l.UnitPrice, Discount: l.Discount, Gift: l.Gift,
+			TaxClass: l.TaxClass, TaxRate: l.TaxRate, Tax: l.Tax, Shipping: l.Shipping,
+		})
 	}
 
-	// 3) crear orden
-	o := &models.Order{ UserID: userID, Status: "pending", Total: total }
+	// 4) crear orden (canjea las promociones en la misma transacción)
+	o := &models.Order{
+		UserID: userID, Status: models.OrderPending, Region: q.Region, CouponCode: q.CouponCode,
+		Subtotal: q.Subtotal, Discounts: q.Discounts, DiscountTotal: q.DiscountTotal,
+		TaxTotal: q.TaxTotal, ShippingTotal: q.ShippingTotal, Total: q.Total,
+	}
 	o, orderItems, err = s.repo.CreateOrder(ctx, o, orderItems)
+	if errors.Is(err, repo.ErrPromotionExhausted) { metrics.OrdersRejected.WithLabelValues("promotion_exhausted").Inc() }
 	if err != nil { return nil, nil, err }
+	metrics.OrdersCreated.Inc()
+	metrics.OrderValue.Observe(o.Total)
+
+	// 5) descontar stock (delta negativo) por cada línea, regalos incluidos,
+	// con la región de envío para que product-service elija almacén.
+	// Cada línea descontada se marca (stock_applied) con las unidades que
+	// product-service dice haber descontado: es lo que el worker de caducidad
+	// repone. Si product-service rechaza una, o descuenta menos, el pedido no
+	// se puede servir: se devuelve lo ya descontado y se cancela.
+	for i, it := range orderItems {
+		res, err := s.pc.ApplyStockDelta(ctx, it.ProductID, clients.StockChange{
+			Delta: -it.Quantity, Reason: clients.StockSale, ReferenceID: clients.StockRef(o.ID, it.ID), Region: o.Region,
+		})
+		if err != nil {
+			metrics.OrdersRejected.WithLabelValues("stock_update_failed").Inc()
+			s.undoSale(ctx, o)
+			return nil, nil, fmt.Errorf("%w: product %s: %v", ErrStockUpdate, it.ProductID, err)
+		}
+		orderItems[i].StockApplied, orderItems[i].AppliedQuantity = true, -res.Applied
+		// sin la marca ni la caducidad ni la cancelación repondrían la línea:
+		// se repone aquí y se deshace la venta
+		if err := s.repo.MarkStockApplied(ctx, it.ID, orderItems[i].AppliedQuantity); err != nil {
+			restockFunc(s.pc)(context.WithoutCancel(ctx), o, orderItems[i:i+1])
+			s.undoSale(ctx, o)
+			return nil, nil, err
+		}
+		if orderItems[i].AppliedQuantity != it.Quantity {
+			metrics.OrdersRejected.WithLabelValues("stock_update_failed").Inc()
+			s.undoSale(ctx, o)
+			return nil, nil, fmt.Errorf("%w: product %s: %d of %d units applied", ErrStockUpdate, it.ProductID, orderItems[i].AppliedQuantity, it.Quantity)
+		}
+	}
 
-	// 4) descontar stock (delta negativo) por cada item
-	for _, it := range items {
-		if _, err := s.pc.ApplyStockDelta(ctx, it.ProductID, -it.Quantity); err != nil {
-			// Nota: en un caso real, aquí haríamos compensación/cola
-			return o, orderItems, nil // devolvemos la orden creada aunque haya fallos de stock update
+	// 6) sin nada que cobrar (cupón del 100%...) no habrá pagos que lo
+	// liquiden: con todo el stock ya descontado pasa a paid con su factura
+	if models.Cents(o.Total) == 0 {
+		paid, err := s.repo.MarkPaid(ctx, o.ID)
+		if err != nil {
+			s.undoSale(ctx, o)
+			return nil, nil, err
 		}
+		o = paid
 	}
 
 	return o, orderItems, nil
 }
 
+// undoSale cancela el pedido reponiendo el stock de las líneas ya
+// descontadas. Sus fallos sólo se registran: el error que ve el cliente es
+// el de la venta.
+func (s *service) undoSale(ctx context.Context, o *models.Order) {
+	if _, err := s.cancel(ctx, o.ID, []string{models.OrderPending}); err != nil {
+		slog.ErrorContext(ctx, "order cancel after failed sale", "order_id", o.ID, "error", err)
+	}
+}
+
+// cancel cancela el pedido con repo.Cancel, que repone su stock y libera sus
+// promociones. Sigue aunque ctx se cancele: deshacer la transacción después
+// de reponer parte del stock lo repondría dos veces.
+func (s *service) cancel(ctx context.Context, id string, from []string) (*models.Order, error) {
+	ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), orderExpiryTimeout); defer cancel()
+	return s.repo.Cancel(ctx, id, from, restockFunc(s.pc))
+}
+
+// productLine es la línea a precio de catálogo con los datos del producto
+// que necesitan impuestos y portes, y los que se copian en el pedido.
+func productLine(id string, p *clients.Product, qty int) pricing.Line {
+	class := p.TaxClass
+	if class == "" { class = models.DefaultTaxClass }
+	return pricing.Line{ProductID: id, Product: models.ProductSnapshot{Name: p.Name, SKU: p.SKU, Description: p.Description}, Quantity: qty, UnitPrice: p.UnitPrice(), TaxClass: class, WeightGrams: p.WeightGrams}
+}
+
 func (s *service) Get(ctx context.Context, id string) (*models.Order, error) {
 	return s.repo.GetOrder(ctx, id)
 }
@@ -86,5 +185,8 @@ func (s *service) ByUser(ctx context.Context, userID string) ([]models.Order, er
 
 func (s *service) UpdateStatus(ctx context.Context, id, status string) (*models.Order, error) {
 	if status == "" { return nil, errors.New("status required") }
-	return s.repo.UpdateStatus(ctx, id, status)
+	from, ok := models.ManualStatuses[status]
+	if !ok { return nil, fmt.Errorf("%w: %q", ErrInvalidStatus, status) }
+	if status == models.OrderCancelled { return s.cancel(ctx, id, from) }
+	return s.repo.UpdateStatus(ctx, id, from, status)
 }
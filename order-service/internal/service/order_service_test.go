@@ -2,6 +2,10 @@ package service
 
 import (
 	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
 	"testing"
 
 	"github.com/huntercenter1/backend-test/order-service/internal/clients"
@@ -11,6 +15,12 @@ import (
 
 type fakeUC struct{ ok bool; err error }
 func (f fakeUC) Validate(ctx context.Context, id string)(bool,error){ return f.ok, f.err }
+func (f fakeUC) Get(ctx context.Context, id string)(*clients.User, error){
+	if f.err != nil { return nil, f.err }
+	if !f.ok { return nil, clients.ErrUserNotFound }
+	return &clients.User{ID:id, Username:"demo", Email:"demo@example.com"}, nil
+}
+func (f fakeUC) Ping(ctx context.Context) error { return nil }
 
 type fakePC struct{
 	price float64; stock int; err error
@@ -19,35 +29,199 @@ func (f fakePC) Get(ctx context.Context, id string)(*clients.Product, error){
 	if f.err != nil { return nil, f.err }
 	return &clients.Product{ID:"p1", Price:f.price, Stock:f.stock}, nil
 }
-func (f fakePC) ApplyStockDelta(ctx context.Context, id string, delta int)(*clients.Product, error){
-	return &clients.Product{ID:"p1", Price:f.price, Stock:f.stock - delta}, nil
+func (f fakePC) ApplyStockDelta(ctx context.Context, id string, ch clients.StockChange)(*clients.Product, error){
+	return &clients.Product{ID:"p1", Price:f.price, Stock:f.stock + ch.Delta, Applied:ch.Delta}, nil
 }
 
+func (f fakePC) Ping(ctx context.Context) error { return nil }
+
 type fakeRepo struct{}
 func (f fakeRepo) CreateOrder(ctx context.Context, o *models.Order, items []models.OrderItem)(*models.Order, []models.OrderItem, error){ return o, items, nil }
 func (f fakeRepo) GetOrder(ctx context.Context, id string)(*models.Order, error){ return nil, repo.ErrNotFound }
 func (f fakeRepo) GetItems(ctx context.Context, id string)([]models.OrderItem, error){ return nil, nil }
 func (f fakeRepo) ListByUser(ctx context.Context, userID string)([]models.Order, error){ return nil, nil }
-func (f fakeRepo) UpdateStatus(ctx context.Context, id, status string)(*models.Order, error){ return nil, nil }
+func (f fakeRepo) UpdateStatus(ctx context.Context, id string, from []string, to string)(*models.Order, error){ return nil, nil }
+func (f fakeRepo) MarkStockApplied(ctx context.Context, itemID string, quantity int) error { return nil }
+func (f fakeRepo) Cancel(ctx context.Context, id string, from []string, restock repo.RestockFunc)(*models.Order, error){ return &models.Order{ID:id, Status:models.OrderCancelled}, nil }
+func (f fakeRepo) MarkPaid(ctx context.Context, id string)(*models.Order, error){ return &models.Order{ID:id, Status:models.OrderPaid}, nil }
 
 func TestCreateComputesTotal(t *testing.T){
-	s := New(fakeRepo{}, fakeUC{ok:true}, fakePC{price:100, stock:10})
-	o, items, err := s.Create(context.Background(), "u1", []CreateItem{{ProductID:"p1", Quantity:3}})
+	s := New(fakeRepo{}, fakeUC{ok:true}, fakePC{price:100, stock:10}, nil)
+	o, items, err := s.Create(context.Background(), CreateRequest{UserID: "u1", Items: []CreateItem{{ProductID:"p1", Quantity:3}}})
 	if err != nil { t.Fatal(err) }
 	if o.Total != 300 { t.Fatalf("want total=300 got %v", o.Total) }
 	if len(items) != 1 || items[0].Price != 100 { t.Fatalf("items wrong") }
 }
 
+func TestCreateZeroTotalIsPaid(t *testing.T){
+	pc := &saleFailPC{fakePC: fakePC{price:0, stock:10}}
+	r := &statusRepo{status: map[string]string{}}
+	s := New(r, fakeUC{ok:true}, pc, nil)
+	o, _, err := s.Create(context.Background(), CreateRequest{UserID: "u1", Items: []CreateItem{{ProductID:"p1", Quantity:1}, {ProductID:"p2", Quantity:1}}})
+	if err != nil { t.Fatal(err) }
+	// se paga después de descontar todas las líneas
+	if o.Status != models.OrderPaid || r.paidAfter != 2 { t.Fatalf("zero-total order status=%q paid after %d lines", o.Status, r.paidAfter) }
+
+	// si una línea falla no llega a pagarse: se cancela sin factura
+	pc.fail, r.status, r.paidAfter = "p2", map[string]string{}, -1
+	if _, _, err := s.Create(context.Background(), CreateRequest{UserID: "u1", Items: []CreateItem{{ProductID:"p1", Quantity:1}, {ProductID:"p2", Quantity:1}}}); !errors.Is(err, ErrStockUpdate) { t.Fatalf("err=%v", err) }
+	if r.status["o1"] != models.OrderCancelled || r.paidAfter != -1 { t.Fatalf("status=%q paid after %d lines", r.status["o1"], r.paidAfter) }
+}
+
 func TestCreateInvalidUser(t *testing.T){
-	s := New(fakeRepo{}, fakeUC{ok:false}, fakePC{price:100, stock:10})
-	if _, _, err := s.Create(context.Background(), "u1", []CreateItem{{ProductID:"p1", Quantity:1}}); err == nil {
+	s := New(fakeRepo{}, fakeUC{ok:false}, fakePC{price:100, stock:10}, nil)
+	if _, _, err := s.Create(context.Background(), CreateRequest{UserID: "u1", Items: []CreateItem{{ProductID:"p1", Quantity:1}}}); err == nil {
 		t.Fatalf("expected error")
 	}
 }
 
 func TestCreateInsufficientStock(t *testing.T){
-	s := New(fakeRepo{}, fakeUC{ok:true}, fakePC{price:100, stock:0})
-	if _, _, err := s.Create(context.Background(), "u1", []CreateItem{{ProductID:"p1", Quantity:1}}); err == nil {
+	s := New(fakeRepo{}, fakeUC{ok:true}, fakePC{price:100, stock:0}, nil)
+	if _, _, err := s.Create(context.Background(), CreateRequest{UserID: "u1", Items: []CreateItem{{ProductID:"p1", Quantity:1}}}); err == nil {
 		t.Fatalf("expected error")
 	}
 }
+
+func TestCreateUsesEffectivePrice(t *testing.T){
+	s := New(fakeRepo{}, fakeUC{ok:true}, effectivePC{}, nil)
+	o, items, err := s.Create(context.Background(), CreateRequest{UserID: "u1", Items: []CreateItem{{ProductID:"p1", Quantity:2}}})
+	if err != nil { t.Fatal(err) }
+	if items[0].Price != 80 || o.Total != 160 { t.Fatalf("want scheduled price 80 got item=%v total=%v", items[0].Price, o.Total) }
+}
+
+type effectivePC struct{ fakePC }
+func (effectivePC) Get(ctx context.Context, id string)(*clients.Product, error){
+	return &clients.Product{ID:"p1", Price:100, EffectivePrice:80, Stock:10}, nil
+}
+
+func TestCreateSnapshotsProduct(t *testing.T){
+	pc := &catalogPC{products: map[string]clients.Product{
+		"p1": {ID:"p1", Name:"Laptop", SKU:"LP-14", Description:"14 pulgadas", Price:100, Stock:5},
+		"p2": {ID:"p2", Name:"Mouse", Price:10, Stock:5},
+	}}
+	s := New(fakeRepo{}, fakeUC{ok:true}, pc, nil)
+	_, items, err := s.Create(context.Background(), CreateRequest{UserID: "u1", Items: []CreateItem{{ProductID:"p1", Quantity:1}, {ProductID:"p2", Quantity:2}}})
+	if err != nil { t.Fatal(err) }
+	want := []models.ProductSnapshot{{Name:"Laptop", SKU:"LP-14", Description:"14 pulgadas"}, {Name:"Mouse"}}
+	for i, it := range items {
+		if it.ProductSnapshot != want[i] { t.Fatalf("item %d snapshot=%+v want %+v", i, it.ProductSnapshot, want[i]) }
+	}
+}
+
+// saleFailPC rechaza la venta de fail, descuenta como mucho short[id]
+// unidades y anota los movimientos de stock aplicados y la región de las
+// ventas.
+type saleFailPC struct{ fakePC; fail string; short map[string]int; deltas, regions []string }
+func (f *saleFailPC) ApplyStockDelta(ctx context.Context, id string, ch clients.StockChange)(*clients.Product, error){
+	if ch.Reason == clients.StockSale { f.regions = append(f.regions, ch.Region) }
+	if id == f.fail && ch.Reason == clients.StockSale { return nil, &clients.StatusError{Op:"stock update", Code:429} }
+	if n, ok := f.short[id]; ok && ch.Reason == clients.StockSale { ch.Delta = -n }
+	f.deltas = append(f.deltas, fmt.Sprintf("%s %s %+d", id, ch.Reason, ch.Delta))
+	return &clients.Product{ID:id, Applied:ch.Delta}, nil
+}
+
+// statusRepo guarda el estado de cada pedido, sus líneas, las marcadas
+// como descontadas y cuántas lo estaban al pagarlo; markErr hace fallar la
+// marca.
+type statusRepo struct{ fakeRepo; status map[string]string; items []models.OrderItem; applied []string; markErr error; paidAfter int }
+func (r *statusRepo) CreateOrder(ctx context.Context, o *models.Order, items []models.OrderItem)(*models.Order, []models.OrderItem, error){
+	o.ID = "o1"
+	for i := range items { items[i].ID, items[i].OrderID = fmt.Sprintf("i%d", i+1), o.ID }
+	r.items = slices.Clone(items)
+	return o, items, nil
+}
+func (r *statusRepo) MarkStockApplied(ctx context.Context, itemID string, quantity int) error {
+	if r.markErr != nil { return r.markErr }
+	r.applied = append(r.applied, fmt.Sprintf("%s:%d", itemID, quantity))
+	for i := range r.items {
+		if r.items[i].ID == itemID { r.items[i].StockApplied, r.items[i].AppliedQuantity = true, quantity }
+	}
+	return nil
+}
+// Cancel repone como el repo de verdad las líneas descontadas sin reponer.
+func (r *statusRepo) Cancel(ctx context.Context, id string, from []string, restock repo.RestockFunc)(*models.Order, error){
+	cur, ok := r.status[id]
+	if !ok { cur = models.OrderPending }
+	if !slices.Contains(from, cur) { return nil, repo.ErrOrderState }
+	o := &models.Order{ID:id, Status:cur}
+	var pending []models.OrderItem
+	for _, it := range r.items { if it.StockApplied && !it.Restocked { pending = append(pending, it) } }
+	for _, done := range restock(ctx, o, pending) {
+		for i := range r.items { if r.items[i].ID == done { r.items[i].Restocked = true } }
+	}
+	r.status[id], o.Status = models.OrderCancelled, models.OrderCancelled
+	return o, nil
+}
+func (r *statusRepo) MarkPaid(ctx context.Context, id string)(*models.Order, error){
+	r.paidAfter = len(r.applied)
+	return r.UpdateStatus(ctx, id, []string{models.OrderPending}, models.OrderPaid)
+}
+func (r *statusRepo) UpdateStatus(ctx context.Context, id string, from []string, to string)(*models.Order, error){
+	cur, ok := r.status[id]
+	if !ok { cur = models.OrderPending }
+	if !slices.Contains(from, cur) { return nil, repo.ErrOrderState }
+	r.status[id] = to
+	return &models.Order{ID:id, Status:to}, nil
+}
+
+func TestCreateUndoesSaleWhenStockUpdateFails(t *testing.T){
+	pc := &saleFailPC{fakePC: fakePC{price:10, stock:10}, fail:"p2"}
+	r := &statusRepo{status: map[string]string{}}
+	s := New(r, fakeUC{ok:true}, pc, nil)
+	_, _, err := s.Create(context.Background(), CreateRequest{UserID: "u1", Items: []CreateItem{{ProductID:"p1", Quantity:2}, {ProductID:"p2", Quantity:1}, {ProductID:"p3", Quantity:1}}})
+	if !errors.Is(err, ErrStockUpdate) { t.Fatalf("err=%v", err) }
+	// p1 se repone, p3 no llega a descontarse
+	if got := strings.Join(pc.deltas, ","); got != "p1 sale -2,p1 restock +2" { t.Fatalf("deltas=%s", got) }
+	if r.status["o1"] != models.OrderCancelled || strings.Join(r.applied, ",") != "i1:2" { t.Fatalf("status=%q applied=%v", r.status["o1"], r.applied) }
+}
+
+func TestCreateMarksStockApplied(t *testing.T){
+	pc := &saleFailPC{fakePC: fakePC{price:10, stock:10}}
+	r := &statusRepo{status: map[string]string{}}
+	s := New(r, fakeUC{ok:true}, pc, nil)
+	_, items, err := s.Create(context.Background(), CreateRequest{UserID: "u1", Region: "es", Items: []CreateItem{{ProductID:"p1", Quantity:2}, {ProductID:"p2", Quantity:1}}})
+	if err != nil || !items[0].StockApplied || !items[1].StockApplied || strings.Join(r.applied, ",") != "i1:2,i2:1" { t.Fatalf("err=%v applied=%v", err, r.applied) }
+	// product-service elige almacén con la región de envío
+	if strings.Join(pc.regions, ",") != "ES,ES" { t.Fatalf("sale regions=%v", pc.regions) }
+
+	// si no se puede marcar, la venta se deshace: la caducidad no la repondría
+	pc.deltas, r.markErr = nil, errors.New("db down")
+	if _, _, err := s.Create(context.Background(), CreateRequest{UserID: "u1", Items: []CreateItem{{ProductID:"p1", Quantity:2}}}); err == nil { t.Fatal("want error") }
+	if got := strings.Join(pc.deltas, ","); got != "p1 sale -2,p1 restock +2" || r.status["o1"] != models.OrderCancelled { t.Fatalf("deltas=%s status=%q", got, r.status["o1"]) }
+}
+
+func TestCreateUndoesShortSale(t *testing.T){
+	// product-service sólo descuenta 1 de las 3 unidades de p2
+	pc := &saleFailPC{fakePC: fakePC{price:10, stock:10}, short: map[string]int{"p2": 1}}
+	r := &statusRepo{status: map[string]string{}}
+	s := New(r, fakeUC{ok:true}, pc, nil)
+	_, _, err := s.Create(context.Background(), CreateRequest{UserID: "u1", Items: []CreateItem{{ProductID:"p1", Quantity:2}, {ProductID:"p2", Quantity:3}}})
+	if !errors.Is(err, ErrStockUpdate) { t.Fatalf("err=%v", err) }
+	// se repone lo que se descontó de verdad, no la cantidad pedida
+	if got := strings.Join(pc.deltas, ","); got != "p1 sale -2,p2 sale -1,p1 restock +2,p2 restock +1" { t.Fatalf("deltas=%s", got) }
+	if r.status["o1"] != models.OrderCancelled || strings.Join(r.applied, ",") != "i1:2,i2:1" { t.Fatalf("status=%q applied=%v", r.status["o1"], r.applied) }
+}
+
+func TestCancelRestoresStock(t *testing.T){
+	pc := &saleFailPC{fakePC: fakePC{price:10, stock:10}}
+	r := &statusRepo{status: map[string]string{}}
+	s := New(r, fakeUC{ok:true}, pc, nil)
+	ctx := context.Background()
+	if _, _, err := s.Create(ctx, CreateRequest{UserID: "u1", Items: []CreateItem{{ProductID:"p1", Quantity:2}, {ProductID:"p2", Quantity:1}}}); err != nil { t.Fatal(err) }
+	r.status["o1"] = models.OrderPaymentFailed
+	if o, err := s.UpdateStatus(ctx, "o1", models.OrderCancelled); err != nil || o.Status != models.OrderCancelled { t.Fatalf("cancel: %v %+v", err, o) }
+	if got := strings.Join(pc.deltas, ","); got != "p1 sale -2,p2 sale -1,p1 restock +2,p2 restock +1" { t.Fatalf("deltas=%s", got) }
+	// un segundo intento no vuelve a reponer
+	if _, err := s.UpdateStatus(ctx, "o1", models.OrderCancelled); !errors.Is(err, repo.ErrOrderState) || len(pc.deltas) != 4 { t.Fatalf("err=%v deltas=%v", err, pc.deltas) }
+}
+
+func TestUpdateStatusOnlyAllowsManualCancel(t *testing.T){
+	r := &statusRepo{status: map[string]string{"o1": models.OrderPending, "o2": models.OrderShipped}}
+	s := New(r, fakeUC{ok:true}, fakePC{}, nil)
+	ctx := context.Background()
+	for _, st := range []string{models.OrderPaid, models.OrderShipped, models.OrderDelivered, models.OrderExpired, "bogus"} {
+		if _, err := s.UpdateStatus(ctx, "o1", st); !errors.Is(err, ErrInvalidStatus) { t.Errorf("%s: %v", st, err) }
+	}
+	if _, err := s.UpdateStatus(ctx, "o2", models.OrderCancelled); !errors.Is(err, repo.ErrOrderState) { t.Fatalf("cancel shipped: %v", err) }
+	if o, err := s.UpdateStatus(ctx, "o1", models.OrderCancelled); err != nil || o.Status != models.OrderCancelled { t.Fatalf("cancel pending: %v %+v", err, o) }
+}
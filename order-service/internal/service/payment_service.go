@@ -0,0 +1,199 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/huntercenter1/backend-test/order-service/internal/metrics"
+	"github.com/huntercenter1/backend-test/order-service/internal/models"
+	"github.com/huntercenter1/backend-test/order-service/internal/payments"
+	"github.com/huntercenter1/backend-test/order-service/internal/repo"
+)
+
+var (
+	ErrOrderNotPayable = errors.New("order cannot be paid in its current status")
+	ErrInvalidAmount   = errors.New("amount must be > 0")
+	ErrAmountExceeds   = errors.New("amount exceeds what is due")
+	ErrInvalidPayment  = errors.New("method is required and capture_method must be automatic or manual")
+	ErrPaymentState    = errors.New("operation not allowed in the payment's current status")
+	// ErrProvider envuelve los fallos del proveedor de pagos.
+	ErrProvider     = errors.New("payment provider error")
+	ErrInvalidEvent = errors.New("invalid payment event")
+)
+
+type PaymentRequest struct {
+	Amount        float64 `json:"amount"`         // 0 = lo que queda por pagar del pedido
+	Method        string  `json:"method"`         // token o medio de pago del proveedor
+	CaptureMethod string  `json:"capture_method"` // automatic (por defecto) | manual
+}
+
+type PaymentConfig struct {
+	Currency         string
+	WebhookSecret    string
+	WebhookTolerance time.Duration
+}
+
+type PaymentService interface {
+	Create(ctx context.Context, orderID string, req PaymentRequest) (*models.Payment, error)
+	List(ctx context.Context, orderID string) ([]models.Payment, []models.Refund, error)
+	// Capture cobra amount (0 = todo lo autorizado pendiente) de un pago manual.
+	Capture(ctx context.Context, orderID, paymentID string, amount float64) (*models.Payment, error)
+	// Refund devuelve amount (0 = todo lo capturado pendiente de devolver).
+	Refund(ctx context.Context, orderID, paymentID string, amount float64, reason string) (*models.Refund, *models.Payment, error)
+	// RefundOrder devuelve hasta amount repartido entre los pagos del pedido,
+	// del más antiguo al más reciente, y enlaza los reembolsos a la
+	// devolución returnID. Si se ha cobrado menos, devuelve lo que haya.
+	RefundOrder(ctx context.Context, orderID string, amount float64, reason, returnID string) ([]models.Refund, error)
+	// HandleWebhook verifica la firma y aplica el evento; los repetidos se ignoran.
+	HandleWebhook(ctx context.Context, payload []byte, signature string) error
+}
+
+type paymentService struct {
+	repo   repo.PaymentRepo
+	orders Service
+	gw     payments.PaymentGateway
+	cfg    PaymentConfig
+}
+
+func NewPaymentService(r repo.PaymentRepo, orders Service, gw payments.PaymentGateway, cfg PaymentConfig) PaymentService {
+	if cfg.Currency == "" { cfg.Currency = "EUR" }
+	if cfg.WebhookTolerance <= 0 { cfg.WebhookTolerance = 5 * time.Minute }
+	return &paymentService{repo: r, orders: orders, gw: gw, cfg: cfg}
+}
+
+// Create registra el intento antes de llamar al proveedor, así queda
+// constancia aunque el proveedor falle o tarde. Lo que queda por pagar lo
+// comprueba el repo con el pedido bloqueado (ver repo.PaymentRepo.Create).
+func (s *paymentService) Create(ctx context.Context, orderID string, req PaymentRequest) (*models.Payment, error) {
+	if req.CaptureMethod == "" { req.CaptureMethod = payments.CaptureAutomatic }
+	if req.Method == "" || (req.CaptureMethod != payments.CaptureAutomatic && req.CaptureMethod != payments.CaptureManual) {
+		return nil, ErrInvalidPayment
+	}
+	if req.Amount < 0 { return nil, ErrInvalidAmount }
+	o, err := s.orders.Get(ctx, orderID)
+	if err != nil { return nil, err }
+	if !o.Payable() { return nil, ErrOrderNotPayable }
+
+	p, err := s.repo.Create(ctx, &models.Payment{
+		OrderID: orderID, Provider: s.gw.Name(), Method: req.Method, CaptureMethod: req.CaptureMethod,
+		Currency: s.cfg.Currency, Amount: req.Amount,
+	})
+	if errors.Is(err, repo.ErrOrderNotPayable) { return nil, ErrOrderNotPayable }
+	if errors.Is(err, repo.ErrAmountExceeds) { return nil, ErrAmountExceeds }
+	if err != nil { return nil, err }
+
+	intent, err := s.gw.CreateIntent(ctx, payments.IntentRequest{
+		PaymentID: p.ID, OrderID: orderID, Amount: p.Amount, Currency: p.Currency, Method: p.Method, CaptureMethod: p.CaptureMethod,
+	})
+	if err != nil {
+		p.Status, p.FailureReason = models.PaymentFailed, err.Error()
+		if _, serr := s.repo.SaveIntent(context.WithoutCancel(ctx), p); serr != nil {
+			slog.ErrorContext(ctx, "payment save failure", "payment_id", p.ID, "error", serr)
+		}
+		return nil, fmt.Errorf("%w: %v", ErrProvider, err)
+	}
+	p.ProviderRef = intent.Ref
+	saved, err := s.repo.SaveIntent(ctx, p)
+	if err != nil { return nil, err }
+	saved.ClientSecret = intent.ClientSecret
+	return saved, nil
+}
+
+func (s *paymentService) List(ctx context.Context, orderID string) ([]models.Payment, []models.Refund, error) {
+	if _, err := s.orders.Get(ctx, orderID); err != nil { return nil, nil, err }
+	list, err := s.repo.ByOrder(ctx, orderID)
+	if err != nil { return nil, nil, err }
+	refunds, err := s.repo.Refunds(ctx, orderID)
+	if err != nil { return nil, nil, err }
+	return list, refunds, nil
+}
+
+// Capture sólo cobra si el pedido aún admite pagos: capturar en uno
+// cancelado cobraría un pedido cuyo stock ya se repuso.
+func (s *paymentService) Capture(ctx context.Context, orderID, paymentID string, amount float64) (*models.Payment, error) {
+	p, err := s.payment(ctx, orderID, paymentID)
+	if err != nil { return nil, err }
+	o, err := s.orders.Get(ctx, orderID)
+	if err != nil { return nil, err }
+	if !o.Payable() { return nil, ErrOrderNotPayable }
+	if p.CaptureMethod != payments.CaptureManual || (p.Status != models.PaymentAuthorized && p.Status != models.PaymentPartiallyCaptured) {
+		return nil, ErrPaymentState
+	}
+	if amount < 0 { return nil, ErrInvalidAmount }
+	if amount == 0 { amount = p.Outstanding() }
+	if models.Cents(amount) > models.Cents(p.Outstanding()) { return nil, ErrAmountExceeds }
+	captured, err := s.gw.Capture(ctx, p.ProviderRef, amount)
+	if err != nil { return nil, fmt.Errorf("%w: %v", ErrProvider, err) }
+	return s.repo.Capture(ctx, p.ID, captured)
+}
+
+func (s *paymentService) Refund(ctx context.Context, orderID, paymentID string, amount float64, reason string) (*models.Refund, *models.Payment, error) {
+	p, err := s.payment(ctx, orderID, paymentID)
+	if err != nil { return nil, nil, err }
+	if models.Cents(p.Refundable()) <= 0 { return nil, nil, ErrPaymentState }
+	if amount < 0 { return nil, nil, ErrInvalidAmount }
+	if amount == 0 { amount = p.Refundable() }
+	if models.Cents(amount) > models.Cents(p.Refundable()) { return nil, nil, ErrAmountExceeds }
+	return s.refund(ctx, p, amount, reason, nil)
+}
+
+func (s *paymentService) RefundOrder(ctx context.Context, orderID string, amount float64, reason, returnID string) ([]models.Refund, error) {
+	if amount <= 0 { return nil, ErrInvalidAmount }
+	list, err := s.repo.ByOrder(ctx, orderID)
+	if err != nil { return nil, err }
+	left := models.Cents(amount)
+	var out []models.Refund
+	for i := range list {
+		if left <= 0 { break }
+		n := min(left, models.Cents(list[i].Refundable()))
+		if n <= 0 { continue }
+		rf, _, err := s.refund(ctx, &list[i], float64(n)/100, reason, &returnID)
+		if err != nil { return out, err }
+		out = append(out, *rf)
+		left -= n
+	}
+	return out, nil
+}
+
+func (s *paymentService) refund(ctx context.Context, p *models.Payment, amount float64, reason string, returnID *string) (*models.Refund, *models.Payment, error) {
+	res, err := s.gw.Refund(ctx, p.ProviderRef, amount, reason)
+	if err != nil { return nil, nil, fmt.Errorf("%w: %v", ErrProvider, err) }
+	rf := &models.Refund{PaymentID: p.ID, ReturnID: returnID, Amount: amount, ProviderRef: res.Ref, Reason: reason}
+	p, err = s.repo.Refund(ctx, rf, res.Refunded)
+	if err != nil { return nil, nil, err }
+	return rf, p, nil
+}
+
+func (s *paymentService) HandleWebhook(ctx context.Context, payload []byte, signature string) error {
+	if err := payments.Verify(s.cfg.WebhookSecret, payload, signature, time.Now(), s.cfg.WebhookTolerance); err != nil {
+		metrics.PaymentEvents.WithLabelValues("unknown", "rejected").Inc()
+		return err
+	}
+	var ev payments.Event
+	if err := json.Unmarshal(payload, &ev); err != nil || ev.ID == "" || ev.PaymentRef == "" { return ErrInvalidEvent }
+	switch ev.Type {
+	case payments.EventAuthorized, payments.EventCaptured, payments.EventFailed, payments.EventRefunded:
+	default:
+		// eventos que no nos interesan: se aceptan para que el proveedor no reintente
+		slog.InfoContext(ctx, "payment event ignored", "event_id", ev.ID, "type", ev.Type)
+		return nil
+	}
+	p, err := s.repo.ApplyEvent(ctx, ev, payload)
+	if errors.Is(err, repo.ErrDuplicateEvent) { metrics.PaymentEvents.WithLabelValues(ev.Type, "duplicate").Inc(); return nil }
+	if err != nil { return err }
+	metrics.PaymentEvents.WithLabelValues(ev.Type, "applied").Inc()
+	slog.InfoContext(ctx, "payment event", "event_id", ev.ID, "type", ev.Type, "payment_id", p.ID, "order_id", p.OrderID, "status", p.Status)
+	return nil
+}
+
+// payment carga el pago comprobando que es del pedido de la URL.
+func (s *paymentService) payment(ctx context.Context, orderID, paymentID string) (*models.Payment, error) {
+	p, err := s.repo.Get(ctx, paymentID)
+	if err != nil { return nil, err }
+	if p.OrderID != orderID { return nil, repo.ErrNotFound }
+	return p, nil
+}
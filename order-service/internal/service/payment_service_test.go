@@ -0,0 +1,297 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/huntercenter1/backend-test/order-service/internal/models"
+	"github.com/huntercenter1/backend-test/order-service/internal/payments"
+	"github.com/huntercenter1/backend-test/order-service/internal/repo"
+)
+
+// memPayments es un PaymentRepo en memoria que, como el de Postgres,
+// comprueba lo que queda por pagar al dar de alta un pago y recalcula el
+// estado del pedido con models.SettleOrder en cada cambio. mu hace de
+// bloqueo del pedido.
+type memPayments struct {
+	mu       sync.Mutex
+	orders   map[string]*models.Order
+	payments []*models.Payment
+	refunds  []models.Refund
+	events   map[string]bool
+}
+
+func newMemPayments(orders ...*models.Order) *memPayments {
+	m := &memPayments{orders: map[string]*models.Order{}, events: map[string]bool{}}
+	for _, o := range orders { m.orders[o.ID] = o }
+	return m
+}
+
+func (m *memPayments) Create(_ context.Context, p *models.Payment) (*models.Payment, error) {
+	m.mu.Lock(); defer m.mu.Unlock()
+	o := m.orders[p.OrderID]
+	if o == nil { return nil, repo.ErrNotFound }
+	if !o.Payable() { return nil, repo.ErrOrderNotPayable }
+	var existing []models.Payment
+	for _, q := range m.payments { if q.OrderID == p.OrderID { existing = append(existing, *q) } }
+	due := models.Due(o.Total, existing)
+	if due <= 0 { return nil, repo.ErrOrderNotPayable }
+	amount := models.Cents(p.Amount)
+	if amount == 0 { amount = due }
+	if amount > due { return nil, repo.ErrAmountExceeds }
+	p.Amount = float64(amount) / 100
+	p.ID = fmt.Sprintf("pay%d", len(m.payments)+1); p.Status = models.PaymentPending
+	p.CreatedAt = time.Now().Add(time.Duration(len(m.payments)) * time.Second)
+	cp := *p
+	m.payments = append(m.payments, &cp)
+	return p, nil
+}
+func (m *memPayments) find(match func(*models.Payment) bool) *models.Payment {
+	m.mu.Lock(); defer m.mu.Unlock()
+	for _, p := range m.payments { if match(p) { return p } }
+	return nil
+}
+func (m *memPayments) update(p *models.Payment, fn func(p *models.Payment) error) (*models.Payment, error) {
+	m.mu.Lock(); defer m.mu.Unlock()
+	if p == nil { return nil, repo.ErrNotFound }
+	if err := fn(p); err != nil { return nil, err }
+	p.Recompute()
+	o := m.orders[p.OrderID]
+	var captured, refunded float64
+	for _, q := range m.payments {
+		if q.OrderID == p.OrderID { captured += q.CapturedAmount; refunded += q.RefundedAmount }
+	}
+	last := m.payments[len(m.payments)-1]
+	o.Status = models.SettleOrder(o.Status, o.Total, captured, refunded, last.Status == models.PaymentFailed)
+	o.RefundedTotal = refunded
+	cp := *p
+	return &cp, nil
+}
+func (m *memPayments) SaveIntent(_ context.Context, in *models.Payment) (*models.Payment, error) {
+	return m.update(m.find(func(p *models.Payment) bool { return p.ID == in.ID }), func(p *models.Payment) error {
+		p.ProviderRef, p.Status, p.FailureReason = in.ProviderRef, in.Status, in.FailureReason
+		return nil
+	})
+}
+func (m *memPayments) Get(_ context.Context, id string) (*models.Payment, error) {
+	p := m.find(func(p *models.Payment) bool { return p.ID == id })
+	if p == nil { return nil, repo.ErrNotFound }
+	m.mu.Lock(); defer m.mu.Unlock()
+	cp := *p
+	return &cp, nil
+}
+func (m *memPayments) ByOrder(_ context.Context, orderID string) ([]models.Payment, error) {
+	m.mu.Lock(); defer m.mu.Unlock()
+	var out []models.Payment
+	for _, p := range m.payments { if p.OrderID == orderID { out = append(out, *p) } }
+	return out, nil
+}
+func (m *memPayments) Refunds(_ context.Context, orderID string) ([]models.Refund, error) { return m.refunds, nil }
+func (m *memPayments) ApplyEvent(_ context.Context, ev payments.Event, _ []byte) (*models.Payment, error) {
+	return m.update(m.find(func(p *models.Payment) bool { return p.ProviderRef == ev.PaymentRef }), func(p *models.Payment) error {
+		if m.events[ev.ID] { return repo.ErrDuplicateEvent }
+		m.events[ev.ID] = true
+		switch ev.Type {
+		case payments.EventAuthorized:
+			if p.Status == models.PaymentPending { p.Status = models.PaymentAuthorized }
+		case payments.EventCaptured:
+			p.CapturedAmount = max(p.CapturedAmount, ev.AmountCaptured)
+		case payments.EventRefunded:
+			p.RefundedAmount = max(p.RefundedAmount, ev.AmountRefunded)
+		case payments.EventFailed:
+			p.Status, p.FailureReason = models.PaymentFailed, ev.FailureReason
+		}
+		return nil
+	})
+}
+func (m *memPayments) Capture(_ context.Context, id string, captured float64) (*models.Payment, error) {
+	return m.update(m.find(func(p *models.Payment) bool { return p.ID == id }), func(p *models.Payment) error {
+		p.CapturedAmount = max(p.CapturedAmount, captured); return nil
+	})
+}
+func (m *memPayments) Refund(_ context.Context, rf *models.Refund, refunded float64) (*models.Payment, error) {
+	return m.update(m.find(func(p *models.Payment) bool { return p.ID == rf.PaymentID }), func(p *models.Payment) error {
+		rf.OrderID = p.OrderID
+		m.refunds = append(m.refunds, *rf)
+		p.RefundedAmount = max(p.RefundedAmount, refunded); return nil
+	})
+}
+
+// ordersFrom expone a Service.Get los pedidos que modifica un repo en
+// memoria; mu, si lo hay, es el bloqueo de ese repo.
+type ordersFrom struct {
+	fakeRepo
+	orders map[string]*models.Order
+	mu     *sync.Mutex
+}
+
+func (o ordersFrom) GetOrder(_ context.Context, id string) (*models.Order, error) {
+	if o.mu != nil { o.mu.Lock(); defer o.mu.Unlock() }
+	if ord, ok := o.orders[id]; ok { cp := *ord; return &cp, nil }
+	return nil, repo.ErrNotFound
+}
+
+const testSecret = "whsec_test"
+
+// payFixture usa el Fake sin URL de webhook: los eventos se entregan a mano
+// con deliver, en el orden que quiera cada test.
+type payFixture struct {
+	svc   PaymentService
+	store *memPayments
+	fake  *payments.Fake
+}
+
+func newPayFixture(total float64) *payFixture {
+	store := newMemPayments(&models.Order{ID: "o1", UserID: "u1", Status: models.OrderPending, Total: total})
+	fake := payments.NewFake(payments.FakeConfig{})
+	svc := NewPaymentService(store, New(ordersFrom{orders: store.orders, mu: &store.mu}, fakeUC{ok: true}, fakePC{}, nil), fake, PaymentConfig{WebhookSecret: testSecret})
+	return &payFixture{svc: svc, store: store, fake: fake}
+}
+
+// deliver envía un webhook firmado como lo haría el proveedor.
+func (f *payFixture) deliver(t *testing.T, ev payments.Event) error {
+	t.Helper()
+	body, _ := json.Marshal(ev)
+	return f.svc.HandleWebhook(context.Background(), body, payments.Sign(testSecret, body, time.Now()))
+}
+
+func (f *payFixture) orderStatus() string { return f.store.orders["o1"].Status }
+
+func TestPaymentWebhookMarksOrderPaid(t *testing.T) {
+	f := newPayFixture(120)
+	ctx := context.Background()
+	p, err := f.svc.Create(ctx, "o1", PaymentRequest{Method: "card"})
+	if err != nil { t.Fatal(err) }
+	if p.Amount != 120 || p.Status != models.PaymentPending || p.ProviderRef == "" || p.ClientSecret == "" { t.Fatalf("payment: %+v", p) }
+	if f.orderStatus() != models.OrderPending { t.Fatalf("order must stay pending until the webhook, got %s", f.orderStatus()) }
+
+	// otro pago mientras el primero está pendiente: ya no queda nada por pagar
+	if _, err := f.svc.Create(ctx, "o1", PaymentRequest{Method: "card"}); !errors.Is(err, ErrOrderNotPayable) { t.Fatalf("second payment: %v", err) }
+
+	ev := payments.Event{ID: "evt_1", Type: payments.EventCaptured, PaymentRef: p.ProviderRef, AmountCaptured: 120}
+	if err := f.deliver(t, ev); err != nil { t.Fatal(err) }
+	if f.orderStatus() != models.OrderPaid { t.Fatalf("order status=%s, want paid", f.orderStatus()) }
+	// el mismo webhook otra vez no falla ni cambia nada
+	if err := f.deliver(t, ev); err != nil { t.Fatalf("duplicate webhook: %v", err) }
+
+	body, _ := json.Marshal(ev)
+	if err := f.svc.HandleWebhook(ctx, body, payments.Sign("wrong", body, time.Now())); !errors.Is(err, payments.ErrInvalidSignature) { t.Fatalf("bad signature: %v", err) }
+	if _, err := f.svc.Create(ctx, "o1", PaymentRequest{Method: "card"}); !errors.Is(err, ErrOrderNotPayable) { t.Fatalf("paying a paid order: %v", err) }
+}
+
+func TestPaymentFailureAndRetry(t *testing.T) {
+	f := newPayFixture(50)
+	ctx := context.Background()
+	p, _ := f.svc.Create(ctx, "o1", PaymentRequest{Method: payments.MethodDeclined})
+	if err := f.deliver(t, payments.Event{ID: "evt_f", Type: payments.EventFailed, PaymentRef: p.ProviderRef, FailureReason: "card_declined"}); err != nil { t.Fatal(err) }
+	if f.orderStatus() != models.OrderPaymentFailed { t.Fatalf("order status=%s, want payment_failed", f.orderStatus()) }
+
+	// el pago fallido no cuenta: se puede reintentar por el total
+	p2, err := f.svc.Create(ctx, "o1", PaymentRequest{Method: "card"})
+	if err != nil || p2.Amount != 50 { t.Fatalf("retry: %v %+v", err, p2) }
+	if err := f.deliver(t, payments.Event{ID: "evt_ok", Type: payments.EventCaptured, PaymentRef: p2.ProviderRef, AmountCaptured: 50}); err != nil { t.Fatal(err) }
+	if f.orderStatus() != models.OrderPaid { t.Fatalf("order status=%s, want paid", f.orderStatus()) }
+}
+
+func TestPartialCapturesAndRefunds(t *testing.T) {
+	f := newPayFixture(100)
+	ctx := context.Background()
+	if _, err := f.svc.Create(ctx, "o1", PaymentRequest{Method: "card", Amount: 150}); !errors.Is(err, ErrAmountExceeds) { t.Fatalf("amount over total: %v", err) }
+	p, err := f.svc.Create(ctx, "o1", PaymentRequest{Method: "card", CaptureMethod: payments.CaptureManual})
+	if err != nil { t.Fatal(err) }
+	if _, err := f.svc.Capture(ctx, "o1", p.ID, 10); !errors.Is(err, ErrPaymentState) { t.Fatalf("capture before authorization: %v", err) }
+	if err := f.deliver(t, payments.Event{ID: "evt_a", Type: payments.EventAuthorized, PaymentRef: p.ProviderRef}); err != nil { t.Fatal(err) }
+
+	p, err = f.svc.Capture(ctx, "o1", p.ID, 40)
+	if err != nil || p.Status != models.PaymentPartiallyCaptured || p.CapturedAmount != 40 { t.Fatalf("partial capture: %v %+v", err, p) }
+	if f.orderStatus() != models.OrderPending { t.Fatalf("partially captured order must stay pending, got %s", f.orderStatus()) }
+	if _, err := f.svc.Capture(ctx, "o1", p.ID, 61); !errors.Is(err, ErrAmountExceeds) { t.Fatalf("over-capture: %v", err) }
+	if _, err := f.svc.Capture(ctx, "other", p.ID, 1); !errors.Is(err, repo.ErrNotFound) { t.Fatalf("payment of another order: %v", err) }
+
+	// el webhook de la primera captura llega tarde: el acumulado no se duplica
+	if err := f.deliver(t, payments.Event{ID: "evt_c1", Type: payments.EventCaptured, PaymentRef: p.ProviderRef, AmountCaptured: 40}); err != nil { t.Fatal(err) }
+	p, err = f.svc.Capture(ctx, "o1", p.ID, 0) // 0 = el resto
+	if err != nil || p.CapturedAmount != 100 || p.Status != models.PaymentCaptured { t.Fatalf("final capture: %v %+v", err, p) }
+	if f.orderStatus() != models.OrderPaid { t.Fatalf("order status=%s, want paid", f.orderStatus()) }
+
+	rf, p, err := f.svc.Refund(ctx, "o1", p.ID, 30, "damaged")
+	if err != nil || rf.Amount != 30 || rf.ProviderRef == "" || p.Status != models.PaymentPartiallyRefunded { t.Fatalf("partial refund: %v %+v %+v", err, rf, p) }
+	if f.orderStatus() != models.OrderPaid { t.Fatalf("partial refund keeps the order paid, got %s", f.orderStatus()) }
+	if _, _, err := f.svc.Refund(ctx, "o1", p.ID, 71, ""); !errors.Is(err, ErrAmountExceeds) { t.Fatalf("over-refund: %v", err) }
+	if _, p, err = f.svc.Refund(ctx, "o1", p.ID, 0, ""); err != nil || p.Status != models.PaymentRefunded { t.Fatalf("full refund: %v %+v", err, p) }
+	if f.orderStatus() != models.OrderRefunded { t.Fatalf("order status=%s, want refunded", f.orderStatus()) }
+
+	_, refunds, _ := f.svc.List(ctx, "o1")
+	if len(refunds) != 2 { t.Fatalf("refunds=%d", len(refunds)) }
+}
+
+func TestConcurrentPaymentsCannotOverpay(t *testing.T) {
+	f := newPayFixture(60)
+	ctx := context.Background()
+	// dos pagos del total a la vez: sólo uno cabe en lo pendiente
+	errs := make(chan error, 2)
+	var wg sync.WaitGroup
+	for range 2 {
+		wg.Add(1)
+		go func() { defer wg.Done(); _, err := f.svc.Create(ctx, "o1", PaymentRequest{Method: "card"}); errs <- err }()
+	}
+	wg.Wait()
+	close(errs)
+	var ok, refused int
+	for err := range errs {
+		switch {
+		case err == nil: ok++
+		case errors.Is(err, ErrOrderNotPayable): refused++
+		default: t.Fatalf("unexpected error: %v", err)
+		}
+	}
+	if ok != 1 || refused != 1 { t.Fatalf("ok=%d refused=%d", ok, refused) }
+	list, _ := f.store.ByOrder(ctx, "o1")
+	if len(list) != 1 || list[0].Amount != 60 { t.Fatalf("payments=%+v", list) }
+}
+
+func TestCaptureNeedsPayableOrder(t *testing.T) {
+	f := newPayFixture(80)
+	ctx := context.Background()
+	p, err := f.svc.Create(ctx, "o1", PaymentRequest{Method: "card", CaptureMethod: payments.CaptureManual})
+	if err != nil { t.Fatal(err) }
+	if err := f.deliver(t, payments.Event{ID: "evt_a", Type: payments.EventAuthorized, PaymentRef: p.ProviderRef}); err != nil { t.Fatal(err) }
+
+	// el pedido se canceló o caducó por otro camino: capturar lo dejaría
+	// cobrado con el stock ya repuesto
+	f.store.orders["o1"].Status = models.OrderCancelled
+	if _, err := f.svc.Capture(ctx, "o1", p.ID, 0); !errors.Is(err, ErrOrderNotPayable) { t.Fatalf("capture on cancelled order: %v", err) }
+	if got, _ := f.store.Get(ctx, p.ID); got.CapturedAmount != 0 { t.Fatalf("captured=%v", got.CapturedAmount) }
+	if _, err := f.svc.Create(ctx, "o1", PaymentRequest{Method: "card"}); !errors.Is(err, ErrOrderNotPayable) { t.Fatalf("payment on cancelled order: %v", err) }
+}
+
+func TestSettleOrder(t *testing.T) {
+	cases := []struct {
+		current            string
+		captured, refunded float64
+		lastFailed         bool
+		want               string
+	}{
+		{models.OrderPending, 0, 0, false, models.OrderPending},
+		{models.OrderPending, 0, 0, true, models.OrderPaymentFailed},
+		{models.OrderPending, 99.99, 0, false, models.OrderPending},
+		{models.OrderPaymentFailed, 100, 0, false, models.OrderPaid},
+		{models.OrderPaid, 100, 0, true, models.OrderPaid}, // un intento fallido posterior no deshace el pago
+		// cuenta lo capturado bruto: un reembolso parcial tras cobrar no deshace el pago
+		{models.OrderPaid, 100, 40, false, models.OrderPaid},
+		{models.OrderPaid, 100, 99.99, false, models.OrderPaid},
+		{models.OrderPending, 100, 40, false, models.OrderPaid},
+		{models.OrderPaid, 100, 100, false, models.OrderRefunded},
+		{models.OrderShipped, 100, 100, false, models.OrderShipped},
+	}
+	for _, tc := range cases {
+		if got := models.SettleOrder(tc.current, 100, tc.captured, tc.refunded, tc.lastFailed); got != tc.want {
+			t.Errorf("SettleOrder(%s, captured=%v, refunded=%v, failed=%v) = %s, want %s", tc.current, tc.captured, tc.refunded, tc.lastFailed, got, tc.want)
+		}
+	}
+}
@@ -0,0 +1,85 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/huntercenter1/backend-test/order-service/internal/models"
+	"github.com/huntercenter1/backend-test/order-service/internal/repo"
+)
+
+var ErrInvalidRule = errors.New("invalid pricing rule")
+
+// PricingRuleService administra las reglas de impuestos y portes que usa el
+// pipeline de precios; los cambios valen para los pedidos siguientes.
+type PricingRuleService interface {
+	CreateTaxRule(ctx context.Context, r *models.TaxRule) (*models.TaxRule, error)
+	ListTaxRules(ctx context.Context) ([]models.TaxRule, error)
+	DeleteTaxRule(ctx context.Context, id string) error
+	CreateShippingRule(ctx context.Context, r *models.ShippingRule) (*models.ShippingRule, error)
+	ListShippingRules(ctx context.Context) ([]models.ShippingRule, error)
+	DeleteShippingRule(ctx context.Context, id string) error
+}
+
+type pricingRuleService struct{ repo repo.PricingRuleRepo }
+
+func NewPricingRuleService(r repo.PricingRuleRepo) PricingRuleService { return &pricingRuleService{repo: r} }
+
+// CreateTaxRule guarda la región en mayúsculas y la clase en minúsculas,
+// como las normalizan los pedidos y product-service.
+func (s *pricingRuleService) CreateTaxRule(ctx context.Context, r *models.TaxRule) (*models.TaxRule, error) {
+	r.Region = strings.ToUpper(strings.TrimSpace(r.Region))
+	r.TaxClass = strings.ToLower(strings.TrimSpace(r.TaxClass))
+	r.Name = strings.TrimSpace(r.Name)
+	switch {
+	case r.Region == "" || len(r.Region) > 16:
+		return nil, fmt.Errorf("%w: region must be 1-16 characters (or *)", ErrInvalidRule)
+	case r.TaxClass == "" || len(r.TaxClass) > 32:
+		return nil, fmt.Errorf("%w: tax_class must be 1-32 characters (or *)", ErrInvalidRule)
+	case r.Name == "":
+		return nil, fmt.Errorf("%w: name is required", ErrInvalidRule)
+	case r.Rate < 0 || r.Rate > 100:
+		return nil, fmt.Errorf("%w: rate must be in [0, 100]", ErrInvalidRule)
+	}
+	r.ID = ""
+	return s.repo.CreateTaxRule(ctx, r)
+}
+
+func (s *pricingRuleService) ListTaxRules(ctx context.Context) ([]models.TaxRule, error) { return s.repo.ListTaxRules(ctx) }
+
+func (s *pricingRuleService) DeleteTaxRule(ctx context.Context, id string) error { return s.repo.DeleteTaxRule(ctx, id) }
+
+func (s *pricingRuleService) CreateShippingRule(ctx context.Context, r *models.ShippingRule) (*models.ShippingRule, error) {
+	r.Region = strings.ToUpper(strings.TrimSpace(r.Region))
+	r.Name = strings.TrimSpace(r.Name)
+	switch {
+	case r.Region == "" || len(r.Region) > 16:
+		return nil, fmt.Errorf("%w: region must be 1-16 characters (or *)", ErrInvalidRule)
+	case r.Name == "":
+		return nil, fmt.Errorf("%w: name is required", ErrInvalidRule)
+	case r.Fee < 0 || r.PerKg < 0 || r.Threshold < 0:
+		return nil, fmt.Errorf("%w: fee, per_kg and threshold must be >= 0", ErrInvalidRule)
+	}
+	switch r.Type {
+	case models.ShippingFlat:
+		r.PerKg, r.Threshold = 0, 0
+	case models.ShippingWeight:
+		if r.PerKg == 0 { return nil, fmt.Errorf("%w: weight rules need per_kg > 0", ErrInvalidRule) }
+		r.Threshold = 0
+	case models.ShippingFreeOver:
+		if r.Threshold == 0 { return nil, fmt.Errorf("%w: free_over rules need threshold > 0", ErrInvalidRule) }
+		r.Fee, r.PerKg = 0, 0
+	default:
+		return nil, fmt.Errorf("%w: type must be flat, weight or free_over", ErrInvalidRule)
+	}
+	r.ID = ""
+	return s.repo.CreateShippingRule(ctx, r)
+}
+
+func (s *pricingRuleService) ListShippingRules(ctx context.Context) ([]models.ShippingRule, error) {
+	return s.repo.ListShippingRules(ctx)
+}
+
+func (s *pricingRuleService) DeleteShippingRule(ctx context.Context, id string) error { return s.repo.DeleteShippingRule(ctx, id) }
@@ -0,0 +1,111 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/huntercenter1/backend-test/order-service/internal/clients"
+	"github.com/huntercenter1/backend-test/order-service/internal/models"
+	"github.com/huntercenter1/backend-test/order-service/internal/pricing"
+	"github.com/huntercenter1/backend-test/order-service/internal/repo"
+)
+
+// memRules guarda las reglas en memoria; TaxRules y ShippingRules filtran
+// por región como el repositorio.
+type memRules struct {
+	tax  []models.TaxRule
+	ship []models.ShippingRule
+}
+
+func (m *memRules) CreateTaxRule(_ context.Context, r *models.TaxRule) (*models.TaxRule, error) {
+	for _, x := range m.tax {
+		if x.Region == r.Region && x.TaxClass == r.TaxClass { return nil, repo.ErrDuplicateRule }
+	}
+	r.ID = r.Region + "/" + r.TaxClass
+	m.tax = append(m.tax, *r)
+	return r, nil
+}
+func (m *memRules) ListTaxRules(context.Context) ([]models.TaxRule, error) { return m.tax, nil }
+func (m *memRules) DeleteTaxRule(_ context.Context, id string) error {
+	for i, x := range m.tax {
+		if x.ID == id { m.tax = append(m.tax[:i], m.tax[i+1:]...); return nil }
+	}
+	return repo.ErrNotFound
+}
+func (m *memRules) TaxRules(_ context.Context, region string) ([]models.TaxRule, error) {
+	var out []models.TaxRule
+	for _, x := range m.tax {
+		if x.Region == region || x.Region == models.AnyRegion { out = append(out, x) }
+	}
+	return out, nil
+}
+func (m *memRules) CreateShippingRule(_ context.Context, r *models.ShippingRule) (*models.ShippingRule, error) {
+	m.ship = append(m.ship, *r)
+	return r, nil
+}
+func (m *memRules) ListShippingRules(context.Context) ([]models.ShippingRule, error) { return m.ship, nil }
+func (m *memRules) DeleteShippingRule(context.Context, string) error { return repo.ErrNotFound }
+func (m *memRules) ShippingRules(_ context.Context, region string) ([]models.ShippingRule, error) {
+	var out []models.ShippingRule
+	for _, x := range m.ship {
+		if x.Region == region || x.Region == models.AnyRegion { out = append(out, x) }
+	}
+	return out, nil
+}
+
+func TestPricingRuleValidation(t *testing.T) {
+	ctx := context.Background()
+	s := NewPricingRuleService(&memRules{})
+	for _, r := range []models.TaxRule{
+		{TaxClass: "standard", Name: "x", Rate: 21},
+		{Region: "ES", Name: "x", Rate: 21},
+		{Region: "ES", TaxClass: "standard", Rate: 21},
+		{Region: "ES", TaxClass: "standard", Name: "x", Rate: 101},
+	} {
+		if _, err := s.CreateTaxRule(ctx, &r); !errors.Is(err, ErrInvalidRule) { t.Errorf("%+v: want ErrInvalidRule got %v", r, err) }
+	}
+	r, err := s.CreateTaxRule(ctx, &models.TaxRule{Region: " es ", TaxClass: "Reduced", Name: "IVA reducido", Rate: 10})
+	if err != nil { t.Fatal(err) }
+	if r.Region != "ES" || r.TaxClass != "reduced" { t.Fatalf("not normalized: %+v", r) }
+	if _, err := s.CreateTaxRule(ctx, &models.TaxRule{Region: "ES", TaxClass: "reduced", Name: "again", Rate: 4}); !errors.Is(err, repo.ErrDuplicateRule) { t.Fatalf("duplicate: %v", err) }
+
+	for _, r := range []models.ShippingRule{
+		{Region: "ES", Name: "x", Type: "express"},
+		{Region: "ES", Name: "x", Type: models.ShippingWeight, Fee: 3},
+		{Region: "ES", Name: "x", Type: models.ShippingFreeOver},
+		{Region: "ES", Name: "x", Type: models.ShippingFlat, Fee: -1},
+		{Name: "x", Type: models.ShippingFlat, Fee: 5},
+	} {
+		if _, err := s.CreateShippingRule(ctx, &r); !errors.Is(err, ErrInvalidRule) { t.Errorf("%+v: want ErrInvalidRule got %v", r, err) }
+	}
+	sr, err := s.CreateShippingRule(ctx, &models.ShippingRule{Region: "*", Name: "Gratis", Type: models.ShippingFreeOver, Fee: 5, Threshold: 50})
+	if err != nil { t.Fatal(err) }
+	if sr.Fee != 0 { t.Fatalf("free_over keeps fee: %+v", sr) }
+}
+
+func TestCreateStoresTaxAndShippingPerLine(t *testing.T) {
+	rules := &memRules{
+		tax:  []models.TaxRule{{Region: "ES", TaxClass: "*", Rate: 21}, {Region: "ES", TaxClass: "reduced", Rate: 4}},
+		ship: []models.ShippingRule{{Region: "*", Type: models.ShippingWeight, Fee: 3, PerKg: 1}},
+	}
+	pc := &catalogPC{products: map[string]clients.Product{
+		"book": {ID: "book", Price: 10, Stock: 5, TaxClass: "reduced", WeightGrams: 400},
+		"lamp": {ID: "lamp", Price: 30, Stock: 5, WeightGrams: 1200}, // sin tax_class: standard
+	}}
+	s := New(orderRepo{}, fakeUC{ok: true}, pc, pricing.Pipeline{pricing.Taxes(rules), pricing.Shipping(rules)})
+
+	o, items, err := s.Create(context.Background(), CreateRequest{UserID: "u1", Region: "es", Items: []CreateItem{{ProductID: "book", Quantity: 2}, {ProductID: "lamp", Quantity: 1}}})
+	if err != nil { t.Fatal(err) }
+	// 2 kg empezados: 3 + 2*1 = 5 de portes, repartidos por peso (800 g / 1200 g)
+	if o.Region != "ES" || o.Subtotal != 50 || o.TaxTotal != 7.1 || o.ShippingTotal != 5 || o.Total != 62.1 { t.Fatalf("order=%+v", o) }
+	book, lamp := items[0], items[1]
+	if book.TaxClass != "reduced" || book.TaxRate != 4 || book.Tax != 0.8 || book.Shipping != 2 { t.Fatalf("book=%+v", book) }
+	if lamp.TaxClass != models.DefaultTaxClass || lamp.TaxRate != 21 || lamp.Tax != 6.3 || lamp.Shipping != 3 { t.Fatalf("lamp=%+v", lamp) }
+	if book.PaidUnitPrice() != 10.4 { t.Fatalf("paid unit price=%v", book.PaidUnitPrice()) }
+
+	// fuera de ES no hay impuestos, los portes son los de *
+	o, _, err = s.Create(context.Background(), CreateRequest{UserID: "u1", Region: "PT", Items: []CreateItem{{ProductID: "book", Quantity: 1}}})
+	if err != nil { t.Fatal(err) }
+	if o.TaxTotal != 0 || o.ShippingTotal != 4 || o.Total != 14 { t.Fatalf("order=%+v", o) }
+}
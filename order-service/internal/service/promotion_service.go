@@ -0,0 +1,116 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strings"
+	"time"
+
+	"github.com/huntercenter1/backend-test/order-service/internal/clients"
+	"github.com/huntercenter1/backend-test/order-service/internal/models"
+	"github.com/huntercenter1/backend-test/order-service/internal/pricing"
+	"github.com/huntercenter1/backend-test/order-service/internal/repo"
+)
+
+var ErrInvalidPromotion = errors.New("invalid promotion")
+
+type PromotionService interface {
+	Create(ctx context.Context, p *models.Promotion) (*models.Promotion, error)
+	List(ctx context.Context) ([]models.Promotion, error)
+	SetActive(ctx context.Context, id string, active bool) (*models.Promotion, error)
+}
+
+type promotionService struct{ repo repo.PromotionRepo }
+
+func NewPromotionService(r repo.PromotionRepo) PromotionService { return &promotionService{repo: r} }
+
+// Create valida los campos que necesita cada tipo; el código se guarda en
+// mayúsculas y sin espacios.
+func (s *promotionService) Create(ctx context.Context, p *models.Promotion) (*models.Promotion, error) {
+	p.Code = strings.ToUpper(strings.TrimSpace(p.Code))
+	p.Name = strings.TrimSpace(p.Name)
+	if err := validatePromotion(p); err != nil { return nil, fmt.Errorf("%w: %v", ErrInvalidPromotion, err) }
+	p.ID, p.Uses, p.Active = "", 0, true
+	return s.repo.Create(ctx, p)
+}
+
+func (s *promotionService) List(ctx context.Context) ([]models.Promotion, error) { return s.repo.List(ctx) }
+
+func (s *promotionService) SetActive(ctx context.Context, id string, active bool) (*models.Promotion, error) {
+	return s.repo.SetActive(ctx, id, active)
+}
+
+func validatePromotion(p *models.Promotion) error {
+	if p.Name == "" { return errors.New("name is required") }
+	switch p.Type {
+	case models.PromoPercentage:
+		if p.Value <= 0 || p.Value > 100 { return errors.New("percentage value must be in (0, 100]") }
+	case models.PromoFixed:
+		if p.Value <= 0 { return errors.New("fixed value must be > 0") }
+	case models.PromoBuyXGetY:
+		if p.ProductID == "" || p.BuyQuantity < 1 || p.GetQuantity < 1 { return errors.New("buy_x_get_y needs product_id, buy_quantity >= 1 and get_quantity >= 1") }
+	case models.PromoFreeItem:
+		if p.FreeProductID == "" { return errors.New("free_item needs free_product_id") }
+		if p.FreeQuantity == 0 { p.FreeQuantity = 1 }
+		if p.FreeQuantity < 0 { return errors.New("free_quantity must be >= 1") }
+	default:
+		return errors.New("type must be percentage, fixed, buy_x_get_y or free_item")
+	}
+	if p.MinSpend < 0 { return errors.New("min_spend must be >= 0") }
+	if p.StartsAt != nil && p.EndsAt != nil && !p.EndsAt.After(*p.StartsAt) { return errors.New("ends_at must be after starts_at") }
+	if (p.MaxUses != nil && *p.MaxUses < 1) || (p.MaxUsesPerUser != nil && *p.MaxUsesPerUser < 1) { return errors.New("usage limits must be >= 1") }
+	if len(p.Code) > 50 { return errors.New("code must be at most 50 characters") }
+	return nil
+}
+
+// promotionStage es el paso de promociones del pipeline: las automáticas y
+// el cupón del pedido.
+type promotionStage struct {
+	promos repo.PromotionRepo
+	pc     clients.ProductClient
+}
+
+// NewPromotionStage aplica las promociones de promos; pc da precio, clase
+// fiscal y peso de los regalos.
+func NewPromotionStage(promos repo.PromotionRepo, pc clients.ProductClient) pricing.Stage {
+	return &promotionStage{promos: promos, pc: pc}
+}
+
+// Apply aplica a q las promociones automáticas y el cupón. Un cupón que no
+// se puede usar rechaza el pedido (ErrCoupon con el motivo); una automática
+// que no aplica simplemente no descuenta.
+func (st *promotionStage) Apply(ctx context.Context, q *pricing.Quote) error {
+	now := time.Now()
+	promos, err := st.promos.Automatic(ctx, q.UserID, now)
+	if err != nil { return err }
+	if code := q.CouponCode; code != "" {
+		c, err := st.promos.ByCode(ctx, code)
+		if errors.Is(err, repo.ErrNotFound) { return fmt.Errorf("%w: unknown code %s", ErrCoupon, code) }
+		if err != nil { return err }
+		if err := pricing.Eligible(c, q.Subtotal, now); err != nil { return fmt.Errorf("%w: %v", ErrCoupon, err) }
+		if c.MaxUses != nil && c.Uses >= *c.MaxUses { return fmt.Errorf("%w: %v", ErrCoupon, repo.ErrPromotionExhausted) }
+		if c.MaxUsesPerUser != nil {
+			n, err := st.promos.Redemptions(ctx, c.ID, q.UserID)
+			if err != nil { return err }
+			if n >= *c.MaxUsesPerUser { return fmt.Errorf("%w: already used the maximum number of times", ErrCoupon) }
+		}
+		promos = append(promos, *c)
+		q.CouponApplied = true
+	}
+
+	// regalos: si el producto no existe o no hay stock, no se regala
+	gifts := map[string]pricing.Line{}
+	for _, p := range promos {
+		if p.Type != models.PromoFreeItem || pricing.Eligible(&p, q.Subtotal, now) != nil { continue }
+		prod, err := st.pc.Get(ctx, p.FreeProductID)
+		if err != nil || prod.Stock < max(p.FreeQuantity, 1) {
+			slog.WarnContext(ctx, "promotion gift unavailable", "promotion_id", p.ID, "product_id", p.FreeProductID, "error", err)
+			continue
+		}
+		gifts[p.FreeProductID] = productLine(p.FreeProductID, prod, 0)
+	}
+	pricing.ApplyPromotions(q, promos, gifts, now)
+	return nil
+}
@@ -0,0 +1,133 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/huntercenter1/backend-test/order-service/internal/clients"
+	"github.com/huntercenter1/backend-test/order-service/internal/models"
+	"github.com/huntercenter1/backend-test/order-service/internal/pricing"
+	"github.com/huntercenter1/backend-test/order-service/internal/repo"
+)
+
+// memPromotions guarda las promociones y los canjes por usuario en memoria.
+type memPromotions struct {
+	list  []models.Promotion
+	usage map[string]int // promotionID/userID → canjes
+}
+
+func (m *memPromotions) Create(_ context.Context, p *models.Promotion) (*models.Promotion, error) {
+	for _, x := range m.list {
+		if p.Code != "" && x.Code == p.Code { return nil, repo.ErrDuplicateCode }
+	}
+	p.ID = fmt.Sprintf("pr%d", len(m.list)+1)
+	m.list = append(m.list, *p)
+	return p, nil
+}
+func (m *memPromotions) List(context.Context) ([]models.Promotion, error) { return m.list, nil }
+func (m *memPromotions) SetActive(_ context.Context, id string, active bool) (*models.Promotion, error) {
+	for i := range m.list {
+		if m.list[i].ID == id { m.list[i].Active = active; return &m.list[i], nil }
+	}
+	return nil, repo.ErrNotFound
+}
+func (m *memPromotions) Automatic(_ context.Context, userID string, now time.Time) ([]models.Promotion, error) {
+	var out []models.Promotion
+	for _, p := range m.list {
+		if p.Code == "" && p.Active { out = append(out, p) }
+	}
+	return out, nil
+}
+func (m *memPromotions) ByCode(_ context.Context, code string) (*models.Promotion, error) {
+	for _, p := range m.list {
+		if p.Code == code { return &p, nil }
+	}
+	return nil, repo.ErrNotFound
+}
+func (m *memPromotions) Redemptions(_ context.Context, promotionID, userID string) (int, error) {
+	return m.usage[promotionID+"/"+userID], nil
+}
+
+func TestPromotionValidation(t *testing.T) {
+	start := time.Now()
+	end := start.Add(-time.Hour)
+	zero := 0
+	invalid := []models.Promotion{
+		{Type: models.PromoFixed, Value: 5},
+		{Name: "x", Type: "bogus"},
+		{Name: "x", Type: models.PromoPercentage, Value: 120},
+		{Name: "x", Type: models.PromoFixed},
+		{Name: "x", Type: models.PromoBuyXGetY, ProductID: "p1", BuyQuantity: 2},
+		{Name: "x", Type: models.PromoFreeItem},
+		{Name: "x", Type: models.PromoFixed, Value: 5, StartsAt: &start, EndsAt: &end},
+		{Name: "x", Type: models.PromoFixed, Value: 5, MaxUses: &zero},
+	}
+	s := NewPromotionService(&memPromotions{})
+	for _, p := range invalid {
+		if _, err := s.Create(context.Background(), &p); !errors.Is(err, ErrInvalidPromotion) { t.Errorf("%+v: want ErrInvalidPromotion got %v", p, err) }
+	}
+
+	p, err := s.Create(context.Background(), &models.Promotion{Code: " summer10 ", Name: "Summer", Type: models.PromoPercentage, Value: 10})
+	if err != nil { t.Fatal(err) }
+	if p.Code != "SUMMER10" || !p.Active { t.Fatalf("got %+v", p) }
+	if _, err := s.Create(context.Background(), &models.Promotion{Code: "Summer10", Name: "Again", Type: models.PromoFixed, Value: 1}); !errors.Is(err, repo.ErrDuplicateCode) { t.Fatalf("duplicate: %v", err) }
+	if p, err = s.SetActive(context.Background(), p.ID, false); err != nil || p.Active { t.Fatalf("deactivate: %+v %v", p, err) }
+}
+
+func newPromoSvc(promos *memPromotions) Service {
+	pc := &catalogPC{products: map[string]clients.Product{
+		"p1":   {ID: "p1", Price: 20, Stock: 10},
+		"gift": {ID: "gift", Price: 5, Stock: 1},
+	}}
+	return New(orderRepo{}, fakeUC{ok: true}, pc, pricing.Pipeline{NewPromotionStage(promos, pc)})
+}
+
+func TestCreateAppliesCouponAndAutomaticPromotions(t *testing.T) {
+	one := 1
+	promos := &memPromotions{list: []models.Promotion{
+		{ID: "auto", Name: "Gift over 50", Type: models.PromoFreeItem, FreeProductID: "gift", FreeQuantity: 1, MinSpend: 50, Active: true},
+		{ID: "c1", Code: "TENOFF", Name: "10 off", Type: models.PromoFixed, Value: 10, MaxUsesPerUser: &one, Active: true},
+	}, usage: map[string]int{}}
+	s := newPromoSvc(promos)
+
+	o, items, err := s.Create(context.Background(), CreateRequest{UserID: "u1", Items: []CreateItem{{ProductID: "p1", Quantity: 3}}, CouponCode: " tenoff"})
+	if err != nil { t.Fatal(err) }
+	if o.CouponCode != "TENOFF" || o.Subtotal != 65 || o.DiscountTotal != 15 || o.Total != 50 { t.Fatalf("order=%+v", o) }
+	if len(o.Discounts) != 2 || o.Discounts[1].Code != "TENOFF" { t.Fatalf("breakdown=%+v", o.Discounts) }
+	if len(items) != 2 || !items[1].Gift || items[1].NetUnitPrice() != 0 { t.Fatalf("items=%+v", items) }
+
+	// sin llegar al mínimo no hay regalo, pero el cupón sigue valiendo
+	o, items, err = s.Create(context.Background(), CreateRequest{UserID: "u2", Items: []CreateItem{{ProductID: "p1", Quantity: 1}}, CouponCode: "TENOFF"})
+	if err != nil { t.Fatal(err) }
+	if len(items) != 1 || o.Total != 10 { t.Fatalf("order=%+v items=%+v", o, items) }
+}
+
+func TestCreateRejectsUnusableCoupon(t *testing.T) {
+	one, past := 1, time.Now().Add(-time.Hour)
+	promos := &memPromotions{list: []models.Promotion{
+		{ID: "c1", Code: "ONCE", Name: "Once", Type: models.PromoFixed, Value: 5, MaxUsesPerUser: &one, Active: true},
+		{ID: "c2", Code: "OLD", Name: "Old", Type: models.PromoFixed, Value: 5, EndsAt: &past, Active: true},
+		{ID: "c3", Code: "BIG", Name: "Big", Type: models.PromoFixed, Value: 5, MinSpend: 100, Active: true},
+		{ID: "c4", Code: "GONE", Name: "Gone", Type: models.PromoFixed, Value: 5, MaxUses: &one, Uses: 1, Active: true},
+	}, usage: map[string]int{"c1/u1": 1}}
+	s := newPromoSvc(promos)
+
+	for code, reason := range map[string]string{
+		"ONCE":    "maximum number of times",
+		"OLD":     "expired",
+		"BIG":     "minimum spend",
+		"GONE":    "usage limit",
+		"UNKNOWN": "unknown code",
+	} {
+		_, _, err := s.Create(context.Background(), CreateRequest{UserID: "u1", Items: []CreateItem{{ProductID: "p1", Quantity: 1}}, CouponCode: code})
+		if !errors.Is(err, ErrCoupon) || !strings.Contains(err.Error(), reason) { t.Errorf("%s: got %v, want %q", code, err, reason) }
+	}
+
+	// sin paso de promociones no se aceptan cupones
+	s = New(orderRepo{}, fakeUC{ok: true}, fakePC{price: 20, stock: 10}, nil)
+	if _, _, err := s.Create(context.Background(), CreateRequest{UserID: "u1", Items: []CreateItem{{ProductID: "p1", Quantity: 1}}, CouponCode: "ONCE"}); !errors.Is(err, ErrCoupon) { t.Fatalf("got %v", err) }
+}
@@ -0,0 +1,209 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/huntercenter1/backend-test/order-service/internal/models"
+	"github.com/huntercenter1/backend-test/order-service/internal/repo"
+)
+
+var ErrInvalidReport = errors.New("invalid report parameters")
+
+// Intervalos del informe de ventas; la semana empieza en lunes, como en
+// date_trunc.
+const (
+	ReportDay   = "day"
+	ReportWeek  = "week"
+	ReportMonth = "month"
+)
+
+const (
+	defaultReportDays = 30   // sin from, los últimos 30 días hasta to
+	maxReportPeriods  = 1000 // filas como mucho del informe de ventas
+	defaultReportRows = 10
+	maxReportRows     = 1000
+)
+
+// ReportParams son los filtros de los informes tal como llegan en la query.
+// from y to son días (YYYY-MM-DD) en la zona tz, ambos incluidos.
+type ReportParams struct {
+	From     string `form:"from"`
+	To       string `form:"to"`
+	TZ       string `form:"tz"`       // IANA; por defecto el de ReportConfig
+	Interval string `form:"interval"` // sales: day (por defecto), week o month
+	By       string `form:"by"`       // products: quantity (por defecto) o revenue
+	UserID   string `form:"user_id"`  // customers: sólo ese usuario
+	Limit    int    `form:"limit"`    // products y customers
+}
+
+type ReportConfig struct {
+	Timezone string // zona por defecto de los informes
+}
+
+// ReportWindow es el rango aplicado, para devolverlo con el informe.
+type ReportWindow struct {
+	From string `json:"from,omitempty"`
+	To   string `json:"to,omitempty"`
+	TZ   string `json:"tz"`
+}
+
+type SalesReport struct {
+	ReportWindow
+	Interval string               `json:"interval"`
+	Periods  []models.SalesPeriod `json:"periods"` // todos los del rango, también los que no tienen ventas
+	Totals   models.Sales         `json:"totals"`
+}
+
+type ProductsReport struct {
+	ReportWindow
+	By       string                `json:"by"`
+	Products []models.ProductSales `json:"products"`
+}
+
+type CustomersReport struct {
+	ReportWindow
+	Customers []models.CustomerValue `json:"customers"`
+}
+
+// ReportService da los informes de ventas sobre los pedidos vendidos (ver
+// models.SoldStatuses), por fecha de creación del pedido.
+type ReportService interface {
+	Sales(ctx context.Context, p ReportParams) (*SalesReport, error)
+	TopProducts(ctx context.Context, p ReportParams) (*ProductsReport, error)
+	// Customers da el lifetime value por usuario: sin from ni to, de siempre.
+	Customers(ctx context.Context, p ReportParams) (*CustomersReport, error)
+}
+
+type reportService struct {
+	repo repo.ReportRepo
+	cfg  ReportConfig
+	now  func() time.Time
+}
+
+func NewReportService(r repo.ReportRepo, cfg ReportConfig) ReportService {
+	if cfg.Timezone == "" { cfg.Timezone = "UTC" }
+	return &reportService{repo: r, cfg: cfg, now: time.Now}
+}
+
+func invalidReport(format string, args ...any) error {
+	return fmt.Errorf("%w: %s", ErrInvalidReport, fmt.Sprintf(format, args...))
+}
+
+// window convierte from/to en un rango [From, To) en la zona pedida. Con
+// days == 0 un extremo vacío no acota; si no, to es hoy por defecto y from,
+// days días antes.
+func (s *reportService) window(p ReportParams, days int) (repo.ReportRange, ReportWindow, *time.Location, error) {
+	tz := p.TZ
+	if tz == "" { tz = s.cfg.Timezone }
+	loc, err := time.LoadLocation(tz)
+	if err != nil || tz == "Local" { return repo.ReportRange{}, ReportWindow{}, nil, invalidReport("unknown time zone %q", tz) }
+
+	day := func(name, v string) (time.Time, error) {
+		d, err := time.ParseInLocation(time.DateOnly, v, loc)
+		if err != nil { return time.Time{}, invalidReport("%s must be a date (YYYY-MM-DD)", name) }
+		return d, nil
+	}
+	var from, to time.Time
+	if p.To != "" {
+		if to, err = day("to", p.To); err != nil { return repo.ReportRange{}, ReportWindow{}, nil, err }
+	} else if days > 0 {
+		now := s.now().In(loc)
+		to = time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, loc)
+	}
+	if p.From != "" {
+		if from, err = day("from", p.From); err != nil { return repo.ReportRange{}, ReportWindow{}, nil, err }
+	} else if days > 0 {
+		from = to.AddDate(0, 0, 1-days)
+	}
+	if !from.IsZero() && !to.IsZero() && from.After(to) { return repo.ReportRange{}, ReportWindow{}, nil, invalidReport("from must not be after to") }
+
+	rg, w := repo.ReportRange{From: from, TZ: tz}, ReportWindow{TZ: tz}
+	if !from.IsZero() { w.From = from.Format(time.DateOnly) }
+	if !to.IsZero() { rg.To, w.To = to.AddDate(0, 0, 1), to.Format(time.DateOnly) }
+	return rg, w, loc, nil
+}
+
+func reportLimit(n int) (int, error) {
+	if n == 0 { return defaultReportRows, nil }
+	if n < 0 || n > maxReportRows { return 0, invalidReport("limit must be between 1 and %d", maxReportRows) }
+	return n, nil
+}
+
+// periodStart da el inicio del periodo que contiene t, en la zona de t.
+func periodStart(t time.Time, interval string) time.Time {
+	switch interval {
+	case ReportWeek:
+		t = t.AddDate(0, 0, -(int(t.Weekday())+6)%7)
+	case ReportMonth:
+		t = t.AddDate(0, 0, 1-t.Day())
+	}
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, t.Location())
+}
+
+func nextPeriod(t time.Time, interval string) time.Time {
+	switch interval {
+	case ReportWeek:
+		return t.AddDate(0, 0, 7)
+	case ReportMonth:
+		return t.AddDate(0, 1, 0)
+	}
+	return t.AddDate(0, 0, 1)
+}
+
+func (s *reportService) Sales(ctx context.Context, p ReportParams) (*SalesReport, error) {
+	if p.Interval == "" { p.Interval = ReportDay }
+	if p.Interval != ReportDay && p.Interval != ReportWeek && p.Interval != ReportMonth {
+		return nil, invalidReport("interval must be day, week or month")
+	}
+	rg, w, loc, err := s.window(p, defaultReportDays)
+	if err != nil { return nil, err }
+
+	// los periodos sin ventas también salen, a cero
+	var periods []time.Time
+	for t := periodStart(rg.From.In(loc), p.Interval); t.Before(rg.To); t = nextPeriod(t, p.Interval) {
+		if len(periods) == maxReportPeriods { return nil, invalidReport("more than %d periods, use a shorter range or a longer interval", maxReportPeriods) }
+		periods = append(periods, t)
+	}
+	rows, err := s.repo.Sales(ctx, rg, p.Interval)
+	if err != nil { return nil, err }
+	byPeriod := make(map[string]models.Sales, len(rows))
+	for _, r := range rows { byPeriod[r.Period] = r.Sales }
+
+	out := &SalesReport{ReportWindow: w, Interval: p.Interval, Periods: make([]models.SalesPeriod, 0, len(periods))}
+	var orders int
+	var gross, refunded int64
+	for _, t := range periods {
+		key := t.Format(time.DateOnly)
+		sales, ok := byPeriod[key]
+		if !ok { sales = models.NewSales(0, 0, 0) }
+		out.Periods = append(out.Periods, models.SalesPeriod{Period: key, Sales: sales})
+		orders, gross, refunded = orders+sales.Orders, gross+models.Cents(sales.Gross), refunded+models.Cents(sales.Refunded)
+	}
+	out.Totals = models.NewSales(orders, float64(gross)/100, float64(refunded)/100)
+	return out, nil
+}
+
+func (s *reportService) TopProducts(ctx context.Context, p ReportParams) (*ProductsReport, error) {
+	if p.By == "" { p.By = "quantity" }
+	if p.By != "quantity" && p.By != "revenue" { return nil, invalidReport("by must be quantity or revenue") }
+	limit, err := reportLimit(p.Limit)
+	if err != nil { return nil, err }
+	rg, w, _, err := s.window(p, defaultReportDays)
+	if err != nil { return nil, err }
+	list, err := s.repo.TopProducts(ctx, rg, p.By, limit)
+	if err != nil { return nil, err }
+	return &ProductsReport{ReportWindow: w, By: p.By, Products: list}, nil
+}
+
+func (s *reportService) Customers(ctx context.Context, p ReportParams) (*CustomersReport, error) {
+	limit, err := reportLimit(p.Limit)
+	if err != nil { return nil, err }
+	rg, w, _, err := s.window(p, 0)
+	if err != nil { return nil, err }
+	list, err := s.repo.Customers(ctx, rg, p.UserID, limit)
+	if err != nil { return nil, err }
+	return &CustomersReport{ReportWindow: w, Customers: list}, nil
+}
@@ -0,0 +1,101 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/huntercenter1/backend-test/order-service/internal/models"
+	"github.com/huntercenter1/backend-test/order-service/internal/repo"
+)
+
+// memReports devuelve filas fijas y guarda el último rango y parámetros
+// que le llegan.
+type memReports struct {
+	sales    []models.SalesPeriod
+	rg       repo.ReportRange
+	interval string
+	by       string
+	userID   string
+	limit    int
+}
+
+func (m *memReports) Sales(_ context.Context, rg repo.ReportRange, interval string) ([]models.SalesPeriod, error) {
+	m.rg, m.interval = rg, interval
+	return m.sales, nil
+}
+func (m *memReports) TopProducts(_ context.Context, rg repo.ReportRange, by string, limit int) ([]models.ProductSales, error) {
+	m.rg, m.by, m.limit = rg, by, limit
+	return []models.ProductSales{{ProductID: "p1", Quantity: 3, Revenue: 30}}, nil
+}
+func (m *memReports) Customers(_ context.Context, rg repo.ReportRange, userID string, limit int) ([]models.CustomerValue, error) {
+	m.rg, m.userID, m.limit = rg, userID, limit
+	return []models.CustomerValue{{UserID: "u1", Sales: models.NewSales(2, 30, 5)}}, nil
+}
+
+func newReportSvc(m *memReports) *reportService {
+	s := NewReportService(m, ReportConfig{Timezone: "Europe/Madrid"}).(*reportService)
+	// 23:30 UTC del 10 de agosto ya es día 11 en Madrid
+	s.now = func() time.Time { return time.Date(2025, 8, 10, 23, 30, 0, 0, time.UTC) }
+	return s
+}
+
+func TestSalesReportFillsPeriodsAndTotals(t *testing.T) {
+	m := &memReports{sales: []models.SalesPeriod{
+		{Period: "2025-08-04", Sales: models.NewSales(2, 30, 0)},
+		{Period: "2025-08-18", Sales: models.NewSales(1, 10.5, 10.5)},
+	}}
+	rep, err := newReportSvc(m).Sales(context.Background(), ReportParams{From: "2025-08-06", To: "2025-08-20", Interval: ReportWeek})
+	if err != nil { t.Fatal(err) }
+
+	madrid, _ := time.LoadLocation("Europe/Madrid")
+	if !m.rg.From.Equal(time.Date(2025, 8, 6, 0, 0, 0, 0, madrid)) || !m.rg.To.Equal(time.Date(2025, 8, 21, 0, 0, 0, 0, madrid)) || m.rg.TZ != "Europe/Madrid" {
+		t.Fatalf("range=%+v", m.rg)
+	}
+	// semanas desde el lunes de from, con las vacías a cero
+	var got []string
+	for _, p := range rep.Periods { got = append(got, p.Period) }
+	if len(got) != 3 || got[0] != "2025-08-04" || got[1] != "2025-08-11" || got[2] != "2025-08-18" || rep.Periods[1].Orders != 0 { t.Fatalf("periods=%v", got) }
+	if rep.Totals.Orders != 3 || rep.Totals.Gross != 40.5 || rep.Totals.Net != 30 || rep.Totals.AverageOrderValue != 10 { t.Fatalf("totals=%+v", rep.Totals) }
+}
+
+func TestSalesReportDefaultsToLast30DaysInTZ(t *testing.T) {
+	m := &memReports{}
+	rep, err := newReportSvc(m).Sales(context.Background(), ReportParams{})
+	if err != nil { t.Fatal(err) }
+	if rep.From != "2025-07-13" || rep.To != "2025-08-11" || rep.Interval != ReportDay || len(rep.Periods) != 30 { t.Fatalf("from=%s to=%s periods=%d", rep.From, rep.To, len(rep.Periods)) }
+
+	rep, err = newReportSvc(m).Sales(context.Background(), ReportParams{TZ: "UTC", Interval: ReportMonth})
+	if err != nil { t.Fatal(err) }
+	if rep.To != "2025-08-10" || len(rep.Periods) != 2 || rep.Periods[0].Period != "2025-07-01" { t.Fatalf("utc: to=%s periods=%+v", rep.To, rep.Periods) }
+}
+
+func TestReportValidation(t *testing.T) {
+	ctx := context.Background()
+	s := newReportSvc(&memReports{})
+	for name, p := range map[string]ReportParams{
+		"interval": {Interval: "year"},
+		"tz":       {TZ: "Mars/Olympus"},
+		"date":     {From: "10/08/2025"},
+		"reversed": {From: "2025-08-10", To: "2025-08-01"},
+		"periods":  {From: "2000-01-01", To: "2025-01-01"},
+	} {
+		if _, err := s.Sales(ctx, p); !errors.Is(err, ErrInvalidReport) { t.Errorf("%s: %v", name, err) }
+	}
+	if _, err := s.TopProducts(ctx, ReportParams{By: "margin"}); !errors.Is(err, ErrInvalidReport) { t.Errorf("by: %v", err) }
+	if _, err := s.Customers(ctx, ReportParams{Limit: 5000}); !errors.Is(err, ErrInvalidReport) { t.Errorf("limit: %v", err) }
+}
+
+func TestTopProductsAndCustomers(t *testing.T) {
+	ctx := context.Background()
+	m := &memReports{}
+	s := newReportSvc(m)
+	rep, err := s.TopProducts(ctx, ReportParams{By: "revenue", Limit: 5})
+	if err != nil || rep.By != "revenue" || m.by != "revenue" || m.limit != 5 || len(rep.Products) != 1 { t.Fatalf("products=%+v err=%v", rep, err) }
+
+	// el lifetime value no se acota por fechas salvo que se pidan
+	cr, err := s.Customers(ctx, ReportParams{UserID: "u1"})
+	if err != nil || !m.rg.From.IsZero() || !m.rg.To.IsZero() || m.userID != "u1" || m.limit != defaultReportRows { t.Fatalf("range=%+v err=%v", m.rg, err) }
+	if cr.Customers[0].Net != 25 || cr.Customers[0].AverageOrderValue != 12.5 || cr.From != "" { t.Fatalf("customers=%+v", cr) }
+}
@@ -0,0 +1,156 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+
+	"github.com/huntercenter1/backend-test/order-service/internal/clients"
+	"github.com/huntercenter1/backend-test/order-service/internal/models"
+	"github.com/huntercenter1/backend-test/order-service/internal/repo"
+)
+
+var ErrInvalidReturn = errors.New("items are required, quantities must be > 0 and reason must be damaged, defective, wrong_item, not_as_described, no_longer_needed or other")
+
+type ReturnRequest struct {
+	Reason string       `json:"reason"` // motivo por defecto de las líneas
+	Note   string       `json:"note"`
+	Items  []ReturnLine `json:"items"`
+}
+
+type ReturnLine struct {
+	OrderItemID string `json:"order_item_id"`
+	Quantity    int    `json:"quantity"`
+	Reason      string `json:"reason"`
+}
+
+// OrderReturns son las devoluciones de un pedido con su valor neto.
+type OrderReturns struct {
+	Returns       []models.Return `json:"returns"`
+	Total         float64         `json:"total"`
+	RefundedTotal float64         `json:"refunded_total"`
+	NetTotal      float64         `json:"net_total"`
+}
+
+type ReturnService interface {
+	Create(ctx context.Context, orderID string, req ReturnRequest) (*models.Return, error)
+	List(ctx context.Context, orderID string) (*OrderReturns, error)
+	Approve(ctx context.Context, orderID, returnID string) (*models.Return, error)
+	Reject(ctx context.Context, orderID, returnID, note string) (*models.Return, error)
+	// Receive marca la mercancía como recibida, repone el stock y reembolsa
+	// el valor de las líneas devueltas. Sobre una devolución ya recibida
+	// reintenta lo que quedó pendiente.
+	Receive(ctx context.Context, orderID, returnID string) (*models.Return, error)
+}
+
+type returnService struct {
+	repo     repo.ReturnRepo
+	orders   Service
+	pc       clients.ProductClient
+	payments PaymentService
+}
+
+// NewReturnService: con payments nil las devoluciones no reembolsan nada.
+func NewReturnService(r repo.ReturnRepo, orders Service, pc clients.ProductClient, payments PaymentService) ReturnService {
+	return &returnService{repo: r, orders: orders, pc: pc, payments: payments}
+}
+
+func (s *returnService) Create(ctx context.Context, orderID string, req ReturnRequest) (*models.Return, error) {
+	if len(req.Items) == 0 { return nil, ErrInvalidReturn }
+	// la misma línea repetida se suma
+	var items []models.ReturnItem
+	pos := map[string]int{}
+	for _, l := range req.Items {
+		if l.Reason == "" { l.Reason = req.Reason }
+		if l.OrderItemID == "" || l.Quantity <= 0 || !models.ValidReturnReason(l.Reason) { return nil, ErrInvalidReturn }
+		if i, ok := pos[l.OrderItemID]; ok { items[i].Quantity += l.Quantity; continue }
+		pos[l.OrderItemID] = len(items)
+		items = append(items, models.ReturnItem{OrderItemID: l.OrderItemID, Quantity: l.Quantity, Reason: l.Reason})
+	}
+	rt, err := s.repo.Create(ctx, &models.Return{OrderID: orderID, Note: req.Note, Items: items})
+	if err != nil { return nil, err }
+	slog.InfoContext(ctx, "return requested", "return_id", rt.ID, "order_id", orderID, "items", len(rt.Items))
+	return rt, nil
+}
+
+func (s *returnService) List(ctx context.Context, orderID string) (*OrderReturns, error) {
+	o, err := s.orders.Get(ctx, orderID)
+	if err != nil { return nil, err }
+	list, err := s.repo.ByOrder(ctx, orderID)
+	if err != nil { return nil, err }
+	return &OrderReturns{Returns: list, Total: o.Total, RefundedTotal: o.RefundedTotal, NetTotal: o.NetTotal()}, nil
+}
+
+func (s *returnService) Approve(ctx context.Context, orderID, returnID string) (*models.Return, error) {
+	return s.transition(ctx, orderID, returnID, []string{models.ReturnRequested}, models.ReturnApproved, "")
+}
+
+func (s *returnService) Reject(ctx context.Context, orderID, returnID, note string) (*models.Return, error) {
+	return s.transition(ctx, orderID, returnID, []string{models.ReturnRequested, models.ReturnApproved}, models.ReturnRejected, note)
+}
+
+// Receive cambia primero el estado y luego repone y reembolsa. Cada línea se
+// reclama antes de reponerla y el importe se reserva antes de reembolsarlo,
+// así dos llamadas no lo hacen dos veces. Un producto que ya no existe en
+// product-service (un 404) se da por repuesto; lo demás que falle queda pendiente
+// (restocked=false, refunded_amount por debajo del valor) y una nueva
+// llamada sobre la devolución ya recibida sólo hace lo que falta. La
+// reposición lleva la referencia de la línea: si falló después de aplicarse
+// (un timeout), product-service no la repite en el reintento.
+func (s *returnService) Receive(ctx context.Context, orderID, returnID string) (*models.Return, error) {
+	rt, err := s.transition(ctx, orderID, returnID, []string{models.ReturnApproved}, models.ReturnReceived, "")
+	if errors.Is(err, repo.ErrReturnState) {
+		cur, gerr := s.repo.Get(ctx, returnID)
+		if gerr != nil { return nil, gerr }
+		if !cur.Unfinished() { return nil, err }
+		rt, err = cur, nil
+	}
+	if err != nil { return nil, err }
+
+	for i, it := range rt.Items {
+		if it.Restocked { continue }
+		claimed, err := s.repo.ClaimRestock(ctx, it.ID)
+		if err != nil { return nil, err }
+		rt.Items[i].Restocked = true
+		if !claimed { continue }
+		_, err = s.pc.ApplyStockDelta(ctx, it.ProductID, clients.StockChange{
+			Delta: it.Quantity, Reason: clients.StockReturn, ReferenceID: clients.StockRef(rt.ID, it.ID),
+		})
+		switch {
+		case err == nil:
+		case clients.IsNotFound(err):
+			// como al caducar: un producto borrado no se reintenta siempre
+			slog.WarnContext(ctx, "return product gone, not restocked", "return_id", rt.ID, "product_id", it.ProductID, "quantity", it.Quantity)
+		default:
+			slog.ErrorContext(ctx, "return restock failed", "return_id", rt.ID, "product_id", it.ProductID, "quantity", it.Quantity, "error", err)
+			if err := s.repo.ReleaseRestock(context.WithoutCancel(ctx), it.ID); err != nil { return nil, err }
+			rt.Items[i].Restocked = false
+		}
+	}
+
+	due := models.Cents(rt.Value()) - models.Cents(rt.RefundedAmount)
+	if s.payments == nil || due <= 0 { return rt, nil }
+	reserved, err := s.repo.ReserveRefund(ctx, rt.ID, rt.RefundedAmount, float64(due)/100)
+	if err != nil { return nil, err }
+	// otra llamada está reembolsando lo que queda
+	if !reserved { return s.repo.Get(ctx, rt.ID) }
+	refunds, err := s.payments.RefundOrder(ctx, orderID, float64(due)/100, "return "+rt.ID, rt.ID)
+	var refunded int64
+	for _, rf := range refunds { refunded += models.Cents(rf.Amount) }
+	if left := due - refunded; left > 0 {
+		if rerr := s.repo.AddRefunded(context.WithoutCancel(ctx), rt.ID, -float64(left)/100); rerr != nil { return nil, rerr }
+	}
+	rt.RefundedAmount += float64(refunded) / 100
+	if err != nil { return nil, err }
+	slog.InfoContext(ctx, "return received", "return_id", rt.ID, "order_id", orderID, "value", rt.Value(), "refunded", rt.RefundedAmount)
+	return rt, nil
+}
+
+// transition comprueba que la devolución es del pedido de la URL antes de
+// cambiarla.
+func (s *returnService) transition(ctx context.Context, orderID, returnID string, from []string, to, note string) (*models.Return, error) {
+	rt, err := s.repo.Get(ctx, returnID)
+	if err != nil { return nil, err }
+	if rt.OrderID != orderID { return nil, repo.ErrNotFound }
+	return s.repo.Transition(ctx, returnID, from, to, note)
+}
@@ -0,0 +1,266 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"slices"
+	"testing"
+
+	"github.com/huntercenter1/backend-test/order-service/internal/clients"
+	"github.com/huntercenter1/backend-test/order-service/internal/models"
+	"github.com/huntercenter1/backend-test/order-service/internal/payments"
+	"github.com/huntercenter1/backend-test/order-service/internal/repo"
+)
+
+// memReturns es un ReturnRepo en memoria con las reglas del de Postgres.
+type memReturns struct {
+	orders  map[string]*models.Order
+	items   []models.OrderItem
+	returns []*models.Return
+}
+
+func (m *memReturns) Create(_ context.Context, rt *models.Return) (*models.Return, error) {
+	o, ok := m.orders[rt.OrderID]
+	if !ok { return nil, repo.ErrNotFound }
+	if o.Status != models.OrderDelivered { return nil, repo.ErrOrderNotReturnable }
+	var all []models.Return
+	for _, r := range m.returns { all = append(all, *r) }
+	left := models.Returnable(m.items, all)
+	for i, ri := range rt.Items {
+		idx := slices.IndexFunc(m.items, func(it models.OrderItem) bool { return it.ID == ri.OrderItemID })
+		if idx < 0 || ri.Quantity > left[ri.OrderItemID] { return nil, repo.ErrReturnQuantity }
+		left[ri.OrderItemID] -= ri.Quantity
+		rt.Items[i].ID = fmt.Sprintf("ri%d", i+1)
+		rt.Items[i].ProductID, rt.Items[i].UnitPrice = m.items[idx].ProductID, m.items[idx].PaidUnitPrice()
+		rt.Items[i].Amount = models.ReturnAmount(m.items[idx], ri.Quantity, all)
+	}
+	rt.ID, rt.Status = fmt.Sprintf("rt%d", len(m.returns)+1), models.ReturnRequested
+	m.returns = append(m.returns, rt)
+	return m.Get(context.Background(), rt.ID)
+}
+func (m *memReturns) Get(_ context.Context, id string) (*models.Return, error) {
+	for _, r := range m.returns {
+		if r.ID == id { cp := *r; cp.Items = slices.Clone(r.Items); return &cp, nil }
+	}
+	return nil, repo.ErrNotFound
+}
+func (m *memReturns) ByOrder(_ context.Context, orderID string) ([]models.Return, error) {
+	out := []models.Return{}
+	for _, r := range m.returns { if r.OrderID == orderID { out = append(out, *r) } }
+	return out, nil
+}
+func (m *memReturns) Transition(ctx context.Context, id string, from []string, to, note string) (*models.Return, error) {
+	for _, r := range m.returns {
+		if r.ID != id { continue }
+		if !slices.Contains(from, r.Status) { return nil, repo.ErrReturnState }
+		r.Status = to
+		if note != "" { r.Note = note }
+		return m.Get(ctx, id)
+	}
+	return nil, repo.ErrNotFound
+}
+func (m *memReturns) setRestocked(itemID string, to bool) (bool, error) {
+	for _, r := range m.returns {
+		for i := range r.Items {
+			if r.Items[i].ID != itemID { continue }
+			changed := r.Items[i].Restocked != to
+			r.Items[i].Restocked = to
+			return changed, nil
+		}
+	}
+	return false, repo.ErrNotFound
+}
+func (m *memReturns) ClaimRestock(_ context.Context, itemID string) (bool, error) { return m.setRestocked(itemID, true) }
+func (m *memReturns) ReleaseRestock(_ context.Context, itemID string) error {
+	_, err := m.setRestocked(itemID, false)
+	return err
+}
+func (m *memReturns) ReserveRefund(_ context.Context, id string, seen, amount float64) (bool, error) {
+	for _, r := range m.returns {
+		if r.ID != id { continue }
+		if r.RefundedAmount != seen { return false, nil }
+		r.RefundedAmount += amount
+		return true, nil
+	}
+	return false, repo.ErrNotFound
+}
+func (m *memReturns) AddRefunded(_ context.Context, id string, amount float64) error {
+	for _, r := range m.returns { if r.ID == id { r.RefundedAmount += amount; return nil } }
+	return repo.ErrNotFound
+}
+
+// stockPC registra los movimientos de stock; failFor hace fallar un producto
+// y "gone" no existe en product-service.
+type stockPC struct {
+	fakePC
+	moves   []clients.StockChange
+	failFor string
+}
+
+func (s *stockPC) ApplyStockDelta(_ context.Context, id string, ch clients.StockChange) (*clients.Product, error) {
+	if id == "gone" { return nil, &clients.StatusError{Op: "stock update", Code: http.StatusNotFound} }
+	if id == s.failFor { return nil, errors.New("product-service down") }
+	s.moves = append(s.moves, ch)
+	return &clients.Product{ID: id}, nil
+}
+
+type returnFixture struct {
+	svc  ReturnService
+	rets *memReturns
+	pays *memPayments
+	pc   *stockPC
+}
+
+// newReturnFixture: pedido entregado de 2×10 (p1) + 1×30 (p2) con un pago
+// capturado por el total.
+func newReturnFixture(t *testing.T) *returnFixture {
+	t.Helper()
+	pays := newMemPayments(&models.Order{ID: "o1", UserID: "u1", Status: models.OrderPending, Total: 50})
+	fake := payments.NewFake(payments.FakeConfig{})
+	pc := &stockPC{}
+	orders := New(ordersFrom{orders: pays.orders}, fakeUC{ok: true}, pc, nil)
+	paySvc := NewPaymentService(pays, orders, fake, PaymentConfig{WebhookSecret: testSecret})
+	p, err := paySvc.Create(context.Background(), "o1", PaymentRequest{Method: "card"})
+	if err != nil { t.Fatal(err) }
+	if _, err := pays.Capture(context.Background(), p.ID, 50); err != nil { t.Fatal(err) }
+	pays.orders["o1"].Status = models.OrderDelivered
+
+	rets := &memReturns{orders: pays.orders, items: []models.OrderItem{
+		{ID: "i1", OrderID: "o1", ProductID: "p1", Quantity: 2, Price: 10},
+		{ID: "i2", OrderID: "o1", ProductID: "p2", Quantity: 1, Price: 30},
+	}}
+	return &returnFixture{svc: NewReturnService(rets, orders, pc, paySvc), rets: rets, pays: pays, pc: pc}
+}
+
+func TestReturnReceiveRestocksAndRefunds(t *testing.T) {
+	f := newReturnFixture(t)
+	ctx := context.Background()
+	rt, err := f.svc.Create(ctx, "o1", ReturnRequest{Reason: models.ReasonDamaged, Items: []ReturnLine{{OrderItemID: "i1", Quantity: 1}, {OrderItemID: "i2", Quantity: 1, Reason: models.ReasonWrongItem}}})
+	if err != nil { t.Fatal(err) }
+	if rt.Status != models.ReturnRequested || rt.Items[0].Reason != models.ReasonDamaged || rt.Items[1].Reason != models.ReasonWrongItem { t.Fatalf("return: %+v", rt) }
+	if _, err := f.svc.Receive(ctx, "o1", rt.ID); !errors.Is(err, repo.ErrReturnState) { t.Fatalf("receive before approval: %v", err) }
+	if _, err := f.svc.Approve(ctx, "o1", rt.ID); err != nil { t.Fatal(err) }
+
+	rt, err = f.svc.Receive(ctx, "o1", rt.ID)
+	if err != nil { t.Fatal(err) }
+	if rt.Status != models.ReturnReceived || rt.RefundedAmount != 40 || !rt.Items[0].Restocked || !rt.Items[1].Restocked { t.Fatalf("received: %+v", rt) }
+	if len(f.pc.moves) != 2 || f.pc.moves[0].Delta != 1 || f.pc.moves[0].Reason != clients.StockReturn || f.pc.moves[0].ReferenceID != clients.StockRef(rt.ID, rt.Items[0].ID) { t.Fatalf("stock moves: %+v", f.pc.moves) }
+	refunds := f.pays.refunds
+	if len(refunds) != 1 || refunds[0].Amount != 40 || refunds[0].ReturnID == nil || *refunds[0].ReturnID != rt.ID { t.Fatalf("refunds: %+v", refunds) }
+
+	// recibir otra vez no repone ni reembolsa de nuevo
+	if _, err := f.svc.Receive(ctx, "o1", rt.ID); !errors.Is(err, repo.ErrReturnState) { t.Fatalf("second receive: %v", err) }
+	if len(f.pc.moves) != 2 { t.Fatalf("stock moved twice: %+v", f.pc.moves) }
+
+	list, err := f.svc.List(ctx, "o1")
+	if err != nil || len(list.Returns) != 1 || list.RefundedTotal != 40 || list.NetTotal != 10 { t.Fatalf("list: %v %+v", err, list) }
+}
+
+func TestReturnQuantitiesAndRejection(t *testing.T) {
+	f := newReturnFixture(t)
+	ctx := context.Background()
+	line := func(id string, q int) ReturnRequest { return ReturnRequest{Reason: models.ReasonOther, Items: []ReturnLine{{OrderItemID: id, Quantity: q}}} }
+
+	if _, err := f.svc.Create(ctx, "o1", ReturnRequest{Reason: "meh", Items: []ReturnLine{{OrderItemID: "i1", Quantity: 1}}}); !errors.Is(err, ErrInvalidReturn) { t.Fatalf("bad reason: %v", err) }
+	if _, err := f.svc.Create(ctx, "o1", ReturnRequest{Reason: models.ReasonOther}); !errors.Is(err, ErrInvalidReturn) { t.Fatalf("no items: %v", err) }
+	if _, err := f.svc.Create(ctx, "o1", line("i1", 3)); !errors.Is(err, repo.ErrReturnQuantity) { t.Fatalf("too many: %v", err) }
+	if _, err := f.svc.Create(ctx, "o1", line("nope", 1)); !errors.Is(err, repo.ErrReturnQuantity) { t.Fatalf("foreign item: %v", err) }
+
+	rt, err := f.svc.Create(ctx, "o1", line("i1", 2))
+	if err != nil { t.Fatal(err) }
+	if _, err := f.svc.Create(ctx, "o1", line("i1", 1)); !errors.Is(err, repo.ErrReturnQuantity) { t.Fatalf("already returned: %v", err) }
+	rt, err = f.svc.Reject(ctx, "o1", rt.ID, "outside return window")
+	if err != nil || rt.Status != models.ReturnRejected || rt.Note != "outside return window" { t.Fatalf("reject: %v %+v", err, rt) }
+	if _, err := f.svc.Approve(ctx, "o1", rt.ID); !errors.Is(err, repo.ErrReturnState) { t.Fatalf("approve rejected: %v", err) }
+	if _, err := f.svc.Approve(ctx, "o2", rt.ID); !errors.Is(err, repo.ErrNotFound) { t.Fatalf("return of another order: %v", err) }
+	// las unidades de una devolución rechazada se pueden volver a pedir
+	if _, err := f.svc.Create(ctx, "o1", line("i1", 2)); err != nil { t.Fatalf("after rejection: %v", err) }
+
+	f.pays.orders["o1"].Status = models.OrderShipped
+	if _, err := f.svc.Create(ctx, "o1", line("i2", 1)); !errors.Is(err, repo.ErrOrderNotReturnable) { t.Fatalf("not delivered: %v", err) }
+}
+
+func TestReturnRestockFailureKeepsItemPending(t *testing.T) {
+	f := newReturnFixture(t)
+	f.pc.failFor = "p2"
+	ctx := context.Background()
+	rt, _ := f.svc.Create(ctx, "o1", ReturnRequest{Reason: models.ReasonDefective, Items: []ReturnLine{{OrderItemID: "i1", Quantity: 2}, {OrderItemID: "i2", Quantity: 1}}})
+	_, _ = f.svc.Approve(ctx, "o1", rt.ID)
+	rt, err := f.svc.Receive(ctx, "o1", rt.ID)
+	if err != nil { t.Fatal(err) }
+	if !rt.Items[0].Restocked || rt.Items[1].Restocked { t.Fatalf("restocked flags: %+v", rt.Items) }
+	if rt.RefundedAmount != 50 { t.Fatalf("refunded=%v", rt.RefundedAmount) }
+}
+
+func TestReturnRestockOfDeletedProductIsNotRetried(t *testing.T) {
+	f := newReturnFixture(t)
+	f.rets.items[1].ProductID = "gone"
+	ctx := context.Background()
+	rt, _ := f.svc.Create(ctx, "o1", ReturnRequest{Reason: models.ReasonDefective, Items: []ReturnLine{{OrderItemID: "i1", Quantity: 1}, {OrderItemID: "i2", Quantity: 1}}})
+	_, _ = f.svc.Approve(ctx, "o1", rt.ID)
+	rt, err := f.svc.Receive(ctx, "o1", rt.ID)
+	if err != nil { t.Fatal(err) }
+	// el producto borrado se da por repuesto: la devolución queda terminada
+	if !rt.Items[0].Restocked || !rt.Items[1].Restocked || rt.RefundedAmount != 40 || len(f.pc.moves) != 1 { t.Fatalf("received: %+v moves=%+v", rt, f.pc.moves) }
+	if _, err := f.svc.Receive(ctx, "o1", rt.ID); !errors.Is(err, repo.ErrReturnState) { t.Fatalf("finished return received again: %v", err) }
+}
+
+func TestReturnValueNeverExceedsWhatWasPaid(t *testing.T) {
+	f := newReturnFixture(t)
+	// 3×3,34 con 0,01 de descuento: se pagaron 10,01, no 10,02
+	f.rets.items = append(f.rets.items, models.OrderItem{ID: "i3", OrderID: "o1", ProductID: "p3", Quantity: 3, Price: 3.34, Discount: 0.01})
+	ctx := context.Background()
+	line := func(q int) ReturnRequest { return ReturnRequest{Reason: models.ReasonOther, Items: []ReturnLine{{OrderItemID: "i3", Quantity: q}}} }
+
+	first, err := f.svc.Create(ctx, "o1", line(1))
+	if err != nil || first.Value() != 3.33 { t.Fatalf("first unit: %v %+v", err, first) }
+	second, err := f.svc.Create(ctx, "o1", line(1))
+	if err != nil || second.Value() != 3.33 { t.Fatalf("second unit: %v %+v", err, second) }
+	// rechazar la primera libera su unidad; la que se lleva las dos
+	// últimas cobra los céntimos del redondeo
+	if _, err := f.svc.Reject(ctx, "o1", first.ID, ""); err != nil { t.Fatal(err) }
+	last, err := f.svc.Create(ctx, "o1", line(2))
+	if err != nil || last.Value() != 6.68 { t.Fatalf("last units: %v %+v", err, last) }
+	if got := models.Cents(second.Value()) + models.Cents(last.Value()); got != 1001 { t.Fatalf("returned %d cents, paid 1001", got) }
+}
+
+func TestReturnReceiveRetriesWhatIsPending(t *testing.T) {
+	f := newReturnFixture(t)
+	f.pc.failFor = "p2"
+	ctx := context.Background()
+	rt, _ := f.svc.Create(ctx, "o1", ReturnRequest{Reason: models.ReasonDefective, Items: []ReturnLine{{OrderItemID: "i1", Quantity: 1}, {OrderItemID: "i2", Quantity: 1}}})
+	_, _ = f.svc.Approve(ctx, "o1", rt.ID)
+	if _, err := f.svc.Receive(ctx, "o1", rt.ID); err != nil { t.Fatal(err) }
+
+	// product-service vuelve: el segundo Receive sólo repone p2 y no reembolsa otra vez
+	f.pc.failFor = ""
+	rt, err := f.svc.Receive(ctx, "o1", rt.ID)
+	if err != nil { t.Fatal(err) }
+	if !rt.Items[0].Restocked || !rt.Items[1].Restocked || rt.RefundedAmount != 40 { t.Fatalf("retried: %+v", rt) }
+	if len(f.pc.moves) != 2 || len(f.pays.refunds) != 1 { t.Fatalf("moves=%+v refunds=%+v", f.pc.moves, f.pays.refunds) }
+	if _, err := f.svc.Receive(ctx, "o1", rt.ID); !errors.Is(err, repo.ErrReturnState) { t.Fatalf("finished return received again: %v", err) }
+
+	// un reembolso que se quedó corto se completa con lo que falta
+	f.rets.returns[0].RefundedAmount = 30
+	if rt, err = f.svc.Receive(ctx, "o1", rt.ID); err != nil || rt.RefundedAmount != 40 { t.Fatalf("refund retry: %v %+v", err, rt) }
+	if len(f.pays.refunds) != 2 || f.pays.refunds[1].Amount != 10 { t.Fatalf("refunds=%+v", f.pays.refunds) }
+}
+
+func TestRefundOrderSpreadsAcrossPayments(t *testing.T) {
+	pays := newMemPayments(&models.Order{ID: "o1", Status: models.OrderPending, Total: 100})
+	svc := NewPaymentService(pays, New(ordersFrom{orders: pays.orders}, fakeUC{ok: true}, fakePC{}, nil), payments.NewFake(payments.FakeConfig{}), PaymentConfig{WebhookSecret: testSecret})
+	ctx := context.Background()
+	for _, amount := range []float64{30, 70} {
+		p, err := svc.Create(ctx, "o1", PaymentRequest{Method: "card", Amount: amount})
+		if err != nil { t.Fatal(err) }
+		if _, err := pays.Capture(ctx, p.ID, amount); err != nil { t.Fatal(err) }
+	}
+	refunds, err := svc.RefundOrder(ctx, "o1", 45, "return rt1", "rt1")
+	if err != nil || len(refunds) != 2 || refunds[0].Amount != 30 || refunds[1].Amount != 15 { t.Fatalf("refunds: %v %+v", err, refunds) }
+	// más de lo cobrado: se devuelve lo que queda
+	refunds, err = svc.RefundOrder(ctx, "o1", 500, "", "rt2")
+	if err != nil || len(refunds) != 1 || refunds[0].Amount != 55 { t.Fatalf("capped refund: %v %+v", err, refunds) }
+	if pays.orders["o1"].RefundedTotal != 100 { t.Fatalf("refunded_total=%v", pays.orders["o1"].RefundedTotal) }
+}
@@ -0,0 +1,100 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"strings"
+	"time"
+
+	"github.com/huntercenter1/backend-test/order-service/internal/models"
+	"github.com/huntercenter1/backend-test/order-service/internal/repo"
+)
+
+var (
+	ErrInvalidShipment = errors.New("carrier is required and item quantities must be > 0")
+	ErrShipmentStatus  = errors.New("status must be shipped, delivered or cancelled")
+	ErrShipmentState   = errors.New("operation not allowed in the shipment's current status")
+)
+
+type ShipmentRequest struct {
+	Carrier        string         `json:"carrier"`
+	TrackingNumber string         `json:"tracking_number"`
+	Items          []ShipmentLine `json:"items"` // vacío = todo lo que queda por enviar
+}
+
+type ShipmentLine struct {
+	OrderItemID string `json:"order_item_id"`
+	Quantity    int    `json:"quantity"`
+}
+
+// ShipmentUpdate cambia el estado y/o los datos del transportista; los
+// campos nil no se tocan.
+type ShipmentUpdate struct {
+	Status         string  `json:"status"`
+	Carrier        *string `json:"carrier"`
+	TrackingNumber *string `json:"tracking_number"`
+}
+
+type ShipmentService interface {
+	Create(ctx context.Context, orderID string, req ShipmentRequest) (*models.Shipment, error)
+	List(ctx context.Context, orderID string) ([]models.Shipment, error)
+	Update(ctx context.Context, orderID, shipmentID string, req ShipmentUpdate) (*models.Shipment, error)
+}
+
+type shipmentService struct {
+	repo   repo.ShipmentRepo
+	orders Service
+}
+
+func NewShipmentService(r repo.ShipmentRepo, orders Service) ShipmentService {
+	return &shipmentService{repo: r, orders: orders}
+}
+
+func (s *shipmentService) Create(ctx context.Context, orderID string, req ShipmentRequest) (*models.Shipment, error) {
+	req.Carrier = strings.TrimSpace(req.Carrier)
+	if req.Carrier == "" { return nil, ErrInvalidShipment }
+	// la misma línea repetida se suma
+	var items []models.ShipmentItem
+	pos := map[string]int{}
+	for _, l := range req.Items {
+		if l.OrderItemID == "" || l.Quantity <= 0 { return nil, ErrInvalidShipment }
+		if i, ok := pos[l.OrderItemID]; ok { items[i].Quantity += l.Quantity; continue }
+		pos[l.OrderItemID] = len(items)
+		items = append(items, models.ShipmentItem{OrderItemID: l.OrderItemID, Quantity: l.Quantity})
+	}
+	sh, err := s.repo.Create(ctx, &models.Shipment{OrderID: orderID, Carrier: req.Carrier, TrackingNumber: strings.TrimSpace(req.TrackingNumber), Items: items})
+	if err != nil { return nil, err }
+	slog.InfoContext(ctx, "shipment created", "shipment_id", sh.ID, "order_id", orderID, "items", len(sh.Items))
+	return sh, nil
+}
+
+func (s *shipmentService) List(ctx context.Context, orderID string) ([]models.Shipment, error) {
+	if _, err := s.orders.Get(ctx, orderID); err != nil { return nil, err }
+	return s.repo.ByOrder(ctx, orderID)
+}
+
+// Update sólo deja avanzar el envío (ver models.CanTransition); carrier y
+// tracking se pueden corregir mientras no esté entregado o cancelado.
+func (s *shipmentService) Update(ctx context.Context, orderID, shipmentID string, req ShipmentUpdate) (*models.Shipment, error) {
+	if req.Carrier != nil && strings.TrimSpace(*req.Carrier) == "" { return nil, ErrInvalidShipment }
+	switch req.Status {
+	case "", models.ShipmentPending, models.ShipmentShipped, models.ShipmentDelivered, models.ShipmentCancelled:
+	default:
+		return nil, ErrShipmentStatus
+	}
+	sh, err := s.repo.Update(ctx, shipmentID, func(sh *models.Shipment) error {
+		if sh.OrderID != orderID { return repo.ErrNotFound }
+		closed := sh.Status == models.ShipmentDelivered || sh.Status == models.ShipmentCancelled
+		if (req.Carrier != nil || req.TrackingNumber != nil) && closed { return ErrShipmentState }
+		if req.Carrier != nil { sh.Carrier = strings.TrimSpace(*req.Carrier) }
+		if req.TrackingNumber != nil { sh.TrackingNumber = strings.TrimSpace(*req.TrackingNumber) }
+		if req.Status == "" || req.Status == sh.Status { return nil }
+		if !models.CanTransition(sh.Status, req.Status) { return ErrShipmentState }
+		sh.Advance(req.Status, time.Now())
+		return nil
+	})
+	if err != nil { return nil, err }
+	slog.InfoContext(ctx, "shipment updated", "shipment_id", sh.ID, "order_id", orderID, "status", sh.Status)
+	return sh, nil
+}
@@ -0,0 +1,127 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/huntercenter1/backend-test/order-service/internal/models"
+	"github.com/huntercenter1/backend-test/order-service/internal/repo"
+)
+
+// memShipments es un ShipmentRepo en memoria con las mismas reglas que el de
+// Postgres (reparto con models.Unshipped, estado con models.FulfillOrder).
+type memShipments struct {
+	orders    map[string]*models.Order
+	items     []models.OrderItem
+	shipments []*models.Shipment
+}
+
+func (m *memShipments) all() []models.Shipment {
+	out := make([]models.Shipment, 0, len(m.shipments))
+	for _, sh := range m.shipments { out = append(out, *sh) }
+	return out
+}
+func (m *memShipments) Create(_ context.Context, sh *models.Shipment) (*models.Shipment, error) {
+	o, ok := m.orders[sh.OrderID]
+	if !ok { return nil, repo.ErrNotFound }
+	if o.Status != models.OrderPaid && o.Status != models.OrderPartiallyShipped { return nil, repo.ErrOrderNotShippable }
+	left := models.Unshipped(m.items, m.all())
+	if len(sh.Items) == 0 {
+		for _, it := range m.items {
+			if left[it.ID] > 0 { sh.Items = append(sh.Items, models.ShipmentItem{OrderItemID: it.ID, Quantity: left[it.ID]}) }
+		}
+		if len(sh.Items) == 0 { return nil, repo.ErrNothingToShip }
+	}
+	for _, si := range sh.Items {
+		if n, ok := left[si.OrderItemID]; !ok || si.Quantity > n { return nil, repo.ErrShipmentQuantity }
+	}
+	sh.ID, sh.Status = fmt.Sprintf("sh%d", len(m.shipments)+1), models.ShipmentPending
+	cp := *sh
+	m.shipments = append(m.shipments, &cp)
+	return sh, nil
+}
+func (m *memShipments) Get(_ context.Context, id string) (*models.Shipment, error) {
+	for _, sh := range m.shipments { if sh.ID == id { cp := *sh; return &cp, nil } }
+	return nil, repo.ErrNotFound
+}
+func (m *memShipments) ByOrder(_ context.Context, orderID string) ([]models.Shipment, error) { return m.all(), nil }
+func (m *memShipments) Update(ctx context.Context, id string, fn func(sh *models.Shipment) error) (*models.Shipment, error) {
+	sh, err := m.Get(ctx, id)
+	if err != nil { return nil, err }
+	if err := fn(sh); err != nil { return nil, err }
+	for i := range m.shipments { if m.shipments[i].ID == id { m.shipments[i] = sh } }
+	o := m.orders[sh.OrderID]
+	o.Status = models.FulfillOrder(o.Status, m.items, m.all())
+	return sh, nil
+}
+
+func newShipFixture(status string) (ShipmentService, *memShipments) {
+	m := &memShipments{
+		orders: map[string]*models.Order{"o1": {ID: "o1", Status: status}},
+		items:  []models.OrderItem{{ID: "i1", OrderID: "o1", Quantity: 3}, {ID: "i2", OrderID: "o1", Quantity: 1}},
+	}
+	return NewShipmentService(m, New(ordersFrom{orders: m.orders}, fakeUC{ok: true}, fakePC{}, nil)), m
+}
+
+func TestPartialShipmentsDeriveOrderStatus(t *testing.T) {
+	svc, m := newShipFixture(models.OrderPaid)
+	ctx := context.Background()
+	status := func() string { return m.orders["o1"].Status }
+	shipped := models.ShipmentShipped
+
+	first, err := svc.Create(ctx, "o1", ShipmentRequest{Carrier: "SEUR", Items: []ShipmentLine{{"i1", 1}, {"i1", 1}}})
+	if err != nil || len(first.Items) != 1 || first.Items[0].Quantity != 2 { t.Fatalf("first shipment: %v %+v", err, first) }
+	if status() != models.OrderPaid { t.Fatalf("a pending shipment must not change the order, got %s", status()) }
+	if _, err := svc.Create(ctx, "o1", ShipmentRequest{Carrier: "SEUR", Items: []ShipmentLine{{"i1", 2}}}); !errors.Is(err, repo.ErrShipmentQuantity) { t.Fatalf("over-allocation: %v", err) }
+	if _, err := svc.Create(ctx, "o1", ShipmentRequest{Carrier: "SEUR", Items: []ShipmentLine{{"other", 1}}}); !errors.Is(err, repo.ErrShipmentQuantity) { t.Fatalf("foreign item: %v", err) }
+
+	first, err = svc.Update(ctx, "o1", first.ID, ShipmentUpdate{Status: shipped, TrackingNumber: ptr("TRK1")})
+	if err != nil || first.ShippedAt == nil || first.TrackingNumber != "TRK1" { t.Fatalf("ship: %v %+v", err, first) }
+	if status() != models.OrderPartiallyShipped { t.Fatalf("order status=%s, want partially_shipped", status()) }
+
+	// sin items se envía el resto: una unidad de i1 y la de i2
+	rest, err := svc.Create(ctx, "o1", ShipmentRequest{Carrier: "MRW"})
+	if err != nil || len(rest.Items) != 2 { t.Fatalf("rest: %v %+v", err, rest) }
+	if _, err := svc.Create(ctx, "o1", ShipmentRequest{Carrier: "MRW"}); !errors.Is(err, repo.ErrNothingToShip) { t.Fatalf("nothing left: %v", err) }
+
+	if _, err := svc.Update(ctx, "o1", rest.ID, ShipmentUpdate{Status: models.ShipmentDelivered}); err != nil { t.Fatal(err) }
+	if status() != models.OrderShipped { t.Fatalf("order status=%s, want shipped", status()) }
+	if _, err := svc.Update(ctx, "o1", first.ID, ShipmentUpdate{Status: models.ShipmentDelivered}); err != nil { t.Fatal(err) }
+	if status() != models.OrderDelivered { t.Fatalf("order status=%s, want delivered", status()) }
+
+	if _, err := svc.Update(ctx, "o1", first.ID, ShipmentUpdate{Status: shipped}); !errors.Is(err, ErrShipmentState) { t.Fatalf("going back: %v", err) }
+	if _, err := svc.Update(ctx, "o1", first.ID, ShipmentUpdate{Carrier: ptr("DHL")}); !errors.Is(err, ErrShipmentState) { t.Fatalf("edit delivered: %v", err) }
+	if _, err := svc.Update(ctx, "o2", first.ID, ShipmentUpdate{Status: shipped}); !errors.Is(err, repo.ErrNotFound) { t.Fatalf("shipment of another order: %v", err) }
+}
+
+func TestCancelledShipmentFreesItems(t *testing.T) {
+	svc, m := newShipFixture(models.OrderPaid)
+	ctx := context.Background()
+	sh, err := svc.Create(ctx, "o1", ShipmentRequest{Carrier: "SEUR"})
+	if err != nil { t.Fatal(err) }
+	if _, err := svc.Update(ctx, "o1", sh.ID, ShipmentUpdate{Status: "lost"}); !errors.Is(err, ErrShipmentStatus) { t.Fatalf("unknown status: %v", err) }
+	if _, err := svc.Update(ctx, "o1", sh.ID, ShipmentUpdate{Status: models.ShipmentCancelled}); err != nil { t.Fatal(err) }
+	if m.orders["o1"].Status != models.OrderPaid { t.Fatalf("order status=%s", m.orders["o1"].Status) }
+	if _, err := svc.Create(ctx, "o1", ShipmentRequest{Carrier: "SEUR"}); err != nil { t.Fatalf("items of a cancelled shipment must be shippable again: %v", err) }
+}
+
+func TestCreateShipmentValidation(t *testing.T) {
+	svc, _ := newShipFixture(models.OrderPending)
+	ctx := context.Background()
+	if _, err := svc.Create(ctx, "o1", ShipmentRequest{Carrier: " "}); !errors.Is(err, ErrInvalidShipment) { t.Fatalf("no carrier: %v", err) }
+	if _, err := svc.Create(ctx, "o1", ShipmentRequest{Carrier: "SEUR", Items: []ShipmentLine{{"i1", 0}}}); !errors.Is(err, ErrInvalidShipment) { t.Fatalf("zero quantity: %v", err) }
+	if _, err := svc.Create(ctx, "o1", ShipmentRequest{Carrier: "SEUR"}); !errors.Is(err, repo.ErrOrderNotShippable) { t.Fatalf("unpaid order: %v", err) }
+	if _, err := svc.List(ctx, "missing"); !errors.Is(err, repo.ErrNotFound) { t.Fatalf("list of missing order: %v", err) }
+}
+
+func TestFulfillOrderLeavesClosedOrders(t *testing.T) {
+	items := []models.OrderItem{{ID: "i1", Quantity: 1}}
+	delivered := []models.Shipment{{Status: models.ShipmentDelivered, Items: []models.ShipmentItem{{OrderItemID: "i1", Quantity: 1}}}}
+	for _, st := range []string{models.OrderPending, models.OrderCancelled, models.OrderRefunded} {
+		if got := models.FulfillOrder(st, items, delivered); got != st { t.Errorf("FulfillOrder(%s) = %s", st, got) }
+	}
+}
+
+func ptr(s string) *string { return &s }
@@ -0,0 +1,126 @@
+package http
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/huntercenter1/backend-test/order-service/internal/repo"
+	"github.com/huntercenter1/backend-test/order-service/internal/service"
+)
+
+func (rt *Router) registerCarts(r *gin.Engine) {
+	r.POST("/carts", rt.createCart)
+	r.POST("/carts/merge", rt.mergeCart)
+	r.GET("/carts/user/:user_id", rt.userCart)
+	r.GET("/carts/:id", rt.getCart)
+	r.POST("/carts/:id/items", rt.addCartItem)
+	r.PUT("/carts/:id/items/:product_id", rt.updateCartItem)
+	r.DELETE("/carts/:id/items/:product_id", rt.removeCartItem)
+	r.POST("/carts/:id/checkout", rt.checkoutCart)
+}
+
+// cartError traduce los errores de carritos; el resto (usuario inválido,
+// falta de stock en el checkout...) son 400 como en POST /orders.
+func cartError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, repo.ErrNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error":"not found"})
+	case errors.Is(err, repo.ErrCartNotOpen), errors.Is(err, repo.ErrCartOwned), errors.Is(err, service.ErrGuestCart), errors.Is(err, repo.ErrPromotionExhausted):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+	case errors.Is(err, service.ErrStockUpdate):
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": err.Error()})
+	default:
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	}
+}
+
+type createCartReq struct{ UserID string `json:"user_id"` }
+
+// createCart sin user_id crea un carrito de invitado; con user_id devuelve
+// el carrito abierto del usuario (200) o crea uno (201).
+func (rt *Router) createCart(c *gin.Context) {
+	var req createCartReq
+	if c.Request.ContentLength != 0 {
+		if err := c.ShouldBindJSON(&req); err != nil { c.JSON(http.StatusBadRequest, gin.H{"error":"invalid body"}); return }
+	}
+	cart, created, err := rt.carts.Create(c.Request.Context(), req.UserID)
+	if err != nil { cartError(c, err); return }
+	code := http.StatusOK
+	if created { code = http.StatusCreated }
+	c.JSON(code, cart)
+}
+
+func (rt *Router) getCart(c *gin.Context) {
+	cart, err := rt.carts.Get(c.Request.Context(), c.Param("id"))
+	if err != nil { cartError(c, err); return }
+	c.JSON(http.StatusOK, cart)
+}
+
+func (rt *Router) userCart(c *gin.Context) {
+	cart, err := rt.carts.ByUser(c.Request.Context(), c.Param("user_id"))
+	if err != nil { cartError(c, err); return }
+	c.JSON(http.StatusOK, cart)
+}
+
+type cartItemReq struct {
+	ProductID string `json:"product_id"`
+	Quantity  int    `json:"quantity"`
+}
+
+func (rt *Router) addCartItem(c *gin.Context) {
+	var req cartItemReq
+	if err := c.ShouldBindJSON(&req); err != nil || req.ProductID == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error":"invalid payload"}); return
+	}
+	cart, err := rt.carts.AddItem(c.Request.Context(), c.Param("id"), req.ProductID, req.Quantity)
+	if err != nil { cartError(c, err); return }
+	c.JSON(http.StatusOK, cart)
+}
+
+type quantityReq struct{ Quantity *int `json:"quantity"` }
+
+func (rt *Router) updateCartItem(c *gin.Context) {
+	var req quantityReq
+	if err := c.ShouldBindJSON(&req); err != nil || req.Quantity == nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error":"invalid payload"}); return
+	}
+	cart, err := rt.carts.UpdateItem(c.Request.Context(), c.Param("id"), c.Param("product_id"), *req.Quantity)
+	if err != nil { cartError(c, err); return }
+	c.JSON(http.StatusOK, cart)
+}
+
+func (rt *Router) removeCartItem(c *gin.Context) {
+	cart, err := rt.carts.RemoveItem(c.Request.Context(), c.Param("id"), c.Param("product_id"))
+	if err != nil { cartError(c, err); return }
+	c.JSON(http.StatusOK, cart)
+}
+
+type mergeReq struct {
+	GuestCartID string `json:"guest_cart_id"`
+	UserID      string `json:"user_id"`
+}
+
+// mergeCart lo llama el cliente al iniciar sesión con el carrito que tenía
+// como invitado; devuelve el carrito del usuario ya fusionado.
+func (rt *Router) mergeCart(c *gin.Context) {
+	var req mergeReq
+	if err := c.ShouldBindJSON(&req); err != nil || req.GuestCartID == "" || req.UserID == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error":"invalid payload"}); return
+	}
+	cart, err := rt.carts.Merge(c.Request.Context(), req.GuestCartID, req.UserID)
+	if err != nil { cartError(c, err); return }
+	c.JSON(http.StatusOK, cart)
+}
+
+// checkoutCart acepta cuerpo vacío o {"coupon_code": "...", "region": "..."}.
+func (rt *Router) checkoutCart(c *gin.Context) {
+	var req service.CheckoutRequest
+	if c.Request.ContentLength != 0 {
+		if err := c.ShouldBindJSON(&req); err != nil { c.JSON(http.StatusBadRequest, gin.H{"error":"invalid payload"}); return }
+	}
+	o, items, err := rt.carts.Checkout(c.Request.Context(), c.Param("id"), req)
+	if err != nil { cartError(c, err); return }
+	c.JSON(http.StatusCreated, gin.H{"order": o, "items": items})
+}
@@ -0,0 +1,97 @@
+package http
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/huntercenter1/backend-test/order-service/internal/models"
+	"github.com/huntercenter1/backend-test/order-service/internal/repo"
+	"github.com/huntercenter1/backend-test/order-service/internal/service"
+)
+
+// stubCarts devuelve err en todas las operaciones si está puesto.
+type stubCarts struct {
+	err     error
+	created bool
+	qty     int
+	coupon  string
+}
+
+func (s *stubCarts) view(id string) *service.CartView {
+	return &service.CartView{Cart: &models.Cart{ID: id, Status: models.CartOpen}, Items: []service.CartLine{}}
+}
+func (s *stubCarts) Create(_ context.Context, userID string) (*service.CartView, bool, error) {
+	if s.err != nil { return nil, false, s.err }
+	return s.view("c1"), s.created, nil
+}
+func (s *stubCarts) Get(_ context.Context, id string) (*service.CartView, error) {
+	if s.err != nil { return nil, s.err }
+	return s.view(id), nil
+}
+func (s *stubCarts) ByUser(_ context.Context, userID string) (*service.CartView, error) { return s.Get(context.TODO(), "c1") }
+func (s *stubCarts) AddItem(_ context.Context, id, productID string, qty int) (*service.CartView, error) {
+	s.qty = qty
+	return s.Get(context.TODO(), id)
+}
+func (s *stubCarts) UpdateItem(_ context.Context, id, productID string, qty int) (*service.CartView, error) {
+	s.qty = qty
+	return s.Get(context.TODO(), id)
+}
+func (s *stubCarts) RemoveItem(_ context.Context, id, productID string) (*service.CartView, error) { return s.Get(context.TODO(), id) }
+func (s *stubCarts) Merge(_ context.Context, guestID, userID string) (*service.CartView, error) { return s.Get(context.TODO(), "c1") }
+func (s *stubCarts) Checkout(_ context.Context, id string, req service.CheckoutRequest) (*models.Order, []models.OrderItem, error) {
+	s.coupon = req.CouponCode
+	if s.err != nil { return nil, nil, s.err }
+	return &models.Order{ID: "o1", Status: "pending"}, []models.OrderItem{}, nil
+}
+
+func TestCartRoutes(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	carts := &stubCarts{created: true}
+	r := gin.New()
+	New(&memSvc{}, Options{Carts: carts}).Register(r)
+
+	do := func(method, path, body string) int {
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest(method, path, bytes.NewBufferString(body)))
+		return w.Code
+	}
+
+	if code := do(http.MethodPost, "/carts", ""); code != http.StatusCreated { t.Fatalf("guest create=%d", code) }
+	carts.created = false
+	if code := do(http.MethodPost, "/carts", `{"user_id":"u1"}`); code != http.StatusOK { t.Fatalf("existing cart=%d", code) }
+	if code := do(http.MethodGet, "/carts/c1", ""); code != http.StatusOK { t.Fatalf("get=%d", code) }
+	if code := do(http.MethodGet, "/carts/user/u1", ""); code != http.StatusOK { t.Fatalf("by user=%d", code) }
+	if code := do(http.MethodPost, "/carts/c1/items", `{"product_id":"p1","quantity":2}`); code != http.StatusOK || carts.qty != 2 { t.Fatalf("add=%d qty=%d", code, carts.qty) }
+	if code := do(http.MethodPost, "/carts/c1/items", `{"quantity":2}`); code != http.StatusBadRequest { t.Fatalf("add without product=%d", code) }
+	if code := do(http.MethodPut, "/carts/c1/items/p1", `{"quantity":0}`); code != http.StatusOK || carts.qty != 0 { t.Fatalf("update=%d qty=%d", code, carts.qty) }
+	if code := do(http.MethodPut, "/carts/c1/items/p1", `{}`); code != http.StatusBadRequest { t.Fatalf("update without quantity=%d", code) }
+	if code := do(http.MethodDelete, "/carts/c1/items/p1", ""); code != http.StatusOK { t.Fatalf("remove=%d", code) }
+	if code := do(http.MethodPost, "/carts/merge", `{"guest_cart_id":"c2","user_id":"u1"}`); code != http.StatusOK { t.Fatalf("merge=%d", code) }
+	if code := do(http.MethodPost, "/carts/merge", `{"guest_cart_id":"c2"}`); code != http.StatusBadRequest { t.Fatalf("merge without user=%d", code) }
+	if code := do(http.MethodPost, "/carts/c1/checkout", ""); code != http.StatusCreated { t.Fatalf("checkout=%d", code) }
+	if code := do(http.MethodPost, "/carts/c1/checkout", `{"coupon_code":"SUMMER10"}`); code != http.StatusCreated || carts.coupon != "SUMMER10" { t.Fatalf("checkout with coupon=%d coupon=%q", code, carts.coupon) }
+
+	for err, want := range map[error]int{
+		repo.ErrNotFound:           http.StatusNotFound,
+		repo.ErrCartNotOpen:        http.StatusConflict,
+		repo.ErrPromotionExhausted: http.StatusConflict,
+		service.ErrGuestCart:       http.StatusConflict,
+		service.ErrEmptyCart:       http.StatusBadRequest,
+	} {
+		carts.err = err
+		if code := do(http.MethodPost, "/carts/c1/checkout", ""); code != want { t.Fatalf("checkout with %v = %d, want %d", err, code, want) }
+	}
+}
+
+func TestCartRoutesDisabledWithoutService(t *testing.T) {
+	r, _, _ := setupOrderRouter()
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/carts/c1", nil))
+	if w.Code != http.StatusNotFound { t.Fatalf("code=%d", w.Code) }
+}
@@ -0,0 +1,19 @@
+package http
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+func (rt *Router) registerExpiry(r *gin.Engine) {
+	r.GET("/workers/order-expiry", rt.expiryStatus)
+}
+
+// expiryStatus muestra el estado del worker de caducidad en esta réplica y
+// cuántos pedidos quedan por tratar.
+func (rt *Router) expiryStatus(c *gin.Context) {
+	st, err := rt.expiry.Status(c.Request.Context())
+	if err != nil { c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()}); return }
+	c.JSON(http.StatusOK, st)
+}
@@ -0,0 +1,41 @@
+package http
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/huntercenter1/backend-test/order-service/internal/service"
+)
+
+type stubExpiry struct{ err error }
+
+func (s stubExpiry) Status(context.Context) (service.ExpiryStatus, error) {
+	if s.err != nil { return service.ExpiryStatus{}, s.err }
+	return service.ExpiryStatus{TTL: "30m0s", BatchSize: 100, TotalExpired: 3, Backlog: 2}, nil
+}
+
+func TestExpiryStatusRoute(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	get := func(opts Options) *httptest.ResponseRecorder {
+		r := gin.New()
+		New(&memSvc{}, opts).Register(r)
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/workers/order-expiry", nil))
+		return w
+	}
+
+	w := get(Options{Expiry: stubExpiry{}})
+	var st service.ExpiryStatus
+	if w.Code != http.StatusOK || json.Unmarshal(w.Body.Bytes(), &st) != nil || st.Backlog != 2 || st.TotalExpired != 3 || st.TTL != "30m0s" {
+		t.Fatalf("status: %d %s", w.Code, w.Body)
+	}
+	if w := get(Options{Expiry: stubExpiry{err: errors.New("db down")}}); w.Code != http.StatusInternalServerError { t.Fatalf("error=%d", w.Code) }
+	// sin worker no hay ruta
+	if w := get(Options{}); w.Code != http.StatusNotFound { t.Fatalf("disabled=%d", w.Code) }
+}
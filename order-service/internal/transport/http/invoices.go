@@ -0,0 +1,50 @@
+package http
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/huntercenter1/backend-test/order-service/internal/invoice"
+	"github.com/huntercenter1/backend-test/order-service/internal/repo"
+	"github.com/huntercenter1/backend-test/order-service/internal/service"
+)
+
+func (rt *Router) registerInvoices(r *gin.Engine) {
+	r.GET("/orders/:id/invoice", rt.getInvoice)
+}
+
+func invoiceError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, repo.ErrNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error":"not found"})
+	case errors.Is(err, service.ErrNoInvoice):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+	case errors.Is(err, service.ErrCustomer):
+		c.JSON(http.StatusBadGateway, gin.H{"error": err.Error()})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	}
+}
+
+// getInvoice sirve HTML por defecto; ?format=pdf o Accept: application/pdf
+// piden el PDF.
+func (rt *Router) getInvoice(c *gin.Context) {
+	format := c.Query("format")
+	if format == "" && strings.Contains(c.GetHeader("Accept"), "application/pdf") { format = "pdf" }
+	if format == "" { format = "html" }
+	if format != "html" && format != "pdf" { c.JSON(http.StatusBadRequest, gin.H{"error":"format must be html or pdf"}); return }
+
+	d, err := rt.invoices.Get(c.Request.Context(), c.Param("id"))
+	if err != nil { invoiceError(c, err); return }
+	var buf bytes.Buffer
+	render, contentType := invoice.HTML, "text/html; charset=utf-8"
+	if format == "pdf" { render, contentType = invoice.PDF, "application/pdf" }
+	if err := render(&buf, *d); err != nil { c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()}); return }
+	c.Header("Content-Disposition", fmt.Sprintf(`inline; filename="invoice-%s.%s"`, d.Number, format))
+	c.Data(http.StatusOK, contentType, buf.Bytes())
+}
@@ -0,0 +1,56 @@
+package http
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/huntercenter1/backend-test/order-service/internal/invoice"
+	"github.com/huntercenter1/backend-test/order-service/internal/repo"
+	"github.com/huntercenter1/backend-test/order-service/internal/service"
+)
+
+type stubInvoices struct{ err error }
+
+func (s stubInvoices) Get(_ context.Context, orderID string) (*invoice.Document, error) {
+	if s.err != nil { return nil, s.err }
+	return &invoice.Document{Number: "2025-000001", OrderID: orderID, Currency: "EUR", Customer: invoice.Party{Name: "demo"}, Total: 10}, nil
+}
+
+func TestInvoiceRoute(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	get := func(inv stubInvoices, path, accept string) *httptest.ResponseRecorder {
+		r := gin.New()
+		New(&memSvc{}, Options{Invoices: inv}).Register(r)
+		req := httptest.NewRequest(http.MethodGet, path, nil)
+		if accept != "" { req.Header.Set("Accept", accept) }
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		return w
+	}
+
+	w := get(stubInvoices{}, "/orders/o1/invoice", "")
+	if w.Code != http.StatusOK || !strings.HasPrefix(w.Header().Get("Content-Type"), "text/html") || !strings.Contains(w.Body.String(), "Factura 2025-000001") {
+		t.Fatalf("html: %d %s", w.Code, w.Header().Get("Content-Type"))
+	}
+	for _, w := range []*httptest.ResponseRecorder{get(stubInvoices{}, "/orders/o1/invoice?format=pdf", ""), get(stubInvoices{}, "/orders/o1/invoice", "application/pdf")} {
+		if w.Code != http.StatusOK || w.Header().Get("Content-Type") != "application/pdf" || !bytes.HasPrefix(w.Body.Bytes(), []byte("%PDF-")) {
+			t.Fatalf("pdf: %d %s", w.Code, w.Header().Get("Content-Type"))
+		}
+		if cd := w.Header().Get("Content-Disposition"); cd != `inline; filename="invoice-2025-000001.pdf"` { t.Fatalf("disposition=%q", cd) }
+	}
+	if w := get(stubInvoices{}, "/orders/o1/invoice?format=xml", ""); w.Code != http.StatusBadRequest { t.Fatalf("bad format=%d", w.Code) }
+
+	for err, want := range map[error]int{
+		repo.ErrNotFound:     http.StatusNotFound,
+		service.ErrNoInvoice: http.StatusConflict,
+		service.ErrCustomer:  http.StatusBadGateway,
+	} {
+		if w := get(stubInvoices{err: err}, "/orders/o1/invoice", ""); w.Code != want { t.Fatalf("%v: code=%d want %d", err, w.Code, want) }
+	}
+}
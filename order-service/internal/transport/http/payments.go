@@ -0,0 +1,96 @@
+package http
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/huntercenter1/backend-test/order-service/internal/payments"
+	"github.com/huntercenter1/backend-test/order-service/internal/repo"
+	"github.com/huntercenter1/backend-test/order-service/internal/service"
+)
+
+func (rt *Router) registerPayments(r *gin.Engine) {
+	r.POST("/orders/:id/payments", rt.createPayment)
+	r.GET("/orders/:id/payments", rt.listPayments)
+	r.POST("/orders/:id/payments/:payment_id/capture", rt.capturePayment)
+	r.POST("/orders/:id/payments/:payment_id/refunds", rt.refundPayment)
+	r.POST("/payments/webhook", rt.paymentWebhook)
+}
+
+func paymentError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, repo.ErrNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error":"not found"})
+	case errors.Is(err, service.ErrOrderNotPayable), errors.Is(err, service.ErrPaymentState):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+	case errors.Is(err, service.ErrInvalidAmount), errors.Is(err, service.ErrAmountExceeds), errors.Is(err, service.ErrInvalidPayment):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	case errors.Is(err, service.ErrProvider):
+		c.JSON(http.StatusBadGateway, gin.H{"error": err.Error()})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	}
+}
+
+func (rt *Router) createPayment(c *gin.Context) {
+	var req service.PaymentRequest
+	if err := c.ShouldBindJSON(&req); err != nil { c.JSON(http.StatusBadRequest, gin.H{"error":"invalid payload"}); return }
+	p, err := rt.payments.Create(c.Request.Context(), c.Param("id"), req)
+	if err != nil { paymentError(c, err); return }
+	c.JSON(http.StatusCreated, p)
+}
+
+func (rt *Router) listPayments(c *gin.Context) {
+	list, refunds, err := rt.payments.List(c.Request.Context(), c.Param("id"))
+	if err != nil { paymentError(c, err); return }
+	c.JSON(http.StatusOK, gin.H{"payments": list, "refunds": refunds})
+}
+
+type amountReq struct {
+	Amount float64 `json:"amount"`
+	Reason string  `json:"reason"`
+}
+
+// bindAmount acepta cuerpo vacío: sin amount se captura/devuelve todo lo pendiente.
+func bindAmount(c *gin.Context) (amountReq, bool) {
+	var req amountReq
+	if c.Request.ContentLength == 0 { return req, true }
+	if err := c.ShouldBindJSON(&req); err != nil { c.JSON(http.StatusBadRequest, gin.H{"error":"invalid payload"}); return req, false }
+	return req, true
+}
+
+func (rt *Router) capturePayment(c *gin.Context) {
+	req, ok := bindAmount(c)
+	if !ok { return }
+	p, err := rt.payments.Capture(c.Request.Context(), c.Param("id"), c.Param("payment_id"), req.Amount)
+	if err != nil { paymentError(c, err); return }
+	c.JSON(http.StatusOK, p)
+}
+
+func (rt *Router) refundPayment(c *gin.Context) {
+	req, ok := bindAmount(c)
+	if !ok { return }
+	rf, p, err := rt.payments.Refund(c.Request.Context(), c.Param("id"), c.Param("payment_id"), req.Amount, req.Reason)
+	if err != nil { paymentError(c, err); return }
+	c.JSON(http.StatusCreated, gin.H{"refund": rf, "payment": p})
+}
+
+// paymentWebhook responde 2xx sólo cuando el evento queda aplicado (o ya lo
+// estaba): con cualquier otro código el proveedor lo reintenta.
+func (rt *Router) paymentWebhook(c *gin.Context) {
+	body, err := c.GetRawData()
+	if err != nil { c.JSON(http.StatusBadRequest, gin.H{"error":"invalid body"}); return }
+	err = rt.payments.HandleWebhook(c.Request.Context(), body, c.GetHeader(payments.HeaderSignature))
+	switch {
+	case err == nil:
+		c.JSON(http.StatusOK, gin.H{"received": true})
+	case errors.Is(err, payments.ErrInvalidSignature):
+		c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+	case errors.Is(err, service.ErrInvalidEvent):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	default:
+		paymentError(c, err)
+	}
+}
@@ -0,0 +1,89 @@
+package http
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/huntercenter1/backend-test/order-service/internal/models"
+	"github.com/huntercenter1/backend-test/order-service/internal/payments"
+	"github.com/huntercenter1/backend-test/order-service/internal/repo"
+	"github.com/huntercenter1/backend-test/order-service/internal/service"
+)
+
+// stubPayments devuelve err en todas las operaciones si está puesto; el
+// webhook sólo acepta la firma "ok".
+type stubPayments struct {
+	err    error
+	amount float64
+}
+
+func (s *stubPayments) Create(_ context.Context, orderID string, req service.PaymentRequest) (*models.Payment, error) {
+	if s.err != nil { return nil, s.err }
+	return &models.Payment{ID: "pay1", OrderID: orderID, Amount: req.Amount}, nil
+}
+func (s *stubPayments) List(_ context.Context, orderID string) ([]models.Payment, []models.Refund, error) {
+	return []models.Payment{}, []models.Refund{}, s.err
+}
+func (s *stubPayments) Capture(_ context.Context, orderID, paymentID string, amount float64) (*models.Payment, error) {
+	s.amount = amount
+	if s.err != nil { return nil, s.err }
+	return &models.Payment{ID: paymentID, OrderID: orderID}, nil
+}
+func (s *stubPayments) Refund(_ context.Context, orderID, paymentID string, amount float64, reason string) (*models.Refund, *models.Payment, error) {
+	s.amount = amount
+	if s.err != nil { return nil, nil, s.err }
+	return &models.Refund{PaymentID: paymentID, Amount: amount, Reason: reason}, &models.Payment{ID: paymentID}, nil
+}
+func (s *stubPayments) RefundOrder(_ context.Context, orderID string, amount float64, reason, returnID string) ([]models.Refund, error) {
+	return nil, s.err
+}
+func (s *stubPayments) HandleWebhook(_ context.Context, payload []byte, signature string) error {
+	if signature != "ok" { return payments.ErrInvalidSignature }
+	return s.err
+}
+
+func TestPaymentRoutes(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	pay := &stubPayments{}
+	r := gin.New()
+	New(&memSvc{}, Options{Payments: pay}).Register(r)
+
+	do := func(method, path, body string, header ...string) int {
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest(method, path, bytes.NewBufferString(body))
+		if len(header) == 2 { req.Header.Set(header[0], header[1]) }
+		r.ServeHTTP(w, req)
+		return w.Code
+	}
+
+	if code := do(http.MethodPost, "/orders/o1/payments", `{"method":"card"}`); code != http.StatusCreated { t.Fatalf("create=%d", code) }
+	if code := do(http.MethodGet, "/orders/o1/payments", ""); code != http.StatusOK { t.Fatalf("list=%d", code) }
+	if code := do(http.MethodPost, "/orders/o1/payments/pay1/capture", ""); code != http.StatusOK || pay.amount != 0 { t.Fatalf("capture without body=%d amount=%v", code, pay.amount) }
+	if code := do(http.MethodPost, "/orders/o1/payments/pay1/capture", `{"amount":12.5}`); code != http.StatusOK || pay.amount != 12.5 { t.Fatalf("partial capture=%d amount=%v", code, pay.amount) }
+	if code := do(http.MethodPost, "/orders/o1/payments/pay1/refunds", `{"amount":"x"}`); code != http.StatusBadRequest { t.Fatalf("bad refund payload=%d", code) }
+	if code := do(http.MethodPost, "/orders/o1/payments/pay1/refunds", `{"amount":5,"reason":"damaged"}`); code != http.StatusCreated { t.Fatalf("refund=%d", code) }
+
+	if code := do(http.MethodPost, "/payments/webhook", `{}`); code != http.StatusUnauthorized { t.Fatalf("unsigned webhook=%d", code) }
+	if code := do(http.MethodPost, "/payments/webhook", `{}`, payments.HeaderSignature, "ok"); code != http.StatusOK { t.Fatalf("webhook=%d", code) }
+	pay.err = fmt.Errorf("%w: missing type", service.ErrInvalidEvent)
+	if code := do(http.MethodPost, "/payments/webhook", `{}`, payments.HeaderSignature, "ok"); code != http.StatusBadRequest { t.Fatalf("invalid event=%d", code) }
+
+	for err, want := range map[error]int{
+		repo.ErrNotFound:           http.StatusNotFound,
+		service.ErrOrderNotPayable: http.StatusConflict,
+		service.ErrPaymentState:    http.StatusConflict,
+		service.ErrAmountExceeds:   http.StatusBadRequest,
+		service.ErrProvider:        http.StatusBadGateway,
+		errors.New("boom"):         http.StatusInternalServerError,
+	} {
+		pay.err = err
+		if code := do(http.MethodPost, "/orders/o1/payments/pay1/capture", ""); code != want { t.Fatalf("capture with %v = %d, want %d", err, code, want) }
+	}
+}
@@ -0,0 +1,72 @@
+package http
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/huntercenter1/backend-test/order-service/internal/models"
+	"github.com/huntercenter1/backend-test/order-service/internal/repo"
+	"github.com/huntercenter1/backend-test/order-service/internal/service"
+)
+
+func (rt *Router) registerPricingRules(r *gin.Engine) {
+	r.POST("/tax-rules", rt.createTaxRule)
+	r.GET("/tax-rules", rt.listTaxRules)
+	r.DELETE("/tax-rules/:id", rt.deleteTaxRule)
+	r.POST("/shipping-rules", rt.createShippingRule)
+	r.GET("/shipping-rules", rt.listShippingRules)
+	r.DELETE("/shipping-rules/:id", rt.deleteShippingRule)
+}
+
+func ruleError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, repo.ErrNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error":"not found"})
+	case errors.Is(err, repo.ErrDuplicateRule):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+	case errors.Is(err, service.ErrInvalidRule):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	}
+}
+
+func (rt *Router) createTaxRule(c *gin.Context) {
+	var r models.TaxRule
+	if err := c.ShouldBindJSON(&r); err != nil { c.JSON(http.StatusBadRequest, gin.H{"error":"invalid payload"}); return }
+	out, err := rt.rules.CreateTaxRule(c.Request.Context(), &r)
+	if err != nil { ruleError(c, err); return }
+	c.JSON(http.StatusCreated, out)
+}
+
+func (rt *Router) listTaxRules(c *gin.Context) {
+	list, err := rt.rules.ListTaxRules(c.Request.Context())
+	if err != nil { ruleError(c, err); return }
+	c.JSON(http.StatusOK, gin.H{"tax_rules": list})
+}
+
+func (rt *Router) deleteTaxRule(c *gin.Context) {
+	if err := rt.rules.DeleteTaxRule(c.Request.Context(), c.Param("id")); err != nil { ruleError(c, err); return }
+	c.Status(http.StatusNoContent)
+}
+
+func (rt *Router) createShippingRule(c *gin.Context) {
+	var r models.ShippingRule
+	if err := c.ShouldBindJSON(&r); err != nil { c.JSON(http.StatusBadRequest, gin.H{"error":"invalid payload"}); return }
+	out, err := rt.rules.CreateShippingRule(c.Request.Context(), &r)
+	if err != nil { ruleError(c, err); return }
+	c.JSON(http.StatusCreated, out)
+}
+
+func (rt *Router) listShippingRules(c *gin.Context) {
+	list, err := rt.rules.ListShippingRules(c.Request.Context())
+	if err != nil { ruleError(c, err); return }
+	c.JSON(http.StatusOK, gin.H{"shipping_rules": list})
+}
+
+func (rt *Router) deleteShippingRule(c *gin.Context) {
+	if err := rt.rules.DeleteShippingRule(c.Request.Context(), c.Param("id")); err != nil { ruleError(c, err); return }
+	c.Status(http.StatusNoContent)
+}
@@ -0,0 +1,65 @@
+package http
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/huntercenter1/backend-test/order-service/internal/models"
+	"github.com/huntercenter1/backend-test/order-service/internal/repo"
+	"github.com/huntercenter1/backend-test/order-service/internal/service"
+)
+
+// stubRules devuelve err en todas las operaciones si está puesto.
+type stubRules struct{ err error }
+
+func (s *stubRules) CreateTaxRule(_ context.Context, r *models.TaxRule) (*models.TaxRule, error) {
+	if s.err != nil { return nil, s.err }
+	r.ID = "tr1"
+	return r, nil
+}
+func (s *stubRules) ListTaxRules(context.Context) ([]models.TaxRule, error) { return []models.TaxRule{}, s.err }
+func (s *stubRules) DeleteTaxRule(context.Context, string) error { return s.err }
+func (s *stubRules) CreateShippingRule(_ context.Context, r *models.ShippingRule) (*models.ShippingRule, error) {
+	if s.err != nil { return nil, s.err }
+	r.ID = "shr1"
+	return r, nil
+}
+func (s *stubRules) ListShippingRules(context.Context) ([]models.ShippingRule, error) { return []models.ShippingRule{}, s.err }
+func (s *stubRules) DeleteShippingRule(context.Context, string) error { return s.err }
+
+func TestPricingRuleRoutes(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	rules := &stubRules{}
+	r := gin.New()
+	New(&memSvc{}, Options{PricingRules: rules}).Register(r)
+
+	do := func(method, path, body string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest(method, path, bytes.NewBufferString(body)))
+		return w
+	}
+
+	if w := do(http.MethodPost, "/tax-rules", `{"region":"ES","tax_class":"standard","name":"IVA","rate":21}`); w.Code != http.StatusCreated { t.Fatalf("create tax=%d", w.Code) }
+	if w := do(http.MethodGet, "/tax-rules", ""); w.Code != http.StatusOK || !bytes.Contains(w.Body.Bytes(), []byte(`"tax_rules"`)) { t.Fatalf("list tax=%d %s", w.Code, w.Body) }
+	if w := do(http.MethodDelete, "/tax-rules/tr1", ""); w.Code != http.StatusNoContent { t.Fatalf("delete tax=%d", w.Code) }
+	if w := do(http.MethodPost, "/shipping-rules", `{"region":"*","name":"Estándar","type":"flat","fee":4.95}`); w.Code != http.StatusCreated { t.Fatalf("create shipping=%d", w.Code) }
+	if w := do(http.MethodGet, "/shipping-rules", ""); w.Code != http.StatusOK || !bytes.Contains(w.Body.Bytes(), []byte(`"shipping_rules"`)) { t.Fatalf("list shipping=%d %s", w.Code, w.Body) }
+	if w := do(http.MethodPost, "/tax-rules", `[]`); w.Code != http.StatusBadRequest { t.Fatalf("invalid json=%d", w.Code) }
+
+	for err, want := range map[error]int{
+		repo.ErrNotFound:      http.StatusNotFound,
+		repo.ErrDuplicateRule: http.StatusConflict,
+		fmt.Errorf("%w: name is required", service.ErrInvalidRule): http.StatusBadRequest,
+	} {
+		rules.err = err
+		if w := do(http.MethodPost, "/tax-rules", `{"region":"ES"}`); w.Code != want { t.Fatalf("create with %v = %d, want %d", err, w.Code, want) }
+	}
+	rules.err = repo.ErrNotFound
+	if w := do(http.MethodDelete, "/shipping-rules/x", ""); w.Code != http.StatusNotFound { t.Fatalf("delete missing=%d", w.Code) }
+}
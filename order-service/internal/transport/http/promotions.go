@@ -0,0 +1,57 @@
+package http
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/huntercenter1/backend-test/order-service/internal/models"
+	"github.com/huntercenter1/backend-test/order-service/internal/repo"
+	"github.com/huntercenter1/backend-test/order-service/internal/service"
+)
+
+func (rt *Router) registerPromotions(r *gin.Engine) {
+	r.POST("/promotions", rt.createPromotion)
+	r.GET("/promotions", rt.listPromotions)
+	r.PATCH("/promotions/:id", rt.setPromotionActive)
+}
+
+func promotionError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, repo.ErrNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error":"not found"})
+	case errors.Is(err, repo.ErrDuplicateCode):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+	case errors.Is(err, service.ErrInvalidPromotion):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	}
+}
+
+func (rt *Router) createPromotion(c *gin.Context) {
+	var p models.Promotion
+	if err := c.ShouldBindJSON(&p); err != nil { c.JSON(http.StatusBadRequest, gin.H{"error":"invalid payload"}); return }
+	out, err := rt.promotions.Create(c.Request.Context(), &p)
+	if err != nil { promotionError(c, err); return }
+	c.JSON(http.StatusCreated, out)
+}
+
+func (rt *Router) listPromotions(c *gin.Context) {
+	list, err := rt.promotions.List(c.Request.Context())
+	if err != nil { promotionError(c, err); return }
+	c.JSON(http.StatusOK, gin.H{"promotions": list})
+}
+
+type activeReq struct{ Active *bool `json:"active"` }
+
+// setPromotionActive activa o desactiva la promoción; no se borran para no
+// perder el histórico de canjes.
+func (rt *Router) setPromotionActive(c *gin.Context) {
+	var req activeReq
+	if err := c.ShouldBindJSON(&req); err != nil || req.Active == nil { c.JSON(http.StatusBadRequest, gin.H{"error":"invalid payload"}); return }
+	p, err := rt.promotions.SetActive(c.Request.Context(), c.Param("id"), *req.Active)
+	if err != nil { promotionError(c, err); return }
+	c.JSON(http.StatusOK, p)
+}
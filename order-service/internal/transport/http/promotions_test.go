@@ -0,0 +1,60 @@
+package http
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/huntercenter1/backend-test/order-service/internal/models"
+	"github.com/huntercenter1/backend-test/order-service/internal/repo"
+	"github.com/huntercenter1/backend-test/order-service/internal/service"
+)
+
+// stubPromotions devuelve err en todas las operaciones si está puesto.
+type stubPromotions struct{ err error }
+
+func (s *stubPromotions) Create(_ context.Context, p *models.Promotion) (*models.Promotion, error) {
+	if s.err != nil { return nil, s.err }
+	p.ID, p.Active = "pr1", true
+	return p, nil
+}
+func (s *stubPromotions) List(context.Context) ([]models.Promotion, error) {
+	if s.err != nil { return nil, s.err }
+	return []models.Promotion{{ID: "pr1", Name: "Summer", Type: models.PromoFixed, Value: 5}}, nil
+}
+func (s *stubPromotions) SetActive(_ context.Context, id string, active bool) (*models.Promotion, error) {
+	if s.err != nil { return nil, s.err }
+	return &models.Promotion{ID: id, Active: active}, nil
+}
+
+func TestPromotionRoutes(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	promos := &stubPromotions{}
+	r := gin.New()
+	New(&memSvc{}, Options{Promotions: promos}).Register(r)
+
+	do := func(method, path, body string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest(method, path, bytes.NewBufferString(body)))
+		return w
+	}
+
+	if w := do(http.MethodPost, "/promotions", `{"code":"SUMMER","name":"Summer","type":"fixed","value":5}`); w.Code != http.StatusCreated { t.Fatalf("create=%d", w.Code) }
+	if w := do(http.MethodGet, "/promotions", ""); w.Code != http.StatusOK || !bytes.Contains(w.Body.Bytes(), []byte(`"promotions"`)) { t.Fatalf("list=%d %s", w.Code, w.Body) }
+	if w := do(http.MethodPatch, "/promotions/pr1", `{"active":false}`); w.Code != http.StatusOK || !bytes.Contains(w.Body.Bytes(), []byte(`"active":false`)) { t.Fatalf("patch=%d %s", w.Code, w.Body) }
+	if w := do(http.MethodPatch, "/promotions/pr1", `{}`); w.Code != http.StatusBadRequest { t.Fatalf("patch without active=%d", w.Code) }
+
+	for err, want := range map[error]int{
+		repo.ErrNotFound:      http.StatusNotFound,
+		repo.ErrDuplicateCode: http.StatusConflict,
+		fmt.Errorf("%w: name is required", service.ErrInvalidPromotion): http.StatusBadRequest,
+	} {
+		promos.err = err
+		if w := do(http.MethodPost, "/promotions", `{"name":"x"}`); w.Code != want { t.Fatalf("create with %v = %d, want %d", err, w.Code, want) }
+	}
+}
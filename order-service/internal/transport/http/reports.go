@@ -0,0 +1,96 @@
+package http
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/huntercenter1/backend-test/order-service/internal/models"
+	"github.com/huntercenter1/backend-test/order-service/internal/service"
+)
+
+func (rt *Router) registerReports(r *gin.Engine) {
+	r.GET("/reports/sales", rt.salesReport)
+	r.GET("/reports/products", rt.productsReport)
+	r.GET("/reports/customers", rt.customersReport)
+}
+
+func reportError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, service.ErrInvalidReport):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	}
+}
+
+// reportParams lee la query y el formato: JSON por defecto; ?format=csv o
+// Accept: text/csv piden CSV.
+func reportParams(c *gin.Context) (service.ReportParams, bool, bool) {
+	var p service.ReportParams
+	if err := c.ShouldBindQuery(&p); err != nil { c.JSON(http.StatusBadRequest, gin.H{"error":"invalid query"}); return p, false, false }
+	format := c.Query("format")
+	if format == "" && strings.Contains(c.GetHeader("Accept"), "text/csv") { format = "csv" }
+	if format != "" && format != "json" && format != "csv" { c.JSON(http.StatusBadRequest, gin.H{"error":"format must be json or csv"}); return p, false, false }
+	return p, format == "csv", true
+}
+
+// writeCSV manda el informe como adjunto: name-from-to.csv.
+func writeCSV(c *gin.Context, name string, w service.ReportWindow, rows [][]string) {
+	var b strings.Builder
+	cw := csv.NewWriter(&b)
+	if err := cw.WriteAll(rows); err != nil { c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()}); return }
+	for _, part := range []string{w.From, w.To} { if part != "" { name += "-" + part } }
+	c.Header("Content-Disposition", fmt.Sprintf(`attachment; filename="%s.csv"`, name))
+	c.Data(http.StatusOK, "text/csv; charset=utf-8", []byte(b.String()))
+}
+
+func money(v float64) string { return strconv.FormatFloat(v, 'f', 2, 64) }
+
+func salesColumns(s models.Sales) []string {
+	return []string{strconv.Itoa(s.Orders), money(s.Gross), money(s.Refunded), money(s.Net), money(s.AverageOrderValue)}
+}
+
+func (rt *Router) salesReport(c *gin.Context) {
+	p, asCSV, ok := reportParams(c)
+	if !ok { return }
+	rep, err := rt.reports.Sales(c.Request.Context(), p)
+	if err != nil { reportError(c, err); return }
+	if !asCSV { c.JSON(http.StatusOK, rep); return }
+	rows := [][]string{{"period", "orders", "gross", "refunded", "net", "average_order_value"}}
+	for _, pr := range rep.Periods { rows = append(rows, append([]string{pr.Period}, salesColumns(pr.Sales)...)) }
+	writeCSV(c, "sales-"+rep.Interval, rep.ReportWindow, rows)
+}
+
+func (rt *Router) productsReport(c *gin.Context) {
+	p, asCSV, ok := reportParams(c)
+	if !ok { return }
+	rep, err := rt.reports.TopProducts(c.Request.Context(), p)
+	if err != nil { reportError(c, err); return }
+	if !asCSV { c.JSON(http.StatusOK, rep); return }
+	rows := [][]string{{"product_id", "name", "sku", "quantity", "orders", "revenue"}}
+	for _, ps := range rep.Products {
+		rows = append(rows, []string{ps.ProductID, ps.Name, ps.SKU, strconv.Itoa(ps.Quantity), strconv.Itoa(ps.Orders), money(ps.Revenue)})
+	}
+	writeCSV(c, "products-by-"+rep.By, rep.ReportWindow, rows)
+}
+
+func (rt *Router) customersReport(c *gin.Context) {
+	p, asCSV, ok := reportParams(c)
+	if !ok { return }
+	rep, err := rt.reports.Customers(c.Request.Context(), p)
+	if err != nil { reportError(c, err); return }
+	if !asCSV { c.JSON(http.StatusOK, rep); return }
+	rows := [][]string{{"user_id", "orders", "gross", "refunded", "net", "average_order_value", "first_order_at", "last_order_at"}}
+	for _, cv := range rep.Customers {
+		row := append([]string{cv.UserID}, salesColumns(cv.Sales)...)
+		rows = append(rows, append(row, cv.FirstOrderAt.UTC().Format(time.RFC3339), cv.LastOrderAt.UTC().Format(time.RFC3339)))
+	}
+	writeCSV(c, "customers", rep.ReportWindow, rows)
+}
@@ -0,0 +1,77 @@
+package http
+
+import (
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/huntercenter1/backend-test/order-service/internal/models"
+	"github.com/huntercenter1/backend-test/order-service/internal/service"
+)
+
+// stubReports guarda los parámetros recibidos; interval=bad da ErrInvalidReport.
+type stubReports struct{ got service.ReportParams }
+
+func (s *stubReports) Sales(_ context.Context, p service.ReportParams) (*service.SalesReport, error) {
+	s.got = p
+	if p.Interval == "bad" { return nil, service.ErrInvalidReport }
+	return &service.SalesReport{
+		ReportWindow: service.ReportWindow{From: "2025-08-01", To: "2025-08-02", TZ: "UTC"}, Interval: "day",
+		Periods: []models.SalesPeriod{{Period: "2025-08-01", Sales: models.NewSales(2, 25, 5)}, {Period: "2025-08-02", Sales: models.NewSales(0, 0, 0)}},
+		Totals:  models.NewSales(2, 25, 5),
+	}, nil
+}
+func (s *stubReports) TopProducts(_ context.Context, p service.ReportParams) (*service.ProductsReport, error) {
+	s.got = p
+	return &service.ProductsReport{By: "revenue", Products: []models.ProductSales{{ProductID: "p1", Name: "Taza, grande", Quantity: 3, Orders: 2, Revenue: 30}}}, nil
+}
+func (s *stubReports) Customers(_ context.Context, p service.ReportParams) (*service.CustomersReport, error) {
+	s.got = p
+	at := time.Date(2025, 8, 1, 10, 0, 0, 0, time.UTC)
+	return &service.CustomersReport{Customers: []models.CustomerValue{{UserID: "u1", Sales: models.NewSales(1, 10, 0), FirstOrderAt: at, LastOrderAt: at}}}, nil
+}
+
+func TestReportRoutes(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	stub := &stubReports{}
+	r := gin.New()
+	New(&memSvc{}, Options{Reports: stub}).Register(r)
+	get := func(path, accept string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, path, nil)
+		if accept != "" { req.Header.Set("Accept", accept) }
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		return w
+	}
+
+	w := get("/reports/sales?from=2025-08-01&to=2025-08-02&tz=Europe/Madrid&interval=day", "")
+	var rep service.SalesReport
+	if w.Code != http.StatusOK || json.Unmarshal(w.Body.Bytes(), &rep) != nil || len(rep.Periods) != 2 || rep.Totals.Net != 20 { t.Fatalf("sales: %d %s", w.Code, w.Body) }
+	if stub.got.From != "2025-08-01" || stub.got.TZ != "Europe/Madrid" || stub.got.Interval != "day" { t.Fatalf("params=%+v", stub.got) }
+
+	w = get("/reports/sales?format=csv", "")
+	rows, err := csv.NewReader(w.Body).ReadAll()
+	if w.Code != http.StatusOK || err != nil || len(rows) != 3 { t.Fatalf("csv: %d %v %v", w.Code, err, rows) }
+	if strings.Join(rows[1], ",") != "2025-08-01,2,25.00,5.00,20.00,10.00" { t.Fatalf("row=%v", rows[1]) }
+	if cd := w.Header().Get("Content-Disposition"); cd != `attachment; filename="sales-day-2025-08-01-2025-08-02.csv"` { t.Fatalf("disposition=%q", cd) }
+
+	// Accept: text/csv también vale; los campos con comas van entre comillas
+	w = get("/reports/products?by=revenue&limit=5", "text/csv")
+	if !strings.HasPrefix(w.Header().Get("Content-Type"), "text/csv") || !strings.Contains(w.Body.String(), `p1,"Taza, grande",,3,2,30.00`) || stub.got.Limit != 5 { t.Fatalf("products csv: %s", w.Body) }
+	if w := get("/reports/customers?user_id=u1&format=csv", ""); !strings.Contains(w.Body.String(), "u1,1,10.00,0.00,10.00,10.00,2025-08-01T10:00:00Z") || stub.got.UserID != "u1" { t.Fatalf("customers csv: %s", w.Body) }
+
+	for path, want := range map[string]int{
+		"/reports/sales?interval=bad":   http.StatusBadRequest,
+		"/reports/sales?format=xml":     http.StatusBadRequest,
+		"/reports/customers?limit=many": http.StatusBadRequest,
+	} {
+		if w := get(path, ""); w.Code != want { t.Errorf("%s: code=%d want %d", path, w.Code, want) }
+	}
+}
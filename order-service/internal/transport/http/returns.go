@@ -0,0 +1,74 @@
+package http
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/huntercenter1/backend-test/order-service/internal/repo"
+	"github.com/huntercenter1/backend-test/order-service/internal/service"
+)
+
+func (rt *Router) registerReturns(r *gin.Engine) {
+	r.POST("/orders/:id/returns", rt.createReturn)
+	r.GET("/orders/:id/returns", rt.listReturns)
+	r.POST("/orders/:id/returns/:return_id/approve", rt.approveReturn)
+	r.POST("/orders/:id/returns/:return_id/reject", rt.rejectReturn)
+	r.POST("/orders/:id/returns/:return_id/receive", rt.receiveReturn)
+}
+
+func returnError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, repo.ErrNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error":"not found"})
+	case errors.Is(err, repo.ErrOrderNotReturnable), errors.Is(err, repo.ErrReturnState):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+	case errors.Is(err, service.ErrInvalidReturn), errors.Is(err, repo.ErrReturnQuantity):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	case errors.Is(err, service.ErrProvider):
+		c.JSON(http.StatusBadGateway, gin.H{"error": err.Error()})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	}
+}
+
+func (rt *Router) createReturn(c *gin.Context) {
+	var req service.ReturnRequest
+	if err := c.ShouldBindJSON(&req); err != nil { c.JSON(http.StatusBadRequest, gin.H{"error":"invalid payload"}); return }
+	ret, err := rt.returns.Create(c.Request.Context(), c.Param("id"), req)
+	if err != nil { returnError(c, err); return }
+	c.JSON(http.StatusCreated, ret)
+}
+
+func (rt *Router) listReturns(c *gin.Context) {
+	res, err := rt.returns.List(c.Request.Context(), c.Param("id"))
+	if err != nil { returnError(c, err); return }
+	c.JSON(http.StatusOK, res)
+}
+
+func (rt *Router) approveReturn(c *gin.Context) {
+	ret, err := rt.returns.Approve(c.Request.Context(), c.Param("id"), c.Param("return_id"))
+	if err != nil { returnError(c, err); return }
+	c.JSON(http.StatusOK, ret)
+}
+
+type rejectReq struct{ Note string `json:"note"` }
+
+func (rt *Router) rejectReturn(c *gin.Context) {
+	var req rejectReq
+	if c.Request.ContentLength != 0 {
+		if err := c.ShouldBindJSON(&req); err != nil { c.JSON(http.StatusBadRequest, gin.H{"error":"invalid payload"}); return }
+	}
+	ret, err := rt.returns.Reject(c.Request.Context(), c.Param("id"), c.Param("return_id"), req.Note)
+	if err != nil { returnError(c, err); return }
+	c.JSON(http.StatusOK, ret)
+}
+
+// receiveReturn: con 502 la devolución ya está recibida y el stock repuesto;
+// el reembolso pendiente se hace a mano con POST /orders/:id/payments/:payment_id/refunds.
+func (rt *Router) receiveReturn(c *gin.Context) {
+	ret, err := rt.returns.Receive(c.Request.Context(), c.Param("id"), c.Param("return_id"))
+	if err != nil { returnError(c, err); return }
+	c.JSON(http.StatusOK, ret)
+}
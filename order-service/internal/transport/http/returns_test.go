@@ -0,0 +1,70 @@
+package http
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/huntercenter1/backend-test/order-service/internal/models"
+	"github.com/huntercenter1/backend-test/order-service/internal/repo"
+	"github.com/huntercenter1/backend-test/order-service/internal/service"
+)
+
+// stubReturns devuelve err en todas las operaciones si está puesto.
+type stubReturns struct {
+	err  error
+	note string
+}
+
+func (s *stubReturns) ret(id, status string) (*models.Return, error) {
+	if s.err != nil { return nil, s.err }
+	return &models.Return{ID: id, OrderID: "o1", Status: status, Items: []models.ReturnItem{}}, nil
+}
+func (s *stubReturns) Create(_ context.Context, orderID string, req service.ReturnRequest) (*models.Return, error) { return s.ret("rt1", models.ReturnRequested) }
+func (s *stubReturns) List(_ context.Context, orderID string) (*service.OrderReturns, error) {
+	if s.err != nil { return nil, s.err }
+	return &service.OrderReturns{Returns: []models.Return{}, Total: 50, RefundedTotal: 20, NetTotal: 30}, nil
+}
+func (s *stubReturns) Approve(_ context.Context, orderID, id string) (*models.Return, error) { return s.ret(id, models.ReturnApproved) }
+func (s *stubReturns) Reject(_ context.Context, orderID, id, note string) (*models.Return, error) {
+	s.note = note
+	return s.ret(id, models.ReturnRejected)
+}
+func (s *stubReturns) Receive(_ context.Context, orderID, id string) (*models.Return, error) { return s.ret(id, models.ReturnReceived) }
+
+func TestReturnRoutes(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	rets := &stubReturns{}
+	r := gin.New()
+	New(&memSvc{}, Options{Returns: rets}).Register(r)
+
+	do := func(method, path, body string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest(method, path, bytes.NewBufferString(body)))
+		return w
+	}
+
+	if w := do(http.MethodPost, "/orders/o1/returns", `{"reason":"damaged","items":[{"order_item_id":"i1","quantity":1}]}`); w.Code != http.StatusCreated { t.Fatalf("create=%d", w.Code) }
+	if w := do(http.MethodGet, "/orders/o1/returns", ""); w.Code != http.StatusOK || !bytes.Contains(w.Body.Bytes(), []byte(`"net_total":30`)) { t.Fatalf("list=%d %s", w.Code, w.Body) }
+	if w := do(http.MethodPost, "/orders/o1/returns/rt1/approve", ""); w.Code != http.StatusOK { t.Fatalf("approve=%d", w.Code) }
+	if w := do(http.MethodPost, "/orders/o1/returns/rt1/reject", ""); w.Code != http.StatusOK { t.Fatalf("reject without body=%d", w.Code) }
+	if w := do(http.MethodPost, "/orders/o1/returns/rt1/reject", `{"note":"used"}`); w.Code != http.StatusOK || rets.note != "used" { t.Fatalf("reject=%d note=%q", w.Code, rets.note) }
+	if w := do(http.MethodPost, "/orders/o1/returns/rt1/receive", ""); w.Code != http.StatusOK { t.Fatalf("receive=%d", w.Code) }
+
+	for err, want := range map[error]int{
+		repo.ErrNotFound:           http.StatusNotFound,
+		repo.ErrOrderNotReturnable: http.StatusConflict,
+		repo.ErrReturnState:        http.StatusConflict,
+		repo.ErrReturnQuantity:     http.StatusBadRequest,
+		service.ErrInvalidReturn:   http.StatusBadRequest,
+		fmt.Errorf("%w: timeout", service.ErrProvider): http.StatusBadGateway,
+	} {
+		rets.err = err
+		if w := do(http.MethodPost, "/orders/o1/returns/rt1/receive", ""); w.Code != want { t.Fatalf("receive with %v = %d, want %d", err, w.Code, want) }
+	}
+}
@@ -1,39 +1,111 @@
 package http
 
 import (
+	"errors"
+	"log/slog"
 	"net/http"
+	"time"
 
 	"github.com/gin-gonic/gin"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"go.opentelemetry.io/contrib/instrumentation/github.com/gin-gonic/gin/otelgin"
 
+	"github.com/huntercenter1/backend-test/platform/health"
+	"github.com/huntercenter1/backend-test/platform/metrics"
+	"github.com/huntercenter1/backend-test/platform/middleware"
+	"github.com/huntercenter1/backend-test/platform/ratelimit"
+
+	"github.com/huntercenter1/backend-test/order-service/internal/repo"
 	"github.com/huntercenter1/backend-test/order-service/internal/service"
 )
 
+// DefaultRequestTimeout acota cada petición; su deadline se propaga a las
+// llamadas a user-service y product-service.
+const DefaultRequestTimeout = 10 * time.Second
+
+// ProbeRoutes nunca se limitan: las consultan el orquestador y Prometheus.
+var ProbeRoutes = []string{"GET /health", "GET /livez", "GET /readyz", "GET /metrics"}
+
 type Router struct {
-	svc service.Service
+	svc        service.Service
+	carts      service.CartService
+	payments   service.PaymentService
+	shipments  service.ShipmentService
+	returns    service.ReturnService
+	promotions service.PromotionService
+	rules      service.PricingRuleService
+	invoices   service.InvoiceService
+	expiry     service.ExpiryService
+	reports    service.ReportService
+	ready      *health.Checker
+	timeout    time.Duration
+	limiter    *ratelimit.Limiter
+}
+
+type Options struct {
+	Checks         []health.Check             // dependencias que debe comprobar /readyz
+	RequestTimeout time.Duration              // 0 = DefaultRequestTimeout
+	RateLimit      *ratelimit.Limiter         // nil = sin límite
+	Carts          service.CartService        // nil = sin /carts
+	Payments       service.PaymentService     // nil = sin pagos
+	Shipments      service.ShipmentService    // nil = sin envíos
+	Returns        service.ReturnService      // nil = sin devoluciones
+	Promotions     service.PromotionService   // nil = sin /promotions
+	PricingRules   service.PricingRuleService // nil = sin /tax-rules ni /shipping-rules
+	Invoices       service.InvoiceService     // nil = sin /orders/:id/invoice
+	Expiry         service.ExpiryService      // nil = worker de caducidad desactivado
+	Reports        service.ReportService      // nil = sin /reports
 }
 
-func New(svc service.Service) *Router { return &Router{svc: svc} }
+func New(svc service.Service, opts Options) *Router {
+	if opts.RequestTimeout <= 0 { opts.RequestTimeout = DefaultRequestTimeout }
+	return &Router{svc: svc, carts: opts.Carts, payments: opts.Payments, shipments: opts.Shipments, returns: opts.Returns, promotions: opts.Promotions, rules: opts.PricingRules, invoices: opts.Invoices, expiry: opts.Expiry, reports: opts.Reports, ready: health.NewChecker(2*time.Second, opts.Checks...), timeout: opts.RequestTimeout, limiter: opts.RateLimit}
+}
 
 func (rt *Router) Register(r *gin.Engine) {
-	r.GET("/health", func(c *gin.Context){ c.JSON(http.StatusOK, gin.H{"status":"ok"}) })
+	r.Use(otelgin.Middleware("order-service"), metrics.HTTP(), middleware.RequestID(), middleware.AccessLog(slog.Default()), middleware.Recovery(slog.Default()), middleware.Timeout(rt.timeout))
+	if rt.limiter != nil { r.Use(rt.limiter.Gin()) }
+
+	r.GET("/health", rt.livez) // compatibilidad: igual que /livez
+	r.GET("/livez", rt.livez)
+	r.GET("/readyz", rt.readyz)
+	r.GET("/metrics", gin.WrapH(promhttp.Handler()))
 	r.POST("/orders", rt.create)
 	r.GET("/orders/:id", rt.get)
 	r.GET("/orders/:id/items", rt.items)
 	r.GET("/orders/user/:user_id", rt.byUser)
 	r.PUT("/orders/:id/status", rt.updateStatus)
+	if rt.carts != nil { rt.registerCarts(r) }
+	if rt.payments != nil { rt.registerPayments(r) }
+	if rt.shipments != nil { rt.registerShipments(r) }
+	if rt.returns != nil { rt.registerReturns(r) }
+	if rt.promotions != nil { rt.registerPromotions(r) }
+	if rt.rules != nil { rt.registerPricingRules(r) }
+	if rt.invoices != nil { rt.registerInvoices(r) }
+	if rt.expiry != nil { rt.registerExpiry(r) }
+	if rt.reports != nil { rt.registerReports(r) }
 }
 
-type createReq struct {
-	UserID string               `json:"user_id"`
-	Items  []service.CreateItem `json:"items"`
+// livez sólo indica que el proceso responde; las dependencias van en readyz.
+func (rt *Router) livez(c *gin.Context) { c.JSON(http.StatusOK, gin.H{"status": health.StatusOK}) }
+
+func (rt *Router) readyz(c *gin.Context) {
+	rep := rt.ready.Run(c.Request.Context())
+	code := http.StatusOK
+	if !rep.OK() { code = http.StatusServiceUnavailable }
+	c.JSON(code, rep)
 }
 
 func (rt *Router) create(c *gin.Context) {
-	var req createReq
+	var req service.CreateRequest
 	if err := c.ShouldBindJSON(&req); err != nil || req.UserID == "" || len(req.Items) == 0 {
 		c.JSON(http.StatusBadRequest, gin.H{"error":"invalid payload"}); return
 	}
-	o, items, err := rt.svc.Create(c.Request.Context(), req.UserID, req.Items)
+	o, items, err := rt.svc.Create(c.Request.Context(), req)
+	// otro pedido agotó la promoción entre el cálculo y el canje
+	if errors.Is(err, repo.ErrPromotionExhausted) { c.JSON(http.StatusConflict, gin.H{"error": err.Error()}); return }
+	// el pedido se canceló sin vender nada; el cliente puede reintentar
+	if errors.Is(err, service.ErrStockUpdate) { c.JSON(http.StatusServiceUnavailable, gin.H{"error": err.Error()}); return }
 	if err != nil { c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()}); return }
 	c.JSON(http.StatusCreated, gin.H{"order": o, "items": items})
 }
@@ -64,6 +136,15 @@ func (rt *Router) updateStatus(c *gin.Context) {
 		c.JSON(http.StatusBadRequest, gin.H{"error":"invalid body"}); return
 	}
 	o, err := rt.svc.UpdateStatus(c.Request.Context(), c.Param("id"), body.Status)
-	if err != nil { c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()}); return }
+	switch {
+	case errors.Is(err, repo.ErrNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error":"not found"}); return
+	case errors.Is(err, service.ErrInvalidStatus):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()}); return
+	case errors.Is(err, repo.ErrOrderState):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()}); return
+	case err != nil:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()}); return
+	}
 	c.JSON(http.StatusOK, o)
 }
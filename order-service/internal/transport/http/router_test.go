@@ -4,12 +4,18 @@ import (
 	"bytes"
 	"context"
 	"encoding/json"
+	"errors"
 	"net/http"
 	"net/http/httptest"
 	"testing"
 
 	"github.com/gin-gonic/gin"
+
+	"github.com/huntercenter1/backend-test/platform/health"
+	"github.com/huntercenter1/backend-test/platform/ratelimit"
+
 	"github.com/huntercenter1/backend-test/order-service/internal/models"
+	"github.com/huntercenter1/backend-test/order-service/internal/repo"
 	"github.com/huntercenter1/backend-test/order-service/internal/service"
 )
 
@@ -18,21 +24,27 @@ type memSvc struct{
 	it []models.OrderItem
 	byUser []models.Order
 }
-func (m *memSvc) Create(_ context.Context, userID string, items []service.CreateItem) (*models.Order, []models.OrderItem, error) {
-	m.o = &models.Order{ID:"o1", UserID:userID, Status:"pending", Total:100}
-	m.it = []models.OrderItem{{ID:"i1", OrderID:"o1", ProductID:items[0].ProductID, Quantity:items[0].Quantity, Price:100}}
+func (m *memSvc) Create(_ context.Context, req service.CreateRequest) (*models.Order, []models.OrderItem, error) {
+	m.o = &models.Order{ID:"o1", UserID:req.UserID, Status:"pending", Total:100}
+	m.it = []models.OrderItem{{ID:"i1", OrderID:"o1", ProductID:req.Items[0].ProductID, Quantity:req.Items[0].Quantity, Price:100,
+		ProductSnapshot: models.ProductSnapshot{Name:"Mouse", SKU:"MS-1"}}}
 	return m.o, m.it, nil
 }
-func (m *memSvc) Get(_ context.Context, id string) (*models.Order, error) { return m.o, nil }
+func (m *memSvc) Get(_ context.Context, id string) (*models.Order, error) { o := *m.o; o.Items = m.it; return &o, nil }
 func (m *memSvc) Items(_ context.Context, id string) ([]models.OrderItem, error) { return m.it, nil }
 func (m *memSvc) ByUser(_ context.Context, userID string) ([]models.Order, error) { if m.o!=nil { m.byUser = []models.Order{*m.o} }; return m.byUser, nil }
-func (m *memSvc) UpdateStatus(_ context.Context, id, status string) (*models.Order, error) { m.o.Status = status; return m.o, nil }
+func (m *memSvc) UpdateStatus(_ context.Context, id, status string) (*models.Order, error) {
+	if m.o == nil || id != m.o.ID { return nil, repo.ErrNotFound }
+	if status != models.OrderCancelled { return nil, service.ErrInvalidStatus }
+	m.o.Status = status
+	return m.o, nil
+}
 
 func setupOrderRouter() (*gin.Engine, *Router, *memSvc) {
 	gin.SetMode(gin.TestMode)
 	r := gin.New()
 	s := &memSvc{}
-	rt := New(s)
+	rt := New(s, Options{})
 	rt.Register(r)
 	return r, rt, s
 }
@@ -52,6 +64,9 @@ func TestOrderHandlers(t *testing.T){
 	w = httptest.NewRecorder()
 	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/orders/o1", nil))
 	if w.Code != http.StatusOK { t.Fatalf("get code=%d", w.Code) }
+	var got models.Order
+	_ = json.Unmarshal(w.Body.Bytes(), &got)
+	if len(got.Items) != 1 || got.Items[0].Name != "Mouse" || got.Items[0].SKU != "MS-1" { t.Fatalf("items not embedded: %s", w.Body.String()) }
 
 	// items
 	w = httptest.NewRecorder()
@@ -68,11 +83,19 @@ func TestOrderHandlers(t *testing.T){
 
 	// update status
 	w = httptest.NewRecorder()
-	req = httptest.NewRequest(http.MethodPut, "/orders/o1/status", bytes.NewReader([]byte(`{"status":"paid"}`)))
+	req = httptest.NewRequest(http.MethodPut, "/orders/o1/status", bytes.NewReader([]byte(`{"status":"cancelled"}`)))
 	req.Header.Set("Content-Type","application/json")
 	r.ServeHTTP(w, req)
 	if w.Code != http.StatusOK { t.Fatalf("status code=%d", w.Code) }
-	if s.o.Status != "paid" { t.Fatalf("status not updated") }
+	if s.o.Status != "cancelled" { t.Fatalf("status not updated") }
+
+	// pagado, enviado... no se fijan a mano; un pedido inexistente es 404
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodPut, "/orders/o1/status", bytes.NewReader([]byte(`{"status":"paid"}`))))
+	if w.Code != http.StatusBadRequest { t.Fatalf("manual paid code=%d", w.Code) }
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodPut, "/orders/nope/status", bytes.NewReader([]byte(`{"status":"cancelled"}`))))
+	if w.Code != http.StatusNotFound { t.Fatalf("unknown order code=%d", w.Code) }
 }
 
 func TestOrderBadRequests(t *testing.T){
@@ -88,3 +111,48 @@ func TestOrderBadRequests(t *testing.T){
 	r.ServeHTTP(w, httptest.NewRequest(http.MethodPut, "/orders/o1/status", bytes.NewReader([]byte(`{}`))))
 	if w.Code != http.StatusBadRequest { t.Fatalf("bad status code=%d", w.Code) }
 }
+
+func TestReadyzReportsDependencies(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	New(&memSvc{}, Options{Checks: []health.Check{
+		health.Ping("user-service", func(context.Context) error { return nil }),
+		health.Ping("product-service", func(context.Context) error { return errors.New("connection refused") }),
+	}}).Register(r)
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/readyz", nil))
+	if w.Code != http.StatusServiceUnavailable { t.Fatalf("readyz code=%d", w.Code) }
+	var rep health.Report
+	if err := json.Unmarshal(w.Body.Bytes(), &rep); err != nil { t.Fatal(err) }
+	if len(rep.Checks) != 2 || rep.Checks[0].Status != health.StatusOK || rep.Checks[1].Error != "connection refused" {
+		t.Fatalf("unexpected report: %+v", rep)
+	}
+
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/livez", nil))
+	if w.Code != http.StatusOK { t.Fatalf("livez code=%d", w.Code) }
+}
+
+func TestCreateIsRateLimited(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	policy, err := ratelimit.Config{Enabled: true, Default: "100/m", Routes: "POST /orders=1/m"}.Policy()
+	if err != nil { t.Fatal(err) }
+	policy.Exempt = ProbeRoutes
+	r := gin.New()
+	New(&memSvc{}, Options{RateLimit: ratelimit.New(ratelimit.NewMemoryStore(), policy, nil)}).Register(r)
+
+	post := func() *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		body := `{"user_id":"u1","items":[{"product_id":"p1","quantity":1}]}`
+		r.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/orders", bytes.NewBufferString(body)))
+		return w
+	}
+	if w := post(); w.Code != http.StatusCreated { t.Fatalf("first create: %d %s", w.Code, w.Body) }
+	w := post()
+	if w.Code != http.StatusTooManyRequests || w.Header().Get(ratelimit.HeaderRetryAfter) == "" { t.Fatalf("second create: %d %v", w.Code, w.Header()) }
+
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/livez", nil))
+	if w.Code != http.StatusOK || w.Header().Get(ratelimit.HeaderLimit) != "" { t.Fatalf("probes must not be limited: %d %v", w.Code, w.Header()) }
+}
@@ -0,0 +1,52 @@
+package http
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/huntercenter1/backend-test/order-service/internal/repo"
+	"github.com/huntercenter1/backend-test/order-service/internal/service"
+)
+
+func (rt *Router) registerShipments(r *gin.Engine) {
+	r.POST("/orders/:id/shipments", rt.createShipment)
+	r.GET("/orders/:id/shipments", rt.listShipments)
+	r.PATCH("/orders/:id/shipments/:shipment_id", rt.updateShipment)
+}
+
+func shipmentError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, repo.ErrNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error":"not found"})
+	case errors.Is(err, repo.ErrOrderNotShippable), errors.Is(err, repo.ErrNothingToShip), errors.Is(err, service.ErrShipmentState):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+	case errors.Is(err, service.ErrInvalidShipment), errors.Is(err, service.ErrShipmentStatus), errors.Is(err, repo.ErrShipmentQuantity):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	}
+}
+
+func (rt *Router) createShipment(c *gin.Context) {
+	var req service.ShipmentRequest
+	if err := c.ShouldBindJSON(&req); err != nil { c.JSON(http.StatusBadRequest, gin.H{"error":"invalid payload"}); return }
+	sh, err := rt.shipments.Create(c.Request.Context(), c.Param("id"), req)
+	if err != nil { shipmentError(c, err); return }
+	c.JSON(http.StatusCreated, sh)
+}
+
+func (rt *Router) listShipments(c *gin.Context) {
+	list, err := rt.shipments.List(c.Request.Context(), c.Param("id"))
+	if err != nil { shipmentError(c, err); return }
+	c.JSON(http.StatusOK, list)
+}
+
+func (rt *Router) updateShipment(c *gin.Context) {
+	var req service.ShipmentUpdate
+	if err := c.ShouldBindJSON(&req); err != nil { c.JSON(http.StatusBadRequest, gin.H{"error":"invalid payload"}); return }
+	sh, err := rt.shipments.Update(c.Request.Context(), c.Param("id"), c.Param("shipment_id"), req)
+	if err != nil { shipmentError(c, err); return }
+	c.JSON(http.StatusOK, sh)
+}
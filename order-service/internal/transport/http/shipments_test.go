@@ -0,0 +1,63 @@
+package http
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/huntercenter1/backend-test/order-service/internal/models"
+	"github.com/huntercenter1/backend-test/order-service/internal/repo"
+	"github.com/huntercenter1/backend-test/order-service/internal/service"
+)
+
+// stubShipments devuelve err en todas las operaciones si está puesto.
+type stubShipments struct {
+	err    error
+	update service.ShipmentUpdate
+}
+
+func (s *stubShipments) Create(_ context.Context, orderID string, req service.ShipmentRequest) (*models.Shipment, error) {
+	if s.err != nil { return nil, s.err }
+	return &models.Shipment{ID: "sh1", OrderID: orderID, Carrier: req.Carrier, Status: models.ShipmentPending}, nil
+}
+func (s *stubShipments) List(_ context.Context, orderID string) ([]models.Shipment, error) { return []models.Shipment{}, s.err }
+func (s *stubShipments) Update(_ context.Context, orderID, shipmentID string, req service.ShipmentUpdate) (*models.Shipment, error) {
+	s.update = req
+	if s.err != nil { return nil, s.err }
+	return &models.Shipment{ID: shipmentID, OrderID: orderID, Status: req.Status}, nil
+}
+
+func TestShipmentRoutes(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	ships := &stubShipments{}
+	r := gin.New()
+	New(&memSvc{}, Options{Shipments: ships}).Register(r)
+
+	do := func(method, path, body string) int {
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest(method, path, bytes.NewBufferString(body)))
+		return w.Code
+	}
+
+	if code := do(http.MethodPost, "/orders/o1/shipments", `{"carrier":"SEUR","items":[{"order_item_id":"i1","quantity":1}]}`); code != http.StatusCreated { t.Fatalf("create=%d", code) }
+	if code := do(http.MethodPost, "/orders/o1/shipments", `{"items":"x"}`); code != http.StatusBadRequest { t.Fatalf("bad payload=%d", code) }
+	if code := do(http.MethodGet, "/orders/o1/shipments", ""); code != http.StatusOK { t.Fatalf("list=%d", code) }
+	if code := do(http.MethodPatch, "/orders/o1/shipments/sh1", `{"status":"shipped","tracking_number":"TRK1"}`); code != http.StatusOK { t.Fatalf("update=%d", code) }
+	if ships.update.Status != "shipped" || ships.update.TrackingNumber == nil || *ships.update.TrackingNumber != "TRK1" || ships.update.Carrier != nil { t.Fatalf("update=%+v", ships.update) }
+
+	for err, want := range map[error]int{
+		repo.ErrNotFound:          http.StatusNotFound,
+		repo.ErrOrderNotShippable: http.StatusConflict,
+		repo.ErrNothingToShip:     http.StatusConflict,
+		repo.ErrShipmentQuantity:  http.StatusBadRequest,
+		service.ErrShipmentStatus: http.StatusBadRequest,
+		service.ErrShipmentState:  http.StatusConflict,
+	} {
+		ships.err = err
+		if code := do(http.MethodPatch, "/orders/o1/shipments/sh1", `{"status":"delivered"}`); code != want { t.Fatalf("update with %v = %d, want %d", err, code, want) }
+	}
+}
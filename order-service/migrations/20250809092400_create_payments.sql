@@ -0,0 +1,42 @@
+-- +goose Up
+CREATE TABLE IF NOT EXISTS payments (
+  id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+  order_id UUID NOT NULL REFERENCES orders(id),
+  provider VARCHAR(30) NOT NULL,
+  provider_ref VARCHAR(100) UNIQUE,
+  method VARCHAR(100) NOT NULL,
+  capture_method VARCHAR(20) NOT NULL,
+  status VARCHAR(20) NOT NULL DEFAULT 'pending',
+  currency CHAR(3) NOT NULL,
+  amount NUMERIC(10,2) NOT NULL CHECK (amount > 0),
+  captured_amount NUMERIC(10,2) NOT NULL DEFAULT 0 CHECK (captured_amount <= amount),
+  refunded_amount NUMERIC(10,2) NOT NULL DEFAULT 0 CHECK (refunded_amount <= captured_amount),
+  failure_reason TEXT,
+  created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+  updated_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+CREATE INDEX IF NOT EXISTS idx_payments_order_id ON payments(order_id);
+
+CREATE TABLE IF NOT EXISTS refunds (
+  id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+  payment_id UUID NOT NULL REFERENCES payments(id),
+  order_id UUID NOT NULL REFERENCES orders(id),
+  amount NUMERIC(10,2) NOT NULL CHECK (amount > 0),
+  provider_ref VARCHAR(100),
+  reason TEXT,
+  created_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+CREATE INDEX IF NOT EXISTS idx_refunds_order_id ON refunds(order_id);
+
+CREATE TABLE IF NOT EXISTS payment_events (
+  id VARCHAR(100) PRIMARY KEY,
+  type VARCHAR(50) NOT NULL,
+  provider_ref VARCHAR(100) NOT NULL,
+  payload JSONB NOT NULL,
+  received_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+-- +goose Down
+DROP TABLE IF EXISTS payment_events;
+DROP TABLE IF EXISTS refunds;
+DROP TABLE IF EXISTS payments;
@@ -0,0 +1,26 @@
+-- +goose Up
+CREATE TABLE IF NOT EXISTS shipments (
+  id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+  order_id UUID NOT NULL REFERENCES orders(id),
+  carrier VARCHAR(50) NOT NULL,
+  tracking_number VARCHAR(100),
+  status VARCHAR(20) NOT NULL DEFAULT 'pending',
+  shipped_at TIMESTAMPTZ,
+  delivered_at TIMESTAMPTZ,
+  created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+  updated_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+CREATE INDEX IF NOT EXISTS idx_shipments_order_id ON shipments(order_id);
+
+CREATE TABLE IF NOT EXISTS shipment_items (
+  id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+  shipment_id UUID NOT NULL REFERENCES shipments(id) ON DELETE CASCADE,
+  order_item_id UUID NOT NULL REFERENCES order_items(id),
+  quantity INT NOT NULL CHECK (quantity > 0),
+  UNIQUE (shipment_id, order_item_id)
+);
+CREATE INDEX IF NOT EXISTS idx_shipment_items_order_item_id ON shipment_items(order_item_id);
+
+-- +goose Down
+DROP TABLE IF EXISTS shipment_items;
+DROP TABLE IF EXISTS shipments;
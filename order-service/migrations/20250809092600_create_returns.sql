@@ -0,0 +1,37 @@
+-- +goose Up
+CREATE TABLE IF NOT EXISTS returns (
+  id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+  order_id UUID NOT NULL REFERENCES orders(id),
+  status VARCHAR(20) NOT NULL DEFAULT 'requested',
+  note TEXT,
+  refunded_amount NUMERIC(10,2) NOT NULL DEFAULT 0,
+  approved_at TIMESTAMPTZ,
+  received_at TIMESTAMPTZ,
+  rejected_at TIMESTAMPTZ,
+  created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+  updated_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+CREATE INDEX IF NOT EXISTS idx_returns_order_id ON returns(order_id);
+
+CREATE TABLE IF NOT EXISTS return_items (
+  id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+  return_id UUID NOT NULL REFERENCES returns(id) ON DELETE CASCADE,
+  order_item_id UUID NOT NULL REFERENCES order_items(id),
+  product_id UUID NOT NULL,
+  quantity INT NOT NULL CHECK (quantity > 0),
+  unit_price NUMERIC(10,2) NOT NULL,
+  reason VARCHAR(30) NOT NULL,
+  restocked BOOLEAN NOT NULL DEFAULT false,
+  UNIQUE (return_id, order_item_id)
+);
+
+-- los reembolsos de una devolución apuntan a ella
+ALTER TABLE refunds ADD COLUMN IF NOT EXISTS return_id UUID REFERENCES returns(id);
+-- lo devuelto de cada pedido (suma de refunded_amount de sus pagos)
+ALTER TABLE orders ADD COLUMN IF NOT EXISTS refunded_total NUMERIC(10,2) NOT NULL DEFAULT 0;
+
+-- +goose Down
+ALTER TABLE orders DROP COLUMN IF EXISTS refunded_total;
+ALTER TABLE refunds DROP COLUMN IF EXISTS return_id;
+DROP TABLE IF EXISTS return_items;
+DROP TABLE IF EXISTS returns;
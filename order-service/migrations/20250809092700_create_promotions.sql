@@ -0,0 +1,54 @@
+-- +goose Up
+CREATE TABLE IF NOT EXISTS promotions (
+  id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+  code VARCHAR(50),
+  name VARCHAR(200) NOT NULL,
+  type VARCHAR(20) NOT NULL CHECK (type IN ('percentage', 'fixed', 'buy_x_get_y', 'free_item')),
+  value NUMERIC(10,2) NOT NULL DEFAULT 0,
+  product_id UUID,
+  buy_quantity INT NOT NULL DEFAULT 0,
+  get_quantity INT NOT NULL DEFAULT 0,
+  free_product_id UUID,
+  free_quantity INT NOT NULL DEFAULT 0,
+  min_spend NUMERIC(10,2) NOT NULL DEFAULT 0,
+  starts_at TIMESTAMPTZ,
+  ends_at TIMESTAMPTZ,
+  max_uses INT,
+  max_uses_per_user INT,
+  uses INT NOT NULL DEFAULT 0 CHECK (max_uses IS NULL OR uses <= max_uses),
+  active BOOLEAN NOT NULL DEFAULT true,
+  created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+  updated_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_promotions_code ON promotions(code) WHERE code IS NOT NULL;
+-- las automáticas activas se leen en cada pedido
+CREATE INDEX IF NOT EXISTS idx_promotions_automatic ON promotions(active) WHERE code IS NULL;
+
+CREATE TABLE IF NOT EXISTS promotion_redemptions (
+  id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+  promotion_id UUID NOT NULL REFERENCES promotions(id),
+  order_id UUID NOT NULL REFERENCES orders(id),
+  user_id UUID NOT NULL,
+  created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+  UNIQUE (promotion_id, order_id)
+);
+CREATE INDEX IF NOT EXISTS idx_promotion_redemptions_user ON promotion_redemptions(promotion_id, user_id);
+
+-- desglose del total: subtotal - discount_total = total
+ALTER TABLE orders ADD COLUMN IF NOT EXISTS coupon_code VARCHAR(50);
+ALTER TABLE orders ADD COLUMN IF NOT EXISTS subtotal NUMERIC(10,2) NOT NULL DEFAULT 0;
+ALTER TABLE orders ADD COLUMN IF NOT EXISTS discount_total NUMERIC(10,2) NOT NULL DEFAULT 0;
+ALTER TABLE orders ADD COLUMN IF NOT EXISTS discounts JSONB NOT NULL DEFAULT '[]';
+UPDATE orders SET subtotal = total WHERE subtotal = 0;
+ALTER TABLE order_items ADD COLUMN IF NOT EXISTS discount NUMERIC(10,2) NOT NULL DEFAULT 0;
+ALTER TABLE order_items ADD COLUMN IF NOT EXISTS gift BOOLEAN NOT NULL DEFAULT false;
+
+-- +goose Down
+ALTER TABLE order_items DROP COLUMN IF EXISTS gift;
+ALTER TABLE order_items DROP COLUMN IF EXISTS discount;
+ALTER TABLE orders DROP COLUMN IF EXISTS discounts;
+ALTER TABLE orders DROP COLUMN IF EXISTS discount_total;
+ALTER TABLE orders DROP COLUMN IF EXISTS subtotal;
+ALTER TABLE orders DROP COLUMN IF EXISTS coupon_code;
+DROP TABLE IF EXISTS promotion_redemptions;
+DROP TABLE IF EXISTS promotions;
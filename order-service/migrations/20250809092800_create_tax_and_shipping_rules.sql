@@ -0,0 +1,42 @@
+-- +goose Up
+CREATE TABLE IF NOT EXISTS tax_rules (
+  id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+  region VARCHAR(16) NOT NULL,
+  tax_class VARCHAR(32) NOT NULL,
+  name VARCHAR(100) NOT NULL,
+  rate NUMERIC(6,3) NOT NULL CHECK (rate >= 0 AND rate <= 100),
+  created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+  UNIQUE (region, tax_class)
+);
+
+CREATE TABLE IF NOT EXISTS shipping_rules (
+  id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+  region VARCHAR(16) NOT NULL,
+  name VARCHAR(100) NOT NULL,
+  type VARCHAR(20) NOT NULL CHECK (type IN ('flat', 'weight', 'free_over')),
+  fee NUMERIC(10,2) NOT NULL DEFAULT 0 CHECK (fee >= 0),
+  per_kg NUMERIC(10,2) NOT NULL DEFAULT 0 CHECK (per_kg >= 0),
+  threshold NUMERIC(10,2) NOT NULL DEFAULT 0 CHECK (threshold >= 0),
+  created_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+CREATE INDEX IF NOT EXISTS idx_shipping_rules_region ON shipping_rules(region);
+
+-- desglose del total: subtotal - discount_total + tax_total + shipping_total = total
+ALTER TABLE orders ADD COLUMN IF NOT EXISTS region VARCHAR(16);
+ALTER TABLE orders ADD COLUMN IF NOT EXISTS tax_total NUMERIC(10,2) NOT NULL DEFAULT 0;
+ALTER TABLE orders ADD COLUMN IF NOT EXISTS shipping_total NUMERIC(10,2) NOT NULL DEFAULT 0;
+ALTER TABLE order_items ADD COLUMN IF NOT EXISTS tax_class VARCHAR(32);
+ALTER TABLE order_items ADD COLUMN IF NOT EXISTS tax_rate NUMERIC(6,3) NOT NULL DEFAULT 0;
+ALTER TABLE order_items ADD COLUMN IF NOT EXISTS tax NUMERIC(10,2) NOT NULL DEFAULT 0;
+ALTER TABLE order_items ADD COLUMN IF NOT EXISTS shipping NUMERIC(10,2) NOT NULL DEFAULT 0;
+
+-- +goose Down
+ALTER TABLE order_items DROP COLUMN IF EXISTS shipping;
+ALTER TABLE order_items DROP COLUMN IF EXISTS tax;
+ALTER TABLE order_items DROP COLUMN IF EXISTS tax_rate;
+ALTER TABLE order_items DROP COLUMN IF EXISTS tax_class;
+ALTER TABLE orders DROP COLUMN IF EXISTS shipping_total;
+ALTER TABLE orders DROP COLUMN IF EXISTS tax_total;
+ALTER TABLE orders DROP COLUMN IF EXISTS region;
+DROP TABLE IF EXISTS shipping_rules;
+DROP TABLE IF EXISTS tax_rules;
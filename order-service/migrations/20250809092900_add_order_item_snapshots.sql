@@ -0,0 +1,13 @@
+-- +goose Up
+-- copia del producto al crear el pedido; las líneas anteriores se quedan sin
+-- ella porque product-service está en otra base
+ALTER TABLE order_items ADD COLUMN IF NOT EXISTS name VARCHAR(100);
+ALTER TABLE order_items ADD COLUMN IF NOT EXISTS sku VARCHAR(64);
+ALTER TABLE order_items ADD COLUMN IF NOT EXISTS description TEXT;
+ALTER TABLE order_items ADD COLUMN IF NOT EXISTS position INTEGER NOT NULL DEFAULT 0;
+
+-- +goose Down
+ALTER TABLE order_items DROP COLUMN IF EXISTS position;
+ALTER TABLE order_items DROP COLUMN IF EXISTS description;
+ALTER TABLE order_items DROP COLUMN IF EXISTS sku;
+ALTER TABLE order_items DROP COLUMN IF EXISTS name;
@@ -0,0 +1,24 @@
+-- +goose Up
+-- último número emitido por año; la fila se bloquea al emitir, así la
+-- numeración no tiene huecos aunque una transacción falle
+CREATE TABLE IF NOT EXISTS invoice_sequences (
+  year INTEGER PRIMARY KEY,
+  last_number INTEGER NOT NULL
+);
+
+CREATE TABLE IF NOT EXISTS invoices (
+  id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+  order_id UUID NOT NULL UNIQUE REFERENCES orders(id),
+  year INTEGER NOT NULL,
+  seq INTEGER NOT NULL,
+  number VARCHAR(20) NOT NULL UNIQUE,
+  user_id UUID NOT NULL,
+  tax_total NUMERIC(10,2) NOT NULL,
+  total NUMERIC(10,2) NOT NULL,
+  issued_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+  UNIQUE (year, seq)
+);
+
+-- +goose Down
+DROP TABLE IF EXISTS invoices;
+DROP TABLE IF EXISTS invoice_sequences;
@@ -0,0 +1,9 @@
+-- +goose Up
+-- el worker de caducidad busca pending por antigüedad y marca las líneas
+-- cuyo stock ya ha devuelto a product-service
+ALTER TABLE order_items ADD COLUMN IF NOT EXISTS restocked BOOLEAN NOT NULL DEFAULT false;
+CREATE INDEX IF NOT EXISTS idx_orders_status_updated_at ON orders(status, updated_at);
+
+-- +goose Down
+DROP INDEX IF EXISTS idx_orders_status_updated_at;
+ALTER TABLE order_items DROP COLUMN IF EXISTS restocked;
@@ -0,0 +1,9 @@
+-- +goose Up
+-- stock_applied marca las líneas cuya venta ya descontó stock en
+-- product-service: el worker de caducidad sólo repone ésas. Las líneas
+-- anteriores se crearon con el flujo que descontaba todas.
+ALTER TABLE order_items ADD COLUMN IF NOT EXISTS stock_applied BOOLEAN NOT NULL DEFAULT false;
+UPDATE order_items SET stock_applied = true;
+
+-- +goose Down
+ALTER TABLE order_items DROP COLUMN IF EXISTS stock_applied;
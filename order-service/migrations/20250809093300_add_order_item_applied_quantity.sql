@@ -0,0 +1,9 @@
+-- +goose Up
+-- applied_quantity son las unidades que la venta descontó de verdad en
+-- product-service: es lo que se repone al caducar o cancelar el pedido. Las
+-- líneas anteriores descontaron su cantidad entera.
+ALTER TABLE order_items ADD COLUMN IF NOT EXISTS applied_quantity INT NOT NULL DEFAULT 0;
+UPDATE order_items SET applied_quantity = quantity WHERE stock_applied;
+
+-- +goose Down
+ALTER TABLE order_items DROP COLUMN IF EXISTS applied_quantity;
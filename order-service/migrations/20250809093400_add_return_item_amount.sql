@@ -0,0 +1,9 @@
+-- +goose Up
+-- amount es lo que se reembolsa por las unidades de la línea devuelta: su
+-- parte de lo pagado por la línea del pedido. Las devoluciones anteriores
+-- valían unit_price por unidad.
+ALTER TABLE return_items ADD COLUMN IF NOT EXISTS amount NUMERIC(10,2) NOT NULL DEFAULT 0;
+UPDATE return_items SET amount = ROUND(unit_price * quantity, 2);
+
+-- +goose Down
+ALTER TABLE return_items DROP COLUMN IF EXISTS amount;
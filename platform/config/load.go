@@ -0,0 +1,199 @@
+// Package config carga structs de configuración anotados desde un fichero
+// YAML/TOML, variables de entorno y flags. Cada servicio define su Config en
+// su internal/config y la valida; aquí están el loader y las comprobaciones
+// que comparten (ver ValidateProxies).
+package config
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	toml "github.com/pelletier/go-toml/v2"
+	"gopkg.in/yaml.v3"
+)
+
+// El loader trabaja sobre structs anotados:
+//
+//	key:"dsn"     nombre en el fichero y en los flags (con el prefijo de los
+//	              structs padre: db.dsn -> -db.dsn)
+//	env:"DB_DSN"  variable de entorno; en un struct es un prefijo para las
+//	              variables de sus campos (USER_CLIENT_ + TIMEOUT)
+//	secret:"true" se oculta al imprimir; secret:"url" oculta sólo la
+//	              contraseña de una URL/DSN
+//
+// Tipos soportados: string, bool, int, int64, float64 y time.Duration.
+
+// field es una hoja del struct de configuración.
+type field struct {
+	key    string
+	env    string
+	secret string
+	v      reflect.Value
+}
+
+func fields(cfg any) []field {
+	var out []field
+	var walk func(v reflect.Value, keyPrefix, envPrefix string)
+	walk = func(v reflect.Value, keyPrefix, envPrefix string) {
+		t := v.Type()
+		for i := 0; i < t.NumField(); i++ {
+			sf := t.Field(i)
+			key := sf.Tag.Get("key")
+			if key == "" { continue }
+			if keyPrefix != "" { key = keyPrefix + "." + key }
+			env := sf.Tag.Get("env")
+			if sf.Type.Kind() == reflect.Struct && sf.Type != reflect.TypeOf(time.Duration(0)) {
+				walk(v.Field(i), key, envPrefix+env)
+				continue
+			}
+			if env != "" { env = envPrefix + env }
+			out = append(out, field{key: key, env: env, secret: sf.Tag.Get("secret"), v: v.Field(i)})
+		}
+	}
+	walk(reflect.ValueOf(cfg).Elem(), "", "")
+	return out
+}
+
+func (f field) set(s string) error {
+	switch f.v.Interface().(type) {
+	case string:
+		f.v.SetString(s)
+	case bool:
+		b, err := strconv.ParseBool(s)
+		if err != nil { return fmt.Errorf("%s: %q is not a boolean", f.key, s) }
+		f.v.SetBool(b)
+	case time.Duration:
+		d, err := time.ParseDuration(s)
+		if err != nil { return fmt.Errorf("%s: %q is not a duration (e.g. 5s, 1m)", f.key, s) }
+		f.v.SetInt(int64(d))
+	case int, int64:
+		n, err := strconv.ParseInt(s, 10, 64)
+		if err != nil { return fmt.Errorf("%s: %q is not an integer", f.key, s) }
+		f.v.SetInt(n)
+	case float64:
+		n, err := strconv.ParseFloat(s, 64)
+		if err != nil { return fmt.Errorf("%s: %q is not a number", f.key, s) }
+		f.v.SetFloat(n)
+	default:
+		return fmt.Errorf("%s: unsupported type %s", f.key, f.v.Type())
+	}
+	return nil
+}
+
+func (f field) String() string {
+	s := fmt.Sprint(f.v.Interface())
+	switch {
+	case f.secret == "url" && s != "":
+		return redactURL(s)
+	case f.secret != "" && s != "":
+		return "****"
+	}
+	return s
+}
+
+// redactURL oculta la contraseña del userinfo y de parámetros como
+// ?password=. Lo que no es una URL con esquema (un DSN clave=valor, por
+// ejemplo) no se puede redactar por partes y sale entero como ****.
+func redactURL(s string) string {
+	u, err := url.Parse(s)
+	if err != nil || u.Scheme == "" || u.Opaque != "" { return "****" }
+	if u.RawQuery != "" {
+		q := u.Query()
+		for k := range q {
+			if strings.Contains(strings.ToLower(k), "password") { q.Set(k, "xxxxx") }
+		}
+		u.RawQuery = q.Encode()
+	}
+	return u.Redacted()
+}
+
+// Load rellena cfg (un puntero a struct), que ya debe traer sus valores por
+// defecto, con (de menor a mayor prioridad) el fichero indicado en -config o CONFIG_FILE (YAML o
+// TOML según la extensión), las variables de entorno y los flags de args.
+// Devuelve true en printOnly si se pidió -print-config.
+func Load(cfg any, args []string) (printOnly bool, err error) {
+	fs := fields(cfg)
+	set := flag.NewFlagSet("config", flag.ContinueOnError)
+	set.SetOutput(io.Discard)
+	file := set.String("config", os.Getenv("CONFIG_FILE"), "fichero de configuración YAML o TOML")
+	printCfg := set.Bool("print-config", false, "imprime la configuración efectiva y sale")
+	flags := map[string]*string{}
+	for _, f := range fs { flags[f.key] = set.String(f.key, "", "") }
+	if err := set.Parse(args); err != nil { return false, fmt.Errorf("flags: %w", err) }
+
+	var errs []error
+	if *file != "" {
+		values, err := readFile(*file)
+		if err != nil { return false, err }
+		byKey := map[string]field{}
+		for _, f := range fs { byKey[f.key] = f }
+		keys := make([]string, 0, len(values))
+		for k := range values { keys = append(keys, k) }
+		sort.Strings(keys)
+		for _, k := range keys {
+			f, ok := byKey[k]
+			if !ok { errs = append(errs, fmt.Errorf("%s: unknown setting %q", *file, k)); continue }
+			if err := f.set(values[k]); err != nil { errs = append(errs, err) }
+		}
+	}
+	for _, f := range fs {
+		if f.env == "" { continue }
+		if v, ok := os.LookupEnv(f.env); ok && v != "" {
+			if err := f.set(v); err != nil { errs = append(errs, fmt.Errorf("%s (env %s)", err, f.env)) }
+		}
+	}
+	set.Visit(func(fl *flag.Flag) {
+		for _, f := range fs {
+			if f.key == fl.Name {
+				if err := f.set(*flags[f.key]); err != nil { errs = append(errs, fmt.Errorf("%s (flag -%s)", err, f.key)) }
+			}
+		}
+	})
+	return *printCfg, errors.Join(errs...)
+}
+
+func readFile(path string) (map[string]string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil { return nil, fmt.Errorf("config file: %w", err) }
+	raw := map[string]any{}
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".yaml", ".yml":
+		err = yaml.Unmarshal(data, &raw)
+	case ".toml":
+		err = toml.Unmarshal(data, &raw)
+	default:
+		return nil, fmt.Errorf("config file %s: unsupported extension (use .yaml, .yml or .toml)", path)
+	}
+	if err != nil { return nil, fmt.Errorf("config file %s: %w", path, err) }
+	out := map[string]string{}
+	flatten("", raw, out)
+	return out, nil
+}
+
+func flatten(prefix string, m map[string]any, out map[string]string) {
+	for k, v := range m {
+		if prefix != "" { k = prefix + "." + k }
+		if sub, ok := v.(map[string]any); ok { flatten(k, sub, out); continue }
+		out[k] = fmt.Sprint(v)
+	}
+}
+
+// Print escribe la configuración efectiva, una clave por línea y con los
+// secretos ocultos.
+func Print(w io.Writer, cfg any) {
+	for _, f := range fields(cfg) {
+		src := ""
+		if f.env != "" { src = "  # " + f.env }
+		fmt.Fprintf(w, "%s = %s%s\n", f.key, f.String(), src)
+	}
+}
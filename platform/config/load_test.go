@@ -0,0 +1,85 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+type testConfig struct {
+	Addr string `key:"addr" env:"TEST_ADDR"`
+	DB   struct {
+		DSN      string        `key:"dsn" env:"TEST_DSN" secret:"url"`
+		MaxConns int           `key:"max_conns" env:"TEST_MAX_CONNS"`
+		Timeout  time.Duration `key:"timeout"`
+	} `key:"db"`
+	Client struct {
+		Ratio float64 `key:"ratio" env:"RATIO"`
+	} `key:"client" env:"TEST_CLIENT_"`
+	Token string `key:"token" env:"TEST_TOKEN" secret:"true"`
+}
+
+func writeFile(t *testing.T, name, data string) string {
+	t.Helper()
+	p := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(p, []byte(data), 0o600); err != nil { t.Fatal(err) }
+	return p
+}
+
+func TestLoadPrecedence(t *testing.T) {
+	file := writeFile(t, "svc.yaml", "addr: \":9000\"\ndb:\n  max_conns: 20\n  timeout: 3s\n")
+	t.Setenv("CONFIG_FILE", file)
+	t.Setenv("TEST_MAX_CONNS", "30")
+	t.Setenv("TEST_CLIENT_RATIO", "0.5")
+	cfg := testConfig{Addr: ":8000"}
+	printOnly, err := Load(&cfg, []string{"-addr", ":9100"})
+	if err != nil { t.Fatal(err) }
+	if printOnly { t.Fatal("print-config not requested") }
+	switch {
+	case cfg.Addr != ":9100":
+		t.Fatalf("flag should win: %q", cfg.Addr)
+	case cfg.DB.MaxConns != 30:
+		t.Fatalf("env should override file: %d", cfg.DB.MaxConns)
+	case cfg.DB.Timeout != 3*time.Second:
+		t.Fatalf("file value not applied: %v", cfg.DB.Timeout)
+	case cfg.Client.Ratio != 0.5:
+		t.Fatalf("env prefix not applied: %v", cfg.Client.Ratio)
+	}
+}
+
+func TestLoadTOMLAndErrors(t *testing.T) {
+	var cfg testConfig
+	if _, err := Load(&cfg, []string{"-config", writeFile(t, "svc.toml", "[db]\ntimeout = \"2s\"\n")}); err != nil { t.Fatal(err) }
+	if cfg.DB.Timeout != 2*time.Second { t.Fatalf("timeout=%v", cfg.DB.Timeout) }
+
+	_, err := Load(&cfg, []string{"-config", writeFile(t, "bad.yaml", "db:\n  max_conns: lots\n  port: 1\n")})
+	for _, want := range []string{`db.max_conns: "lots" is not an integer`, `unknown setting "db.port"`} {
+		if err == nil || !strings.Contains(err.Error(), want) { t.Fatalf("error %v does not mention %q", err, want) }
+	}
+}
+
+func TestPrintRedactsSecrets(t *testing.T) {
+	var cfg testConfig
+	cfg.DB.DSN = "postgres://user:hunter2@db:5432/app?sslmode=disable"
+	cfg.Token = "s3cr3t"
+	var b strings.Builder
+	Print(&b, &cfg)
+	out := b.String()
+	if strings.Contains(out, "hunter2") || strings.Contains(out, "s3cr3t") { t.Fatalf("secret leaked:\n%s", out) }
+	if !strings.Contains(out, "db.dsn = postgres://user:xxxxx@db:5432/app") || !strings.Contains(out, "# TEST_DSN") {
+		t.Fatalf("unexpected output:\n%s", out)
+	}
+
+	for dsn, want := range map[string]string{
+		"host=db user=u password=hunter2 dbname=app":            "db.dsn = ****",
+		"postgres://db/app?user=u&password=hunter2&sslmode=off": "db.dsn = postgres://db/app?password=xxxxx&sslmode=off&user=u",
+		"db:5432/app?password=hunter2":                          "db.dsn = ****",
+	} {
+		cfg.DB.DSN = dsn
+		b.Reset()
+		Print(&b, &cfg)
+		if out := b.String(); strings.Contains(out, "hunter2") || !strings.Contains(out, want) { t.Fatalf("%s:\n%s", dsn, out) }
+	}
+}
@@ -0,0 +1,31 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"strings"
+)
+
+// Proxies convierte una lista de IPs o CIDR separadas por comas (como
+// http.trusted_proxies) en la que espera gin.Engine.SetTrustedProxies. Vacía
+// da nil: no se confía en ningún proxy.
+func Proxies(list string) []string {
+	var out []string
+	for _, p := range strings.Split(list, ",") {
+		if p = strings.TrimSpace(p); p != "" { out = append(out, p) }
+	}
+	return out
+}
+
+// ValidateProxies devuelve un error por cada entrada de list que no es una IP
+// ni un CIDR; key es la clave de configuración que sale en el mensaje.
+func ValidateProxies(key, list string) error {
+	var errs []error
+	for _, p := range Proxies(list) {
+		if _, _, err := net.ParseCIDR(p); err != nil && net.ParseIP(p) == nil {
+			errs = append(errs, fmt.Errorf("%s: %q is not an IP or CIDR", key, p))
+		}
+	}
+	return errors.Join(errs...)
+}
@@ -0,0 +1,16 @@
+package config
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestProxies(t *testing.T) {
+	if got := Proxies(" , "); got != nil { t.Fatalf("no proxy must be trusted by default: %v", got) }
+	if got := Proxies("10.0.0.0/8, 192.168.1.10,"); len(got) != 2 || got[1] != "192.168.1.10" { t.Fatalf("proxies=%v", got) }
+	if err := ValidateProxies("http.trusted_proxies", "10.0.0.0/8, ::1"); err != nil { t.Fatal(err) }
+	err := ValidateProxies("http.trusted_proxies", "10.0.0.0/8,lb.internal,10.0.0.0/33")
+	for _, want := range []string{`http.trusted_proxies: "lb.internal" is not an IP or CIDR`, `"10.0.0.0/33"`} {
+		if err == nil || !strings.Contains(err.Error(), want) { t.Fatalf("error %v does not mention %q", err, want) }
+	}
+}
@@ -0,0 +1,77 @@
+// Package db abre la conexión bun/pgx de un servicio con el pool configurado
+// y los hooks comunes (log de SQL, spans OpenTelemetry y métricas del pool).
+package db
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+
+	_ "github.com/jackc/pgx/v5/stdlib"
+	"github.com/uptrace/bun"
+	"github.com/uptrace/bun/dialect/pgdialect"
+	"github.com/uptrace/bun/extra/bunotel"
+
+	"github.com/huntercenter1/backend-test/platform/logging"
+	"github.com/huntercenter1/backend-test/platform/metrics"
+)
+
+// Config es la sección db de la configuración de cada servicio.
+type Config struct {
+	DSN             string        `key:"dsn" env:"DB_DSN" secret:"url"`
+	MaxOpenConns    int           `key:"max_open_conns" env:"DB_MAX_OPEN_CONNS"`
+	MaxIdleConns    int           `key:"max_idle_conns" env:"DB_MAX_IDLE_CONNS"`
+	ConnMaxLifetime time.Duration `key:"conn_max_lifetime" env:"DB_CONN_MAX_LIFETIME"`
+	PingTimeout     time.Duration `key:"ping_timeout" env:"DB_PING_TIMEOUT"`
+}
+
+func DefaultConfig() Config {
+	return Config{MaxOpenConns: 10, MaxIdleConns: 5, ConnMaxLifetime: 30 * time.Minute, PingTimeout: 5 * time.Second}
+}
+
+func (c Config) Validate() error {
+	var errs []error
+	if strings.TrimSpace(c.DSN) == "" { errs = append(errs, errors.New("db.dsn is required (env DB_DSN)")) }
+	if c.MaxOpenConns <= 0 { errs = append(errs, errors.New("db.max_open_conns must be > 0")) }
+	if c.MaxIdleConns < 0 || c.MaxIdleConns > c.MaxOpenConns {
+		errs = append(errs, fmt.Errorf("db.max_idle_conns must be between 0 and db.max_open_conns (%d)", c.MaxOpenConns))
+	}
+	if c.ConnMaxLifetime < 0 { errs = append(errs, errors.New("db.conn_max_lifetime must be >= 0")) }
+	if c.PingTimeout <= 0 { errs = append(errs, errors.New("db.ping_timeout must be > 0")) }
+	return errors.Join(errs...)
+}
+
+// New abre la base, añade los hooks comunes más los de hooks y comprueba la
+// conexión con un ping acotado por cfg.PingTimeout.
+func New(ctx context.Context, cfg Config, hooks ...bun.QueryHook) (*bun.DB, error) {
+	sqldb, err := sql.Open("pgx", cfg.DSN)
+	if err != nil { return nil, err }
+	sqldb.SetMaxOpenConns(cfg.MaxOpenConns)
+	sqldb.SetMaxIdleConns(cfg.MaxIdleConns)
+	sqldb.SetConnMaxLifetime(cfg.ConnMaxLifetime)
+	name := Name(cfg.DSN)
+	if err := metrics.RegisterDB(sqldb, name); err != nil { return nil, err }
+
+	db := bun.NewDB(sqldb, pgdialect.New())
+	// SQL a nivel debug (LOG_LEVEL=debug), errores siempre
+	db.AddQueryHook(logging.QueryHook{})
+	// un span por consulta, hijo del span de la petición que la origina
+	db.AddQueryHook(bunotel.NewQueryHook(bunotel.WithDBName(name)))
+	for _, h := range hooks { db.AddQueryHook(h) }
+
+	ctx, cancel := context.WithTimeout(ctx, cfg.PingTimeout)
+	defer cancel()
+	if err := db.PingContext(ctx); err != nil { db.Close(); return nil, err }
+	return db, nil
+}
+
+// Name es el nombre de la base según el path del DSN (products_db).
+func Name(dsn string) string {
+	u, err := url.Parse(dsn)
+	if err != nil { return "" }
+	return strings.TrimPrefix(u.Path, "/")
+}
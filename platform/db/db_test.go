@@ -0,0 +1,23 @@
+package db
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestConfigValidate(t *testing.T) {
+	cfg := DefaultConfig()
+	cfg.DSN = "postgres://u:p@db:5432/orders_db"
+	if err := cfg.Validate(); err != nil { t.Fatal(err) }
+
+	cfg.DSN = ""
+	cfg.MaxIdleConns = 50
+	err := cfg.Validate()
+	for _, want := range []string{"db.dsn is required", "db.max_idle_conns must be between 0 and db.max_open_conns (10)"} {
+		if err == nil || !strings.Contains(err.Error(), want) { t.Fatalf("error %v does not mention %q", err, want) }
+	}
+}
+
+func TestName(t *testing.T) {
+	if got := Name("postgres://u:p@db:5432/orders_db?sslmode=disable"); got != "orders_db" { t.Fatalf("Name=%q", got) }
+}
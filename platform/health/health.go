@@ -0,0 +1,98 @@
+package health
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/huntercenter1/backend-test/platform/migrate"
+)
+
+const (
+	StatusOK   = "ok"
+	StatusFail = "fail"
+)
+
+// Check es una comprobación de una dependencia; devuelve un detalle opcional
+// (p.ej. la versión de esquema) y error si la dependencia no está lista.
+type Check struct {
+	Name string
+	Fn   func(ctx context.Context) (detail string, err error)
+}
+
+type Result struct {
+	Name      string  `json:"name"`
+	Status    string  `json:"status"`
+	LatencyMS float64 `json:"latency_ms"`
+	Detail    string  `json:"detail,omitempty"`
+	Error     string  `json:"error,omitempty"`
+}
+
+type Report struct {
+	Status string   `json:"status"`
+	Checks []Result `json:"checks"`
+}
+
+func (r Report) OK() bool { return r.Status == StatusOK }
+
+// Checker ejecuta todas las comprobaciones en paralelo, cada una con su
+// propio timeout, para que una dependencia colgada no bloquee el resto.
+type Checker struct {
+	checks  []Check
+	timeout time.Duration
+}
+
+func NewChecker(timeout time.Duration, checks ...Check) *Checker {
+	if timeout <= 0 { timeout = 2 * time.Second }
+	return &Checker{checks: checks, timeout: timeout}
+}
+
+func (c *Checker) Run(ctx context.Context) Report {
+	rep := Report{Status: StatusOK, Checks: make([]Result, len(c.checks))}
+	var wg sync.WaitGroup
+	for i, ch := range c.checks {
+		wg.Add(1)
+		go func(i int, ch Check) {
+			defer wg.Done()
+			ctx, cancel := context.WithTimeout(ctx, c.timeout); defer cancel()
+			start := time.Now()
+			detail, err := ch.Fn(ctx)
+			res := Result{Name: ch.Name, Status: StatusOK, Detail: detail, LatencyMS: float64(time.Since(start).Microseconds()) / 1000}
+			if err != nil { res.Status, res.Error = StatusFail, err.Error() }
+			rep.Checks[i] = res
+		}(i, ch)
+	}
+	wg.Wait()
+	for _, r := range rep.Checks {
+		if r.Status != StatusOK { rep.Status = StatusFail }
+	}
+	return rep
+}
+
+// DB comprueba que hay conexión con la base de datos.
+func DB(db *sql.DB) Check {
+	return Check{Name: "database", Fn: func(ctx context.Context) (string, error) {
+		return "", db.PingContext(ctx)
+	}}
+}
+
+// Ping adapta una función de ping (p.ej. de un cliente a otro servicio).
+func Ping(name string, fn func(ctx context.Context) error) Check {
+	return Check{Name: name, Fn: func(ctx context.Context) (string, error) { return "", fn(ctx) }}
+}
+
+// Migrations comprueba que la versión aplicada del esquema es al menos la
+// última migración de dir. Si dir no se puede leer sólo informa la versión.
+func Migrations(db *sql.DB, dir string) Check {
+	want, err := migrate.Latest(dir)
+	if err != nil { want = -1 }
+	return Check{Name: "migrations", Fn: func(ctx context.Context) (string, error) {
+		got, err := migrate.Current(ctx, db)
+		if err != nil { return "", err }
+		detail := fmt.Sprintf("version %d", got)
+		if want >= 0 && got < want { return detail, fmt.Errorf("schema at %d, want %d", got, want) }
+		return detail, nil
+	}}
+}
@@ -0,0 +1,26 @@
+package health
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestCheckerRun(t *testing.T) {
+	c := NewChecker(50*time.Millisecond,
+		Check{Name: "a", Fn: func(context.Context) (string, error) { return "v1", nil }},
+		Check{Name: "slow", Fn: func(ctx context.Context) (string, error) { <-ctx.Done(); return "", ctx.Err() }},
+		Check{Name: "b", Fn: func(context.Context) (string, error) { return "", errors.New("down") }},
+	)
+	rep := c.Run(context.Background())
+	if rep.OK() { t.Fatal("report should fail") }
+	want := []string{StatusOK, StatusFail, StatusFail}
+	for i, r := range rep.Checks {
+		if r.Status != want[i] { t.Fatalf("%s: status %s, want %s", r.Name, r.Status, want[i]) }
+	}
+	if rep.Checks[0].Detail != "v1" || rep.Checks[2].Error != "down" {
+		t.Fatalf("unexpected results: %+v", rep.Checks)
+	}
+	if !NewChecker(0).Run(context.Background()).OK() { t.Fatal("no checks should be ok") }
+}
@@ -0,0 +1,73 @@
+package health
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"time"
+
+	grpchealth "google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+
+	"github.com/huntercenter1/backend-test/platform/server"
+)
+
+// Watch ejecuta c cada interval y publica el resultado en el servicio
+// grpc.health.v1 para el servidor global ("") y cada uno de services.
+// Termina cuando ctx se cancela.
+func Watch(ctx context.Context, hs *grpchealth.Server, c *Checker, interval time.Duration, services ...string) {
+	t := time.NewTicker(interval)
+	defer t.Stop()
+	for {
+		st := healthpb.HealthCheckResponse_SERVING
+		if !c.Run(ctx).OK() { st = healthpb.HealthCheckResponse_NOT_SERVING }
+		hs.SetServingStatus("", st)
+		for _, s := range services { hs.SetServingStatus(s, st) }
+		select {
+		case <-ctx.Done():
+			return
+		case <-t.C:
+		}
+	}
+}
+
+// WatchServer ejecuta Watch como parte del ciclo de vida de server.Run. Al
+// pararse marca todo NOT_SERVING, así que conviene registrarlo el último
+// para que se pare antes que el servidor gRPC.
+func WatchServer(hs *grpchealth.Server, c *Checker, interval time.Duration, services ...string) server.Server {
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	return server.Server{
+		Name:  "grpc-health",
+		Serve: func() error { defer close(done); Watch(ctx, hs, c, interval, services...); return nil },
+		Shutdown: func(context.Context) error {
+			cancel()
+			<-done
+			hs.Shutdown()
+			return nil
+		},
+	}
+}
+
+// LivezHandler responde ok mientras el proceso esté vivo.
+func LivezHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, http.StatusOK, map[string]string{"status": StatusOK})
+	})
+}
+
+// ReadyzHandler ejecuta las comprobaciones y responde 503 si alguna falla.
+func ReadyzHandler(c *Checker) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		rep := c.Run(r.Context())
+		code := http.StatusOK
+		if !rep.OK() { code = http.StatusServiceUnavailable }
+		writeJSON(w, code, rep)
+	})
+}
+
+func writeJSON(w http.ResponseWriter, code int, v any) {
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.WriteHeader(code)
+	_ = json.NewEncoder(w).Encode(v)
+}
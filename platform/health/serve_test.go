@@ -0,0 +1,49 @@
+package health
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	grpchealth "google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+)
+
+func TestWatchPublishesStatus(t *testing.T) {
+	hs := grpchealth.NewServer()
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	fail := Check{Name: "database", Fn: func(context.Context) (string, error) { return "", errors.New("down") }}
+	go func() { Watch(ctx, hs, NewChecker(time.Second, fail), time.Hour, "user.UserService"); close(done) }()
+
+	deadline := time.Now().Add(time.Second)
+	for {
+		resp, err := hs.Check(context.Background(), &healthpb.HealthCheckRequest{Service: "user.UserService"})
+		if err == nil && resp.GetStatus() == healthpb.HealthCheckResponse_NOT_SERVING { break }
+		if time.Now().After(deadline) { t.Fatalf("status not published: %v %v", resp, err) }
+		time.Sleep(5 * time.Millisecond)
+	}
+	cancel()
+	<-done
+}
+
+func TestWatchServerShutdownStopsServing(t *testing.T) {
+	hs := grpchealth.NewServer()
+	ok := Check{Name: "database", Fn: func(context.Context) (string, error) { return "", nil }}
+	ws := WatchServer(hs, NewChecker(time.Second, ok), time.Hour, "user.UserService")
+	served := make(chan error, 1)
+	go func() { served <- ws.Serve() }()
+
+	deadline := time.Now().Add(time.Second)
+	for {
+		resp, _ := hs.Check(context.Background(), &healthpb.HealthCheckRequest{Service: "user.UserService"})
+		if resp.GetStatus() == healthpb.HealthCheckResponse_SERVING { break }
+		if time.Now().After(deadline) { t.Fatal("never SERVING") }
+		time.Sleep(5 * time.Millisecond)
+	}
+	if err := ws.Shutdown(context.Background()); err != nil { t.Fatal(err) }
+	if err := <-served; err != nil { t.Fatal(err) }
+	resp, _ := hs.Check(context.Background(), &healthpb.HealthCheckRequest{Service: "user.UserService"})
+	if resp.GetStatus() != healthpb.HealthCheckResponse_NOT_SERVING { t.Fatalf("status after shutdown = %v", resp.GetStatus()) }
+}
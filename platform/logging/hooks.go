@@ -0,0 +1,48 @@
+package logging
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"time"
+
+	"github.com/uptrace/bun"
+)
+
+// QueryHook registra cada consulta de bun a nivel debug y las fallidas a
+// nivel error. Sustituye a bundebug para que el SQL respete LOG_LEVEL.
+type QueryHook struct{ Logger *slog.Logger }
+
+var _ bun.QueryHook = QueryHook{}
+
+func (h QueryHook) BeforeQuery(ctx context.Context, _ *bun.QueryEvent) context.Context { return ctx }
+
+func (h QueryHook) AfterQuery(ctx context.Context, e *bun.QueryEvent) {
+	l := h.Logger
+	if l == nil { l = slog.Default() }
+	level := slog.LevelDebug
+	if e.Err != nil && !errors.Is(e.Err, sql.ErrNoRows) { level = slog.LevelError }
+	if !l.Enabled(ctx, level) { return }
+	attrs := []any{
+		"operation", e.Operation(),
+		"duration_ms", float64(time.Since(e.StartTime).Microseconds()) / 1000,
+		"query", e.Query,
+	}
+	if e.Err != nil { attrs = append(attrs, "error", e.Err.Error()) }
+	l.Log(ctx, level, "sql query", attrs...)
+}
+
+// GooseLogger adapta slog a la interfaz de logger de goose.
+type GooseLogger struct{ Logger *slog.Logger }
+
+func (g GooseLogger) Printf(format string, v ...any) {
+	g.Logger.Info(fmt.Sprintf(format, v...), "component", "migrations")
+}
+
+func (g GooseLogger) Fatalf(format string, v ...any) {
+	g.Logger.Error(fmt.Sprintf(format, v...), "component", "migrations")
+	os.Exit(1)
+}
@@ -0,0 +1,102 @@
+package logging
+
+import (
+	"context"
+	"crypto/rand"
+	"fmt"
+	"log/slog"
+	"os"
+	"strings"
+
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Config es la sección log de la configuración de cada servicio.
+type Config struct {
+	Level string `key:"level" env:"LOG_LEVEL"`
+}
+
+func (c Config) Validate() error {
+	switch strings.ToLower(strings.TrimSpace(c.Level)) {
+	case "debug", "info", "warn", "warning", "error":
+		return nil
+	}
+	return fmt.Errorf("log.level must be debug, info, warn or error, got %q", c.Level)
+}
+
+// New crea el logger JSON del servicio con el nivel dado (log.level /
+// LOG_LEVEL) y lo deja como logger por defecto de slog.
+func New(service, level string) *slog.Logger {
+	h := slog.NewJSONHandler(os.Stdout, &slog.HandlerOptions{Level: ParseLevel(level)})
+	l := slog.New(contextHandler{h}).With("service", service)
+	slog.SetDefault(l)
+	return l
+}
+
+// ParseLevel acepta debug, info, warn/warning y error; por defecto info.
+func ParseLevel(s string) slog.Level {
+	switch strings.ToLower(strings.TrimSpace(s)) {
+	case "debug":
+		return slog.LevelDebug
+	case "warn", "warning":
+		return slog.LevelWarn
+	case "error":
+		return slog.LevelError
+	}
+	return slog.LevelInfo
+}
+
+type requestIDKey struct{}
+
+func WithRequestID(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, requestIDKey{}, id)
+}
+
+// RequestID devuelve el id de correlación de la petición en curso, o "".
+func RequestID(ctx context.Context) string {
+	v, _ := ctx.Value(requestIDKey{}).(string)
+	return v
+}
+
+// NewRequestID genera un UUID v4.
+func NewRequestID() string {
+	var b [16]byte
+	_, _ = rand.Read(b[:])
+	b[6] = b[6]&0x0f | 0x40
+	b[8] = b[8]&0x3f | 0x80
+	return fmt.Sprintf("%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:])
+}
+
+// ValidRequestID limita los ids aceptados de fuera a algo razonable para
+// logs y cabeceras.
+func ValidRequestID(id string) bool {
+	if id == "" || len(id) > 128 { return false }
+	for _, r := range id {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9', r == '-', r == '_', r == '.', r == ':':
+		default:
+			return false
+		}
+	}
+	return true
+}
+
+// contextHandler añade request_id y, si hay un span activo, trace_id y
+// span_id a cada registro emitido con *Context.
+type contextHandler struct{ slog.Handler }
+
+func (h contextHandler) Handle(ctx context.Context, r slog.Record) error {
+	if id := RequestID(ctx); id != "" { r.AddAttrs(slog.String("request_id", id)) }
+	if sc := trace.SpanContextFromContext(ctx); sc.IsValid() {
+		r.AddAttrs(slog.String("trace_id", sc.TraceID().String()), slog.String("span_id", sc.SpanID().String()))
+	}
+	return h.Handler.Handle(ctx, r)
+}
+
+func (h contextHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return contextHandler{h.Handler.WithAttrs(attrs)}
+}
+
+func (h contextHandler) WithGroup(name string) slog.Handler {
+	return contextHandler{h.Handler.WithGroup(name)}
+}
@@ -0,0 +1,89 @@
+// Package metrics agrupa las métricas Prometheus comunes a los servicios:
+// peticiones HTTP (gin), llamadas gRPC entrantes y el pool de database/sql.
+// Las métricas de negocio viven en el internal/metrics de cada servicio.
+package metrics
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/status"
+)
+
+var (
+	httpRequests = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "http_requests_total",
+		Help: "Peticiones HTTP atendidas por método, ruta y código.",
+	}, []string{"method", "route", "status"})
+
+	httpErrors = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "http_request_errors_total",
+		Help: "Peticiones HTTP que terminaron en 5xx.",
+	}, []string{"method", "route"})
+
+	httpDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "http_request_duration_seconds",
+		Help:    "Latencia de las peticiones HTTP.",
+		Buckets: prometheus.DefBuckets,
+	}, []string{"method", "route"})
+
+	grpcRequests = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "grpc_server_handled_total",
+		Help: "Llamadas gRPC atendidas por método y código.",
+	}, []string{"method", "code"})
+
+	grpcErrors = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "grpc_server_errors_total",
+		Help: "Llamadas gRPC que terminaron con un código distinto de OK.",
+	}, []string{"method"})
+
+	grpcDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "grpc_server_handling_seconds",
+		Help:    "Latencia de las llamadas gRPC.",
+		Buckets: prometheus.DefBuckets,
+	}, []string{"method"})
+)
+
+// HTTP registra count/latencia/errores por ruta. La ruta es la plantilla de
+// gin (/products/:id) para no disparar la cardinalidad con ids.
+func HTTP() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		start := time.Now()
+		c.Next()
+		route := c.FullPath()
+		if route == "" { route = "unmatched" }
+		status := c.Writer.Status()
+		httpRequests.WithLabelValues(c.Request.Method, route, strconv.Itoa(status)).Inc()
+		httpDuration.WithLabelValues(c.Request.Method, route).Observe(time.Since(start).Seconds())
+		if status >= 500 { httpErrors.WithLabelValues(c.Request.Method, route).Inc() }
+	}
+}
+
+// UnaryServerInterceptor registra count/latencia/errores por método gRPC.
+func UnaryServerInterceptor() grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		start := time.Now()
+		resp, err := handler(ctx, req)
+		grpcRequests.WithLabelValues(info.FullMethod, status.Code(err).String()).Inc()
+		grpcDuration.WithLabelValues(info.FullMethod).Observe(time.Since(start).Seconds())
+		if err != nil { grpcErrors.WithLabelValues(info.FullMethod).Inc() }
+		return resp, err
+	}
+}
+
+// RegisterDB publica las estadísticas del pool de database/sql. Registrar
+// dos veces la misma base no es un error.
+func RegisterDB(db *sql.DB, name string) error {
+	err := prometheus.Register(collectors.NewDBStatsCollector(db, name))
+	var dup prometheus.AlreadyRegisteredError
+	if errors.As(err, &dup) { return nil }
+	return err
+}
@@ -0,0 +1,50 @@
+package metrics
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestHTTPUsesRouteTemplate(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.Use(HTTP())
+	r.GET("/products/:id", func(c *gin.Context) { c.Status(http.StatusInternalServerError) })
+
+	for _, id := range []string{"a", "b"} {
+		r.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/products/"+id, nil))
+	}
+	r.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/nope", nil))
+
+	if got := testutil.ToFloat64(httpRequests.WithLabelValues("GET", "/products/:id", "500")); got != 2 {
+		t.Fatalf("requests = %v, want 2", got)
+	}
+	if got := testutil.ToFloat64(httpErrors.WithLabelValues("GET", "/products/:id")); got != 2 {
+		t.Fatalf("errors = %v, want 2", got)
+	}
+	if got := testutil.ToFloat64(httpRequests.WithLabelValues("GET", "unmatched", "404")); got != 1 {
+		t.Fatalf("unmatched = %v, want 1", got)
+	}
+}
+
+func TestUnaryServerInterceptor(t *testing.T) {
+	info := &grpc.UnaryServerInfo{FullMethod: "/user.UserService/GetUser"}
+	ic := UnaryServerInterceptor()
+	_, _ = ic(context.Background(), nil, info, func(context.Context, any) (any, error) { return "ok", nil })
+	_, _ = ic(context.Background(), nil, info, func(context.Context, any) (any, error) { return nil, status.Error(codes.NotFound, "x") })
+
+	if got := testutil.ToFloat64(grpcRequests.WithLabelValues(info.FullMethod, "NotFound")); got != 1 {
+		t.Fatalf("NotFound = %v, want 1", got)
+	}
+	if got := testutil.ToFloat64(grpcErrors.WithLabelValues(info.FullMethod)); got != 1 {
+		t.Fatalf("errors = %v, want 1", got)
+	}
+}
@@ -0,0 +1,78 @@
+package middleware
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/huntercenter1/backend-test/platform/logging"
+)
+
+const HeaderRequestID = "X-Request-Id"
+
+// RequestID reutiliza el X-Request-Id entrante si es válido (así una
+// petición se puede seguir entre servicios) y si no genera uno nuevo. El id
+// viaja en el contexto de la petición para que los logs lo incluyan.
+func RequestID() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id := c.GetHeader(HeaderRequestID)
+		if !logging.ValidRequestID(id) { id = logging.NewRequestID() }
+		c.Writer.Header().Set(HeaderRequestID, id)
+		c.Set("request_id", id)
+		c.Request = c.Request.WithContext(logging.WithRequestID(c.Request.Context(), id))
+		c.Next()
+	}
+}
+
+// AccessLog emite una línea por petición. Debe ir después de RequestID.
+func AccessLog(l *slog.Logger) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		start := time.Now()
+		c.Next()
+		status := c.Writer.Status()
+		level := slog.LevelInfo
+		switch {
+		case status >= 500:
+			level = slog.LevelError
+		case status >= 400:
+			level = slog.LevelWarn
+		}
+		attrs := []any{
+			"method", c.Request.Method,
+			"route", c.FullPath(),
+			"path", c.Request.URL.Path,
+			"status", status,
+			"duration_ms", float64(time.Since(start).Microseconds()) / 1000,
+			"bytes", c.Writer.Size(),
+			"client_ip", c.ClientIP(),
+		}
+		if len(c.Errors) > 0 { attrs = append(attrs, "errors", c.Errors.String()) }
+		l.Log(c.Request.Context(), level, "http request", attrs...)
+	}
+}
+
+// Recovery convierte un panic en 500 y lo registra con el id de la petición.
+func Recovery(l *slog.Logger) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		defer func() {
+			if rec := recover(); rec != nil {
+				l.ErrorContext(c.Request.Context(), "panic recovered", "panic", fmt.Sprint(rec), "path", c.Request.URL.Path)
+				c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": "internal error"})
+			}
+		}()
+		c.Next()
+	}
+}
+
+func Timeout(d time.Duration) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		ctx, cancel := context.WithTimeout(c.Request.Context(), d)
+		defer cancel()
+		c.Request = c.Request.WithContext(ctx)
+		c.Next()
+	}
+}
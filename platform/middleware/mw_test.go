@@ -0,0 +1,46 @@
+package middleware
+
+import (
+	"bytes"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/huntercenter1/backend-test/platform/logging"
+)
+
+func TestRequestIDPropagation(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	var buf bytes.Buffer
+	l := slog.New(slog.NewJSONHandler(&buf, nil))
+	r := gin.New()
+	r.Use(RequestID(), AccessLog(l))
+	var seen string
+	r.GET("/x/:id", func(c *gin.Context) { seen = logging.RequestID(c.Request.Context()); c.Status(http.StatusNoContent) })
+
+	req := httptest.NewRequest(http.MethodGet, "/x/1", nil)
+	req.Header.Set(HeaderRequestID, "abc-123")
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	if got := w.Header().Get(HeaderRequestID); got != "abc-123" || seen != "abc-123" {
+		t.Fatalf("incoming id not reused: header=%q ctx=%q", got, seen)
+	}
+	var entry map[string]any
+	if err := json.Unmarshal(buf.Bytes(), &entry); err != nil { t.Fatalf("access log: %v (%s)", err, buf.String()) }
+	if entry["route"] != "/x/:id" || entry["status"] != float64(http.StatusNoContent) {
+		t.Fatalf("unexpected access log: %v", entry)
+	}
+
+	// ids con caracteres raros (inyección en logs/cabeceras) se sustituyen
+	req = httptest.NewRequest(http.MethodGet, "/x/1", nil)
+	req.Header.Set(HeaderRequestID, "bad id\nfoo")
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	if got := w.Header().Get(HeaderRequestID); got == "" || got == "bad id\nfoo" || got != seen {
+		t.Fatalf("invalid id should be replaced, got %q", got)
+	}
+}
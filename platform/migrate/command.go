@@ -0,0 +1,41 @@
+package migrate
+
+import (
+	"context"
+	"fmt"
+	"io"
+)
+
+// Usage resume los subcomandos para la ayuda de cada binario.
+const Usage = "migrate up|down|status|redo|version [flags]"
+
+// Command ejecuta el subcomando `<servicio> migrate <cmd>` y escribe el
+// resultado en w. up, down y redo se hacen con el advisory lock.
+func Command(ctx context.Context, dsn string, cfg Config, cmd string, w io.Writer) error {
+	switch cmd {
+	case "up", "down", "redo", "status", "version":
+	default:
+		return fmt.Errorf("unknown migrate command %q (usage: %s)", cmd, Usage)
+	}
+	m, err := Open(ctx, dsn, cfg)
+	if err != nil { return err }
+	defer m.Close()
+
+	switch cmd {
+	case "up":
+		err = m.Up(ctx)
+	case "down":
+		err = m.Down(ctx)
+	case "redo":
+		err = m.Redo(ctx)
+	case "status":
+		return m.Status(ctx, w)
+	}
+	if err != nil { return err }
+	got, err := m.Version(ctx)
+	if err != nil { return err }
+	want, err := Latest(cfg.Dir)
+	if err != nil { return err }
+	fmt.Fprintf(w, "version %d (latest migration %d)\n", got, want)
+	return nil
+}
@@ -0,0 +1,208 @@
+// Package migrate aplica las migraciones goose de un servicio. Todas las
+// operaciones que cambian el esquema se hacen con un advisory lock de
+// Postgres, así que varias réplicas arrancando a la vez no compiten: la
+// primera migra y el resto espera y encuentra el esquema al día.
+package migrate
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"path/filepath"
+	"text/tabwriter"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgconn"
+	_ "github.com/jackc/pgx/v5/stdlib"
+	goose "github.com/pressly/goose/v3"
+
+	"github.com/huntercenter1/backend-test/platform/logging"
+)
+
+// lockID identifica el advisory lock de migraciones. Los advisory locks son
+// por base de datos, así que basta una constante para todos los servicios.
+const lockID int64 = 0x676f6f7365 // "goose"
+
+// Config es la sección migrations de la configuración de cada servicio.
+type Config struct {
+	Dir          string        `key:"dir" env:"MIGRATIONS_DIR"`
+	Auto         bool          `key:"auto" env:"MIGRATIONS_AUTO"`
+	RetryTimeout time.Duration `key:"retry_timeout" env:"MIGRATIONS_RETRY_TIMEOUT"`
+	LockTimeout  time.Duration `key:"lock_timeout" env:"MIGRATIONS_LOCK_TIMEOUT"`
+}
+
+func DefaultConfig() Config {
+	return Config{Dir: "./migrations", Auto: true, RetryTimeout: 60 * time.Second, LockTimeout: 5 * time.Minute}
+}
+
+func (c Config) Validate() error {
+	var errs []error
+	if c.Dir == "" { errs = append(errs, errors.New("migrations.dir is required (env MIGRATIONS_DIR)")) }
+	if c.RetryTimeout < 0 { errs = append(errs, errors.New("migrations.retry_timeout must be >= 0")) }
+	if c.LockTimeout <= 0 { errs = append(errs, errors.New("migrations.lock_timeout must be > 0")) }
+	return errors.Join(errs...)
+}
+
+// Migrator ejecuta comandos de goose sobre una conexión propia.
+type Migrator struct {
+	db  *sql.DB
+	cfg Config
+	log *slog.Logger
+}
+
+// Open conecta con la base, reintentando mientras arranca hasta
+// cfg.RetryTimeout. Sólo se reintenta la conexión, nunca una migración.
+func Open(ctx context.Context, dsn string, cfg Config) (*Migrator, error) {
+	if err := goose.SetDialect("postgres"); err != nil { return nil, err }
+	l := slog.Default().With("component", "migrations")
+	goose.SetLogger(logging.GooseLogger{Logger: slog.Default()})
+
+	db, err := sql.Open("pgx", dsn)
+	if err != nil { return nil, err }
+	deadline := time.Now().Add(cfg.RetryTimeout)
+	for {
+		err = db.PingContext(ctx)
+		if err == nil { return &Migrator{db: db, cfg: cfg, log: l}, nil }
+		if time.Now().After(deadline) || ctx.Err() != nil {
+			db.Close()
+			return nil, fmt.Errorf("migrate: database not reachable: %w", err)
+		}
+		l.Warn("migrate: waiting for database", "error", err)
+		time.Sleep(2 * time.Second)
+	}
+}
+
+func (m *Migrator) Close() error { return m.db.Close() }
+
+// Up aplica todas las migraciones pendientes.
+func (m *Migrator) Up(ctx context.Context) error {
+	return m.locked(ctx, func(ctx context.Context) error { return goose.UpContext(ctx, m.db, m.cfg.Dir) })
+}
+
+// Down revierte la última migración aplicada.
+func (m *Migrator) Down(ctx context.Context) error {
+	return m.locked(ctx, func(ctx context.Context) error { return goose.DownContext(ctx, m.db, m.cfg.Dir) })
+}
+
+// Redo revierte y vuelve a aplicar la última migración.
+func (m *Migrator) Redo(ctx context.Context) error {
+	return m.locked(ctx, func(ctx context.Context) error { return goose.RedoContext(ctx, m.db, m.cfg.Dir) })
+}
+
+// Version es la versión aplicada del esquema (0 si no hay ninguna).
+func (m *Migrator) Version(ctx context.Context) (int64, error) { return Current(ctx, m.db) }
+
+// Status escribe una línea por migración de cfg.Dir con su estado.
+func (m *Migrator) Status(ctx context.Context, w io.Writer) error {
+	ms, err := goose.CollectMigrations(m.cfg.Dir, 0, goose.MaxVersion)
+	if err != nil { return err }
+	applied, err := appliedAt(ctx, m.db)
+	if err != nil { return err }
+	writeStatus(w, ms, applied)
+	return nil
+}
+
+// locked ejecuta fn con el advisory lock tomado en una conexión dedicada. Si
+// otra réplica lo tiene, espera hasta cfg.LockTimeout.
+func (m *Migrator) locked(ctx context.Context, fn func(context.Context) error) error {
+	ctx, cancel := context.WithTimeout(ctx, m.cfg.LockTimeout)
+	defer cancel()
+	conn, err := m.db.Conn(ctx)
+	if err != nil { return err }
+	defer conn.Close()
+
+	var ok bool
+	if err := conn.QueryRowContext(ctx, `SELECT pg_try_advisory_lock($1)`, lockID).Scan(&ok); err != nil {
+		return fmt.Errorf("migrate: lock: %w", err)
+	}
+	if !ok {
+		m.log.Info("migrate: waiting for another instance to finish migrating")
+		if _, err := conn.ExecContext(ctx, `SELECT pg_advisory_lock($1)`, lockID); err != nil {
+			return fmt.Errorf("migrate: lock: %w", err)
+		}
+	}
+	defer func() { _, _ = conn.ExecContext(context.Background(), `SELECT pg_advisory_unlock($1)`, lockID) }()
+	return fn(ctx)
+}
+
+// Up abre la base, aplica las migraciones pendientes con el lock y cierra.
+// Es lo que hace cada servicio al arrancar si cfg.Auto está activo.
+func Up(ctx context.Context, dsn string, cfg Config) error {
+	m, err := Open(ctx, dsn, cfg)
+	if err != nil { return err }
+	defer m.Close()
+	return m.Up(ctx)
+}
+
+// Latest es la versión de la última migración de dir: la que espera el
+// binario.
+func Latest(dir string) (int64, error) {
+	ms, err := goose.CollectMigrations(dir, 0, goose.MaxVersion)
+	if errors.Is(err, goose.ErrNoMigrationFiles) { return 0, nil }
+	if err != nil { return 0, err }
+	if len(ms) == 0 { return 0, nil }
+	return ms[len(ms)-1].Version, nil
+}
+
+// Current es la versión aplicada en la base; 0 si aún no hay tabla de goose.
+// No crea la tabla, así que sirve en comprobaciones de sólo lectura.
+func Current(ctx context.Context, db *sql.DB) (int64, error) {
+	var v int64
+	err := db.QueryRowContext(ctx, `SELECT COALESCE(MAX(version_id), 0) FROM goose_db_version WHERE is_applied`).Scan(&v)
+	if isUndefinedTable(err) { return 0, nil }
+	return v, err
+}
+
+// ErrSchemaBehind indica que la base no tiene todas las migraciones que el
+// binario espera.
+var ErrSchemaBehind = errors.New("database schema is behind")
+
+// Check devuelve ErrSchemaBehind si la versión aplicada es menor que la
+// última migración de dir. Un esquema por delante se acepta: pasa durante un
+// despliegue en el que conviven la versión nueva y la anterior.
+func Check(ctx context.Context, db *sql.DB, dir string) error {
+	want, err := Latest(dir)
+	if err != nil { return fmt.Errorf("migrations dir: %w", err) }
+	got, err := Current(ctx, db)
+	if err != nil { return err }
+	return compare(got, want)
+}
+
+func compare(got, want int64) error {
+	if got < want { return fmt.Errorf("%w: at version %d, binary expects %d (run `migrate up`)", ErrSchemaBehind, got, want) }
+	return nil
+}
+
+func appliedAt(ctx context.Context, db *sql.DB) (map[int64]time.Time, error) {
+	out := map[int64]time.Time{}
+	rows, err := db.QueryContext(ctx, `SELECT version_id, tstamp FROM goose_db_version WHERE is_applied AND version_id > 0`)
+	if isUndefinedTable(err) { return out, nil }
+	if err != nil { return nil, err }
+	defer rows.Close()
+	for rows.Next() {
+		var v int64
+		var ts time.Time
+		if err := rows.Scan(&v, &ts); err != nil { return nil, err }
+		out[v] = ts
+	}
+	return out, rows.Err()
+}
+
+func writeStatus(w io.Writer, ms goose.Migrations, applied map[int64]time.Time) {
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(tw, "VERSION\tAPPLIED AT\tMIGRATION")
+	for _, mig := range ms {
+		at := "pending"
+		if ts, ok := applied[mig.Version]; ok { at = ts.UTC().Format(time.RFC3339) }
+		fmt.Fprintf(tw, "%d\t%s\t%s\n", mig.Version, at, filepath.Base(mig.Source))
+	}
+	tw.Flush()
+}
+
+func isUndefinedTable(err error) bool {
+	var pgErr *pgconn.PgError
+	return errors.As(err, &pgErr) && pgErr.Code == "42P01"
+}
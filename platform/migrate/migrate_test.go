@@ -0,0 +1,51 @@
+package migrate
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	goose "github.com/pressly/goose/v3"
+)
+
+func migrationsDir(t *testing.T, names ...string) string {
+	t.Helper()
+	dir := t.TempDir()
+	for _, n := range names {
+		if err := os.WriteFile(filepath.Join(dir, n), []byte("-- +goose Up\nSELECT 1;\n"), 0o600); err != nil { t.Fatal(err) }
+	}
+	return dir
+}
+
+func TestLatest(t *testing.T) {
+	v, err := Latest(migrationsDir(t, "0001_init.sql", "0003_orders.sql", "0002_items.sql"))
+	if err != nil || v != 3 { t.Fatalf("Latest = %d, %v", v, err) }
+	if v, err := Latest(t.TempDir()); err != nil || v != 0 { t.Fatalf("empty dir: %d, %v", v, err) }
+}
+
+func TestCompare(t *testing.T) {
+	if err := compare(3, 3); err != nil { t.Fatal(err) }
+	if err := compare(4, 3); err != nil { t.Fatalf("schema ahead must be accepted: %v", err) }
+	err := compare(2, 3)
+	if !errors.Is(err, ErrSchemaBehind) || !strings.Contains(err.Error(), "at version 2, binary expects 3") { t.Fatalf("err=%v", err) }
+}
+
+func TestWriteStatus(t *testing.T) {
+	ms, err := goose.CollectMigrations(migrationsDir(t, "0001_init.sql", "0002_items.sql"), 0, goose.MaxVersion)
+	if err != nil { t.Fatal(err) }
+	var b strings.Builder
+	writeStatus(&b, ms, map[int64]time.Time{1: time.Date(2025, 1, 2, 3, 4, 5, 0, time.UTC)})
+	out := b.String()
+	for _, want := range []string{"2025-01-02T03:04:05Z  0001_init.sql", "pending               0002_items.sql"} {
+		if !strings.Contains(out, want) { t.Fatalf("status missing %q:\n%s", want, out) }
+	}
+}
+
+func TestCommandRejectsUnknown(t *testing.T) {
+	err := Command(context.Background(), "postgres://nowhere", DefaultConfig(), "sideways", nil)
+	if err == nil || !strings.Contains(err.Error(), `unknown migrate command "sideways"`) { t.Fatalf("err=%v", err) }
+}
@@ -0,0 +1,34 @@
+package ratelimit
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Gin limita por "MÉTODO /plantilla" (POST /orders). Debe registrarse con
+// r.Use para que la ruta ya esté resuelta; las rutas inexistentes usan el
+// límite por defecto. Cuenta por servicio interno, usuario o IP (ver
+// Policy.Subject); la IP es c.ClientIP(): el engine debe tener
+// SetTrustedProxies para que X-Forwarded-For sólo valga desde sus proxies.
+func (l *Limiter) Gin() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		route := c.Request.Method + " " + c.FullPath()
+		subject := l.policy.Subject(c.GetHeader(HeaderAPIKey), c.ClientIP())
+		res, lim, ok := l.Allow(c.Request.Context(), route, subject)
+		if !ok { c.Next(); return }
+
+		h := c.Writer.Header()
+		h.Set(HeaderLimit, strconv.Itoa(res.Limit))
+		h.Set(HeaderRemaining, strconv.Itoa(res.Remaining))
+		h.Set(HeaderReset, seconds(res.Reset))
+		h.Set(HeaderPolicy, strconv.Itoa(lim.Burst)+";w="+seconds(lim.Window()))
+		if !res.Allowed {
+			h.Set(HeaderRetryAfter, seconds(res.RetryAfter))
+			c.AbortWithStatusJSON(http.StatusTooManyRequests, gin.H{"error": "rate limit exceeded"})
+			return
+		}
+		c.Next()
+	}
+}
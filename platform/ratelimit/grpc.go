@@ -0,0 +1,54 @@
+package ratelimit
+
+import (
+	"context"
+	"net"
+	"strconv"
+	"strings"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+)
+
+// UnaryServerInterceptor limita por método completo
+// (/user.UserService/AuthenticateUser). Las cabeceras van como metadata en
+// minúsculas (ratelimit-remaining, retry-after) y el rechazo es
+// ResourceExhausted, que los clientes tratan como reintentable.
+func (l *Limiter) UnaryServerInterceptor() grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		md, _ := metadata.FromIncomingContext(ctx)
+		subject := l.policy.Subject(first(md, strings.ToLower(HeaderAPIKey)), peerIP(ctx))
+		res, lim, ok := l.Allow(ctx, info.FullMethod, subject)
+		if !ok { return handler(ctx, req) }
+
+		out := metadata.Pairs(
+			strings.ToLower(HeaderLimit), strconv.Itoa(res.Limit),
+			strings.ToLower(HeaderRemaining), strconv.Itoa(res.Remaining),
+			strings.ToLower(HeaderReset), seconds(res.Reset),
+			strings.ToLower(HeaderPolicy), strconv.Itoa(lim.Burst)+";w="+seconds(lim.Window()),
+		)
+		if !res.Allowed {
+			out.Set(strings.ToLower(HeaderRetryAfter), seconds(res.RetryAfter))
+			_ = grpc.SetHeader(ctx, out)
+			return nil, status.Errorf(codes.ResourceExhausted, "rate limit exceeded, retry in %ss", seconds(res.RetryAfter))
+		}
+		_ = grpc.SetHeader(ctx, out)
+		return handler(ctx, req)
+	}
+}
+
+func first(md metadata.MD, key string) string {
+	if v := md.Get(key); len(v) > 0 { return v[0] }
+	return ""
+}
+
+func peerIP(ctx context.Context) string {
+	p, ok := peer.FromContext(ctx)
+	if !ok || p.Addr == nil { return "" }
+	host, _, err := net.SplitHostPort(p.Addr.String())
+	if err != nil { return p.Addr.String() }
+	return host
+}
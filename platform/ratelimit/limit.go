@@ -0,0 +1,181 @@
+// Package ratelimit limita peticiones con token buckets por cliente y ruta.
+// El estado de los buckets vive en un Store: MemoryStore guarda cada réplica
+// por separado; un store compartido (Redis, Postgres...) sólo tiene que
+// implementar la misma interfaz.
+package ratelimit
+
+import (
+	"crypto/subtle"
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Limit es un token bucket: Burst peticiones seguidas y Rate por segundo
+// repuestas después. Un Limit cero no limita.
+type Limit struct {
+	Rate  float64
+	Burst int
+}
+
+func (l Limit) Unlimited() bool { return l.Burst <= 0 || l.Rate <= 0 }
+
+// Window es lo que tarda el bucket vacío en llenarse, para RateLimit-Policy.
+func (l Limit) Window() time.Duration {
+	if l.Unlimited() { return 0 }
+	return time.Duration(float64(l.Burst) / l.Rate * float64(time.Second))
+}
+
+func (l Limit) String() string {
+	if l.Unlimited() { return "off" }
+	return fmt.Sprintf("%d/%s", l.Burst, l.Window())
+}
+
+// ParseLimit acepta "N/s", "N/m" o "N/h" (N peticiones por unidad, con
+// ráfagas de hasta N) y "off" para no limitar.
+func ParseLimit(s string) (Limit, error) {
+	s = strings.TrimSpace(s)
+	if s == "off" { return Limit{}, nil }
+	n, unit, ok := strings.Cut(s, "/")
+	count, err := strconv.Atoi(strings.TrimSpace(n))
+	if !ok || err != nil || count <= 0 { return Limit{}, fmt.Errorf("rate limit %q: want N/s, N/m, N/h or off", s) }
+	var per time.Duration
+	switch strings.TrimSpace(unit) {
+	case "s":
+		per = time.Second
+	case "m":
+		per = time.Minute
+	case "h":
+		per = time.Hour
+	default:
+		return Limit{}, fmt.Errorf("rate limit %q: unit must be s, m or h", s)
+	}
+	return Limit{Rate: float64(count) / per.Seconds(), Burst: count}, nil
+}
+
+// Config es la sección rate_limit de la configuración de cada servicio.
+// Routes es una lista "ruta=límite" separada por comas; la ruta es
+// "MÉTODO /plantilla" en HTTP (POST /orders, GET /products/:id) y el método
+// completo en gRPC (/user.UserService/AuthenticateUser). Clients son los
+// servicios internos como lista "nombre=clave": las peticiones con una de
+// esas claves en X-API-Key tienen un bucket por servicio con el límite
+// Internal (vacío = el de la ruta, como el resto).
+type Config struct {
+	Enabled  bool   `key:"enabled" env:"RATE_LIMIT_ENABLED"`
+	Default  string `key:"default" env:"RATE_LIMIT_DEFAULT"`
+	Routes   string `key:"routes" env:"RATE_LIMIT_ROUTES"`
+	Clients  string `key:"clients" env:"RATE_LIMIT_CLIENTS" secret:"true"`
+	Internal string `key:"internal" env:"RATE_LIMIT_INTERNAL"`
+}
+
+func (c Config) Validate() error {
+	if !c.Enabled { return nil }
+	_, err := c.Policy()
+	return err
+}
+
+// Policy traduce la configuración a límites.
+func (c Config) Policy() (Policy, error) {
+	var errs []error
+	def, err := ParseLimit(c.Default)
+	if err != nil { errs = append(errs, fmt.Errorf("rate_limit.default: %w", err)) }
+	p := Policy{Default: def, Routes: map[string]Limit{}}
+	for _, rule := range strings.Split(c.Routes, ",") {
+		if strings.TrimSpace(rule) == "" { continue }
+		route, lim, ok := strings.Cut(rule, "=")
+		route = strings.TrimSpace(route)
+		if !ok || route == "" { errs = append(errs, fmt.Errorf("rate_limit.routes: %q is not route=limit", rule)); continue }
+		l, err := ParseLimit(lim)
+		if err != nil { errs = append(errs, fmt.Errorf("rate_limit.routes: %s: %w", route, err)); continue }
+		p.Routes[route] = l
+	}
+	if strings.TrimSpace(c.Internal) != "" {
+		l, err := ParseLimit(c.Internal)
+		switch {
+		case err != nil:
+			errs = append(errs, fmt.Errorf("rate_limit.internal: %w", err))
+		case l.Unlimited():
+			// off dejaría a cualquiera con una clave filtrada sin límite
+			errs = append(errs, errors.New("rate_limit.internal: must be a limit, not off"))
+		default:
+			p.Internal = l
+		}
+	}
+	for i, client := range strings.Split(c.Clients, ",") {
+		if strings.TrimSpace(client) == "" { continue }
+		name, key, _ := strings.Cut(client, "=")
+		name, key = strings.TrimSpace(name), strings.TrimSpace(key)
+		// el error no lleva la clave: es un secreto
+		if name == "" || key == "" { errs = append(errs, fmt.Errorf("rate_limit.clients: entry %d is not name=key", i+1)); continue }
+		if p.Clients == nil { p.Clients = map[string]string{} }
+		p.Clients[name] = key
+	}
+	return p, errors.Join(errs...)
+}
+
+// Policy decide el límite de cada ruta. Exempt son rutas que nunca se
+// limitan (probes y métricas), fijadas por el servicio y no por config.
+type Policy struct {
+	Default  Limit
+	Routes   map[string]Limit
+	Exempt   []string
+	Clients  map[string]string // nombre -> clave de los servicios internos
+	Internal Limit             // límite de los Clients en las rutas limitadas; cero = el de la ruta
+}
+
+// Client devuelve el servicio interno al que pertenece key. Compara todas
+// las claves en tiempo constante para no dar pistas sobre ellas.
+func (p Policy) Client(key string) (string, bool) {
+	if key == "" { return "", false }
+	var found string
+	for name, k := range p.Clients {
+		if subtle.ConstantTimeCompare([]byte(k), []byte(key)) == 1 { found = name }
+	}
+	return found, found != ""
+}
+
+// Subject identifica a quién se cobra una petición: un servicio interno de
+// Policy.Clients o, si no, la IP. Una X-API-Key que no es de Clients se
+// ignora: cualquiera puede inventarse una y estrenar bucket.
+type Subject struct {
+	Key      string // "client:<nombre>" o "ip:<ip>"
+	Internal bool
+}
+
+func (p Policy) Subject(apiKey, ip string) Subject {
+	if name, ok := p.Client(apiKey); ok { return Subject{Key: "client:" + name, Internal: true} }
+	return Subject{Key: "ip:" + ip}
+}
+
+// ForSubject es For con el límite propio de los servicios internos.
+func (p Policy) ForSubject(route string, s Subject) (Limit, bool) {
+	l, ok := p.For(route)
+	if ok && s.Internal && !p.Internal.Unlimited() { l = p.Internal }
+	return l, ok
+}
+
+// For devuelve el límite de route y si se aplica.
+func (p Policy) For(route string) (Limit, bool) {
+	for _, e := range p.Exempt {
+		if e == route || (strings.HasSuffix(e, "/") && strings.HasPrefix(route, e)) { return Limit{}, false }
+	}
+	l, ok := p.Routes[route]
+	if !ok { l = p.Default }
+	return l, !l.Unlimited()
+}
+
+// Result es el estado del bucket tras una petición.
+type Result struct {
+	Allowed    bool
+	Limit      int
+	Remaining  int
+	Reset      time.Duration // hasta que el bucket vuelve a estar lleno
+	RetryAfter time.Duration // hasta que hay un token; 0 si Allowed
+}
+
+// seconds redondea hacia arriba: un cliente que espera Retry-After segundos
+// debe encontrar el token disponible.
+func seconds(d time.Duration) string { return strconv.Itoa(int(math.Ceil(d.Seconds()))) }
@@ -0,0 +1,44 @@
+package ratelimit
+
+import (
+	"context"
+	"log/slog"
+	"time"
+)
+
+// Cabeceras (draft IETF RateLimit header fields) y metadata gRPC.
+const (
+	HeaderLimit      = "RateLimit-Limit"
+	HeaderRemaining  = "RateLimit-Remaining"
+	HeaderReset      = "RateLimit-Reset"
+	HeaderPolicy     = "RateLimit-Policy"
+	HeaderRetryAfter = "Retry-After"
+	HeaderAPIKey     = "X-API-Key"
+)
+
+// Limiter aplica una Policy sobre un Store. Si el store falla se deja pasar
+// la petición: un store compartido caído no debe tumbar la API.
+type Limiter struct {
+	store  Store
+	policy Policy
+	log    *slog.Logger
+	now    func() time.Time
+}
+
+func New(store Store, p Policy, l *slog.Logger) *Limiter {
+	if l == nil { l = slog.Default() }
+	return &Limiter{store: store, policy: p, log: l, now: time.Now}
+}
+
+// Allow consume un token del bucket (route, subject). ok=false si la ruta no
+// está limitada.
+func (l *Limiter) Allow(ctx context.Context, route string, subject Subject) (res Result, lim Limit, ok bool) {
+	lim, ok = l.policy.ForSubject(route, subject)
+	if !ok { return Result{Allowed: true}, lim, false }
+	res, err := l.store.Take(ctx, route+"|"+subject.Key, lim, l.now())
+	if err != nil {
+		l.log.WarnContext(ctx, "rate limit store failed, allowing request", "route", route, "error", err)
+		return Result{Allowed: true}, lim, false
+	}
+	return res, lim, true
+}
@@ -0,0 +1,142 @@
+package ratelimit
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+)
+
+func TestParseLimitAndPolicy(t *testing.T) {
+	l, err := ParseLimit("30/m")
+	if err != nil || l.Burst != 30 || l.Rate != 0.5 || l.Window() != time.Minute { t.Fatalf("30/m = %+v, %v", l, err) }
+	if l, _ := ParseLimit("off"); !l.Unlimited() { t.Fatal("off must be unlimited") }
+
+	p, err := Config{Enabled: true, Default: "100/m", Routes: "POST /orders=5/s, GET /orders/:id=off"}.Policy()
+	if err != nil { t.Fatal(err) }
+	p.Exempt = []string{"GET /livez", "/grpc.health.v1.Health/"}
+	if l, ok := p.For("POST /orders"); !ok || l.Burst != 5 { t.Fatalf("route limit: %+v %v", l, ok) }
+	if _, ok := p.For("GET /orders/:id"); ok { t.Fatal("off route must not be limited") }
+	if l, ok := p.For("GET /products"); !ok || l.Burst != 100 { t.Fatalf("default: %+v %v", l, ok) }
+	if _, ok := p.For("/grpc.health.v1.Health/Check"); ok { t.Fatal("exempt prefix") }
+
+	p, err = Config{Enabled: true, Default: "10/m", Routes: "GET /x=off", Clients: "order-service=k1, batch=k2", Internal: "100/s"}.Policy()
+	if err != nil { t.Fatal(err) }
+	if name, ok := p.Client("k2"); !ok || name != "batch" { t.Fatalf("client k2: %q %v", name, ok) }
+	if _, ok := p.Client("k3"); ok { t.Fatal("unknown key accepted") }
+	if _, ok := p.Client(""); ok { t.Fatal("empty key accepted") }
+	for _, tc := range []struct{ key, want string }{
+		{"k1", "client:order-service"}, {"k3", "ip:10.0.0.1"}, {"", "ip:10.0.0.1"},
+	} {
+		if got := p.Subject(tc.key, "10.0.0.1"); got.Key != tc.want || got.Internal != (tc.key == "k1") { t.Errorf("Subject(%s) = %+v", tc.key, got) }
+	}
+	if l, ok := p.ForSubject("POST /orders", p.Subject("k1", "")); !ok || l.Burst != 100 { t.Fatalf("internal limit: %+v %v", l, ok) }
+	if l, ok := p.ForSubject("POST /orders", p.Subject("", "10.0.0.1")); !ok || l.Burst != 10 { t.Fatalf("ip limit: %+v %v", l, ok) }
+	if _, ok := p.ForSubject("GET /x", p.Subject("k1", "")); ok { t.Fatal("off route must stay off for internal clients") }
+
+	err = Config{Enabled: true, Default: "lots", Routes: "POST /orders", Clients: "order-service=,k9", Internal: "off"}.Validate()
+	for _, want := range []string{"rate_limit.default", `"POST /orders" is not route=limit`, "rate_limit.clients: entry 1", "entry 2", "rate_limit.internal: must be a limit"} {
+		if err == nil || !strings.Contains(err.Error(), want) { t.Fatalf("error %v does not mention %q", err, want) }
+	}
+}
+
+func TestMemoryStoreTokenBucket(t *testing.T) {
+	s := NewMemoryStore()
+	lim := Limit{Rate: 1, Burst: 2} // 2 seguidas, luego 1/s
+	t0 := time.Unix(1000, 0)
+	for i, want := range []bool{true, true, false} {
+		res, _ := s.Take(context.Background(), "k", lim, t0)
+		if res.Allowed != want { t.Fatalf("take %d: allowed=%v", i, res.Allowed) }
+	}
+	res, _ := s.Take(context.Background(), "k", lim, t0)
+	if res.RetryAfter != time.Second || res.Remaining != 0 || res.Reset != 2*time.Second { t.Fatalf("empty bucket: %+v", res) }
+
+	res, _ = s.Take(context.Background(), "k", lim, t0.Add(1500*time.Millisecond))
+	if !res.Allowed || res.Remaining != 0 { t.Fatalf("after refill: %+v", res) }
+	if res, _ := s.Take(context.Background(), "other", lim, t0); !res.Allowed || res.Remaining != 1 { t.Fatalf("keys must be independent: %+v", res) }
+
+	s.sweep(t0.Add(time.Hour))
+	if s.Len() != 0 { t.Fatalf("full buckets should be swept, %d left", s.Len()) }
+}
+
+func newLimiter(t *testing.T, p Policy) (*Limiter, *time.Time) {
+	t.Helper()
+	now := time.Unix(1000, 0)
+	l := New(NewMemoryStore(), p, nil)
+	l.now = func() time.Time { return now }
+	return l, &now
+}
+
+func TestGinMiddleware(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	l, _ := newLimiter(t, Policy{Default: Limit{Rate: 10, Burst: 10}, Routes: map[string]Limit{"POST /orders": {Rate: 1.0 / 60, Burst: 1}}, Exempt: []string{"GET /livez"}})
+	l.policy.Clients = map[string]string{"order-service": "s3cret"}
+	l.policy.Internal = Limit{Rate: 1, Burst: 2}
+	r := gin.New()
+	_ = r.SetTrustedProxies(nil)
+	r.Use(l.Gin())
+	ok := func(c *gin.Context) { c.Status(http.StatusNoContent) }
+	r.POST("/orders", ok)
+	r.GET("/livez", ok)
+
+	do := func(method, path, ip, key string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, nil)
+		req.RemoteAddr = ip + ":1234"
+		if key != "" { req.Header.Set(HeaderAPIKey, key) }
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		return w
+	}
+	if w := do("POST", "/orders", "10.0.0.1", ""); w.Code != http.StatusNoContent || w.Header().Get(HeaderRemaining) != "0" || w.Header().Get(HeaderPolicy) != "1;w=60" {
+		t.Fatalf("first request: %d %v", w.Code, w.Header())
+	}
+	w := do("POST", "/orders", "10.0.0.1", "")
+	if w.Code != http.StatusTooManyRequests || w.Header().Get(HeaderRetryAfter) != "60" || w.Header().Get(HeaderLimit) != "1" {
+		t.Fatalf("second request: %d %v", w.Code, w.Header())
+	}
+	if w := do("POST", "/orders", "10.0.0.2", ""); w.Code != http.StatusNoContent { t.Fatalf("other IP limited: %d", w.Code) }
+	if w := do("POST", "/orders", "10.0.0.1", "made-up"); w.Code != http.StatusTooManyRequests { t.Fatalf("unknown API key must not get a fresh bucket: %d", w.Code) }
+	// el servicio interno tiene su bucket y su límite, pero también se agota
+	for i, want := range []int{http.StatusNoContent, http.StatusNoContent, http.StatusTooManyRequests} {
+		if w := do("POST", "/orders", "10.0.0.1", "s3cret"); w.Code != want || w.Header().Get(HeaderLimit) != "2" { t.Fatalf("internal request %d: %d %v", i, w.Code, w.Header()) }
+	}
+	// X-Forwarded-For sólo cuenta desde un proxy de confianza
+	req := httptest.NewRequest("POST", "/orders", nil)
+	req.RemoteAddr, w = "10.0.0.1:1234", httptest.NewRecorder()
+	req.Header.Set("X-Forwarded-For", "192.0.2.7")
+	r.ServeHTTP(w, req)
+	if w.Code != http.StatusTooManyRequests { t.Fatalf("spoofed X-Forwarded-For got a fresh bucket: %d", w.Code) }
+	for i := 0; i < 20; i++ {
+		if w := do("GET", "/livez", "10.0.0.1", ""); w.Code != http.StatusNoContent || w.Header().Get(HeaderLimit) != "" { t.Fatalf("exempt route limited: %d", w.Code) }
+	}
+}
+
+func TestUnaryServerInterceptor(t *testing.T) {
+	l, now := newLimiter(t, Policy{Routes: map[string]Limit{"/user.UserService/AuthenticateUser": {Rate: 1, Burst: 1}}, Clients: map[string]string{"order-service": "s3cret"}})
+	ic := l.UnaryServerInterceptor()
+	info := &grpc.UnaryServerInfo{FullMethod: "/user.UserService/AuthenticateUser"}
+	ctx := peer.NewContext(context.Background(), &peer.Peer{Addr: &net.TCPAddr{IP: net.ParseIP("10.0.0.1"), Port: 5000}})
+	h := func(context.Context, any) (any, error) { return "ok", nil }
+
+	if _, err := ic(ctx, nil, info, h); err != nil { t.Fatal(err) }
+	_, err := ic(ctx, nil, info, h)
+	if status.Code(err) != codes.ResourceExhausted { t.Fatalf("want ResourceExhausted, got %v", err) }
+	internal := metadata.NewIncomingContext(ctx, metadata.Pairs("x-api-key", "s3cret"))
+	if _, err := ic(internal, nil, info, h); err != nil { t.Fatalf("internal client has its own bucket: %v", err) }
+	if _, err := ic(internal, nil, info, h); status.Code(err) != codes.ResourceExhausted { t.Fatalf("internal client must be limited too: %v", err) }
+
+	*now = now.Add(time.Second)
+	if _, err := ic(ctx, nil, info, h); err != nil { t.Fatalf("after refill: %v", err) }
+	// métodos sin regla ni límite por defecto no se limitan
+	if _, err := ic(ctx, nil, &grpc.UnaryServerInfo{FullMethod: "/user.UserService/GetUser"}, h); err != nil { t.Fatal(err) }
+}
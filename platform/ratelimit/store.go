@@ -0,0 +1,78 @@
+package ratelimit
+
+import (
+	"context"
+	"math"
+	"sync"
+	"time"
+)
+
+// Store guarda los buckets. Take consume un token de key si hay y devuelve
+// el estado resultante; debe ser atómico para peticiones concurrentes de la
+// misma key (en un store compartido, también entre réplicas).
+type Store interface {
+	Take(ctx context.Context, key string, l Limit, now time.Time) (Result, error)
+}
+
+type bucket struct {
+	tokens float64
+	last   time.Time
+	full   time.Time // cuándo estará lleno si no llegan más peticiones
+}
+
+// MemoryStore guarda los buckets en memoria del proceso. Cada cierto número
+// de operaciones descarta los buckets que ya se han rellenado por completo,
+// que equivalen a no tener bucket.
+type MemoryStore struct {
+	mu      sync.Mutex
+	buckets map[string]*bucket
+	ops     int
+}
+
+const sweepEvery = 4096
+
+func NewMemoryStore() *MemoryStore { return &MemoryStore{buckets: map[string]*bucket{}} }
+
+func (s *MemoryStore) Take(_ context.Context, key string, l Limit, now time.Time) (Result, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.ops++; s.ops%sweepEvery == 0 { s.sweep(now) }
+
+	burst := float64(l.Burst)
+	b, ok := s.buckets[key]
+	if !ok {
+		b = &bucket{tokens: burst, last: now}
+		s.buckets[key] = b
+	}
+	if elapsed := now.Sub(b.last).Seconds(); elapsed > 0 {
+		b.tokens = math.Min(burst, b.tokens+elapsed*l.Rate)
+		b.last = now
+	}
+
+	res := Result{Limit: l.Burst}
+	if b.tokens >= 1 {
+		b.tokens--
+		res.Allowed = true
+	} else {
+		res.RetryAfter = secondsDuration((1 - b.tokens) / l.Rate)
+	}
+	res.Remaining = int(math.Floor(b.tokens))
+	res.Reset = secondsDuration((burst - b.tokens) / l.Rate)
+	b.full = now.Add(res.Reset)
+	return res, nil
+}
+
+func (s *MemoryStore) sweep(now time.Time) {
+	for k, b := range s.buckets {
+		if !now.Before(b.full) { delete(s.buckets, k) }
+	}
+}
+
+// Len es el número de buckets vivos (para tests y diagnóstico).
+func (s *MemoryStore) Len() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return len(s.buckets)
+}
+
+func secondsDuration(s float64) time.Duration { return time.Duration(s * float64(time.Second)) }
@@ -0,0 +1,108 @@
+// Package server arranca los servidores de un servicio (HTTP, gRPC...) y los
+// para de forma ordenada al recibir SIGINT/SIGTERM.
+package server
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"google.golang.org/grpc"
+)
+
+// Server es algo que se arranca con Serve (bloqueante) y se para con
+// Shutdown. Serve debe devolver nil cuando termina por un Shutdown.
+type Server struct {
+	Name     string
+	Addr     string
+	Serve    func() error
+	Shutdown func(ctx context.Context) error
+}
+
+// HTTP adapta un *http.Server; Shutdown espera a las peticiones en curso.
+func HTTP(name string, srv *http.Server) Server {
+	return Server{
+		Name: name,
+		Addr: srv.Addr,
+		Serve: func() error {
+			if err := srv.ListenAndServe(); !errors.Is(err, http.ErrServerClosed) { return err }
+			return nil
+		},
+		Shutdown: srv.Shutdown,
+	}
+}
+
+// GRPC adapta un *grpc.Server que escucha en addr. Shutdown hace
+// GracefulStop y, si el contexto vence antes, corta las llamadas con Stop.
+func GRPC(name, addr string, srv *grpc.Server) Server {
+	return Server{
+		Name: name,
+		Addr: addr,
+		Serve: func() error {
+			lis, err := net.Listen("tcp", addr)
+			if err != nil { return err }
+			return srv.Serve(lis)
+		},
+		Shutdown: func(ctx context.Context) error {
+			stopped := make(chan struct{})
+			go func() { srv.GracefulStop(); close(stopped) }()
+			select {
+			case <-stopped:
+				return nil
+			case <-ctx.Done():
+				srv.Stop()
+				return ctx.Err()
+			}
+		},
+	}
+}
+
+// Run arranca todos los servidores y bloquea hasta que llega SIGINT/SIGTERM,
+// se cancela ctx o uno de ellos falla. Entonces los para en orden inverso
+// (el último registrado, el primero) con un plazo total de shutdownTimeout.
+// Devuelve el error del servidor que falló, si lo hubo, y los de la parada.
+func Run(ctx context.Context, l *slog.Logger, shutdownTimeout time.Duration, servers ...Server) error {
+	ctx, stop := signal.NotifyContext(ctx, os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	type result struct {
+		name string
+		err  error
+	}
+	done := make(chan result, len(servers))
+	for _, s := range servers {
+		go func(s Server) {
+			if s.Addr != "" { l.Info("server listening", "server", s.Name, "addr", s.Addr) }
+			done <- result{s.Name, s.Serve()}
+		}(s)
+	}
+
+	var errs []error
+	select {
+	case <-ctx.Done():
+		l.Info("shutting down")
+	case r := <-done:
+		err := r.err
+		if err == nil { err = errors.New("stopped unexpectedly") }
+		l.Error("server failed, shutting down", "server", r.name, "error", err)
+		errs = append(errs, fmt.Errorf("%s: %w", r.name, err))
+	}
+
+	sctx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+	defer cancel()
+	for i := len(servers) - 1; i >= 0; i-- {
+		s := servers[i]
+		if err := s.Shutdown(sctx); err != nil {
+			l.Warn("shutdown", "server", s.Name, "error", err)
+			errs = append(errs, fmt.Errorf("%s shutdown: %w", s.Name, err))
+		}
+	}
+	return errors.Join(errs...)
+}
@@ -0,0 +1,57 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"net/http"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"google.golang.org/grpc"
+)
+
+var discard = slog.New(slog.NewTextHandler(io.Discard, nil))
+
+// fake bloquea en Serve hasta su Shutdown y apunta el orden de parada.
+func fake(name string, order *[]string, mu *sync.Mutex) Server {
+	stop := make(chan struct{})
+	return Server{
+		Name:  name,
+		Serve: func() error { <-stop; return nil },
+		Shutdown: func(context.Context) error {
+			mu.Lock(); *order = append(*order, name); mu.Unlock()
+			close(stop)
+			return nil
+		},
+	}
+}
+
+func TestRunStopsInReverseOrder(t *testing.T) {
+	var order []string
+	var mu sync.Mutex
+	ctx, cancel := context.WithCancel(context.Background())
+	go func() { time.Sleep(20 * time.Millisecond); cancel() }()
+	if err := Run(ctx, discard, time.Second, fake("grpc", &order, &mu), fake("metrics", &order, &mu)); err != nil { t.Fatal(err) }
+	if strings.Join(order, ",") != "metrics,grpc" { t.Fatalf("shutdown order = %v", order) }
+}
+
+func TestRunReportsServeError(t *testing.T) {
+	var order []string
+	var mu sync.Mutex
+	broken := Server{Name: "http", Serve: func() error { return errors.New("address already in use") }, Shutdown: func(context.Context) error { return nil }}
+	err := Run(context.Background(), discard, time.Second, fake("grpc", &order, &mu), broken)
+	if err == nil || !strings.Contains(err.Error(), "http: address already in use") { t.Fatalf("err=%v", err) }
+	if len(order) != 1 { t.Fatalf("the other servers must be shut down: %v", order) }
+}
+
+func TestHTTPAndGRPCAdapters(t *testing.T) {
+	h := HTTP("http", &http.Server{Addr: "127.0.0.1:0", Handler: http.NotFoundHandler()})
+	g := GRPC("grpc", "127.0.0.1:0", grpc.NewServer())
+	ctx, cancel := context.WithCancel(context.Background())
+	go func() { time.Sleep(50 * time.Millisecond); cancel() }()
+	if err := Run(ctx, discard, time.Second, g, h); err != nil { t.Fatal(err) }
+}
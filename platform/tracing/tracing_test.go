@@ -0,0 +1,21 @@
+package tracing
+
+import (
+	"context"
+	"testing"
+)
+
+func TestNewExporter(t *testing.T) {
+	ctx := context.Background()
+	for _, name := range []string{"", "none", "NONE"} {
+		if exp, err := newExporter(ctx, name); err != nil || exp != nil {
+			t.Fatalf("%q: want no exporter, got %v %v", name, exp, err)
+		}
+	}
+	if exp, err := newExporter(ctx, "stdout"); err != nil || exp == nil {
+		t.Fatalf("stdout: %v %v", exp, err)
+	}
+	if _, err := newExporter(ctx, "zipkin"); err == nil {
+		t.Fatal("unknown exporter should fail")
+	}
+}
@@ -0,0 +1,40 @@
+// reconcile comprueba que products.stock coincide con la suma del ledger
+// stock_movements y con la suma de product_stock por almacén. Sale con
+// código 1 si encuentra diferencias.
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"text/tabwriter"
+
+	dbpkg "github.com/huntercenter1/backend-test/platform/db"
+
+	"github.com/huntercenter1/backend-test/product-service/internal/config"
+	"github.com/huntercenter1/backend-test/product-service/internal/repo"
+)
+
+func main() {
+	cfg, _, err := config.Load(os.Args[1:])
+	if err != nil { log.Fatalf("config: %v", err) }
+	db, err := dbpkg.New(context.Background(), cfg.DB)
+	if err != nil { log.Fatalf("db: %v", err) }
+	defer db.Close()
+
+	mismatches, err := repo.NewMovementRepo(db).Reconcile(context.Background())
+	if err != nil { log.Fatalf("reconcile: %v", err) }
+	if len(mismatches) == 0 {
+		fmt.Println("stock reconciled: every product matches its ledger and locations")
+		return
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(w, "PRODUCT\tNAME\tSTOCK\tLEDGER\tLOCATIONS")
+	for _, m := range mismatches {
+		fmt.Fprintf(w, "%s\t%s\t%d\t%d\t%d\n", m.ProductID, m.Name, m.Stock, m.Ledger, m.Locations)
+	}
+	w.Flush()
+	os.Exit(1)
+}
@@ -2,44 +2,99 @@ package main
 
 import (
 	"context"
-	"log"
+	"fmt"
+	"log/slog"
 	"net/http"
 	"os"
-	"os/signal"
-	"syscall"
-	"time"
 
 	"github.com/gin-gonic/gin"
 
-	dbpkg "github.com/huntercenter1/backend-test/product-service/internal/db"
+	platformcfg "github.com/huntercenter1/backend-test/platform/config"
+	dbpkg "github.com/huntercenter1/backend-test/platform/db"
+	"github.com/huntercenter1/backend-test/platform/health"
+	"github.com/huntercenter1/backend-test/platform/logging"
+	"github.com/huntercenter1/backend-test/platform/migrate"
+	"github.com/huntercenter1/backend-test/platform/ratelimit"
+	"github.com/huntercenter1/backend-test/platform/server"
+	"github.com/huntercenter1/backend-test/platform/tracing"
+
+	"github.com/huntercenter1/backend-test/product-service/internal/config"
+	"github.com/huntercenter1/backend-test/product-service/internal/inventory"
+	"github.com/huntercenter1/backend-test/product-service/internal/storage"
 	httpr "github.com/huntercenter1/backend-test/product-service/internal/transport/http"
 )
 
 func main() {
-	if err := dbpkg.Migrate(os.Getenv("DB_DSN"), os.Getenv("MIGRATIONS_DIR")); err != nil {
-		log.Fatalf("migrate: %v", err)
+	if len(os.Args) > 1 && os.Args[1] == "migrate" { os.Exit(runMigrate(os.Args[2:])) }
+
+	cfg, printOnly, err := config.Load(os.Args[1:])
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "invalid configuration:\n%v\n", err)
+		os.Exit(2)
 	}
-	db, err := dbpkg.New(context.Background())
-	if err != nil { log.Fatalf("db: %v", err) }
+	if printOnly { cfg.Print(os.Stdout); return }
+
+	logger := logging.New("product-service", cfg.Log.Level)
+	shutdownTracing, err := tracing.Init(context.Background(), "product-service")
+	if err != nil { fatal("tracing", err) }
+	defer func() { _ = shutdownTracing(context.Background()) }()
+
+	// con migrations.auto=false las migraciones se lanzan aparte (`migrate up`)
+	if cfg.Migrations.Auto {
+		if err := migrate.Up(context.Background(), cfg.DB.DSN, cfg.Migrations); err != nil { fatal("migrate", err) }
+	}
+	db, err := dbpkg.New(context.Background(), cfg.DB)
+	if err != nil { fatal("db", err) }
 	defer db.Close()
+	// no servimos con un esquema más antiguo que el que espera el binario
+	if err := migrate.Check(context.Background(), db.DB, cfg.Migrations.Dir); err != nil { fatal("schema check", err) }
+
+	blobs, err := storage.NewLocalStore(cfg.Media.Dir)
+	if err != nil { fatal("media store", err) }
+
+	var limiter *ratelimit.Limiter
+	if cfg.RateLimit.Enabled {
+		policy, _ := cfg.RateLimit.Policy() // ya validada en config.Load
+		policy.Exempt = httpr.ProbeRoutes
+		limiter = ratelimit.New(ratelimit.NewMemoryStore(), policy, logger)
+	}
 
 	r := gin.New()
-	rt := httpr.New(db)
+	if err := r.SetTrustedProxies(platformcfg.Proxies(cfg.HTTP.TrustedProxies)); err != nil { fatal("trusted proxies", err) }
+	regions, _ := inventory.ParseRegions(cfg.Stock.Regions) // ya validadas en config.Load
+	rt := httpr.New(db, httpr.Options{
+		Blobs:          blobs,
+		MaxImageBytes:  cfg.Media.MaxBytes,
+		Allocation:     cfg.Stock.Allocation,
+		Regions:        regions,
+		Logger:         logger,
+		RequestTimeout: cfg.HTTP.RequestTimeout,
+		RateLimit:      limiter,
+		Checks:         []health.Check{health.DB(db.DB), health.Migrations(db.DB, cfg.Migrations.Dir)},
+	})
 	rt.Register(r)
 
-	srv := &http.Server{ Addr: getenv("APP_PORT", ":8081"), Handler: r }
-	go func(){
-		log.Printf("product-service HTTP listening on %s", srv.Addr)
-		if err := srv.ListenAndServe(); err != nil && err != http.ErrServerClosed {
-			log.Fatalf("listen: %v", err)
-		}
-	}()
-
-	quit := make(chan os.Signal, 1)
-	signal.Notify(quit, os.Interrupt, syscall.SIGTERM)
-	<-quit
-	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second); defer cancel()
-	_ = srv.Shutdown(ctx)
+	srv := &http.Server{Addr: cfg.HTTP.Addr, Handler: r}
+	if err := server.Run(context.Background(), logger, cfg.HTTP.ShutdownTimeout, server.HTTP("http", srv)); err != nil {
+		fatal("server", err)
+	}
+}
+
+// runMigrate atiende `product-service migrate <cmd> [flags]`; los flags son
+// los mismos que los del servidor (-config, -db.dsn...).
+func runMigrate(args []string) int {
+	if len(args) == 0 { fmt.Fprintln(os.Stderr, "usage: product-service", migrate.Usage); return 2 }
+	cfg, _, err := config.Load(args[1:])
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "invalid configuration:\n%v\n", err)
+		return 2
+	}
+	logging.New("product-service", cfg.Log.Level)
+	if err := migrate.Command(context.Background(), cfg.DB.DSN, cfg.Migrations, args[0], os.Stdout); err != nil {
+		slog.Error("migrate", "command", args[0], "error", err)
+		return 1
+	}
+	return 0
 }
 
-func getenv(k, d string) string { v := os.Getenv(k); if v == "" { return d }; return v }
+func fatal(msg string, err error) { slog.Error(msg, "error", err); os.Exit(1) }
@@ -0,0 +1,95 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"time"
+
+	platformcfg "github.com/huntercenter1/backend-test/platform/config"
+	"github.com/huntercenter1/backend-test/platform/db"
+	"github.com/huntercenter1/backend-test/platform/logging"
+	"github.com/huntercenter1/backend-test/platform/migrate"
+	"github.com/huntercenter1/backend-test/platform/ratelimit"
+
+	"github.com/huntercenter1/backend-test/product-service/internal/inventory"
+)
+
+type Config struct {
+	Env string `key:"env" env:"APP_ENV"`
+
+	HTTP struct {
+		Addr            string        `key:"addr" env:"APP_PORT"`
+		RequestTimeout  time.Duration `key:"request_timeout" env:"HTTP_REQUEST_TIMEOUT"`
+		ShutdownTimeout time.Duration `key:"shutdown_timeout" env:"HTTP_SHUTDOWN_TIMEOUT"`
+		// TrustedProxies son las IPs o CIDR de los proxies delante del
+		// servicio, separadas por comas: sólo desde ellos vale X-Forwarded-For
+		// para saber la IP del cliente (rate limit, logs). Vacío = ninguno.
+		TrustedProxies string `key:"trusted_proxies" env:"HTTP_TRUSTED_PROXIES"`
+	} `key:"http"`
+
+	DB db.Config `key:"db"`
+
+	Migrations migrate.Config `key:"migrations"`
+
+	Log logging.Config `key:"log"`
+
+	RateLimit ratelimit.Config `key:"rate_limit"`
+
+	Media struct {
+		Dir      string `key:"dir" env:"MEDIA_DIR"`
+		MaxBytes int64  `key:"max_bytes" env:"MEDIA_MAX_BYTES"`
+	} `key:"media"`
+
+	Stock struct {
+		Allocation string `key:"allocation" env:"STOCK_ALLOCATION"`
+		// Regions es el punto de cada región de envío ("ES=40.42:-3.70,...")
+		// para servir las ventas desde el almacén más cercano.
+		Regions string `key:"regions" env:"STOCK_REGIONS"`
+	} `key:"stock"`
+}
+
+func Default() Config {
+	var c Config
+	c.Env = "local"
+	c.HTTP.Addr = ":8081"
+	c.HTTP.RequestTimeout = 5 * time.Second
+	c.HTTP.ShutdownTimeout = 10 * time.Second
+	c.DB = db.DefaultConfig()
+	c.Migrations = migrate.DefaultConfig()
+	c.Log.Level = "info"
+	c.RateLimit = ratelimit.Config{Enabled: true, Default: "600/m", Internal: "6000/m"}
+	c.Media.Dir = "./media"
+	c.Media.MaxBytes = 5 << 20
+	c.Stock.Allocation = "priority"
+	return c
+}
+
+// Load parte de Default y aplica fichero, entorno y flags; después valida.
+func Load(args []string) (Config, bool, error) {
+	c := Default()
+	printOnly, err := platformcfg.Load(&c, args)
+	if err != nil { return c, false, err }
+	return c, printOnly, c.Validate()
+}
+
+// Print escribe la configuración efectiva con los secretos ocultos.
+func (c Config) Print(w io.Writer) { platformcfg.Print(w, &c) }
+
+func (c Config) Validate() error {
+	errs := []error{c.DB.Validate(), c.Migrations.Validate(), c.Log.Validate(), c.RateLimit.Validate()}
+	if c.HTTP.Addr == "" { errs = append(errs, errors.New("http.addr is required (env APP_PORT)")) }
+	if c.HTTP.RequestTimeout <= 0 { errs = append(errs, errors.New("http.request_timeout must be > 0")) }
+	if c.HTTP.ShutdownTimeout <= 0 { errs = append(errs, errors.New("http.shutdown_timeout must be > 0")) }
+	errs = append(errs, platformcfg.ValidateProxies("http.trusted_proxies", c.HTTP.TrustedProxies))
+	if c.Media.Dir == "" { errs = append(errs, errors.New("media.dir is required (env MEDIA_DIR)")) }
+	if c.Media.MaxBytes <= 0 { errs = append(errs, errors.New("media.max_bytes must be > 0")) }
+	// nearest no vale por defecto: una petición sin location ni región
+	// conocida no tendría punto. Se elige por petición con location, o con
+	// la región de envío si está en stock.regions.
+	if c.Stock.Allocation != "priority" {
+		errs = append(errs, fmt.Errorf("stock.allocation must be priority (nearest is chosen per request with a location or a known region), got %q", c.Stock.Allocation))
+	}
+	if _, err := inventory.ParseRegions(c.Stock.Regions); err != nil { errs = append(errs, fmt.Errorf("stock.regions: %w", err)) }
+	return errors.Join(errs...)
+}
@@ -0,0 +1,99 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func writeFile(t *testing.T, name, data string) string {
+	t.Helper()
+	p := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(p, []byte(data), 0o600); err != nil { t.Fatal(err) }
+	return p
+}
+
+func TestLoadPrecedence(t *testing.T) {
+	file := writeFile(t, "product.yaml", `
+http:
+  addr: ":9000"
+  request_timeout: 3s
+db:
+  dsn: postgres://file:secret@db:5432/products_db
+  max_open_conns: 20
+stock:
+  allocation: priority
+`)
+	t.Setenv("CONFIG_FILE", file)
+	t.Setenv("DB_MAX_OPEN_CONNS", "30")
+	cfg, printOnly, err := Load([]string{"-http.addr", ":9100"})
+	if err != nil { t.Fatal(err) }
+	if printOnly { t.Fatal("print-config not requested") }
+
+	switch {
+	case cfg.HTTP.Addr != ":9100":
+		t.Fatalf("flag should win: %q", cfg.HTTP.Addr)
+	case cfg.DB.MaxOpenConns != 30:
+		t.Fatalf("env should override file: %d", cfg.DB.MaxOpenConns)
+	case cfg.HTTP.RequestTimeout != 3*time.Second || cfg.Stock.Allocation != "priority":
+		t.Fatalf("file values not applied: %+v", cfg)
+	case cfg.DB.MaxIdleConns != 5:
+		t.Fatalf("defaults lost: %d", cfg.DB.MaxIdleConns)
+	}
+}
+
+func TestLoadTOML(t *testing.T) {
+	file := writeFile(t, "product.toml", "[db]\ndsn = \"postgres://u:p@db/products_db\"\nping_timeout = \"2s\"\n")
+	cfg, _, err := Load([]string{"-config", file})
+	if err != nil { t.Fatal(err) }
+	if cfg.DB.PingTimeout != 2*time.Second { t.Fatalf("ping_timeout=%v", cfg.DB.PingTimeout) }
+}
+
+func TestLoadReportsAllErrors(t *testing.T) {
+	file := writeFile(t, "bad.yaml", "db:\n  max_open_conns: lots\nhttp:\n  port: 1\n")
+	t.Setenv("STOCK_ALLOCATION", "random")
+	_, _, err := Load([]string{"-config", file})
+	if err == nil { t.Fatal("want error") }
+	for _, want := range []string{`db.max_open_conns: "lots" is not an integer`, `unknown setting "http.port"`} {
+		if !strings.Contains(err.Error(), want) { t.Fatalf("error %q does not mention %q", err, want) }
+	}
+
+	_, _, err = Load(nil)
+	for _, want := range []string{"db.dsn is required", "stock.allocation must be priority"} {
+		if err == nil || !strings.Contains(err.Error(), want) { t.Fatalf("error %v does not mention %q", err, want) }
+	}
+}
+
+func TestNearestIsNotADefault(t *testing.T) {
+	cfg := Default()
+	cfg.DB.DSN = "postgres://u:p@db/products_db"
+	cfg.Stock.Allocation = "nearest"
+	if err := cfg.Validate(); err == nil || !strings.Contains(err.Error(), "stock.allocation must be priority") { t.Fatalf("nearest accepted as default: %v", err) }
+
+	cfg.Stock.Allocation, cfg.Stock.Regions = "priority", "ES=40.42:-3.70,US-CA"
+	if err := cfg.Validate(); err == nil || !strings.Contains(err.Error(), "stock.regions: entry 2") { t.Fatalf("bad regions: %v", err) }
+}
+
+func TestTrustedProxies(t *testing.T) {
+	cfg := Default()
+	cfg.DB.DSN = "postgres://u:p@db/products_db"
+	if cfg.HTTP.TrustedProxies != "" { t.Fatalf("no proxy must be trusted by default: %q", cfg.HTTP.TrustedProxies) }
+	cfg.HTTP.TrustedProxies = "10.0.0.0/8, 192.168.1.10"
+	if err := cfg.Validate(); err != nil { t.Fatalf("err=%v", err) }
+	cfg.HTTP.TrustedProxies = "10.0.0.0/8,lb.internal"
+	if err := cfg.Validate(); err == nil || !strings.Contains(err.Error(), `"lb.internal" is not an IP or CIDR`) { t.Fatalf("err=%v", err) }
+}
+
+func TestPrintRedactsSecrets(t *testing.T) {
+	cfg := Default()
+	cfg.DB.DSN = "postgres://user:hunter2@db:5432/products_db?sslmode=disable"
+	var b strings.Builder
+	cfg.Print(&b)
+	out := b.String()
+	if strings.Contains(out, "hunter2") { t.Fatalf("password leaked:\n%s", out) }
+	if !strings.Contains(out, "db.dsn = postgres://user:xxxxx@db:5432/products_db") || !strings.Contains(out, "# DB_DSN") {
+		t.Fatalf("unexpected output:\n%s", out)
+	}
+}
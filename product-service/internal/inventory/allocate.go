@@ -0,0 +1,147 @@
+package inventory
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+const (
+	StrategyPriority = "priority"
+	StrategyNearest  = "nearest"
+)
+
+// Point es una coordenada geográfica en grados.
+type Point struct {
+	Lat float64 `json:"lat"`
+	Lng float64 `json:"lng"`
+}
+
+// Regions es el punto de referencia de cada región de envío (ES, US-CA...).
+type Regions map[string]Point
+
+// ParseRegions lee "ES=40.42:-3.70,US-CA=36.78:-119.42" (código=lat:lng).
+// Los códigos se guardan en mayúsculas; vacío no da ninguna región.
+func ParseRegions(s string) (Regions, error) {
+	out := Regions{}
+	for i, entry := range strings.Split(s, ",") {
+		if entry = strings.TrimSpace(entry); entry == "" { continue }
+		code, coords, ok := strings.Cut(entry, "=")
+		lat, lng, ok2 := strings.Cut(coords, ":")
+		if !ok || !ok2 || strings.TrimSpace(code) == "" { return nil, fmt.Errorf("entry %d: %q is not region=lat:lng", i+1, entry) }
+		var p Point
+		var err1, err2 error
+		p.Lat, err1 = strconv.ParseFloat(strings.TrimSpace(lat), 64)
+		p.Lng, err2 = strconv.ParseFloat(strings.TrimSpace(lng), 64)
+		if err1 != nil || err2 != nil || math.Abs(p.Lat) > 90 || math.Abs(p.Lng) > 180 {
+			return nil, fmt.Errorf("entry %d: %q has invalid coordinates", i+1, entry)
+		}
+		out[strings.ToUpper(strings.TrimSpace(code))] = p
+	}
+	return out, nil
+}
+
+// Locate devuelve el punto de la región; si no la hay, el del país (US
+// para US-CA).
+func (r Regions) Locate(code string) (*Point, bool) {
+	code = strings.ToUpper(strings.TrimSpace(code))
+	if code == "" { return nil, false }
+	if p, ok := r[code]; ok { return &p, true }
+	country, _, _ := strings.Cut(code, "-")
+	if p, ok := r[country]; ok { return &p, true }
+	return nil, false
+}
+
+// Level es el stock disponible de un producto en un almacén.
+type Level struct {
+	WarehouseID string
+	Quantity    int
+	Priority    int    // menor = se consume antes
+	Location    *Point // nil si el almacén no tiene coordenadas
+}
+
+type Allocation struct {
+	WarehouseID string
+	Quantity    int
+}
+
+// Strategy decide en qué orden se consumen los almacenes al descontar stock.
+type Strategy interface {
+	Order(levels []Level) []Level
+}
+
+// Priority consume primero los almacenes de menor prioridad y, a igualdad,
+// los que más stock tienen para fragmentar menos el pedido.
+type Priority struct{}
+
+func (Priority) Order(levels []Level) []Level {
+	out := append([]Level(nil), levels...)
+	sort.SliceStable(out, func(i, j int) bool {
+		if out[i].Priority != out[j].Priority { return out[i].Priority < out[j].Priority }
+		return out[i].Quantity > out[j].Quantity
+	})
+	return out
+}
+
+// Nearest consume primero los almacenes más cercanos a From. Los almacenes
+// sin coordenadas van al final, en orden de prioridad.
+type Nearest struct{ From Point }
+
+func (n Nearest) Order(levels []Level) []Level {
+	out := Priority{}.Order(levels)
+	sort.SliceStable(out, func(i, j int) bool {
+		li, lj := out[i].Location, out[j].Location
+		switch {
+		case li == nil || lj == nil:
+			return li != nil && lj == nil
+		default:
+			return Distance(n.From, *li) < Distance(n.From, *lj)
+		}
+	})
+	return out
+}
+
+// ErrNoLocation: nearest necesita el punto de entrega de cada petición.
+var ErrNoLocation = errors.New("nearest allocation requires a location")
+
+// StrategyFor devuelve la estrategia por nombre. nearest sin punto de
+// referencia es un error: degradar a priority en silencio escondería que
+// quien llama no manda la ubicación.
+func StrategyFor(name string, from *Point) (Strategy, error) {
+	switch name {
+	case "", StrategyPriority:
+		return Priority{}, nil
+	case StrategyNearest:
+		if from == nil { return nil, ErrNoLocation }
+		return Nearest{From: *from}, nil
+	}
+	return nil, fmt.Errorf("unknown allocation strategy %q", name)
+}
+
+// Allocate reparte qty entre los almacenes en el orden de s. Devuelve lo que
+// pudo asignar, que es menor que qty si no hay stock suficiente.
+func Allocate(levels []Level, qty int, s Strategy) ([]Allocation, int) {
+	var out []Allocation
+	allocated := 0
+	for _, l := range s.Order(levels) {
+		if allocated == qty { break }
+		if l.Quantity <= 0 { continue }
+		take := min(l.Quantity, qty-allocated)
+		out = append(out, Allocation{WarehouseID: l.WarehouseID, Quantity: take})
+		allocated += take
+	}
+	return out, allocated
+}
+
+// Distance es la distancia haversine en km.
+func Distance(a, b Point) float64 {
+	const earthRadiusKm = 6371.0
+	rad := math.Pi / 180
+	dLat := (b.Lat - a.Lat) * rad
+	dLng := (b.Lng - a.Lng) * rad
+	h := math.Sin(dLat/2)*math.Sin(dLat/2) + math.Cos(a.Lat*rad)*math.Cos(b.Lat*rad)*math.Sin(dLng/2)*math.Sin(dLng/2)
+	return 2 * earthRadiusKm * math.Asin(math.Sqrt(h))
+}
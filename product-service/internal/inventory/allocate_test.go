@@ -0,0 +1,54 @@
+package inventory
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestAllocatePriority(t *testing.T) {
+	levels := []Level{
+		{WarehouseID: "b", Quantity: 5, Priority: 10},
+		{WarehouseID: "a", Quantity: 2, Priority: 0},
+		{WarehouseID: "c", Quantity: 9, Priority: 10},
+	}
+	got, n := Allocate(levels, 6, Priority{})
+	if n != 6 { t.Fatalf("want 6 allocated got %d", n) }
+	want := []Allocation{{"a", 2}, {"c", 4}}
+	if len(got) != len(want) || got[0] != want[0] || got[1] != want[1] { t.Fatalf("want %v got %v", want, got) }
+}
+
+func TestAllocateNearest(t *testing.T) {
+	madrid, lisbon := Point{40.4168, -3.7038}, Point{38.7223, -9.1393}
+	levels := []Level{
+		{WarehouseID: "mad", Quantity: 3, Priority: 0, Location: &madrid},
+		{WarehouseID: "lis", Quantity: 3, Priority: 5, Location: &lisbon},
+		{WarehouseID: "nowhere", Quantity: 3, Priority: 1},
+	}
+	s, err := StrategyFor(StrategyNearest, &Point{38.7, -9.1}) // cerca de Lisboa
+	if err != nil { t.Fatal(err) }
+	got, n := Allocate(levels, 7, s)
+	if n != 7 { t.Fatalf("want 7 allocated got %d", n) }
+	if got[0].WarehouseID != "lis" || got[1].WarehouseID != "mad" || got[2].WarehouseID != "nowhere" || got[2].Quantity != 1 {
+		t.Fatalf("unexpected allocation %v", got)
+	}
+}
+
+func TestAllocateInsufficient(t *testing.T) {
+	got, n := Allocate([]Level{{WarehouseID: "a", Quantity: 2}, {WarehouseID: "b", Quantity: 0}}, 5, Priority{})
+	if n != 2 || len(got) != 1 { t.Fatalf("want 2 from a got %d %v", n, got) }
+	if _, err := StrategyFor("random", nil); err == nil { t.Fatalf("unknown strategy accepted") }
+	if _, err := StrategyFor(StrategyNearest, nil); !errors.Is(err, ErrNoLocation) { t.Fatalf("nearest without location: %v", err) }
+}
+
+func TestRegions(t *testing.T) {
+	r, err := ParseRegions(" es=40.42:-3.70, US=39.8:-98.6,")
+	if err != nil || len(r) != 2 { t.Fatalf("regions=%v err=%v", r, err) }
+	if p, ok := r.Locate("ES"); !ok || p.Lat != 40.42 { t.Fatalf("ES: %v %v", p, ok) }
+	// sin la región vale la del país
+	if p, ok := r.Locate("us-ca"); !ok || p.Lng != -98.6 { t.Fatalf("US-CA: %v %v", p, ok) }
+	if _, ok := r.Locate("FR"); ok { t.Fatal("unknown region located") }
+	if _, ok := r.Locate(""); ok { t.Fatal("empty region located") }
+	for _, bad := range []string{"ES", "ES=40.4", "=1:2", "ES=x:1", "ES=91:0"} {
+		if _, err := ParseRegions(bad); err == nil { t.Errorf("%q accepted", bad) }
+	}
+}
@@ -0,0 +1,110 @@
+package media
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"image"
+	"image/color"
+	_ "image/gif" // registra el decoder para image.Decode
+	"image/jpeg"
+	"image/png"
+
+	"github.com/gabriel-vasile/mimetype"
+)
+
+const (
+	ThumbnailSize = 256
+	maxPixels     = 40_000_000 // evita decompression bombs
+)
+
+var ErrUnsupportedType = errors.New("unsupported image type")
+
+// Sólo aceptamos formatos que la stdlib sabe decodificar.
+var allowed = map[string]bool{
+	"image/jpeg": true,
+	"image/png":  true,
+	"image/gif":  true,
+}
+
+// Image es una imagen subida ya validada junto a su thumbnail codificado.
+type Image struct {
+	ContentType   string
+	Width, Height int
+	Thumbnail     []byte
+	ThumbnailType string
+}
+
+// Process detecta el tipo real por contenido (no por extensión ni cabecera),
+// valida dimensiones y genera el thumbnail.
+func Process(data []byte) (*Image, error) {
+	mt := mimetype.Detect(data).String()
+	if !allowed[mt] { return nil, fmt.Errorf("%w: %s", ErrUnsupportedType, mt) }
+
+	cfg, _, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil { return nil, fmt.Errorf("decode image: %w", err) }
+	if cfg.Width <= 0 || cfg.Height <= 0 || cfg.Width*cfg.Height > maxPixels {
+		return nil, fmt.Errorf("image dimensions %dx%d not allowed", cfg.Width, cfg.Height)
+	}
+	src, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil { return nil, fmt.Errorf("decode image: %w", err) }
+
+	thumb := Resize(src, ThumbnailSize)
+	var buf bytes.Buffer
+	out := &Image{ContentType: mt, Width: cfg.Width, Height: cfg.Height}
+	if mt == "image/jpeg" {
+		err = jpeg.Encode(&buf, thumb, &jpeg.Options{Quality: 85})
+		out.ThumbnailType = "image/jpeg"
+	} else {
+		// png y gif pueden tener transparencia
+		err = png.Encode(&buf, thumb)
+		out.ThumbnailType = "image/png"
+	}
+	if err != nil { return nil, err }
+	out.Thumbnail = buf.Bytes()
+	return out, nil
+}
+
+// Resize reduce src para que su lado mayor sea max, manteniendo proporción,
+// promediando cada bloque de píxeles origen (box filter). No amplía.
+func Resize(src image.Image, max int) image.Image {
+	b := src.Bounds()
+	w, h := b.Dx(), b.Dy()
+	if w <= max && h <= max { return src }
+	dw, dh := max, h*max/w
+	if h > w { dw, dh = w*max/h, max }
+	if dw < 1 { dw = 1 }
+	if dh < 1 { dh = 1 }
+
+	dst := image.NewRGBA(image.Rect(0, 0, dw, dh))
+	for y := 0; y < dh; y++ {
+		y0, y1 := b.Min.Y+y*h/dh, b.Min.Y+(y+1)*h/dh
+		for x := 0; x < dw; x++ {
+			x0, x1 := b.Min.X+x*w/dw, b.Min.X+(x+1)*w/dw
+			var r, g, bl, a, n uint64
+			for sy := y0; sy < y1; sy++ {
+				for sx := x0; sx < x1; sx++ {
+					cr, cg, cb, ca := src.At(sx, sy).RGBA()
+					r += uint64(cr); g += uint64(cg); bl += uint64(cb); a += uint64(ca); n++
+				}
+			}
+			dst.SetRGBA(x, y, color.RGBA{
+				R: uint8(r / n >> 8), G: uint8(g / n >> 8), B: uint8(bl / n >> 8), A: uint8(a / n >> 8),
+			})
+		}
+	}
+	return dst
+}
+
+// Extension devuelve la extensión de fichero para un content type soportado.
+func Extension(contentType string) string {
+	switch contentType {
+	case "image/jpeg":
+		return ".jpg"
+	case "image/png":
+		return ".png"
+	case "image/gif":
+		return ".gif"
+	}
+	return ""
+}
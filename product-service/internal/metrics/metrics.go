@@ -0,0 +1,14 @@
+// Package metrics tiene las métricas de negocio de product-service; las de
+// HTTP y del pool de la base están en platform/metrics.
+package metrics
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+// StockOuts cuenta las veces que un producto se queda sin stock.
+var StockOuts = promauto.NewCounter(prometheus.CounterOpts{
+	Name: "product_stock_out_events_total",
+	Help: "Cambios de stock que dejaron un producto a 0.",
+})
@@ -1,26 +0,0 @@
-package middleware
-
-import (
-	"context"
-	"time"
-
-	"github.com/gin-gonic/gin"
-	"github.com/google/uuid"
-)
-
-func RequestID() gin.HandlerFunc {
-	return func(c *gin.Context) {
-		id := uuid.New().String()
-		c.Writer.Header().Set("X-Request-Id", id)
-		c.Next()
-	}
-}
-
-func Timeout(d time.Duration) gin.HandlerFunc {
-	return func(c *gin.Context) {
-		ctx, cancel := context.WithTimeout(c.Request.Context(), d)
-		defer cancel()
-		c.Request = c.Request.WithContext(ctx)
-		c.Next()
-	}
-}
@@ -1,6 +1,7 @@
 package models
 
 import (
+	"context"
 	"time"
 
 	"github.com/uptrace/bun"
@@ -9,11 +10,139 @@ import (
 type Product struct {
 	bun.BaseModel `bun:"table:products,alias:p"`
 
+	ID          string  `bun:"id,pk,type:uuid,default:gen_random_uuid()" json:"id"`
+	SKU         string  `bun:"sku,nullzero" json:"sku,omitempty"` // único si está puesto
+	Name        string  `bun:"name,notnull" json:"name"`
+	Description string  `bun:"description" json:"description"`
+	Price       float64 `bun:"price,notnull" json:"price"`
+	// EffectivePrice es el precio vigente (programado o, si no hay, el base).
+	// Se resuelve al leer, no se persiste.
+	EffectivePrice float64        `bun:"-" json:"effective_price"`
+	Stock          int            `bun:"stock,notnull" json:"stock"`
+	TaxClass       string         `bun:"tax_class,notnull" json:"tax_class"`       // clase fiscal (standard, reduced...)
+	WeightGrams    int            `bun:"weight_grams,notnull" json:"weight_grams"` // peso de envío por unidad
+	Images         []ProductImage `bun:"rel:has-many,join:id=product_id" json:"images,omitempty"`
+	CreatedAt      time.Time      `bun:"created_at,notnull,default:now()" json:"created_at"`
+	UpdatedAt      time.Time      `bun:"updated_at,notnull,default:now()" json:"updated_at"`
+}
+
+type ProductImage struct {
+	bun.BaseModel `bun:"table:product_images,alias:pi"`
+
+	ID               string    `bun:"id,pk,type:uuid,default:gen_random_uuid()" json:"id"`
+	ProductID        string    `bun:"product_id,notnull" json:"product_id"`
+	Position         int       `bun:"position,notnull" json:"position"`
+	ContentType      string    `bun:"content_type,notnull" json:"content_type"`
+	SizeBytes        int64     `bun:"size_bytes,notnull" json:"size_bytes"`
+	Width            int       `bun:"width,notnull" json:"width"`
+	Height           int       `bun:"height,notnull" json:"height"`
+	BlobKey          string    `bun:"blob_key,notnull" json:"-"`
+	ThumbKey         string    `bun:"thumb_key,notnull" json:"-"`
+	ThumbContentType string    `bun:"thumb_content_type,notnull" json:"-"`
+	CreatedAt        time.Time `bun:"created_at,notnull,default:now()" json:"created_at"`
+
+	URL          string `bun:"-" json:"url"`
+	ThumbnailURL string `bun:"-" json:"thumbnail_url"`
+}
+
+var _ bun.AfterScanRowHook = (*ProductImage)(nil)
+
+// AfterScanRow rellena las URLs públicas; se sirven desde el propio servicio
+// así que no dependen del BlobStore configurado.
+func (i *ProductImage) AfterScanRow(ctx context.Context) error {
+	i.SetURLs()
+	return nil
+}
+
+func (i *ProductImage) SetURLs() {
+	i.URL = "/products/" + i.ProductID + "/images/" + i.ID
+	i.ThumbnailURL = i.URL + "/thumbnail"
+}
+
+// PriceChange es una fila de price_history; se escribe cada vez que cambia
+// el precio base. OldPrice es nil para el precio inicial.
+type PriceChange struct {
+	bun.BaseModel `bun:"table:price_history,alias:ph"`
+
+	ID        string    `bun:"id,pk,type:uuid,default:gen_random_uuid()" json:"id"`
+	ProductID string    `bun:"product_id,notnull" json:"product_id"`
+	OldPrice  *float64  `bun:"old_price" json:"old_price"`
+	NewPrice  float64   `bun:"new_price,notnull" json:"new_price"`
+	ChangedAt time.Time `bun:"changed_at,notnull,default:now()" json:"changed_at"`
+}
+
+// ScheduledPrice sustituye al precio base mientras now ∈ [EffectiveFrom, EffectiveTo).
+// EffectiveTo nil significa sin fecha de fin.
+type ScheduledPrice struct {
+	bun.BaseModel `bun:"table:scheduled_prices,alias:sp"`
+
+	ID            string     `bun:"id,pk,type:uuid,default:gen_random_uuid()" json:"id"`
+	ProductID     string     `bun:"product_id,notnull" json:"product_id"`
+	Price         float64    `bun:"price,notnull" json:"price"`
+	EffectiveFrom time.Time  `bun:"effective_from,notnull" json:"effective_from"`
+	EffectiveTo   *time.Time `bun:"effective_to" json:"effective_to"`
+	CreatedAt     time.Time  `bun:"created_at,notnull,default:now()" json:"created_at"`
+}
+
+// Motivos válidos de un movimiento de stock.
+const (
+	StockSale       = "sale"
+	StockRestock    = "restock"
+	StockAdjustment = "adjustment"
+	StockReturn     = "return"
+	// StockTransfer sólo lo generan las transferencias entre almacenes.
+	StockTransfer = "transfer"
+)
+
+func ValidStockReason(r string) bool {
+	switch r {
+	case StockSale, StockRestock, StockAdjustment, StockReturn:
+		return true
+	}
+	return false
+}
+
+// StockMovement es una entrada del ledger de inventario en un almacén.
+// Balance es el stock total del producto tras aplicar Delta; la suma de
+// deltas de un producto debe coincidir con products.stock.
+type StockMovement struct {
+	bun.BaseModel `bun:"table:stock_movements,alias:sm"`
+
 	ID          string    `bun:"id,pk,type:uuid,default:gen_random_uuid()" json:"id"`
-	Name        string    `bun:"name,notnull" json:"name"`
-	Description string    `bun:"description" json:"description"`
-	Price       float64   `bun:"price,notnull" json:"price"`
-	Stock       int       `bun:"stock,notnull" json:"stock"`
+	ProductID   string    `bun:"product_id,notnull" json:"product_id"`
+	WarehouseID string    `bun:"warehouse_id,type:uuid,nullzero" json:"warehouse_id,omitempty"`
+	Delta       int       `bun:"delta,notnull" json:"delta"`
+	Balance     int       `bun:"balance,notnull" json:"balance"`
+	Reason      string    `bun:"reason,notnull" json:"reason"`
+	ReferenceID string    `bun:"reference_id,nullzero" json:"reference_id,omitempty"`
+	Actor       string    `bun:"actor,nullzero" json:"actor,omitempty"`
 	CreatedAt   time.Time `bun:"created_at,notnull,default:now()" json:"created_at"`
-	UpdatedAt   time.Time `bun:"updated_at,notnull,default:now()" json:"updated_at"`
+}
+
+// Warehouse es una ubicación física con stock. Priority menor se consume
+// antes con la estrategia de asignación por prioridad.
+type Warehouse struct {
+	bun.BaseModel `bun:"table:warehouses,alias:w"`
+
+	ID        string    `bun:"id,pk,type:uuid,default:gen_random_uuid()" json:"id"`
+	Code      string    `bun:"code,unique,notnull" json:"code"`
+	Name      string    `bun:"name,notnull" json:"name"`
+	Priority  int       `bun:"priority,notnull" json:"priority"`
+	Latitude  *float64  `bun:"latitude" json:"latitude"`
+	Longitude *float64  `bun:"longitude" json:"longitude"`
+	Active    bool      `bun:"active,notnull" json:"active"`
+	CreatedAt time.Time `bun:"created_at,notnull,default:now()" json:"created_at"`
+	UpdatedAt time.Time `bun:"updated_at,notnull,default:now()" json:"updated_at"`
+}
+
+// ProductStock es el stock de un producto en un almacén. products.stock es
+// la suma de todas sus filas.
+type ProductStock struct {
+	bun.BaseModel `bun:"table:product_stock,alias:ps"`
+
+	ProductID   string     `bun:"product_id,pk,type:uuid" json:"product_id"`
+	WarehouseID string     `bun:"warehouse_id,pk,type:uuid" json:"warehouse_id"`
+	Quantity    int        `bun:"quantity,notnull" json:"quantity"`
+	UpdatedAt   time.Time  `bun:"updated_at,notnull,default:now()" json:"updated_at"`
+	Warehouse   *Warehouse `bun:"rel:belongs-to,join:warehouse_id=id" json:"warehouse,omitempty"`
 }
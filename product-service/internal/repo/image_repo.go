@@ -0,0 +1,117 @@
+package repo
+
+import (
+	"context"
+	"errors"
+
+	"github.com/uptrace/bun"
+
+	"github.com/huntercenter1/backend-test/product-service/internal/models"
+)
+
+var (
+	ErrImageNotFound = errors.New("image not found")
+	ErrInvalidOrder  = errors.New("image order must list every image of the product exactly once")
+)
+
+type ImageRepo interface {
+	Add(ctx context.Context, img *models.ProductImage) (*models.ProductImage, error)
+	List(ctx context.Context, productID string) ([]models.ProductImage, error)
+	Get(ctx context.Context, productID, imageID string) (*models.ProductImage, error)
+	Delete(ctx context.Context, productID, imageID string) (*models.ProductImage, error)
+	Reorder(ctx context.Context, productID string, imageIDs []string) ([]models.ProductImage, error)
+}
+
+type imageRepo struct{ db *bun.DB }
+
+func NewImageRepo(db *bun.DB) ImageRepo { return &imageRepo{db: db} }
+
+// Add inserta la imagen al final de la lista del producto.
+func (r *imageRepo) Add(ctx context.Context, img *models.ProductImage) (*models.ProductImage, error) {
+	ctx, cancel := context.WithTimeout(ctx, timeout); defer cancel()
+	err := r.db.RunInTx(ctx, nil, func(ctx context.Context, tx bun.Tx) error {
+		// bloquea el producto para serializar altas concurrentes sobre el mismo producto
+		var id string
+		if err := forUpdate(tx.NewSelect().Model((*models.Product)(nil)).Column("id").Where("id = ?", img.ProductID)).Scan(ctx, &id); err != nil {
+			return ErrNotFound
+		}
+		var max int
+		if err := tx.NewSelect().Model((*models.ProductImage)(nil)).ColumnExpr("COALESCE(MAX(position), 0)").
+			Where("product_id = ?", img.ProductID).Scan(ctx, &max); err != nil {
+			return err
+		}
+		img.Position = max + 1
+		_, err := tx.NewInsert().Model(img).Returning("*").Exec(ctx)
+		return err
+	})
+	if err != nil { return nil, err }
+	img.SetURLs()
+	return img, nil
+}
+
+func (r *imageRepo) List(ctx context.Context, productID string) ([]models.ProductImage, error) {
+	ctx, cancel := context.WithTimeout(ctx, timeout); defer cancel()
+	items := []models.ProductImage{}
+	err := r.db.NewSelect().Model(&items).Where("product_id = ?", productID).Order("position ASC").Scan(ctx)
+	return items, err
+}
+
+func (r *imageRepo) Get(ctx context.Context, productID, imageID string) (*models.ProductImage, error) {
+	ctx, cancel := context.WithTimeout(ctx, timeout); defer cancel()
+	var img models.ProductImage
+	if err := r.db.NewSelect().Model(&img).Where("id = ? AND product_id = ?", imageID, productID).Scan(ctx); err != nil {
+		return nil, ErrImageNotFound
+	}
+	return &img, nil
+}
+
+// Delete borra la imagen y compacta las posiciones restantes. Devuelve el
+// registro borrado para que el llamador elimine los blobs.
+func (r *imageRepo) Delete(ctx context.Context, productID, imageID string) (*models.ProductImage, error) {
+	ctx, cancel := context.WithTimeout(ctx, timeout); defer cancel()
+	var img models.ProductImage
+	err := r.db.RunInTx(ctx, nil, func(ctx context.Context, tx bun.Tx) error {
+		res, err := tx.NewDelete().Model(&img).Where("id = ? AND product_id = ?", imageID, productID).Returning("*").Exec(ctx)
+		if err != nil { return err }
+		if n, _ := res.RowsAffected(); n == 0 { return ErrImageNotFound }
+		_, err = tx.NewUpdate().Model((*models.ProductImage)(nil)).Set("position = position - 1").
+			Where("product_id = ? AND position > ?", productID, img.Position).Exec(ctx)
+		return err
+	})
+	if err != nil { return nil, err }
+	return &img, nil
+}
+
+// Reorder asigna posiciones 1..n siguiendo imageIDs, que debe contener todas
+// las imágenes del producto.
+func (r *imageRepo) Reorder(ctx context.Context, productID string, imageIDs []string) ([]models.ProductImage, error) {
+	ctx, cancel := context.WithTimeout(ctx, timeout); defer cancel()
+	err := r.db.RunInTx(ctx, nil, func(ctx context.Context, tx bun.Tx) error {
+		var current []string
+		if err := forUpdate(tx.NewSelect().Model((*models.ProductImage)(nil)).Column("id").
+			Where("product_id = ?", productID)).Scan(ctx, &current); err != nil {
+			return err
+		}
+		if !samePermutation(current, imageIDs) { return ErrInvalidOrder }
+		for i, id := range imageIDs {
+			if _, err := tx.NewUpdate().Model((*models.ProductImage)(nil)).Set("position = ?", i+1).
+				Where("id = ?", id).Exec(ctx); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil { return nil, err }
+	return r.List(ctx, productID)
+}
+
+func samePermutation(a, b []string) bool {
+	if len(a) != len(b) { return false }
+	seen := make(map[string]int, len(a))
+	for _, id := range a { seen[id]++ }
+	for _, id := range b {
+		if seen[id] == 0 { return false }
+		seen[id]--
+	}
+	return true
+}
@@ -0,0 +1,39 @@
+package repo
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/huntercenter1/backend-test/product-service/internal/models"
+)
+
+func TestImagePositions(t *testing.T){
+	db := testDB(t)
+	ctx := context.Background()
+	now := time.Now().UTC()
+	p := &models.Product{ID: uuid.NewString(), Name: "Gallery", Price: 1, CreatedAt: now, UpdatedAt: now}
+	if _, err := New(db).Create(ctx, p); err != nil { t.Fatalf("create: %v", err) }
+	r := NewImageRepo(db)
+
+	if _, err := r.Add(ctx, &models.ProductImage{ID: uuid.NewString(), ProductID: uuid.NewString(), CreatedAt: now}); err != ErrNotFound { t.Fatalf("unknown product: want ErrNotFound got %v", err) }
+	var ids []string
+	for i := 0; i < 3; i++ {
+		img, err := r.Add(ctx, &models.ProductImage{ID: uuid.NewString(), ProductID: p.ID, ContentType: "image/png", BlobKey: "b", ThumbKey: "t", ThumbContentType: "image/jpeg", CreatedAt: now})
+		if err != nil { t.Fatalf("add %d: %v", i, err) }
+		// cada alta va al final
+		if img.Position != i+1 { t.Fatalf("add %d: position %d", i, img.Position) }
+		ids = append(ids, img.ID)
+	}
+
+	// borrar compacta las posiciones de las siguientes
+	if _, err := r.Delete(ctx, p.ID, ids[0]); err != nil { t.Fatalf("delete: %v", err) }
+	if _, err := r.Delete(ctx, p.ID, ids[0]); err != ErrImageNotFound { t.Fatalf("delete twice: want ErrImageNotFound got %v", err) }
+	imgs, err := r.List(ctx, p.ID)
+	if err != nil || len(imgs) != 2 || imgs[0].ID != ids[1] || imgs[0].Position != 1 || imgs[1].Position != 2 { t.Fatalf("after delete: %+v %v", imgs, err) }
+
+	if _, err := r.Reorder(ctx, p.ID, []string{ids[2]}); err != ErrInvalidOrder { t.Fatalf("partial order: want ErrInvalidOrder got %v", err) }
+	imgs, err = r.Reorder(ctx, p.ID, []string{ids[2], ids[1]})
+	if err != nil || len(imgs) != 2 || imgs[0].ID != ids[2] || imgs[0].Position != 1 || imgs[1].ID != ids[1] { t.Fatalf("reorder: %+v %v", imgs, err) }
+}
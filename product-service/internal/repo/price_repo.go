@@ -0,0 +1,108 @@
+package repo
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/uptrace/bun"
+
+	"github.com/huntercenter1/backend-test/product-service/internal/models"
+)
+
+var (
+	ErrScheduleNotFound = errors.New("scheduled price not found")
+	ErrScheduleOverlap  = errors.New("scheduled price overlaps an existing schedule")
+)
+
+// PriceRepo gestiona el historial de precios y los precios programados. El
+// precio vigente se resuelve al leer: no hace falta ningún worker que
+// "active" un precio programado, y order-service siempre ve el precio
+// efectivo en el momento del checkout.
+type PriceRepo interface {
+	History(ctx context.Context, productID string, limit, offset int) ([]models.PriceChange, int, error)
+	Schedule(ctx context.Context, sp *models.ScheduledPrice) (*models.ScheduledPrice, error)
+	Schedules(ctx context.Context, productID string) ([]models.ScheduledPrice, error)
+	CancelSchedule(ctx context.Context, productID, scheduleID string) error
+	EffectivePrices(ctx context.Context, productIDs []string, at time.Time) (map[string]float64, error)
+}
+
+type priceRepo struct{ db *bun.DB }
+
+func NewPriceRepo(db *bun.DB) PriceRepo { return &priceRepo{db: db} }
+
+func recordPrice(ctx context.Context, db bun.IDB, productID string, old *float64, price float64) error {
+	_, err := db.NewInsert().Model(&models.PriceChange{
+		ProductID: productID, OldPrice: old, NewPrice: price, ChangedAt: time.Now().UTC(),
+	}).Exec(ctx)
+	return err
+}
+
+func (r *priceRepo) History(ctx context.Context, productID string, limit, offset int) ([]models.PriceChange, int, error) {
+	ctx, cancel := context.WithTimeout(ctx, timeout); defer cancel()
+	items := []models.PriceChange{}
+	total, err := r.db.NewSelect().Model(&items).Where("product_id = ?", productID).
+		Order("changed_at DESC").Limit(limit).Offset(offset).ScanAndCount(ctx)
+	return items, total, err
+}
+
+// Schedule rechaza ventanas que se solapen con otra del mismo producto, así
+// en cada instante hay como mucho un precio programado aplicable.
+func (r *priceRepo) Schedule(ctx context.Context, sp *models.ScheduledPrice) (*models.ScheduledPrice, error) {
+	ctx, cancel := context.WithTimeout(ctx, timeout); defer cancel()
+	err := r.db.RunInTx(ctx, nil, func(ctx context.Context, tx bun.Tx) error {
+		// bloquea el producto para serializar altas concurrentes
+		var id string
+		if err := forUpdate(tx.NewSelect().Model((*models.Product)(nil)).Column("id").Where("id = ?", sp.ProductID)).Scan(ctx, &id); err != nil {
+			return ErrNotFound
+		}
+		q := tx.NewSelect().Model((*models.ScheduledPrice)(nil)).
+			Where("product_id = ?", sp.ProductID).
+			Where("effective_to IS NULL OR effective_to > ?", sp.EffectiveFrom)
+		if sp.EffectiveTo != nil { q = q.Where("effective_from < ?", *sp.EffectiveTo) }
+		n, err := q.Count(ctx)
+		if err != nil { return err }
+		if n > 0 { return ErrScheduleOverlap }
+		_, err = tx.NewInsert().Model(sp).Returning("*").Exec(ctx)
+		return err
+	})
+	if err != nil { return nil, err }
+	return sp, nil
+}
+
+// Schedules devuelve los precios programados que aún no han terminado.
+func (r *priceRepo) Schedules(ctx context.Context, productID string) ([]models.ScheduledPrice, error) {
+	ctx, cancel := context.WithTimeout(ctx, timeout); defer cancel()
+	items := []models.ScheduledPrice{}
+	err := r.db.NewSelect().Model(&items).
+		Where("product_id = ?", productID).
+		Where("effective_to IS NULL OR effective_to > ?", time.Now().UTC()).
+		Order("effective_from ASC").Scan(ctx)
+	return items, err
+}
+
+func (r *priceRepo) CancelSchedule(ctx context.Context, productID, scheduleID string) error {
+	ctx, cancel := context.WithTimeout(ctx, timeout); defer cancel()
+	res, err := r.db.NewDelete().Model((*models.ScheduledPrice)(nil)).
+		Where("id = ? AND product_id = ?", scheduleID, productID).Exec(ctx)
+	if err != nil { return err }
+	if n, _ := res.RowsAffected(); n == 0 { return ErrScheduleNotFound }
+	return nil
+}
+
+// EffectivePrices devuelve el precio programado vigente en at para los
+// productos que tengan uno; los que no aparecen usan su precio base.
+func (r *priceRepo) EffectivePrices(ctx context.Context, productIDs []string, at time.Time) (map[string]float64, error) {
+	out := map[string]float64{}
+	if len(productIDs) == 0 { return out, nil }
+	ctx, cancel := context.WithTimeout(ctx, timeout); defer cancel()
+	var rows []models.ScheduledPrice
+	err := r.db.NewSelect().Model(&rows).
+		Where("product_id IN (?)", bun.In(productIDs)).
+		Where("effective_from <= ?", at).
+		Where("effective_to IS NULL OR effective_to > ?", at).
+		Order("effective_from ASC").Scan(ctx)
+	if err != nil { return nil, err }
+	for _, sp := range rows { out[sp.ProductID] = sp.Price }
+	return out, nil
+}
@@ -0,0 +1,46 @@
+package repo
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/huntercenter1/backend-test/product-service/internal/models"
+)
+
+func TestScheduledPrices(t *testing.T){
+	db := testDB(t)
+	ctx := context.Background()
+	now := time.Now().UTC().Truncate(time.Second)
+	p := &models.Product{ID: uuid.NewString(), Name: "Scheduled", Price: 10, CreatedAt: now, UpdatedAt: now}
+	if _, err := New(db).Create(ctx, p); err != nil { t.Fatalf("create: %v", err) }
+	r := NewPriceRepo(db)
+	at := func(h int) *time.Time { v := now.Add(time.Duration(h) * time.Hour); return &v }
+	schedule := func(price float64, from int, to *time.Time) error {
+		_, err := r.Schedule(ctx, &models.ScheduledPrice{ID: uuid.NewString(), ProductID: p.ID, Price: price, EffectiveFrom: *at(from), EffectiveTo: to, CreatedAt: now})
+		return err
+	}
+
+	if _, err := r.Schedule(ctx, &models.ScheduledPrice{ID: uuid.NewString(), ProductID: uuid.NewString(), Price: 1, EffectiveFrom: now, CreatedAt: now}); err != ErrNotFound { t.Fatalf("unknown product: want ErrNotFound got %v", err) }
+	// [-1h, +2h) y [+2h, ∞) se tocan pero no se solapan
+	if err := schedule(8, -1, at(2)); err != nil { t.Fatalf("first window: %v", err) }
+	if err := schedule(12, 2, nil); err != nil { t.Fatalf("open window: %v", err) }
+	for _, w := range []struct{ from int; to *time.Time }{{1, at(3)}, {-3, at(0)}, {5, at(6)}, {-5, nil}} {
+		if err := schedule(9, w.from, w.to); err != ErrScheduleOverlap { t.Fatalf("window from %+dh: want ErrScheduleOverlap got %v", w.from, err) }
+	}
+
+	for _, tc := range []struct{ at *time.Time; want float64; ok bool }{
+		{at(-2), 0, false}, {at(0), 8, true}, {at(2), 12, true}, {at(100), 12, true},
+	} {
+		prices, err := r.EffectivePrices(ctx, []string{p.ID}, *tc.at)
+		if err != nil { t.Fatalf("effective: %v", err) }
+		if got, ok := prices[p.ID]; ok != tc.ok || got != tc.want { t.Fatalf("price at %s: %v %v, want %v %v", tc.at.Sub(now), got, ok, tc.want, tc.ok) }
+	}
+
+	items, err := r.Schedules(ctx, p.ID)
+	if err != nil || len(items) != 2 || items[0].Price != 8 || items[1].Price != 12 { t.Fatalf("schedules: %+v %v", items, err) }
+	if err := r.CancelSchedule(ctx, p.ID, items[0].ID); err != nil { t.Fatalf("cancel: %v", err) }
+	if err := r.CancelSchedule(ctx, p.ID, items[0].ID); err != ErrScheduleNotFound { t.Fatalf("cancel twice: want ErrScheduleNotFound got %v", err) }
+	if prices, _ := r.EffectivePrices(ctx, []string{p.ID}, now); len(prices) != 0 { t.Fatalf("cancelled schedule still applies: %v", prices) }
+}
@@ -6,49 +6,101 @@ import (
 	"strings"
 	"time"
 
+	"github.com/jackc/pgx/v5/pgconn"
 	"github.com/uptrace/bun"
 
 	"github.com/huntercenter1/backend-test/product-service/internal/models"
 )
 
 var (
-	ErrNotFound = errors.New("product not found")
-	timeout     = 5 * time.Second
+	ErrNotFound     = errors.New("product not found")
+	ErrDuplicateSKU = errors.New("sku already exists")
+	timeout         = 5 * time.Second
 )
 
 type ProductRepo interface {
 	Create(ctx context.Context, p *models.Product) (*models.Product, error)
 	GetByID(ctx context.Context, id string) (*models.Product, error)
-	Update(ctx context.Context, p *models.Product) (*models.Product, error)
+	// Update escribe las columnas de fields (sin fields, todas). El resto se
+	// queda como está en la base de datos aunque p traiga otro valor leído
+	// antes: así un PATCH sin stock no deshace una venta concurrente.
+	Update(ctx context.Context, p *models.Product, fields ...string) (*models.Product, error)
 	Delete(ctx context.Context, id string) error
 	List(ctx context.Context, limit, offset int) ([]models.Product, int, error)
 	Search(ctx context.Context, q string, limit, offset int) ([]models.Product, int, error)
-	UpdateStock(ctx context.Context, id string, delta int) (*models.Product, error)
+	// UpdateStock devuelve el producto y el delta que se aplicó de verdad.
+	// Una venta mayor que el stock falla con ErrInsufficientStock; sólo un
+	// ajuste a la baja se queda en el stock que haya.
+	UpdateStock(ctx context.Context, id string, ch StockChange) (*models.Product, int, error)
 }
 
 type productRepo struct{ db *bun.DB }
 
 func New(db *bun.DB) ProductRepo { return &productRepo{db: db} }
 
+// Create da de alta el producto; el stock inicial entra en el almacén por
+// defecto como un movimiento más del ledger.
 func (r *productRepo) Create(ctx context.Context, p *models.Product) (*models.Product, error) {
 	ctx, cancel := context.WithTimeout(ctx, timeout); defer cancel()
-	_, err := r.db.NewInsert().Model(p).Exec(ctx)
+	initial := p.Stock
+	p.Stock = 0
+	err := r.db.RunInTx(ctx, nil, func(ctx context.Context, tx bun.Tx) error {
+		if _, err := tx.NewInsert().Model(p).Exec(ctx); err != nil { return duplicateSKU(err) }
+		if err := recordPrice(ctx, tx, p.ID, nil, p.Price); err != nil { return err }
+		if initial == 0 { return nil }
+		res, _, err := applyStock(ctx, tx, p.ID, StockChange{Delta: initial, Reason: models.StockAdjustment, ReferenceID: "initial-stock"})
+		if err != nil { return err }
+		p.Stock = res.Stock
+		return nil
+	})
+	if err != nil { p.Stock = initial }
 	return p, err
 }
 
 func (r *productRepo) GetByID(ctx context.Context, id string) (*models.Product, error) {
 	ctx, cancel := context.WithTimeout(ctx, timeout); defer cancel()
 	var p models.Product
-	if err := r.db.NewSelect().Model(&p).Where("id = ?", id).Scan(ctx); err != nil {
+	if err := r.db.NewSelect().Model(&p).Relation("Images", orderImages).Where("p.id = ?", id).Scan(ctx); err != nil {
 		return nil, ErrNotFound
 	}
 	return &p, nil
 }
 
-func (r *productRepo) Update(ctx context.Context, p *models.Product) (*models.Product, error) {
+// updatable son las columnas que admite Update; stock no se escribe
+// directamente, sino como ajuste en el ledger.
+var updatable = []string{"sku", "name", "description", "price", "stock", "tax_class", "weight_grams"}
+
+// Update guarda el producto y, dentro de la misma transacción, registra en
+// price_history el cambio de precio base y en el ledger el ajuste de stock.
+func (r *productRepo) Update(ctx context.Context, p *models.Product, fields ...string) (*models.Product, error) {
 	ctx, cancel := context.WithTimeout(ctx, timeout); defer cancel()
+	if len(fields) == 0 { fields = updatable }
+	set := map[string]bool{}
+	for _, f := range fields { set[f] = true }
+	columns := []string{"updated_at"}
+	for _, c := range updatable { if set[c] && c != "stock" { columns = append(columns, c) } }
+
 	p.UpdatedAt = time.Now()
-	_, err := r.db.NewUpdate().Model(p).Column("name", "description", "price", "stock", "updated_at").WherePK().Exec(ctx)
+	err := r.db.RunInTx(ctx, nil, func(ctx context.Context, tx bun.Tx) error {
+		var old models.Product
+		if err := forUpdate(tx.NewSelect().Model(&old).Column("price", "stock").Where("id = ?", p.ID)).Scan(ctx); err != nil {
+			return ErrNotFound
+		}
+		// lo que no se escribe se devuelve como está ahora, no como se leyó
+		if !set["price"] { p.Price = old.Price }
+		if !set["stock"] { p.Stock = old.Stock }
+		if _, err := tx.NewUpdate().Model(p).Column(columns...).WherePK().Exec(ctx); err != nil {
+			return duplicateSKU(err)
+		}
+		if old.Price != p.Price {
+			if err := recordPrice(ctx, tx, p.ID, &old.Price, p.Price); err != nil { return err }
+		}
+		if p.Stock == old.Stock { return nil }
+		res, _, err := applyStock(ctx, tx, p.ID, StockChange{Delta: p.Stock - old.Stock, Reason: models.StockAdjustment})
+		if err != nil { return err }
+		p.Stock = res.Stock
+		return nil
+	})
 	return p, err
 }
 
@@ -63,7 +115,7 @@ func (r *productRepo) Delete(ctx context.Context, id string) error {
 func (r *productRepo) List(ctx context.Context, limit, offset int) ([]models.Product, int, error) {
 	ctx, cancel := context.WithTimeout(ctx, timeout); defer cancel()
 	var items []models.Product
-	q := r.db.NewSelect().Model(&items).Order("created_at DESC").Limit(limit).Offset(offset)
+	q := r.db.NewSelect().Model(&items).Relation("Images", orderImages).Order("p.created_at DESC").Limit(limit).Offset(offset)
 	if err := q.Scan(ctx); err != nil { return nil, 0, err }
 	total, err := r.db.NewSelect().Model((*models.Product)(nil)).Count(ctx)
 	return items, total, err
@@ -73,21 +125,33 @@ func (r *productRepo) Search(ctx context.Context, qstr string, limit, offset int
 	ctx, cancel := context.WithTimeout(ctx, timeout); defer cancel()
 	qstr = strings.TrimSpace(qstr)
 	var items []models.Product
-	q := r.db.NewSelect().Model(&items).
+	q := r.db.NewSelect().Model(&items).Relation("Images", orderImages).
 		Where("name ILIKE ? OR description ILIKE ?", "%"+qstr+"%", "%"+qstr+"%").
-		Order("created_at DESC").Limit(limit).Offset(offset)
+		Order("p.created_at DESC").Limit(limit).Offset(offset)
 	if err := q.Scan(ctx); err != nil { return nil, 0, err }
 	total, err := r.db.NewSelect().Model((*models.Product)(nil)).
 		Where("name ILIKE ? OR description ILIKE ?", "%"+qstr+"%", "%"+qstr+"%").Count(ctx)
 	return items, total, err
 }
 
-func (r *productRepo) UpdateStock(ctx context.Context, id string, delta int) (*models.Product, error) {
+// UpdateStock aplica un movimiento de inventario y lo registra en el ledger.
+func (r *productRepo) UpdateStock(ctx context.Context, id string, ch StockChange) (*models.Product, int, error) {
 	ctx, cancel := context.WithTimeout(ctx, timeout); defer cancel()
-	p, err := r.GetByID(ctx, id)
-	if err != nil { return nil, err }
-	p.Stock += delta
-	if p.Stock < 0 { p.Stock = 0 }
-	_, err = r.db.NewUpdate().Model(p).Column("stock", "updated_at").WherePK().Exec(ctx)
-	return p, err
+	var p *models.Product
+	var applied int
+	err := r.db.RunInTx(ctx, nil, func(ctx context.Context, tx bun.Tx) error {
+		var err error
+		p, applied, err = applyStock(ctx, tx, id, ch)
+		return err
+	})
+	return p, applied, err
 }
+
+// duplicateSKU traduce la violación de idx_products_sku a ErrDuplicateSKU.
+func duplicateSKU(err error) error {
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) && pgErr.Code == "23505" && pgErr.ConstraintName == "idx_products_sku" { return ErrDuplicateSKU }
+	return err
+}
+
+func orderImages(q *bun.SelectQuery) *bun.SelectQuery { return q.Order("pi.position ASC") }
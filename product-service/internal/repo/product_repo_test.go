@@ -24,13 +24,74 @@ func testDB(t *testing.T) *bun.DB {
 	_, err = db.Exec(`
 		CREATE TABLE IF NOT EXISTS products(
 			id TEXT PRIMARY KEY,
+			sku TEXT UNIQUE,
 			name TEXT NOT NULL,
 			description TEXT,
 			price REAL NOT NULL,
 			stock INTEGER NOT NULL,
+			tax_class TEXT NOT NULL DEFAULT 'standard',
+			weight_grams INTEGER NOT NULL DEFAULT 0,
 			created_at TEXT NOT NULL,
 			updated_at TEXT NOT NULL
 		);
+		CREATE TABLE IF NOT EXISTS product_images(
+			id TEXT PRIMARY KEY,
+			product_id TEXT NOT NULL REFERENCES products(id) ON DELETE CASCADE,
+			position INTEGER NOT NULL,
+			content_type TEXT NOT NULL,
+			size_bytes INTEGER NOT NULL,
+			width INTEGER NOT NULL,
+			height INTEGER NOT NULL,
+			blob_key TEXT NOT NULL,
+			thumb_key TEXT NOT NULL,
+			thumb_content_type TEXT NOT NULL,
+			created_at TEXT NOT NULL
+		);
+		CREATE TABLE IF NOT EXISTS price_history(
+			id TEXT PRIMARY KEY DEFAULT (lower(hex(randomblob(16)))),
+			product_id TEXT NOT NULL,
+			old_price REAL,
+			new_price REAL NOT NULL,
+			changed_at TEXT NOT NULL
+		);
+		CREATE TABLE IF NOT EXISTS scheduled_prices(
+			id TEXT PRIMARY KEY DEFAULT (lower(hex(randomblob(16)))),
+			product_id TEXT NOT NULL,
+			price REAL NOT NULL,
+			effective_from TEXT NOT NULL,
+			effective_to TEXT,
+			created_at TEXT NOT NULL
+		);
+		CREATE TABLE IF NOT EXISTS stock_movements(
+			id TEXT PRIMARY KEY DEFAULT (lower(hex(randomblob(16)))),
+			product_id TEXT NOT NULL,
+			warehouse_id TEXT,
+			delta INTEGER NOT NULL,
+			balance INTEGER NOT NULL,
+			reason TEXT NOT NULL,
+			reference_id TEXT,
+			actor TEXT,
+			created_at TEXT NOT NULL
+		);
+		CREATE TABLE IF NOT EXISTS warehouses(
+			id TEXT PRIMARY KEY,
+			code TEXT UNIQUE NOT NULL,
+			name TEXT NOT NULL,
+			priority INTEGER NOT NULL,
+			latitude REAL,
+			longitude REAL,
+			active BOOLEAN NOT NULL,
+			created_at TEXT NOT NULL,
+			updated_at TEXT NOT NULL
+		);
+		CREATE TABLE IF NOT EXISTS product_stock(
+			product_id TEXT NOT NULL,
+			warehouse_id TEXT NOT NULL,
+			quantity INTEGER NOT NULL CHECK (quantity >= 0),
+			updated_at TEXT NOT NULL,
+			PRIMARY KEY (product_id, warehouse_id)
+		);
+		INSERT OR IGNORE INTO warehouses VALUES ('00000000-0000-0000-0000-000000000001', 'MAIN', 'Main', 0, NULL, NULL, 1, '2025-01-01 00:00:00+00:00', '2025-01-01 00:00:00+00:00');
 	`)
 	if err != nil { t.Fatal(err) }
 	return db
@@ -51,9 +112,149 @@ func TestUpdateStock(t *testing.T){
 	}
 	if _, err := r.Create(context.Background(), p); err != nil { t.Fatalf("create: %v", err) }
 
-	if _, err := r.UpdateStock(context.Background(), p.ID, -3); err != nil { t.Fatalf("update: %v", err) }
+	if _, _, err := r.UpdateStock(context.Background(), p.ID, StockChange{Delta: -3, Reason: models.StockSale}); err != nil { t.Fatalf("update: %v", err) }
 
 	out, err := r.GetByID(context.Background(), p.ID)
 	if err != nil { t.Fatalf("get: %v", err) }
 	if out.Stock != 2 { t.Fatalf("want 2 got %d", out.Stock) }
 }
+
+func TestUpdateRecordsPriceHistory(t *testing.T){
+	db := testDB(t)
+	r := New(db)
+	ctx := context.Background()
+
+	now := time.Now().UTC()
+	p := &models.Product{ID: uuid.NewString(), Name: "B", Price: 10, Stock: 1, CreatedAt: now, UpdatedAt: now}
+	if _, err := r.Create(ctx, p); err != nil { t.Fatalf("create: %v", err) }
+	p.Name = "B2"
+	if _, err := r.Update(ctx, p); err != nil { t.Fatalf("update name: %v", err) }
+	p.Price = 12
+	if _, err := r.Update(ctx, p); err != nil { t.Fatalf("update price: %v", err) }
+
+	history, total, err := NewPriceRepo(db).History(ctx, p.ID, 10, 0)
+	if err != nil { t.Fatalf("history: %v", err) }
+	if total != 2 { t.Fatalf("want 2 price changes (initial + update) got %d", total) }
+	if history[0].NewPrice != 12 || history[0].OldPrice == nil || *history[0].OldPrice != 10 { t.Fatalf("unexpected latest change %+v", history[0]) }
+}
+
+func TestUpdateKeepsFieldsNotSent(t *testing.T){
+	db := testDB(t)
+	r := New(db)
+	ctx := context.Background()
+
+	now := time.Now().UTC()
+	p := &models.Product{ID: uuid.NewString(), Name: "Race", Price: 10, Stock: 5, CreatedAt: now, UpdatedAt: now}
+	if _, err := r.Create(ctx, p); err != nil { t.Fatalf("create: %v", err) }
+	read, _ := r.GetByID(ctx, p.ID)
+	// una venta y un cambio de precio entre la lectura y el PATCH
+	if _, _, err := r.UpdateStock(ctx, p.ID, StockChange{Delta: -2, Reason: models.StockSale}); err != nil { t.Fatalf("sale: %v", err) }
+	if _, err := db.NewUpdate().Model((*models.Product)(nil)).Set("price = 11").Where("id = ?", p.ID).Exec(ctx); err != nil { t.Fatal(err) }
+
+	read.Name = "Race 2"
+	out, err := r.Update(ctx, read, "name")
+	if err != nil { t.Fatalf("update: %v", err) }
+	got, _ := r.GetByID(ctx, p.ID)
+	if got.Name != "Race 2" || got.Stock != 3 || got.Price != 11 || out.Stock != 3 || out.Price != 11 { t.Fatalf("got=%+v out=%+v", got, out) }
+	moves, total, _ := NewMovementRepo(db).List(ctx, p.ID, 10, 0)
+	if total != 2 { t.Fatalf("want only initial stock and sale, got %+v", moves) }
+}
+
+func TestEffectivePrices(t *testing.T){
+	db := testDB(t)
+	ctx := context.Background()
+	now := time.Now().UTC()
+	past, future := now.Add(-time.Hour), now.Add(time.Hour)
+	rows := []models.ScheduledPrice{
+		{ID: uuid.NewString(), ProductID: "a", Price: 5, EffectiveFrom: past, EffectiveTo: &future, CreatedAt: now},
+		{ID: uuid.NewString(), ProductID: "b", Price: 6, EffectiveFrom: future, CreatedAt: now},
+		{ID: uuid.NewString(), ProductID: "c", Price: 7, EffectiveFrom: past.Add(-time.Hour), EffectiveTo: &past, CreatedAt: now},
+	}
+	if _, err := db.NewInsert().Model(&rows).Exec(ctx); err != nil { t.Fatalf("insert: %v", err) }
+
+	got, err := NewPriceRepo(db).EffectivePrices(ctx, []string{"a", "b", "c"}, now)
+	if err != nil { t.Fatalf("effective: %v", err) }
+	if len(got) != 1 || got["a"] != 5 { t.Fatalf("want only a=5 got %v", got) }
+}
+
+func TestStockLedger(t *testing.T){
+	db := testDB(t)
+	r := New(db)
+	ctx := WithActor(context.Background(), "tester")
+
+	now := time.Now().UTC()
+	p := &models.Product{ID: uuid.NewString(), Name: "Ledger", Price: 1, Stock: 4, CreatedAt: now, UpdatedAt: now}
+	if _, err := r.Create(ctx, p); err != nil { t.Fatalf("create: %v", err) }
+	// una venta sin stock suficiente no descuenta nada
+	if _, _, err := r.UpdateStock(ctx, p.ID, StockChange{Delta: -6, Reason: models.StockSale, ReferenceID: "order-0"}); err != ErrInsufficientStock { t.Fatalf("want ErrInsufficientStock got %v", err) }
+	if got, _ := r.GetByID(ctx, p.ID); got.Stock != 4 { t.Fatalf("rejected sale changed stock to %d", got.Stock) }
+	if _, _, err := r.UpdateStock(ctx, p.ID, StockChange{Delta: -3, Reason: models.StockSale, ReferenceID: "order-1"}); err != nil { t.Fatalf("sale: %v", err) }
+	// repetir la venta con la misma referencia no descuenta otra vez y
+	// devuelve lo que se aplicó entonces
+	if got, applied, err := r.UpdateStock(ctx, p.ID, StockChange{Delta: -3, Reason: models.StockSale, ReferenceID: "order-1"}); err != nil || applied != -3 || got.Stock != 1 { t.Fatalf("repeated sale: applied=%d err=%v", applied, err) }
+	// un ajuste a la baja sí se queda en lo que hay
+	if _, applied, err := r.UpdateStock(ctx, p.ID, StockChange{Delta: -6, Reason: models.StockAdjustment}); err != nil || applied != -1 { t.Fatalf("adjust down: applied=%d err=%v", applied, err) }
+	if _, _, err := r.UpdateStock(ctx, p.ID, StockChange{Delta: 10, Reason: models.StockRestock}); err != nil { t.Fatalf("restock: %v", err) }
+	p.Stock = 7
+	if _, err := r.Update(ctx, p); err != nil { t.Fatalf("adjust: %v", err) }
+
+	moves, total, err := NewMovementRepo(db).List(ctx, p.ID, 10, 0)
+	if err != nil { t.Fatalf("list: %v", err) }
+	if total != 5 { t.Fatalf("want 5 movements got %d", total) }
+	sum := 0
+	for _, m := range moves { sum += m.Delta }
+	if sum != 7 { t.Fatalf("ledger sum %d != stock 7", sum) }
+	for _, m := range moves {
+		if m.Reason == models.StockSale && (m.Delta != -3 || m.Balance != 1 || m.ReferenceID != "order-1" || m.Actor != "tester") {
+			t.Fatalf("unexpected sale movement %+v", m)
+		}
+		if m.Reason == models.StockAdjustment && m.Delta == -1 && m.Balance != 0 { t.Fatalf("unexpected clamped adjustment %+v", m) }
+	}
+
+	mismatches, err := NewMovementRepo(db).Reconcile(ctx)
+	if err != nil { t.Fatalf("reconcile: %v", err) }
+	for _, m := range mismatches { if m.ProductID == p.ID { t.Fatalf("product should reconcile: %+v", m) } }
+
+	if _, err := db.NewUpdate().Model((*models.Product)(nil)).Set("stock = 99").Where("id = ?", p.ID).Exec(ctx); err != nil { t.Fatal(err) }
+	mismatches, _ = NewMovementRepo(db).Reconcile(ctx)
+	found := false
+	for _, m := range mismatches { if m.ProductID == p.ID && m.Stock == 99 && m.Ledger == 7 { found = true } }
+	if !found { t.Fatalf("tampered stock not detected: %+v", mismatches) }
+}
+
+func TestMultiWarehouseAllocationAndTransfer(t *testing.T){
+	db := testDB(t)
+	r := New(db)
+	wr := NewWarehouseRepo(db)
+	ctx := context.Background()
+	const main = "00000000-0000-0000-0000-000000000001"
+
+	lat, lng := 38.72, -9.14
+	now := time.Now().UTC()
+	lis, err := wr.Create(ctx, &models.Warehouse{ID: uuid.NewString(), Code: "LIS-" + uuid.NewString()[:8], Name: "Lisbon", Priority: 10, Latitude: &lat, Longitude: &lng, Active: true, CreatedAt: now, UpdatedAt: now})
+	if err != nil { t.Fatalf("warehouse: %v", err) }
+
+	p := &models.Product{ID: uuid.NewString(), Name: "Multi", Price: 1, Stock: 5, CreatedAt: now, UpdatedAt: now}
+	if _, err := r.Create(ctx, p); err != nil { t.Fatalf("create: %v", err) }
+	if _, _, err := r.UpdateStock(ctx, p.ID, StockChange{Delta: 4, Reason: models.StockRestock, WarehouseID: lis.ID}); err != nil { t.Fatalf("restock: %v", err) }
+
+	// por prioridad sale primero de MAIN (5) y el resto de Lisboa
+	out, _, err := r.UpdateStock(ctx, p.ID, StockChange{Delta: -6, Reason: models.StockSale})
+	if err != nil { t.Fatalf("sale: %v", err) }
+	if out.Stock != 3 { t.Fatalf("want aggregate 3 got %d", out.Stock) }
+	levels := map[string]int{}
+	rows, _ := wr.Levels(ctx, p.ID)
+	for _, l := range rows { levels[l.WarehouseID] = l.Quantity }
+	if levels[main] != 0 || levels[lis.ID] != 3 { t.Fatalf("unexpected levels %v", levels) }
+
+	if _, err := wr.Transfer(ctx, Transfer{ProductID: p.ID, FromWarehouseID: main, ToWarehouseID: lis.ID, Quantity: 1}); err != ErrInsufficientStock {
+		t.Fatalf("want ErrInsufficientStock got %v", err)
+	}
+	rows, err = wr.Transfer(ctx, Transfer{ProductID: p.ID, FromWarehouseID: lis.ID, ToWarehouseID: main, Quantity: 2})
+	if err != nil { t.Fatalf("transfer: %v", err) }
+	for _, l := range rows { levels[l.WarehouseID] = l.Quantity }
+	if levels[main] != 2 || levels[lis.ID] != 1 { t.Fatalf("unexpected levels after transfer %v", levels) }
+
+	got, _ := r.GetByID(ctx, p.ID)
+	if got.Stock != 3 { t.Fatalf("transfer must keep aggregate stock, got %d", got.Stock) }
+}
@@ -0,0 +1,249 @@
+package repo
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/uptrace/bun"
+	"github.com/uptrace/bun/dialect"
+
+	"github.com/huntercenter1/backend-test/product-service/internal/inventory"
+	"github.com/huntercenter1/backend-test/product-service/internal/models"
+)
+
+var (
+	ErrNoWarehouse       = errors.New("no active warehouse to receive stock")
+	ErrWarehouseNotFound = errors.New("warehouse not found")
+	// ErrInsufficientStock: no hay stock para una venta o una transferencia.
+	ErrInsufficientStock = errors.New("insufficient stock")
+)
+
+// StockChange describe un movimiento de inventario solicitado.
+//
+// Las entradas (Delta > 0) van a WarehouseID o, si está vacío, al almacén
+// activo de menor prioridad. Las salidas se descuentan de WarehouseID si se
+// indica; si no, se reparten entre almacenes según Allocation (y Near para
+// la estrategia "nearest").
+type StockChange struct {
+	Delta       int
+	Reason      string
+	ReferenceID string
+	Actor       string
+	WarehouseID string
+	Allocation  string
+	Near        *inventory.Point
+}
+
+// StockMismatch es un producto cuyo stock no coincide con la suma del ledger
+// o con la suma de su stock por almacén.
+type StockMismatch struct {
+	ProductID string `bun:"product_id" json:"product_id"`
+	Name      string `bun:"name" json:"name"`
+	Stock     int    `bun:"stock" json:"stock"`
+	Ledger    int    `bun:"ledger" json:"ledger"`
+	Locations int    `bun:"locations" json:"locations"`
+}
+
+type MovementRepo interface {
+	List(ctx context.Context, productID string, limit, offset int) ([]models.StockMovement, int, error)
+	Reconcile(ctx context.Context) ([]StockMismatch, error)
+}
+
+type movementRepo struct{ db *bun.DB }
+
+func NewMovementRepo(db *bun.DB) MovementRepo { return &movementRepo{db: db} }
+
+type actorKey struct{}
+
+// WithActor anota en el contexto quién origina los cambios de stock que
+// hagan Update o UpdateStock sin actor explícito.
+func WithActor(ctx context.Context, actor string) context.Context {
+	return context.WithValue(ctx, actorKey{}, actor)
+}
+
+func actorFrom(ctx context.Context) string {
+	v, _ := ctx.Value(actorKey{}).(string)
+	return v
+}
+
+// forUpdate bloquea las filas leídas en Postgres; SQLite (tests) ya serializa
+// las escrituras y no soporta FOR UPDATE.
+func forUpdate(q *bun.SelectQuery) *bun.SelectQuery {
+	if q.Dialect().Name() == dialect.SQLite { return q }
+	return q.For("UPDATE")
+}
+
+// applyStock aplica ch sobre el producto dentro de tx y deja un movimiento
+// por almacén en el ledger. Una venta sin stock suficiente falla entera con
+// ErrInsufficientStock: quien vende cuenta con haber descontado todo. El
+// resto de salidas (ajustes) se quedan en el stock que haya, nunca por
+// debajo de 0, y el delta registrado es el realmente aplicado, así la suma
+// del ledger sigue cuadrando. Devuelve también ese delta aplicado.
+//
+// Un cambio con ReferenceID se aplica una sola vez por producto y motivo: si
+// el ledger ya tiene movimientos con esos tres datos no se toca el stock y
+// se devuelve lo que se aplicó entonces, así quien reintenta tras un fallo
+// de red no descuenta ni repone dos veces.
+func applyStock(ctx context.Context, tx bun.Tx, id string, ch StockChange) (*models.Product, int, error) {
+	var p models.Product
+	// el bloqueo del producto serializa todos los cambios de su stock,
+	// también los de product_stock
+	if err := forUpdate(tx.NewSelect().Model(&p).Where("id = ?", id)).Scan(ctx); err != nil {
+		if errors.Is(err, sql.ErrNoRows) { return nil, 0, ErrNotFound }
+		return nil, 0, err
+	}
+	if ch.ReferenceID != "" {
+		applied, done, err := appliedBefore(ctx, tx, id, ch)
+		if err != nil || done { return &p, applied, err }
+	}
+	var moves []inventory.Allocation
+	switch {
+	case ch.Delta > 0:
+		wid := ch.WarehouseID
+		if wid == "" {
+			var err error
+			if wid, err = defaultWarehouse(ctx, tx); err != nil { return nil, 0, err }
+		} else if err := checkWarehouse(ctx, tx, wid); err != nil {
+			return nil, 0, err
+		}
+		moves = []inventory.Allocation{{WarehouseID: wid, Quantity: ch.Delta}}
+	case ch.Delta < 0:
+		levels, err := stockLevels(ctx, tx, id, ch.WarehouseID)
+		if err != nil { return nil, 0, err }
+		strategy, err := inventory.StrategyFor(ch.Allocation, ch.Near)
+		if err != nil { return nil, 0, err }
+		allocs, allocated := inventory.Allocate(levels, -ch.Delta, strategy)
+		if ch.Reason == models.StockSale && allocated < -ch.Delta { return nil, 0, ErrInsufficientStock }
+		for _, a := range allocs { moves = append(moves, inventory.Allocation{WarehouseID: a.WarehouseID, Quantity: -a.Quantity}) }
+	}
+
+	applied := 0
+	for _, m := range moves {
+		applied += m.Quantity
+		if err := addLocationStock(ctx, tx, id, m.WarehouseID, m.Quantity); err != nil { return nil, 0, err }
+		p.Stock += m.Quantity
+		mc := ch
+		mc.WarehouseID = m.WarehouseID
+		if err := recordMovement(ctx, tx, p.ID, m.Quantity, p.Stock, mc); err != nil { return nil, 0, err }
+	}
+	p.UpdatedAt = time.Now()
+	if _, err := tx.NewUpdate().Model(&p).Column("stock", "updated_at").WherePK().Exec(ctx); err != nil {
+		return nil, 0, err
+	}
+	return &p, applied, nil
+}
+
+// appliedBefore suma los movimientos del producto con el motivo y la
+// referencia de ch; done indica si había alguno.
+func appliedBefore(ctx context.Context, db bun.IDB, productID string, ch StockChange) (applied int, done bool, err error) {
+	var n int
+	err = db.NewSelect().Model((*models.StockMovement)(nil)).
+		ColumnExpr("COUNT(*), COALESCE(SUM(delta), 0)").
+		Where("product_id = ? AND reason = ? AND reference_id = ?", productID, ch.Reason, ch.ReferenceID).
+		Scan(ctx, &n, &applied)
+	return applied, n > 0, err
+}
+
+// stockLevels devuelve el stock por almacén activo del producto, limitado a
+// onlyWarehouse si se indica.
+func stockLevels(ctx context.Context, db bun.IDB, productID, onlyWarehouse string) ([]inventory.Level, error) {
+	var rows []models.ProductStock
+	q := db.NewSelect().Model(&rows).Relation("Warehouse").
+		Where("ps.product_id = ?", productID).
+		Where("warehouse.active = ?", true)
+	if onlyWarehouse != "" { q = q.Where("ps.warehouse_id = ?", onlyWarehouse) }
+	if err := q.Scan(ctx); err != nil { return nil, err }
+	levels := make([]inventory.Level, 0, len(rows))
+	for _, r := range rows {
+		l := inventory.Level{WarehouseID: r.WarehouseID, Quantity: r.Quantity, Priority: r.Warehouse.Priority}
+		if r.Warehouse.Latitude != nil && r.Warehouse.Longitude != nil {
+			l.Location = &inventory.Point{Lat: *r.Warehouse.Latitude, Lng: *r.Warehouse.Longitude}
+		}
+		levels = append(levels, l)
+	}
+	return levels, nil
+}
+
+// addLocationStock suma qty (puede ser negativo) al stock del producto en el
+// almacén. Las entradas crean la fila si no existe; las salidas sólo pueden
+// tocar filas existentes (el CHECK quantity >= 0 rechazaría el upsert).
+func addLocationStock(ctx context.Context, db bun.IDB, productID, warehouseID string, qty int) error {
+	now := time.Now()
+	if qty < 0 {
+		_, err := db.NewUpdate().Model((*models.ProductStock)(nil)).
+			Set("quantity = quantity + ?", qty).Set("updated_at = ?", now).
+			Where("product_id = ? AND warehouse_id = ?", productID, warehouseID).Exec(ctx)
+		return err
+	}
+	row := &models.ProductStock{ProductID: productID, WarehouseID: warehouseID, Quantity: qty, UpdatedAt: now}
+	_, err := db.NewInsert().Model(row).
+		On("CONFLICT (product_id, warehouse_id) DO UPDATE").
+		Set("quantity = ps.quantity + EXCLUDED.quantity").
+		Set("updated_at = EXCLUDED.updated_at").
+		Exec(ctx)
+	return err
+}
+
+func defaultWarehouse(ctx context.Context, db bun.IDB) (string, error) {
+	var id string
+	err := db.NewSelect().Model((*models.Warehouse)(nil)).Column("id").
+		Where("active = ?", true).Order("priority ASC", "code ASC").Limit(1).Scan(ctx, &id)
+	if err != nil { return "", ErrNoWarehouse }
+	return id, nil
+}
+
+func checkWarehouse(ctx context.Context, db bun.IDB, id string) error {
+	n, err := db.NewSelect().Model((*models.Warehouse)(nil)).Where("id = ? AND active = ?", id, true).Count(ctx)
+	if err != nil { return err }
+	if n == 0 { return ErrWarehouseNotFound }
+	return nil
+}
+
+func recordMovement(ctx context.Context, db bun.IDB, productID string, delta, balance int, ch StockChange) error {
+	if delta == 0 { return nil }
+	if ch.Actor == "" { ch.Actor = actorFrom(ctx) }
+	_, err := db.NewInsert().Model(&models.StockMovement{
+		ProductID:   productID,
+		WarehouseID: ch.WarehouseID,
+		Delta:       delta,
+		Balance:     balance,
+		Reason:      ch.Reason,
+		ReferenceID: ch.ReferenceID,
+		Actor:       ch.Actor,
+		CreatedAt:   time.Now().UTC(),
+	}).Exec(ctx)
+	return err
+}
+
+func (r *movementRepo) List(ctx context.Context, productID string, limit, offset int) ([]models.StockMovement, int, error) {
+	ctx, cancel := context.WithTimeout(ctx, timeout); defer cancel()
+	items := []models.StockMovement{}
+	total, err := r.db.NewSelect().Model(&items).Where("product_id = ?", productID).
+		Order("created_at DESC").Limit(limit).Offset(offset).ScanAndCount(ctx)
+	return items, total, err
+}
+
+// Reconcile devuelve los productos cuyo stock difiere de la suma de su
+// ledger o de la suma de su stock por almacén.
+func (r *movementRepo) Reconcile(ctx context.Context) ([]StockMismatch, error) {
+	var out []StockMismatch
+	ledger := r.db.NewSelect().TableExpr("stock_movements").
+		ColumnExpr("product_id, SUM(delta) AS total").GroupExpr("product_id")
+	locations := r.db.NewSelect().TableExpr("product_stock").
+		ColumnExpr("product_id, SUM(quantity) AS total").GroupExpr("product_id")
+	err := r.db.NewSelect().
+		With("ledger", ledger).
+		With("locations", locations).
+		TableExpr("products AS p").
+		ColumnExpr("p.id AS product_id, p.name, p.stock").
+		ColumnExpr("COALESCE(l.total, 0) AS ledger").
+		ColumnExpr("COALESCE(loc.total, 0) AS locations").
+		Join("LEFT JOIN ledger AS l ON l.product_id = p.id").
+		Join("LEFT JOIN locations AS loc ON loc.product_id = p.id").
+		Where("p.stock <> COALESCE(l.total, 0) OR p.stock <> COALESCE(loc.total, 0)").
+		OrderExpr("p.name").
+		Scan(ctx, &out)
+	return out, err
+}
@@ -0,0 +1,106 @@
+package repo
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/uptrace/bun"
+
+	"github.com/huntercenter1/backend-test/product-service/internal/models"
+)
+
+var ErrDuplicateWarehouse = errors.New("warehouse code already exists")
+
+// Transfer mueve stock de un producto entre dos almacenes; el stock total
+// del producto no cambia.
+type Transfer struct {
+	ProductID       string `json:"product_id"`
+	FromWarehouseID string `json:"from_warehouse_id"`
+	ToWarehouseID   string `json:"to_warehouse_id"`
+	Quantity        int    `json:"quantity"`
+	ReferenceID     string `json:"reference_id"`
+	Actor           string `json:"actor"`
+}
+
+type WarehouseRepo interface {
+	List(ctx context.Context) ([]models.Warehouse, error)
+	Create(ctx context.Context, w *models.Warehouse) (*models.Warehouse, error)
+	Update(ctx context.Context, w *models.Warehouse) (*models.Warehouse, error)
+	GetByID(ctx context.Context, id string) (*models.Warehouse, error)
+	Levels(ctx context.Context, productID string) ([]models.ProductStock, error)
+	Transfer(ctx context.Context, t Transfer) ([]models.ProductStock, error)
+}
+
+type warehouseRepo struct{ db *bun.DB }
+
+func NewWarehouseRepo(db *bun.DB) WarehouseRepo { return &warehouseRepo{db: db} }
+
+func (r *warehouseRepo) List(ctx context.Context) ([]models.Warehouse, error) {
+	ctx, cancel := context.WithTimeout(ctx, timeout); defer cancel()
+	items := []models.Warehouse{}
+	err := r.db.NewSelect().Model(&items).Order("priority ASC", "code ASC").Scan(ctx)
+	return items, err
+}
+
+func (r *warehouseRepo) Create(ctx context.Context, w *models.Warehouse) (*models.Warehouse, error) {
+	ctx, cancel := context.WithTimeout(ctx, timeout); defer cancel()
+	if _, err := r.db.NewInsert().Model(w).Returning("*").Exec(ctx); err != nil {
+		// índice unique en code → tratamos como duplicado
+		return nil, ErrDuplicateWarehouse
+	}
+	return w, nil
+}
+
+func (r *warehouseRepo) Update(ctx context.Context, w *models.Warehouse) (*models.Warehouse, error) {
+	ctx, cancel := context.WithTimeout(ctx, timeout); defer cancel()
+	w.UpdatedAt = time.Now()
+	res, err := r.db.NewUpdate().Model(w).Column("name", "priority", "latitude", "longitude", "active", "updated_at").WherePK().Exec(ctx)
+	if err != nil { return nil, err }
+	if n, _ := res.RowsAffected(); n == 0 { return nil, ErrWarehouseNotFound }
+	return w, nil
+}
+
+func (r *warehouseRepo) GetByID(ctx context.Context, id string) (*models.Warehouse, error) {
+	ctx, cancel := context.WithTimeout(ctx, timeout); defer cancel()
+	var w models.Warehouse
+	if err := r.db.NewSelect().Model(&w).Where("id = ?", id).Scan(ctx); err != nil {
+		return nil, ErrWarehouseNotFound
+	}
+	return &w, nil
+}
+
+func (r *warehouseRepo) Levels(ctx context.Context, productID string) ([]models.ProductStock, error) {
+	ctx, cancel := context.WithTimeout(ctx, timeout); defer cancel()
+	items := []models.ProductStock{}
+	err := r.db.NewSelect().Model(&items).Relation("Warehouse").
+		Where("ps.product_id = ?", productID).
+		Order("warehouse.priority ASC", "warehouse.code ASC").Scan(ctx)
+	return items, err
+}
+
+func (r *warehouseRepo) Transfer(ctx context.Context, t Transfer) ([]models.ProductStock, error) {
+	ctx, cancel := context.WithTimeout(ctx, timeout); defer cancel()
+	err := r.db.RunInTx(ctx, nil, func(ctx context.Context, tx bun.Tx) error {
+		var p models.Product
+		if err := forUpdate(tx.NewSelect().Model(&p).Where("id = ?", t.ProductID)).Scan(ctx); err != nil {
+			return ErrNotFound
+		}
+		if err := checkWarehouse(ctx, tx, t.ToWarehouseID); err != nil { return err }
+		var available int
+		err := tx.NewSelect().Model((*models.ProductStock)(nil)).Column("quantity").
+			Where("product_id = ? AND warehouse_id = ?", t.ProductID, t.FromWarehouseID).Scan(ctx, &available)
+		if err != nil || available < t.Quantity { return ErrInsufficientStock }
+
+		ch := StockChange{Reason: models.StockTransfer, ReferenceID: t.ReferenceID, Actor: t.Actor}
+		for _, m := range []struct{ wid string; qty int }{{t.FromWarehouseID, -t.Quantity}, {t.ToWarehouseID, t.Quantity}} {
+			if err := addLocationStock(ctx, tx, t.ProductID, m.wid, m.qty); err != nil { return err }
+			mc := ch
+			mc.WarehouseID = m.wid
+			if err := recordMovement(ctx, tx, t.ProductID, m.qty, p.Stock, mc); err != nil { return err }
+		}
+		return nil
+	})
+	if err != nil { return nil, err }
+	return r.Levels(ctx, t.ProductID)
+}
@@ -0,0 +1,69 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+var ErrBlobNotFound = errors.New("blob not found")
+
+// BlobStore guarda binarios (imágenes, thumbnails) por clave. Las claves usan
+// "/" como separador, p.ej. "products/<id>/<image>.jpg".
+type BlobStore interface {
+	Put(ctx context.Context, key string, r io.Reader) error
+	Get(ctx context.Context, key string) (io.ReadCloser, error)
+	Delete(ctx context.Context, key string) error
+}
+
+// LocalStore implementa BlobStore sobre el filesystem local bajo root.
+type LocalStore struct{ root string }
+
+func NewLocalStore(root string) (*LocalStore, error) {
+	if root == "" { return nil, errors.New("storage root required") }
+	if err := os.MkdirAll(root, 0o755); err != nil { return nil, err }
+	return &LocalStore{root: root}, nil
+}
+
+func (s *LocalStore) path(key string) (string, error) {
+	clean := filepath.Clean("/" + key)
+	if key == "" || strings.Contains(key, "..") || clean == "/" {
+		return "", fmt.Errorf("invalid blob key %q", key)
+	}
+	return filepath.Join(s.root, filepath.FromSlash(clean)), nil
+}
+
+// Put escribe primero en un temporal y lo renombra, así un lector nunca ve
+// un blob a medio escribir.
+func (s *LocalStore) Put(ctx context.Context, key string, r io.Reader) error {
+	p, err := s.path(key)
+	if err != nil { return err }
+	if err := os.MkdirAll(filepath.Dir(p), 0o755); err != nil { return err }
+	tmp, err := os.CreateTemp(filepath.Dir(p), ".upload-*")
+	if err != nil { return err }
+	defer os.Remove(tmp.Name())
+	if _, err := io.Copy(tmp, r); err != nil { tmp.Close(); return err }
+	if err := tmp.Close(); err != nil { return err }
+	if err := ctx.Err(); err != nil { return err }
+	return os.Rename(tmp.Name(), p)
+}
+
+func (s *LocalStore) Get(ctx context.Context, key string) (io.ReadCloser, error) {
+	p, err := s.path(key)
+	if err != nil { return nil, err }
+	f, err := os.Open(p)
+	if errors.Is(err, os.ErrNotExist) { return nil, ErrBlobNotFound }
+	if err != nil { return nil, err }
+	return f, nil
+}
+
+func (s *LocalStore) Delete(ctx context.Context, key string) error {
+	p, err := s.path(key)
+	if err != nil { return err }
+	if err := os.Remove(p); err != nil && !errors.Is(err, os.ErrNotExist) { return err }
+	return nil
+}
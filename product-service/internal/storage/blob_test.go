@@ -0,0 +1,32 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+)
+
+func TestLocalStoreRoundTrip(t *testing.T) {
+	s, err := NewLocalStore(t.TempDir())
+	if err != nil { t.Fatal(err) }
+	ctx := context.Background()
+
+	if err := s.Put(ctx, "products/p1/a.png", strings.NewReader("data")); err != nil { t.Fatalf("put: %v", err) }
+	rc, err := s.Get(ctx, "products/p1/a.png")
+	if err != nil { t.Fatalf("get: %v", err) }
+	b, _ := io.ReadAll(rc); rc.Close()
+	if string(b) != "data" { t.Fatalf("want data got %q", b) }
+
+	if err := s.Delete(ctx, "products/p1/a.png"); err != nil { t.Fatalf("delete: %v", err) }
+	if _, err := s.Get(ctx, "products/p1/a.png"); !errors.Is(err, ErrBlobNotFound) { t.Fatalf("want ErrBlobNotFound got %v", err) }
+	if err := s.Delete(ctx, "products/p1/a.png"); err != nil { t.Fatalf("delete twice: %v", err) }
+}
+
+func TestLocalStoreRejectsTraversal(t *testing.T) {
+	s, _ := NewLocalStore(t.TempDir())
+	for _, key := range []string{"", "../etc/passwd", "a/../../b"} {
+		if err := s.Put(context.Background(), key, strings.NewReader("x")); err == nil { t.Fatalf("key %q accepted", key) }
+	}
+}
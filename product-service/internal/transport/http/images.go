@@ -0,0 +1,139 @@
+package http
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+
+	"github.com/huntercenter1/backend-test/product-service/internal/media"
+	"github.com/huntercenter1/backend-test/product-service/internal/models"
+	"github.com/huntercenter1/backend-test/product-service/internal/repo"
+	"github.com/huntercenter1/backend-test/product-service/internal/storage"
+)
+
+const DefaultMaxImageBytes = 5 << 20
+
+func (rt *Router) uploadImage(c *gin.Context) {
+	productID := c.Param("id")
+	if _, err := rt.repo.GetByID(c.Request.Context(), productID); err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error":"not found"}); return
+	}
+
+	// margen para las cabeceras multipart
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, rt.maxImageBytes+64<<10)
+	fh, err := c.FormFile("file")
+	if err != nil {
+		var tooBig *http.MaxBytesError
+		if errors.As(err, &tooBig) { c.JSON(http.StatusRequestEntityTooLarge, gin.H{"error": "image too large"}); return }
+		c.JSON(http.StatusBadRequest, gin.H{"error": "multipart field 'file' required"}); return
+	}
+	if fh.Size > rt.maxImageBytes {
+		c.JSON(http.StatusRequestEntityTooLarge, gin.H{"error": fmt.Sprintf("image exceeds %d bytes", rt.maxImageBytes)}); return
+	}
+	f, err := fh.Open()
+	if err != nil { c.JSON(http.StatusBadRequest, gin.H{"error": "cannot read file"}); return }
+	defer f.Close()
+	data, err := io.ReadAll(io.LimitReader(f, rt.maxImageBytes+1))
+	if err != nil { c.JSON(http.StatusBadRequest, gin.H{"error": "cannot read file"}); return }
+	if int64(len(data)) > rt.maxImageBytes {
+		c.JSON(http.StatusRequestEntityTooLarge, gin.H{"error": fmt.Sprintf("image exceeds %d bytes", rt.maxImageBytes)}); return
+	}
+
+	img, err := media.Process(data)
+	if err != nil {
+		if errors.Is(err, media.ErrUnsupportedType) { c.JSON(http.StatusUnsupportedMediaType, gin.H{"error": err.Error()}); return }
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()}); return
+	}
+
+	id := uuid.NewString()
+	rec := &models.ProductImage{
+		ID:               id,
+		ProductID:        productID,
+		ContentType:      img.ContentType,
+		SizeBytes:        int64(len(data)),
+		Width:            img.Width,
+		Height:           img.Height,
+		BlobKey:          "products/" + productID + "/" + id + media.Extension(img.ContentType),
+		ThumbKey:         "products/" + productID + "/" + id + "_thumb" + media.Extension(img.ThumbnailType),
+		ThumbContentType: img.ThumbnailType,
+	}
+	ctx := c.Request.Context()
+	if err := rt.blobs.Put(ctx, rec.BlobKey, bytes.NewReader(data)); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()}); return
+	}
+	if err := rt.blobs.Put(ctx, rec.ThumbKey, bytes.NewReader(img.Thumbnail)); err != nil {
+		rt.removeBlobs(ctx, *rec)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()}); return
+	}
+	res, err := rt.images.Add(ctx, rec)
+	if err != nil {
+		rt.removeBlobs(ctx, *rec)
+		if errors.Is(err, repo.ErrNotFound) { c.JSON(http.StatusNotFound, gin.H{"error":"not found"}); return }
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()}); return
+	}
+	c.JSON(http.StatusCreated, res)
+}
+
+func (rt *Router) listImages(c *gin.Context) {
+	items, err := rt.images.List(c.Request.Context(), c.Param("id"))
+	if err != nil { c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()}); return }
+	c.JSON(http.StatusOK, gin.H{"items": items})
+}
+
+func (rt *Router) serveImage(c *gin.Context) { rt.serveBlob(c, false) }
+
+func (rt *Router) serveThumbnail(c *gin.Context) { rt.serveBlob(c, true) }
+
+func (rt *Router) serveBlob(c *gin.Context, thumb bool) {
+	img, err := rt.images.Get(c.Request.Context(), c.Param("id"), c.Param("image_id"))
+	if err != nil { c.JSON(http.StatusNotFound, gin.H{"error":"not found"}); return }
+	key, ct := img.BlobKey, img.ContentType
+	if thumb { key, ct = img.ThumbKey, img.ThumbContentType }
+	rc, err := rt.blobs.Get(c.Request.Context(), key)
+	if err != nil {
+		if errors.Is(err, storage.ErrBlobNotFound) { c.JSON(http.StatusNotFound, gin.H{"error":"not found"}); return }
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()}); return
+	}
+	defer rc.Close()
+	// el contenido de una imagen nunca cambia: una nueva subida tiene otro id
+	c.Header("Cache-Control", "public, max-age=31536000, immutable")
+	c.DataFromReader(http.StatusOK, -1, ct, rc, nil)
+}
+
+func (rt *Router) deleteImage(c *gin.Context) {
+	img, err := rt.images.Delete(c.Request.Context(), c.Param("id"), c.Param("image_id"))
+	if err != nil {
+		if errors.Is(err, repo.ErrImageNotFound) { c.JSON(http.StatusNotFound, gin.H{"error":"not found"}); return }
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()}); return
+	}
+	rt.removeBlobs(c.Request.Context(), *img)
+	c.Status(http.StatusNoContent)
+}
+
+type reorderBody struct { ImageIDs []string `json:"image_ids"` }
+
+func (rt *Router) reorderImages(c *gin.Context) {
+	var body reorderBody
+	if err := c.ShouldBindJSON(&body); err != nil || len(body.ImageIDs) == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error":"image_ids required"}); return
+	}
+	items, err := rt.images.Reorder(c.Request.Context(), c.Param("id"), body.ImageIDs)
+	if err != nil {
+		if errors.Is(err, repo.ErrInvalidOrder) { c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()}); return }
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()}); return
+	}
+	c.JSON(http.StatusOK, gin.H{"items": items})
+}
+
+// removeBlobs es best-effort: un blob huérfano sólo ocupa disco.
+func (rt *Router) removeBlobs(ctx context.Context, img models.ProductImage) {
+	for _, key := range []string{img.BlobKey, img.ThumbKey} {
+		if err := rt.blobs.Delete(ctx, key); err != nil { rt.log.WarnContext(ctx, "delete blob", "key", key, "error", err) }
+	}
+}
@@ -0,0 +1,172 @@
+package http
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"image"
+	"image/color"
+	"image/png"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"sync"
+	"testing"
+
+	"github.com/huntercenter1/backend-test/product-service/internal/models"
+	"github.com/huntercenter1/backend-test/product-service/internal/repo"
+	"github.com/huntercenter1/backend-test/product-service/internal/storage"
+)
+
+type memBlobs struct {
+	mu   sync.Mutex
+	data map[string][]byte
+}
+
+func newMemBlobs() *memBlobs { return &memBlobs{data: map[string][]byte{}} }
+
+func (m *memBlobs) Put(_ context.Context, key string, r io.Reader) error {
+	b, err := io.ReadAll(r)
+	if err != nil { return err }
+	m.mu.Lock(); defer m.mu.Unlock()
+	m.data[key] = b
+	return nil
+}
+
+func (m *memBlobs) Get(_ context.Context, key string) (io.ReadCloser, error) {
+	m.mu.Lock(); defer m.mu.Unlock()
+	b, ok := m.data[key]
+	if !ok { return nil, storage.ErrBlobNotFound }
+	return io.NopCloser(bytes.NewReader(b)), nil
+}
+
+func (m *memBlobs) Delete(_ context.Context, key string) error {
+	m.mu.Lock(); defer m.mu.Unlock()
+	delete(m.data, key)
+	return nil
+}
+
+type memImages struct {
+	products *memRepo
+	data     map[string]*models.ProductImage
+}
+
+func newMemImages(products *memRepo) *memImages {
+	return &memImages{products: products, data: map[string]*models.ProductImage{}}
+}
+
+func (m *memImages) Add(_ context.Context, img *models.ProductImage) (*models.ProductImage, error) {
+	if _, ok := m.products.data[img.ProductID]; !ok { return nil, repo.ErrNotFound }
+	list, _ := m.List(context.Background(), img.ProductID)
+	img.Position = len(list) + 1
+	img.SetURLs()
+	cp := *img; m.data[img.ID] = &cp
+	return &cp, nil
+}
+
+func (m *memImages) List(_ context.Context, productID string) ([]models.ProductImage, error) {
+	out := []models.ProductImage{}
+	for _, img := range m.data { if img.ProductID == productID { out = append(out, *img) } }
+	sort.Slice(out, func(i, j int) bool { return out[i].Position < out[j].Position })
+	return out, nil
+}
+
+func (m *memImages) Get(_ context.Context, productID, imageID string) (*models.ProductImage, error) {
+	if img, ok := m.data[imageID]; ok && img.ProductID == productID { cp := *img; return &cp, nil }
+	return nil, repo.ErrImageNotFound
+}
+
+func (m *memImages) Delete(ctx context.Context, productID, imageID string) (*models.ProductImage, error) {
+	img, err := m.Get(ctx, productID, imageID)
+	if err != nil { return nil, err }
+	delete(m.data, imageID)
+	for _, o := range m.data { if o.ProductID == productID && o.Position > img.Position { o.Position-- } }
+	return img, nil
+}
+
+func (m *memImages) Reorder(ctx context.Context, productID string, ids []string) ([]models.ProductImage, error) {
+	list, _ := m.List(ctx, productID)
+	if len(list) != len(ids) { return nil, repo.ErrInvalidOrder }
+	for i, id := range ids {
+		img, ok := m.data[id]
+		if !ok || img.ProductID != productID { return nil, repo.ErrInvalidOrder }
+		img.Position = i + 1
+	}
+	return m.List(ctx, productID)
+}
+
+func pngBytes(t *testing.T, w, h int) []byte {
+	img := image.NewRGBA(image.Rect(0, 0, w, h))
+	for x := 0; x < w; x++ { img.Set(x, 0, color.RGBA{R: 255, A: 255}) }
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil { t.Fatal(err) }
+	return buf.Bytes()
+}
+
+func upload(t *testing.T, h http.Handler, productID string, data []byte) *httptest.ResponseRecorder {
+	var body bytes.Buffer
+	mw := multipart.NewWriter(&body)
+	fw, _ := mw.CreateFormFile("file", "image.png")
+	_, _ = fw.Write(data)
+	_ = mw.Close()
+	req := httptest.NewRequest(http.MethodPost, "/products/"+productID+"/images", &body)
+	req.Header.Set("Content-Type", mw.FormDataContentType())
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, req)
+	return w
+}
+
+func TestImageUploadServeAndOrder(t *testing.T) {
+	r, rt, mem := setupRouter(t)
+	p, _ := mem.Create(context.Background(), &models.Product{Name: "Lamp", Price: 20, Stock: 1})
+
+	var ids []string
+	for i := 0; i < 2; i++ {
+		w := upload(t, r, p.ID, pngBytes(t, 600, 300))
+		if w.Code != http.StatusCreated { t.Fatalf("upload code=%d body=%s", w.Code, w.Body.String()) }
+		var img models.ProductImage
+		_ = json.Unmarshal(w.Body.Bytes(), &img)
+		if img.Position != i+1 || img.Width != 600 || img.URL == "" { t.Fatalf("unexpected image %+v", img) }
+		ids = append(ids, img.ID)
+	}
+
+	// thumbnail reducido manteniendo proporción
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/products/"+p.ID+"/images/"+ids[0]+"/thumbnail", nil))
+	if w.Code != http.StatusOK { t.Fatalf("thumb code=%d", w.Code) }
+	cfg, err := png.DecodeConfig(w.Body)
+	if err != nil || cfg.Width != 256 || cfg.Height != 128 { t.Fatalf("thumb %dx%d err=%v", cfg.Width, cfg.Height, err) }
+
+	// reordenar
+	w = httptest.NewRecorder()
+	buf, _ := json.Marshal(reorderBody{ImageIDs: []string{ids[1], ids[0]}})
+	req := httptest.NewRequest(http.MethodPut, "/products/"+p.ID+"/images/order", bytes.NewReader(buf))
+	req.Header.Set("Content-Type", "application/json")
+	r.ServeHTTP(w, req)
+	if w.Code != http.StatusOK { t.Fatalf("reorder code=%d", w.Code) }
+	if rt.images.(*memImages).data[ids[1]].Position != 1 { t.Fatalf("reorder not applied") }
+
+	// borrar elimina también los blobs
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodDelete, "/products/"+p.ID+"/images/"+ids[1], nil))
+	if w.Code != http.StatusNoContent { t.Fatalf("delete code=%d", w.Code) }
+	if n := len(rt.blobs.(*memBlobs).data); n != 2 { t.Fatalf("want 2 blobs left got %d", n) }
+}
+
+func TestImageUploadValidation(t *testing.T) {
+	r, rt, mem := setupRouter(t)
+	p, _ := mem.Create(context.Background(), &models.Product{Name: "Lamp", Price: 20, Stock: 1})
+
+	if w := upload(t, r, p.ID, []byte("%PDF-1.4 not an image")); w.Code != http.StatusUnsupportedMediaType {
+		t.Fatalf("pdf: want 415 got %d", w.Code)
+	}
+	rt.maxImageBytes = 100
+	if w := upload(t, r, p.ID, pngBytes(t, 300, 300)); w.Code != http.StatusRequestEntityTooLarge {
+		t.Fatalf("big: want 413 got %d", w.Code)
+	}
+	if w := upload(t, r, "missing", pngBytes(t, 10, 10)); w.Code != http.StatusNotFound {
+		t.Fatalf("missing product: want 404 got %d", w.Code)
+	}
+}
@@ -0,0 +1,128 @@
+package http
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/huntercenter1/backend-test/product-service/internal/models"
+)
+
+const (
+	maxNameLen     = 100 // products.name es VARCHAR(100)
+	maxTaxClassLen = 32  // products.tax_class es VARCHAR(32)
+	maxSKULen      = 64  // products.sku es VARCHAR(64)
+
+	// DefaultTaxClass es la clase fiscal de los productos que no indican otra.
+	DefaultTaxClass = "standard"
+)
+
+// productInput es la representación completa que exige PUT. Los punteros
+// permiten distinguir "ausente" de "valor cero".
+type productInput struct {
+	SKU         *string  `json:"sku"`
+	Name        *string  `json:"name"`
+	Description *string  `json:"description"`
+	Price       *float64 `json:"price"`
+	Stock       *int     `json:"stock"`
+	TaxClass    *string  `json:"tax_class"`
+	WeightGrams *int     `json:"weight_grams"`
+}
+
+// replace valida la representación completa y la copia sobre p.
+// sku, description, tax_class y weight_grams son opcionales: si no vienen se
+// guardan vacíos, DefaultTaxClass y 0.
+func (in productInput) replace(p *models.Product) error {
+	switch {
+	case in.Name == nil:
+		return fmt.Errorf("name is required")
+	case in.Price == nil:
+		return fmt.Errorf("price is required")
+	case in.Stock == nil:
+		return fmt.Errorf("stock is required")
+	}
+	p.Name = strings.TrimSpace(*in.Name)
+	p.SKU = ""
+	if in.SKU != nil { p.SKU = *in.SKU }
+	p.Description = ""
+	if in.Description != nil { p.Description = *in.Description }
+	p.Price = *in.Price
+	p.Stock = *in.Stock
+	p.TaxClass, p.WeightGrams = "", 0
+	if in.TaxClass != nil { p.TaxClass = *in.TaxClass }
+	if in.WeightGrams != nil { p.WeightGrams = *in.WeightGrams }
+	return validateProduct(p)
+}
+
+// applyMergePatch aplica un JSON Merge Patch (RFC 7386) sobre p: los campos
+// ausentes no se tocan y un null explícito borra el campo. sku, description,
+// tax_class y weight_grams admiten null (vuelven a su valor por defecto); el
+// resto de campos son obligatorios en el modelo. Devuelve los campos del
+// patch, que son los únicos que hay que guardar.
+func applyMergePatch(p *models.Product, raw []byte) ([]string, error) {
+	var patch map[string]json.RawMessage
+	if err := json.Unmarshal(raw, &patch); err != nil || patch == nil {
+		return nil, fmt.Errorf("body must be a JSON object")
+	}
+	fields := make([]string, 0, len(patch))
+	for field, v := range patch {
+		fields = append(fields, field)
+		null := bytes.Equal(bytes.TrimSpace(v), []byte("null"))
+		switch field {
+		case "name":
+			if null { return nil, fmt.Errorf("name cannot be null") }
+			if err := json.Unmarshal(v, &p.Name); err != nil { return nil, fmt.Errorf("name must be a string") }
+			p.Name = strings.TrimSpace(p.Name)
+		case "sku":
+			if null { p.SKU = ""; continue }
+			if err := json.Unmarshal(v, &p.SKU); err != nil { return nil, fmt.Errorf("sku must be a string") }
+		case "description":
+			if null { p.Description = ""; continue }
+			if err := json.Unmarshal(v, &p.Description); err != nil { return nil, fmt.Errorf("description must be a string") }
+		case "price":
+			if null { return nil, fmt.Errorf("price cannot be null") }
+			if err := json.Unmarshal(v, &p.Price); err != nil { return nil, fmt.Errorf("price must be a number") }
+		case "stock":
+			if null { return nil, fmt.Errorf("stock cannot be null") }
+			if err := json.Unmarshal(v, &p.Stock); err != nil { return nil, fmt.Errorf("stock must be an integer") }
+		case "tax_class":
+			if null { p.TaxClass = ""; continue }
+			if err := json.Unmarshal(v, &p.TaxClass); err != nil { return nil, fmt.Errorf("tax_class must be a string") }
+		case "weight_grams":
+			if null { p.WeightGrams = 0; continue }
+			if err := json.Unmarshal(v, &p.WeightGrams); err != nil { return nil, fmt.Errorf("weight_grams must be an integer") }
+		case "id", "created_at", "updated_at":
+			return nil, fmt.Errorf("%s is read-only", field)
+		default:
+			return nil, fmt.Errorf("unknown field %q", field)
+		}
+	}
+	return fields, validateProduct(p)
+}
+
+// validateProduct normaliza sku (sin espacios) y tax_class (minúsculas,
+// DefaultTaxClass si viene vacía) y valida el producto.
+func validateProduct(p *models.Product) error {
+	p.SKU = strings.TrimSpace(p.SKU)
+	p.TaxClass = strings.ToLower(strings.TrimSpace(p.TaxClass))
+	if p.TaxClass == "" { p.TaxClass = DefaultTaxClass }
+	switch {
+	case p.Name == "":
+		return fmt.Errorf("name must not be empty")
+	case utf8.RuneCountInString(p.Name) > maxNameLen:
+		return fmt.Errorf("name must be at most %d characters", maxNameLen)
+	case p.Price <= 0:
+		return fmt.Errorf("price must be > 0")
+	case p.Stock < 0:
+		return fmt.Errorf("stock must be >= 0")
+	case len(p.SKU) > maxSKULen:
+		return fmt.Errorf("sku must be at most %d characters", maxSKULen)
+	case len(p.TaxClass) > maxTaxClassLen:
+		return fmt.Errorf("tax_class must be at most %d characters", maxTaxClassLen)
+	case p.WeightGrams < 0:
+		return fmt.Errorf("weight_grams must be >= 0")
+	}
+	return nil
+}
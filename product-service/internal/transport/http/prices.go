@@ -0,0 +1,95 @@
+package http
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/huntercenter1/backend-test/product-service/internal/models"
+	"github.com/huntercenter1/backend-test/product-service/internal/repo"
+)
+
+// resolvePrices rellena EffectivePrice con el precio programado vigente o,
+// si no hay ninguno, con el precio base.
+func (rt *Router) resolvePrices(ctx context.Context, items ...*models.Product) error {
+	ids := make([]string, 0, len(items))
+	for _, p := range items { ids = append(ids, p.ID) }
+	active, err := rt.prices.EffectivePrices(ctx, ids, time.Now().UTC())
+	if err != nil { return err }
+	for _, p := range items {
+		p.EffectivePrice = p.Price
+		if v, ok := active[p.ID]; ok { p.EffectivePrice = v }
+	}
+	return nil
+}
+
+func (rt *Router) resolveList(ctx context.Context, items []models.Product) error {
+	ptrs := make([]*models.Product, len(items))
+	for i := range items { ptrs[i] = &items[i] }
+	return rt.resolvePrices(ctx, ptrs...)
+}
+
+func (rt *Router) priceHistory(c *gin.Context) {
+	id := c.Param("id")
+	p, err := rt.repo.GetByID(c.Request.Context(), id)
+	if err != nil { c.JSON(http.StatusNotFound, gin.H{"error":"not found"}); return }
+	if err := rt.resolvePrices(c.Request.Context(), p); err != nil { c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()}); return }
+	limit, offset := parsePag(c)
+	history, total, err := rt.prices.History(c.Request.Context(), id, limit, offset)
+	if err != nil { c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()}); return }
+	schedules, err := rt.prices.Schedules(c.Request.Context(), id)
+	if err != nil { c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()}); return }
+	c.JSON(http.StatusOK, gin.H{
+		"price":           p.Price,
+		"effective_price": p.EffectivePrice,
+		"history":         gin.H{"items": history, "total": total, "limit": limit, "offset": offset},
+		"schedules":       schedules,
+	})
+}
+
+type scheduleBody struct {
+	Price         float64    `json:"price"`
+	EffectiveFrom time.Time  `json:"effective_from"`
+	EffectiveTo   *time.Time `json:"effective_to"`
+}
+
+func (rt *Router) schedulePrice(c *gin.Context) {
+	var body scheduleBody
+	if err := c.ShouldBindJSON(&body); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error":"invalid body"}); return
+	}
+	switch {
+	case body.Price <= 0:
+		c.JSON(http.StatusBadRequest, gin.H{"error":"price must be > 0"}); return
+	case body.EffectiveFrom.IsZero():
+		c.JSON(http.StatusBadRequest, gin.H{"error":"effective_from required"}); return
+	case body.EffectiveTo != nil && !body.EffectiveTo.After(body.EffectiveFrom):
+		c.JSON(http.StatusBadRequest, gin.H{"error":"effective_to must be after effective_from"}); return
+	case body.EffectiveTo != nil && !body.EffectiveTo.After(time.Now()):
+		c.JSON(http.StatusBadRequest, gin.H{"error":"effective_to is in the past"}); return
+	}
+	sp := &models.ScheduledPrice{ProductID: c.Param("id"), Price: body.Price, EffectiveFrom: body.EffectiveFrom.UTC()}
+	if body.EffectiveTo != nil { to := body.EffectiveTo.UTC(); sp.EffectiveTo = &to }
+	res, err := rt.prices.Schedule(c.Request.Context(), sp)
+	switch {
+	case errors.Is(err, repo.ErrNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error":"not found"})
+	case errors.Is(err, repo.ErrScheduleOverlap):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+	case err != nil:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	default:
+		c.JSON(http.StatusCreated, res)
+	}
+}
+
+func (rt *Router) cancelSchedule(c *gin.Context) {
+	if err := rt.prices.CancelSchedule(c.Request.Context(), c.Param("id"), c.Param("schedule_id")); err != nil {
+		if errors.Is(err, repo.ErrScheduleNotFound) { c.JSON(http.StatusNotFound, gin.H{"error":"not found"}); return }
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()}); return
+	}
+	c.Status(http.StatusNoContent)
+}
@@ -0,0 +1,109 @@
+package http
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/huntercenter1/backend-test/product-service/internal/models"
+	"github.com/huntercenter1/backend-test/product-service/internal/repo"
+)
+
+type memPrices struct {
+	history   []models.PriceChange
+	schedules []models.ScheduledPrice
+}
+
+func (m *memPrices) History(_ context.Context, productID string, limit, offset int) ([]models.PriceChange, int, error) {
+	out := []models.PriceChange{}
+	for _, h := range m.history { if h.ProductID == productID { out = append(out, h) } }
+	return out, len(out), nil
+}
+
+func (m *memPrices) Schedule(_ context.Context, sp *models.ScheduledPrice) (*models.ScheduledPrice, error) {
+	for _, o := range m.schedules {
+		if o.ProductID != sp.ProductID { continue }
+		if (o.EffectiveTo == nil || o.EffectiveTo.After(sp.EffectiveFrom)) && (sp.EffectiveTo == nil || o.EffectiveFrom.Before(*sp.EffectiveTo)) {
+			return nil, repo.ErrScheduleOverlap
+		}
+	}
+	sp.ID = uuid.NewString()
+	m.schedules = append(m.schedules, *sp)
+	return sp, nil
+}
+
+func (m *memPrices) Schedules(_ context.Context, productID string) ([]models.ScheduledPrice, error) {
+	out := []models.ScheduledPrice{}
+	for _, sp := range m.schedules { if sp.ProductID == productID { out = append(out, sp) } }
+	return out, nil
+}
+
+func (m *memPrices) CancelSchedule(_ context.Context, productID, scheduleID string) error {
+	for i, sp := range m.schedules {
+		if sp.ID == scheduleID && sp.ProductID == productID {
+			m.schedules = append(m.schedules[:i], m.schedules[i+1:]...)
+			return nil
+		}
+	}
+	return repo.ErrScheduleNotFound
+}
+
+func (m *memPrices) EffectivePrices(_ context.Context, ids []string, at time.Time) (map[string]float64, error) {
+	out := map[string]float64{}
+	for _, sp := range m.schedules {
+		if !sp.EffectiveFrom.After(at) && (sp.EffectiveTo == nil || sp.EffectiveTo.After(at)) { out[sp.ProductID] = sp.Price }
+	}
+	return out, nil
+}
+
+func postSchedule(t *testing.T, h http.Handler, productID string, body scheduleBody) *httptest.ResponseRecorder {
+	buf, _ := json.Marshal(body)
+	req := httptest.NewRequest(http.MethodPost, "/products/"+productID+"/prices/schedules", bytes.NewReader(buf))
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, req)
+	return w
+}
+
+func TestScheduledPriceResolvedAtRead(t *testing.T) {
+	r, _, mem := setupRouter(t)
+	p, _ := mem.Create(context.Background(), &models.Product{Name: "Chair", Price: 100, Stock: 3})
+
+	now := time.Now().UTC()
+	end := now.Add(time.Hour)
+	if w := postSchedule(t, r, p.ID, scheduleBody{Price: 80, EffectiveFrom: now.Add(-time.Minute), EffectiveTo: &end}); w.Code != http.StatusCreated {
+		t.Fatalf("schedule code=%d body=%s", w.Code, w.Body.String())
+	}
+	// futuro y solapado con el anterior
+	if w := postSchedule(t, r, p.ID, scheduleBody{Price: 70, EffectiveFrom: now.Add(30 * time.Minute)}); w.Code != http.StatusConflict {
+		t.Fatalf("overlap: want 409 got %d", w.Code)
+	}
+	if w := postSchedule(t, r, p.ID, scheduleBody{Price: 0, EffectiveFrom: now}); w.Code != http.StatusBadRequest {
+		t.Fatalf("zero price: want 400 got %d", w.Code)
+	}
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/products/"+p.ID, nil))
+	var got models.Product
+	_ = json.Unmarshal(w.Body.Bytes(), &got)
+	if got.Price != 100 || got.EffectivePrice != 80 { t.Fatalf("want price 100 effective 80 got %v/%v", got.Price, got.EffectivePrice) }
+
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/products/"+p.ID+"/prices", nil))
+	if w.Code != http.StatusOK { t.Fatalf("prices code=%d", w.Code) }
+	var resp struct {
+		EffectivePrice float64                 `json:"effective_price"`
+		Schedules      []models.ScheduledPrice `json:"schedules"`
+	}
+	_ = json.Unmarshal(w.Body.Bytes(), &resp)
+	if resp.EffectivePrice != 80 || len(resp.Schedules) != 1 { t.Fatalf("unexpected prices response %s", w.Body.String()) }
+
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodDelete, "/products/"+p.ID+"/prices/schedules/"+resp.Schedules[0].ID, nil))
+	if w.Code != http.StatusNoContent { t.Fatalf("cancel code=%d", w.Code) }
+}
@@ -1,45 +1,127 @@
 package http
 
 import (
+	"errors"
+	"log/slog"
 	"net/http"
 	"strconv"
 	"time"
 
 	"github.com/gin-gonic/gin"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
 	"github.com/uptrace/bun"
+	"go.opentelemetry.io/contrib/instrumentation/github.com/gin-gonic/gin/otelgin"
 
-	"github.com/huntercenter1/backend-test/product-service/internal/middleware"
+	"github.com/huntercenter1/backend-test/platform/health"
+	pmetrics "github.com/huntercenter1/backend-test/platform/metrics"
+	"github.com/huntercenter1/backend-test/platform/middleware"
+	"github.com/huntercenter1/backend-test/platform/ratelimit"
+
+	"github.com/huntercenter1/backend-test/product-service/internal/inventory"
+	"github.com/huntercenter1/backend-test/product-service/internal/metrics"
 	"github.com/huntercenter1/backend-test/product-service/internal/models"
 	"github.com/huntercenter1/backend-test/product-service/internal/repo"
+	"github.com/huntercenter1/backend-test/product-service/internal/storage"
 )
 
+const DefaultRequestTimeout = 5 * time.Second
+
+// ProbeRoutes nunca se limitan: las consultan el orquestador y Prometheus.
+var ProbeRoutes = []string{"GET /health", "GET /livez", "GET /readyz", "GET /metrics"}
+
 type Router struct {
-	db   *bun.DB
-	repo repo.ProductRepo
+	db         *bun.DB
+	repo       repo.ProductRepo
+	images     repo.ImageRepo
+	prices     repo.PriceRepo
+	movements  repo.MovementRepo
+	warehouses repo.WarehouseRepo
+	blobs      storage.BlobStore
+	log        *slog.Logger
+	ready      *health.Checker
+	limiter    *ratelimit.Limiter
+	timeout    time.Duration
+
+	maxImageBytes int64
+	allocation    string
+	regions       inventory.Regions
 }
 
-func New(db *bun.DB) *Router {
-	return &Router{db: db, repo: repo.New(db)}
+type Options struct {
+	Blobs          storage.BlobStore
+	MaxImageBytes  int64              // 0 = DefaultMaxImageBytes
+	Allocation     string             // estrategia por defecto al descontar stock (priority; nearest sólo por petición)
+	Regions        inventory.Regions  // punto de cada región de envío: con él las ventas van por nearest
+	Logger         *slog.Logger       // nil = slog.Default()
+	Checks         []health.Check     // dependencias que debe comprobar /readyz
+	RequestTimeout time.Duration      // 0 = DefaultRequestTimeout
+	RateLimit      *ratelimit.Limiter // nil = sin límite
+}
+
+func New(db *bun.DB, opts Options) *Router {
+	if opts.MaxImageBytes <= 0 { opts.MaxImageBytes = DefaultMaxImageBytes }
+	if opts.Logger == nil { opts.Logger = slog.Default() }
+	if opts.RequestTimeout <= 0 { opts.RequestTimeout = DefaultRequestTimeout }
+	return &Router{
+		db:            db,
+		repo:          repo.New(db),
+		images:        repo.NewImageRepo(db),
+		prices:        repo.NewPriceRepo(db),
+		movements:     repo.NewMovementRepo(db),
+		warehouses:    repo.NewWarehouseRepo(db),
+		blobs:         opts.Blobs,
+		log:           opts.Logger,
+		ready:         health.NewChecker(2*time.Second, opts.Checks...),
+		timeout:       opts.RequestTimeout,
+		limiter:       opts.RateLimit,
+		maxImageBytes: opts.MaxImageBytes,
+		allocation:    opts.Allocation,
+		regions:       opts.Regions,
+	}
 }
 
 func (rt *Router) Register(r *gin.Engine) {
-	r.Use(gin.Recovery(), middleware.RequestID(), middleware.Timeout(5*time.Second))
+	r.Use(otelgin.Middleware("product-service"), pmetrics.HTTP(), middleware.RequestID(), middleware.AccessLog(rt.log), middleware.Recovery(rt.log), middleware.Timeout(rt.timeout))
+	if rt.limiter != nil { r.Use(rt.limiter.Gin()) }
 
-	r.GET("/health", func(c *gin.Context){ c.JSON(http.StatusOK, gin.H{"status":"ok"}) })
+	r.GET("/health", rt.livez) // compatibilidad: igual que /livez
+	r.GET("/livez", rt.livez)
+	r.GET("/readyz", rt.readyz)
+	r.GET("/metrics", gin.WrapH(promhttp.Handler()))
 
 	r.GET("/products", rt.list)
 	r.POST("/products", rt.create)
 	r.GET("/products/:id", rt.get)
 	r.PUT("/products/:id", rt.update)
+	r.PATCH("/products/:id", rt.patch)
 	r.DELETE("/products/:id", rt.delete)
 	r.GET("/products/search", rt.search)
 	r.PUT("/products/:id/stock", rt.updateStock)
+	r.GET("/products/:id/stock/movements", rt.stockMovements)
+	r.GET("/products/:id/stock/locations", rt.stockLocations)
+
+	r.GET("/warehouses", rt.listWarehouses)
+	r.POST("/warehouses", rt.createWarehouse)
+	r.PUT("/warehouses/:id", rt.updateWarehouse)
+	r.POST("/warehouses/transfers", rt.transferStock)
+
+	r.GET("/products/:id/prices", rt.priceHistory)
+	r.POST("/products/:id/prices/schedules", rt.schedulePrice)
+	r.DELETE("/products/:id/prices/schedules/:schedule_id", rt.cancelSchedule)
+
+	r.POST("/products/:id/images", rt.uploadImage)
+	r.GET("/products/:id/images", rt.listImages)
+	r.PUT("/products/:id/images/order", rt.reorderImages)
+	r.GET("/products/:id/images/:image_id", rt.serveImage)
+	r.GET("/products/:id/images/:image_id/thumbnail", rt.serveThumbnail)
+	r.DELETE("/products/:id/images/:image_id", rt.deleteImage)
 }
 
 func (rt *Router) list(c *gin.Context) {
 	limit, offset := parsePag(c)
 	items, total, err := rt.repo.List(c.Request.Context(), limit, offset)
 	if err != nil { c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()}); return }
+	if err := rt.resolveList(c.Request.Context(), items); err != nil { c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()}); return }
 	c.JSON(http.StatusOK, gin.H{"items": items, "total": total, "limit": limit, "offset": offset})
 }
 
@@ -48,9 +130,11 @@ func (rt *Router) create(c *gin.Context) {
 	if err := c.ShouldBindJSON(&p); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid body"}); return
 	}
-	if p.Name == "" || p.Price <= 0 { c.JSON(http.StatusBadRequest, gin.H{"error":"name/price required"}); return }
-	res, err := rt.repo.Create(c.Request.Context(), &p)
+	if err := validateProduct(&p); err != nil { c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()}); return }
+	res, err := rt.repo.Create(repo.WithActor(c.Request.Context(), actor(c)), &p)
+	if errors.Is(err, repo.ErrDuplicateSKU) { c.JSON(http.StatusConflict, gin.H{"error": err.Error()}); return }
 	if err != nil { c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()}); return }
+	if err := rt.resolvePrices(c.Request.Context(), res); err != nil { c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()}); return }
 	c.JSON(http.StatusCreated, res)
 }
 
@@ -58,31 +142,50 @@ func (rt *Router) get(c *gin.Context) {
 	id := c.Param("id")
 	p, err := rt.repo.GetByID(c.Request.Context(), id)
 	if err != nil { c.JSON(http.StatusNotFound, gin.H{"error":"not found"}); return }
+	if err := rt.resolvePrices(c.Request.Context(), p); err != nil { c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()}); return }
 	c.JSON(http.StatusOK, p)
 }
 
 func (rt *Router) update(c *gin.Context) {
 	id := c.Param("id")
-	var body models.Product
+	var body productInput
 	if err := c.ShouldBindJSON(&body); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid body"}); return
 	}
 	p, err := rt.repo.GetByID(c.Request.Context(), id)
 	if err != nil { c.JSON(http.StatusNotFound, gin.H{"error":"not found"}); return }
-	if body.Name != "" { p.Name = body.Name }
-	if body.Description != "" { p.Description = body.Description }
-	if body.Price > 0 { p.Price = body.Price }
-	if body.Stock >= 0 { p.Stock = body.Stock }
-	res, err := rt.repo.Update(c.Request.Context(), p)
+	if err := body.replace(p); err != nil { c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()}); return }
+	res, err := rt.repo.Update(repo.WithActor(c.Request.Context(), actor(c)), p)
+	if errors.Is(err, repo.ErrDuplicateSKU) { c.JSON(http.StatusConflict, gin.H{"error": err.Error()}); return }
+	if err != nil { c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()}); return }
+	if err := rt.resolvePrices(c.Request.Context(), res); err != nil { c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()}); return }
+	c.JSON(http.StatusOK, res)
+}
+
+func (rt *Router) patch(c *gin.Context) {
+	id := c.Param("id")
+	raw, err := c.GetRawData()
+	if err != nil { c.JSON(http.StatusBadRequest, gin.H{"error": "invalid body"}); return }
+	p, err := rt.repo.GetByID(c.Request.Context(), id)
+	if err != nil { c.JSON(http.StatusNotFound, gin.H{"error":"not found"}); return }
+	fields, err := applyMergePatch(p, raw)
+	if err != nil { c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()}); return }
+	// sólo lo que trae el patch: el resto puede haber cambiado desde GetByID
+	res, err := rt.repo.Update(repo.WithActor(c.Request.Context(), actor(c)), p, fields...)
+	if errors.Is(err, repo.ErrDuplicateSKU) { c.JSON(http.StatusConflict, gin.H{"error": err.Error()}); return }
 	if err != nil { c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()}); return }
+	if err := rt.resolvePrices(c.Request.Context(), res); err != nil { c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()}); return }
 	c.JSON(http.StatusOK, res)
 }
 
 func (rt *Router) delete(c *gin.Context) {
 	id := c.Param("id")
+	// las filas de product_images caen en cascada; los blobs hay que borrarlos aparte
+	images, _ := rt.images.List(c.Request.Context(), id)
 	if err := rt.repo.Delete(c.Request.Context(), id); err != nil {
 		c.JSON(http.StatusNotFound, gin.H{"error":"not found"}); return
 	}
+	for _, img := range images { rt.removeBlobs(c.Request.Context(), img) }
 	c.Status(http.StatusNoContent)
 }
 
@@ -91,10 +194,39 @@ func (rt *Router) search(c *gin.Context) {
 	limit, offset := parsePag(c)
 	items, total, err := rt.repo.Search(c.Request.Context(), q, limit, offset)
 	if err != nil { c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()}); return }
+	if err := rt.resolveList(c.Request.Context(), items); err != nil { c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()}); return }
 	c.JSON(http.StatusOK, gin.H{"items": items, "total": total, "limit": limit, "offset": offset})
 }
 
-type stockBody struct { Delta int `json:"delta"` }
+type stockBody struct {
+	Delta       int              `json:"delta"`
+	Reason      string           `json:"reason"`
+	ReferenceID string           `json:"reference_id"`
+	Actor       string           `json:"actor"`
+	WarehouseID string           `json:"warehouse_id"`
+	Allocation  string           `json:"allocation"`
+	Location    *inventory.Point `json:"location"`
+	Region      string           `json:"region"` // región de envío; sin location, da el punto de nearest
+}
+
+// stockResult es el producto tras el cambio más el delta que se aplicó de
+// verdad. Una venta sin stock suficiente falla con 409, así que sólo un
+// ajuste a la baja puede quedarse corto.
+type stockResult struct {
+	*models.Product
+	Applied int `json:"applied"`
+}
+
+// livez sólo indica que el proceso responde; no mira dependencias para que
+// una caída de la base no provoque reinicios en cadena.
+func (rt *Router) livez(c *gin.Context) { c.JSON(http.StatusOK, gin.H{"status": health.StatusOK}) }
+
+func (rt *Router) readyz(c *gin.Context) {
+	rep := rt.ready.Run(c.Request.Context())
+	code := http.StatusOK
+	if !rep.OK() { code = http.StatusServiceUnavailable }
+	c.JSON(code, rep)
+}
 
 func (rt *Router) updateStock(c *gin.Context) {
 	id := c.Param("id")
@@ -102,11 +234,53 @@ func (rt *Router) updateStock(c *gin.Context) {
 	if err := c.ShouldBindJSON(&body); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error":"invalid body"}); return
 	}
-	res, err := rt.repo.UpdateStock(c.Request.Context(), id, body.Delta)
+	if body.Reason == "" { body.Reason = models.StockAdjustment }
+	if !models.ValidStockReason(body.Reason) { c.JSON(http.StatusBadRequest, gin.H{"error":"reason must be one of sale, restock, adjustment, return"}); return }
+	if body.Actor == "" { body.Actor = actor(c) }
+	// sin location, la región de envío da el punto si se conoce y, si no se
+	// pide otra estrategia, la salida sale del almacén más cercano
+	if body.Location == nil {
+		if p, ok := rt.regions.Locate(body.Region); ok {
+			body.Location = p
+			if body.Allocation == "" { body.Allocation = inventory.StrategyNearest }
+		}
+	}
+	if body.Allocation == "" { body.Allocation = rt.allocation }
+	if _, err := inventory.StrategyFor(body.Allocation, body.Location); err != nil { c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()}); return }
+	res, applied, err := rt.repo.UpdateStock(c.Request.Context(), id, repo.StockChange{
+		Delta: body.Delta, Reason: body.Reason, ReferenceID: body.ReferenceID, Actor: body.Actor,
+		WarehouseID: body.WarehouseID, Allocation: body.Allocation, Near: body.Location,
+	})
+	switch {
+	case errors.Is(err, repo.ErrNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()}); return
+	// un warehouse_id inexistente es un error del cuerpo, no del producto: con
+	// un 404 order-service lo tomaría por producto borrado
+	case errors.Is(err, repo.ErrWarehouseNotFound):
+		c.JSON(http.StatusUnprocessableEntity, gin.H{"error": err.Error()}); return
+	case errors.Is(err, repo.ErrNoWarehouse), errors.Is(err, repo.ErrInsufficientStock):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()}); return
+	case err != nil:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()}); return
+	}
+	if body.Delta < 0 && res.Stock == 0 { metrics.StockOuts.Inc() }
+	if err := rt.resolvePrices(c.Request.Context(), res); err != nil { c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()}); return }
+	c.JSON(http.StatusOK, stockResult{Product: res, Applied: applied})
+}
+
+func (rt *Router) stockMovements(c *gin.Context) {
+	id := c.Param("id")
+	if _, err := rt.repo.GetByID(c.Request.Context(), id); err != nil { c.JSON(http.StatusNotFound, gin.H{"error":"not found"}); return }
+	limit, offset := parsePag(c)
+	items, total, err := rt.movements.List(c.Request.Context(), id, limit, offset)
 	if err != nil { c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()}); return }
-	c.JSON(http.StatusOK, res)
+	c.JSON(http.StatusOK, gin.H{"items": items, "total": total, "limit": limit, "offset": offset})
 }
 
+// actor identifica a quien origina un cambio; no hay autenticación, así que
+// se confía en la cabecera X-Actor.
+func actor(c *gin.Context) string { return c.GetHeader("X-Actor") }
+
 func parsePag(c *gin.Context) (int, int) {
 	limit := 20; offset := 0
 	if v := c.Query("limit"); v != "" { if n, err := strconv.Atoi(v); err==nil && n>0 && n<=100 { limit = n } }
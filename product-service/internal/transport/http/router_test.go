@@ -4,6 +4,7 @@ import (
 	"bytes"
 	"context"
 	"encoding/json"
+	"errors"
 	"net/http"
 	"net/http/httptest"
 	"sort"
@@ -13,17 +14,31 @@ import (
 
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
+
+	"github.com/huntercenter1/backend-test/platform/health"
+
+	"github.com/huntercenter1/backend-test/product-service/internal/inventory"
 	"github.com/huntercenter1/backend-test/product-service/internal/models"
 	"github.com/huntercenter1/backend-test/product-service/internal/repo"
 )
 
 type memRepo struct {
-	data map[string]*models.Product
+	data    map[string]*models.Product
+	changes []repo.StockChange
 }
 
 func newMemRepo() *memRepo { return &memRepo{data: map[string]*models.Product{}} }
 
+// skuTaken imita el índice único de sku.
+func (m *memRepo) skuTaken(p *models.Product) bool {
+	for id, o := range m.data {
+		if p.SKU != "" && o.SKU == p.SKU && id != p.ID { return true }
+	}
+	return false
+}
+
 func (m *memRepo) Create(ctx context.Context, p *models.Product) (*models.Product, error) {
+	if m.skuTaken(p) { return nil, repo.ErrDuplicateSKU }
 	if p.ID == "" { p.ID = uuid.NewString() }
 	now := time.Now().UTC()
 	if p.CreatedAt.IsZero() { p.CreatedAt = now }
@@ -38,8 +53,18 @@ func (m *memRepo) GetByID(ctx context.Context, id string) (*models.Product, erro
 	return nil, repo.ErrNotFound
 }
 
-func (m *memRepo) Update(ctx context.Context, p *models.Product) (*models.Product, error) {
-	if _, ok := m.data[p.ID]; !ok { return nil, repo.ErrNotFound }
+// Update respeta fields como el de Postgres: price y stock sólo se escriben
+// si vienen.
+func (m *memRepo) Update(ctx context.Context, p *models.Product, fields ...string) (*models.Product, error) {
+	cur, ok := m.data[p.ID]
+	if !ok { return nil, repo.ErrNotFound }
+	if m.skuTaken(p) { return nil, repo.ErrDuplicateSKU }
+	if len(fields) > 0 {
+		set := map[string]bool{}
+		for _, f := range fields { set[f] = true }
+		if !set["price"] { p.Price = cur.Price }
+		if !set["stock"] { p.Stock = cur.Stock }
+	}
 	p.UpdatedAt = time.Now().UTC()
 	cp := *p; m.data[p.ID] = &cp
 	return &cp, nil
@@ -67,20 +92,60 @@ func (m *memRepo) Search(ctx context.Context, q string, limit, offset int) ([]mo
 	return m.List(ctx, limit, offset)
 }
 
-func (m *memRepo) UpdateStock(ctx context.Context, id string, delta int) (*models.Product, error) {
-	p, ok := m.data[id]; if !ok { return nil, repo.ErrNotFound }
-	p.Stock += delta; if p.Stock < 0 { p.Stock = 0 }
+func (m *memRepo) UpdateStock(ctx context.Context, id string, ch repo.StockChange) (*models.Product, int, error) {
+	p, ok := m.data[id]; if !ok { return nil, 0, repo.ErrNotFound }
+	if ch.WarehouseID == "missing" { return nil, 0, repo.ErrWarehouseNotFound }
+	if ch.Reason == models.StockSale && p.Stock < -ch.Delta { return nil, 0, repo.ErrInsufficientStock }
+	m.changes = append(m.changes, ch)
+	before := p.Stock
+	p.Stock += ch.Delta; if p.Stock < 0 { p.Stock = 0 }
 	p.UpdatedAt = time.Now().UTC()
 	cp := *p; m.data[id] = &cp
-	return &cp, nil
+	return &cp, cp.Stock - before, nil
+}
+
+type memMovements struct{}
+
+func (memMovements) List(ctx context.Context, productID string, limit, offset int) ([]models.StockMovement, int, error) {
+	return []models.StockMovement{}, 0, nil
+}
+func (memMovements) Reconcile(ctx context.Context) ([]repo.StockMismatch, error) { return nil, nil }
+
+type memWarehouses struct {
+	items     []models.Warehouse
+	transfers []repo.Transfer
+}
+
+func (m *memWarehouses) List(ctx context.Context) ([]models.Warehouse, error) { return m.items, nil }
+func (m *memWarehouses) Create(ctx context.Context, w *models.Warehouse) (*models.Warehouse, error) {
+	for _, o := range m.items { if o.Code == w.Code { return nil, repo.ErrDuplicateWarehouse } }
+	w.ID = uuid.NewString()
+	m.items = append(m.items, *w)
+	return w, nil
+}
+func (m *memWarehouses) Update(ctx context.Context, w *models.Warehouse) (*models.Warehouse, error) { return w, nil }
+func (m *memWarehouses) GetByID(ctx context.Context, id string) (*models.Warehouse, error) {
+	for _, o := range m.items { if o.ID == id { cp := o; return &cp, nil } }
+	return nil, repo.ErrWarehouseNotFound
+}
+func (m *memWarehouses) Levels(ctx context.Context, productID string) ([]models.ProductStock, error) {
+	return []models.ProductStock{}, nil
+}
+func (m *memWarehouses) Transfer(ctx context.Context, t repo.Transfer) ([]models.ProductStock, error) {
+	m.transfers = append(m.transfers, t)
+	return []models.ProductStock{}, nil
 }
 
 func setupRouter(t *testing.T) (*gin.Engine, *Router, *memRepo) {
 	gin.SetMode(gin.TestMode)
 	r := gin.New()
-	rt := New(nil) // db nil en test
+	rt := New(nil, Options{Blobs: newMemBlobs()}) // db nil en test
 	mem := newMemRepo()
 	rt.repo = mem // inyectamos fake repo
+	rt.images = newMemImages(mem)
+	rt.prices = &memPrices{}
+	rt.movements = memMovements{}
+	rt.warehouses = &memWarehouses{}
 	rt.Register(r)
 	return r, rt, mem
 }
@@ -130,6 +195,8 @@ func TestProductCRUDAndSearch(t *testing.T) {
 	r.ServeHTTP(w, req)
 	if w.Code != http.StatusOK { t.Fatalf("stock code=%d", w.Code) }
 	if mem.data[created.ID].Stock != 12 { t.Fatalf("want stock 12 got %d", mem.data[created.ID].Stock) }
+	var stocked struct{ Stock, Applied int }
+	if err := json.Unmarshal(w.Body.Bytes(), &stocked); err != nil || stocked.Stock != 12 || stocked.Applied != -3 { t.Fatalf("stock body=%s", w.Body.String()) }
 
 	// delete
 	w = httptest.NewRecorder()
@@ -150,3 +217,149 @@ func TestPaginationParams(t *testing.T) {
 	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, q, nil))
 	if w.Code != http.StatusOK { t.Fatalf("pag2 code=%d", w.Code) }
 }
+
+func TestPatchMergeSemantics(t *testing.T) {
+	r, _, mem := setupRouter(t)
+	p, _ := mem.Create(context.Background(), &models.Product{Name: "Mouse", Description: "Wireless", Price: 10, Stock: 7})
+
+	patch := func(body string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodPatch, "/products/"+p.ID, bytes.NewReader([]byte(body)))
+		req.Header.Set("Content-Type", "application/merge-patch+json")
+		r.ServeHTTP(w, req)
+		return w
+	}
+
+	// campos ausentes no se tocan
+	if w := patch(`{"price": 12.5}`); w.Code != http.StatusOK { t.Fatalf("patch price code=%d", w.Code) }
+	got := mem.data[p.ID]
+	if got.Price != 12.5 || got.Stock != 7 || got.Description != "Wireless" { t.Fatalf("unexpected product %+v", got) }
+
+	// null explícito borra description
+	if w := patch(`{"description": null}`); w.Code != http.StatusOK { t.Fatalf("patch null code=%d", w.Code) }
+	if mem.data[p.ID].Description != "" { t.Fatalf("description not cleared") }
+
+	// tax_class se normaliza y null vuelve a la clase por defecto
+	if w := patch(`{"tax_class": " Reduced ", "weight_grams": 250}`); w.Code != http.StatusOK { t.Fatalf("patch tax_class code=%d", w.Code) }
+	if got := mem.data[p.ID]; got.TaxClass != "reduced" || got.WeightGrams != 250 { t.Fatalf("unexpected product %+v", got) }
+	if w := patch(`{"tax_class": null}`); w.Code != http.StatusOK || mem.data[p.ID].TaxClass != DefaultTaxClass { t.Fatalf("patch null tax_class code=%d", w.Code) }
+
+	// stock 0 es un valor válido
+	if w := patch(`{"stock": 0}`); w.Code != http.StatusOK { t.Fatalf("patch stock code=%d", w.Code) }
+	if mem.data[p.ID].Stock != 0 { t.Fatalf("want stock 0 got %d", mem.data[p.ID].Stock) }
+
+	for _, body := range []string{`{"name": null}`, `{"price": -1}`, `{"stock": "x"}`, `{"weight_grams": -1}`, `{"id": "x"}`, `{"color": "red"}`, `[]`} {
+		if w := patch(body); w.Code != http.StatusBadRequest { t.Fatalf("%s: want 400 got %d", body, w.Code) }
+	}
+	if mem.data[p.ID].Name != "Mouse" { t.Fatalf("rejected patch must not modify product") }
+}
+
+func TestPutRequiresFullRepresentation(t *testing.T) {
+	r, _, mem := setupRouter(t)
+	p, _ := mem.Create(context.Background(), &models.Product{Name: "Mouse", Description: "Wireless", Price: 10, Stock: 7})
+
+	w := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodPut, "/products/"+p.ID, bytes.NewReader([]byte(`{"name":"Mouse 2","price":11}`)))
+	req.Header.Set("Content-Type", "application/json")
+	r.ServeHTTP(w, req)
+	if w.Code != http.StatusBadRequest { t.Fatalf("partial put: want 400 got %d", w.Code) }
+	if mem.data[p.ID].Stock != 7 { t.Fatalf("stock must be untouched, got %d", mem.data[p.ID].Stock) }
+
+	w = httptest.NewRecorder()
+	req = httptest.NewRequest(http.MethodPut, "/products/"+p.ID, bytes.NewReader([]byte(`{"name":"Mouse 2","price":11,"stock":3}`)))
+	req.Header.Set("Content-Type", "application/json")
+	r.ServeHTTP(w, req)
+	if w.Code != http.StatusOK { t.Fatalf("full put code=%d", w.Code) }
+	if got := mem.data[p.ID]; got.Stock != 3 || got.Description != "" { t.Fatalf("unexpected product %+v", got) }
+}
+
+func TestStockMovementMetadata(t *testing.T) {
+	r, rt, mem := setupRouter(t)
+	p, _ := mem.Create(context.Background(), &models.Product{Name: "Desk", Price: 10, Stock: 5})
+
+	put := func(body string) int {
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodPut, "/products/"+p.ID+"/stock", bytes.NewReader([]byte(body)))
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("X-Actor", "order-service")
+		r.ServeHTTP(w, req)
+		return w.Code
+	}
+	if code := put(`{"delta":-2,"reason":"sale","reference_id":"o1"}`); code != http.StatusOK { t.Fatalf("sale code=%d", code) }
+	if code := put(`{"delta":1,"reason":"gift"}`); code != http.StatusBadRequest { t.Fatalf("bad reason: want 400 got %d", code) }
+	if code := put(`{"delta":-4,"reason":"sale","reference_id":"o2"}`); code != http.StatusConflict { t.Fatalf("oversold: want 409 got %d", code) }
+	if mem.data[p.ID].Stock != 3 { t.Fatalf("rejected sale must not touch stock, got %d", mem.data[p.ID].Stock) }
+	// un almacén inexistente no se confunde con un producto borrado
+	if code := put(`{"delta":1,"reason":"restock","warehouse_id":"missing"}`); code != http.StatusUnprocessableEntity { t.Fatalf("unknown warehouse: want 422 got %d", code) }
+	if got := mem.changes[0]; got.Reason != "sale" || got.ReferenceID != "o1" || got.Actor != "order-service" { t.Fatalf("unexpected change %+v", got) }
+
+	// la región de envío da el punto de nearest si se conoce; si no, priority
+	rt.regions = inventory.Regions{"US": {Lat: 39.8, Lng: -98.6}}
+	if code := put(`{"delta":-1,"reason":"sale","reference_id":"o3","region":"us-ca"}`); code != http.StatusOK { t.Fatalf("sale with region code=%d", code) }
+	if got := mem.changes[len(mem.changes)-1]; got.Allocation != inventory.StrategyNearest || got.Near == nil || got.Near.Lat != 39.8 { t.Fatalf("region not used: %+v", got) }
+	if code := put(`{"delta":-1,"reason":"sale","reference_id":"o4","region":"FR"}`); code != http.StatusOK { t.Fatalf("sale with unknown region code=%d", code) }
+	if got := mem.changes[len(mem.changes)-1]; got.Allocation == inventory.StrategyNearest || got.Near != nil { t.Fatalf("unknown region must fall back to priority: %+v", got) }
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/products/"+p.ID+"/stock/movements", nil))
+	if w.Code != http.StatusOK { t.Fatalf("movements code=%d", w.Code) }
+}
+
+func TestWarehousesAndTransfers(t *testing.T) {
+	r, rt, _ := setupRouter(t)
+	send := func(method, path, body string) int {
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest(method, path, bytes.NewReader([]byte(body)))
+		req.Header.Set("Content-Type", "application/json")
+		r.ServeHTTP(w, req)
+		return w.Code
+	}
+
+	if code := send(http.MethodPost, "/warehouses", `{"code":"lis","name":"Lisbon","latitude":38.7,"longitude":-9.1}`); code != http.StatusCreated { t.Fatalf("create code=%d", code) }
+	if code := send(http.MethodPost, "/warehouses", `{"code":"LIS","name":"Dup"}`); code != http.StatusConflict { t.Fatalf("dup code=%d", code) }
+	if code := send(http.MethodPost, "/warehouses", `{"code":"X","name":"Half","latitude":1}`); code != http.StatusBadRequest { t.Fatalf("half coords code=%d", code) }
+
+	if code := send(http.MethodPost, "/warehouses/transfers", `{"product_id":"p","from_warehouse_id":"a","to_warehouse_id":"a","quantity":1}`); code != http.StatusBadRequest { t.Fatalf("same warehouse code=%d", code) }
+	if code := send(http.MethodPost, "/warehouses/transfers", `{"product_id":"p","from_warehouse_id":"a","to_warehouse_id":"b","quantity":2}`); code != http.StatusOK { t.Fatalf("transfer code=%d", code) }
+	if got := rt.warehouses.(*memWarehouses).transfers; len(got) != 1 || got[0].Quantity != 2 { t.Fatalf("unexpected transfers %+v", got) }
+
+	if code := send(http.MethodPut, "/products/x/stock", `{"delta":-1,"allocation":"random"}`); code != http.StatusBadRequest { t.Fatalf("bad allocation code=%d", code) }
+}
+
+func TestLivezAndReadyz(t *testing.T) {
+	r, rt, _ := setupRouter(t)
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/livez", nil))
+	if w.Code != http.StatusOK { t.Fatalf("livez: %d", w.Code) }
+
+	rt.ready = health.NewChecker(time.Second,
+		health.Check{Name: "database", Fn: func(context.Context) (string, error) { return "", nil }},
+		health.Check{Name: "migrations", Fn: func(context.Context) (string, error) { return "version 1", errors.New("schema at 1, want 2") }},
+	)
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/readyz", nil))
+	if w.Code != http.StatusServiceUnavailable { t.Fatalf("readyz: %d %s", w.Code, w.Body.String()) }
+	var rep health.Report
+	if err := json.Unmarshal(w.Body.Bytes(), &rep); err != nil { t.Fatal(err) }
+	if rep.Status != health.StatusFail || len(rep.Checks) != 2 || rep.Checks[0].Status != health.StatusOK || rep.Checks[1].Detail != "version 1" {
+		t.Fatalf("unexpected report: %+v", rep)
+	}
+}
+
+func TestProductSKU(t *testing.T) {
+	r, _, mem := setupRouter(t)
+	do := func(method, path, body string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest(method, path, bytes.NewReader([]byte(body))))
+		return w
+	}
+
+	w := do(http.MethodPost, "/products", `{"sku":" KB-01 ","name":"Keyboard","price":30,"stock":3}`)
+	if w.Code != http.StatusCreated || !bytes.Contains(w.Body.Bytes(), []byte(`"sku":"KB-01"`)) { t.Fatalf("create code=%d body=%s", w.Code, w.Body) }
+	if w := do(http.MethodPost, "/products", `{"sku":"KB-01","name":"Other","price":30,"stock":3}`); w.Code != http.StatusConflict { t.Fatalf("duplicate sku code=%d", w.Code) }
+
+	p, _ := mem.Create(context.Background(), &models.Product{Name: "Mouse", Price: 10, Stock: 7})
+	if w := do(http.MethodPatch, "/products/"+p.ID, `{"sku":"KB-01"}`); w.Code != http.StatusConflict { t.Fatalf("patch duplicate sku code=%d", w.Code) }
+	if w := do(http.MethodPatch, "/products/"+p.ID, `{"sku":"MS-01"}`); w.Code != http.StatusOK || mem.data[p.ID].SKU != "MS-01" { t.Fatalf("patch sku code=%d", w.Code) }
+	if w := do(http.MethodPatch, "/products/"+p.ID, `{"sku":null}`); w.Code != http.StatusOK || mem.data[p.ID].SKU != "" { t.Fatalf("clear sku code=%d", w.Code) }
+}
@@ -0,0 +1,102 @@
+package http
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/huntercenter1/backend-test/product-service/internal/models"
+	"github.com/huntercenter1/backend-test/product-service/internal/repo"
+)
+
+func (rt *Router) listWarehouses(c *gin.Context) {
+	items, err := rt.warehouses.List(c.Request.Context())
+	if err != nil { c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()}); return }
+	c.JSON(http.StatusOK, gin.H{"items": items})
+}
+
+type warehouseBody struct {
+	Code      string   `json:"code"`
+	Name      string   `json:"name"`
+	Priority  *int     `json:"priority"`
+	Latitude  *float64 `json:"latitude"`
+	Longitude *float64 `json:"longitude"`
+	Active    *bool    `json:"active"`
+}
+
+func (b warehouseBody) apply(w *models.Warehouse) error {
+	if b.Name != "" { w.Name = strings.TrimSpace(b.Name) }
+	if b.Priority != nil { w.Priority = *b.Priority }
+	if b.Latitude != nil || b.Longitude != nil { w.Latitude, w.Longitude = b.Latitude, b.Longitude }
+	if b.Active != nil { w.Active = *b.Active }
+	switch {
+	case w.Name == "":
+		return errors.New("name required")
+	case (w.Latitude == nil) != (w.Longitude == nil):
+		return errors.New("latitude and longitude go together")
+	case w.Latitude != nil && (*w.Latitude < -90 || *w.Latitude > 90 || *w.Longitude < -180 || *w.Longitude > 180):
+		return errors.New("coordinates out of range")
+	}
+	return nil
+}
+
+func (rt *Router) createWarehouse(c *gin.Context) {
+	var body warehouseBody
+	if err := c.ShouldBindJSON(&body); err != nil { c.JSON(http.StatusBadRequest, gin.H{"error":"invalid body"}); return }
+	w := &models.Warehouse{Code: strings.ToUpper(strings.TrimSpace(body.Code)), Priority: 100, Active: true}
+	if w.Code == "" || len(w.Code) > 20 { c.JSON(http.StatusBadRequest, gin.H{"error":"code required (max 20 chars)"}); return }
+	if err := body.apply(w); err != nil { c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()}); return }
+	res, err := rt.warehouses.Create(c.Request.Context(), w)
+	if errors.Is(err, repo.ErrDuplicateWarehouse) { c.JSON(http.StatusConflict, gin.H{"error": err.Error()}); return }
+	if err != nil { c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()}); return }
+	c.JSON(http.StatusCreated, res)
+}
+
+// updateWarehouse modifica los campos presentes; el código es inmutable.
+func (rt *Router) updateWarehouse(c *gin.Context) {
+	var body warehouseBody
+	if err := c.ShouldBindJSON(&body); err != nil { c.JSON(http.StatusBadRequest, gin.H{"error":"invalid body"}); return }
+	w, err := rt.warehouses.GetByID(c.Request.Context(), c.Param("id"))
+	if err != nil { c.JSON(http.StatusNotFound, gin.H{"error":"not found"}); return }
+	if body.Code != "" && !strings.EqualFold(body.Code, w.Code) { c.JSON(http.StatusBadRequest, gin.H{"error":"code is immutable"}); return }
+	if err := body.apply(w); err != nil { c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()}); return }
+	res, err := rt.warehouses.Update(c.Request.Context(), w)
+	if err != nil { c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()}); return }
+	c.JSON(http.StatusOK, res)
+}
+
+func (rt *Router) stockLocations(c *gin.Context) {
+	id := c.Param("id")
+	p, err := rt.repo.GetByID(c.Request.Context(), id)
+	if err != nil { c.JSON(http.StatusNotFound, gin.H{"error":"not found"}); return }
+	levels, err := rt.warehouses.Levels(c.Request.Context(), id)
+	if err != nil { c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()}); return }
+	c.JSON(http.StatusOK, gin.H{"product_id": id, "stock": p.Stock, "locations": levels})
+}
+
+func (rt *Router) transferStock(c *gin.Context) {
+	var body repo.Transfer
+	if err := c.ShouldBindJSON(&body); err != nil { c.JSON(http.StatusBadRequest, gin.H{"error":"invalid body"}); return }
+	switch {
+	case body.ProductID == "" || body.FromWarehouseID == "" || body.ToWarehouseID == "":
+		c.JSON(http.StatusBadRequest, gin.H{"error":"product_id, from_warehouse_id and to_warehouse_id required"}); return
+	case body.FromWarehouseID == body.ToWarehouseID:
+		c.JSON(http.StatusBadRequest, gin.H{"error":"source and destination must differ"}); return
+	case body.Quantity <= 0:
+		c.JSON(http.StatusBadRequest, gin.H{"error":"quantity must be > 0"}); return
+	}
+	if body.Actor == "" { body.Actor = actor(c) }
+	levels, err := rt.warehouses.Transfer(c.Request.Context(), body)
+	switch {
+	case errors.Is(err, repo.ErrNotFound), errors.Is(err, repo.ErrWarehouseNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+	case errors.Is(err, repo.ErrInsufficientStock):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+	case err != nil:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	default:
+		c.JSON(http.StatusOK, gin.H{"product_id": body.ProductID, "locations": levels})
+	}
+}
@@ -0,0 +1,28 @@
+-- +goose Up
+CREATE TABLE IF NOT EXISTS price_history (
+  id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+  product_id UUID NOT NULL REFERENCES products(id) ON DELETE CASCADE,
+  old_price NUMERIC(10,2),
+  new_price NUMERIC(10,2) NOT NULL,
+  changed_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+CREATE INDEX IF NOT EXISTS idx_price_history_product_id ON price_history(product_id, changed_at DESC);
+
+-- precio inicial de los productos existentes
+INSERT INTO price_history (product_id, old_price, new_price, changed_at)
+SELECT id, NULL, price, created_at FROM products;
+
+CREATE TABLE IF NOT EXISTS scheduled_prices (
+  id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+  product_id UUID NOT NULL REFERENCES products(id) ON DELETE CASCADE,
+  price NUMERIC(10,2) NOT NULL CHECK (price > 0),
+  effective_from TIMESTAMPTZ NOT NULL,
+  effective_to TIMESTAMPTZ,
+  created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+  CHECK (effective_to IS NULL OR effective_to > effective_from)
+);
+CREATE INDEX IF NOT EXISTS idx_scheduled_prices_product_id ON scheduled_prices(product_id, effective_from);
+
+-- +goose Down
+DROP TABLE IF EXISTS scheduled_prices;
+DROP TABLE IF EXISTS price_history;
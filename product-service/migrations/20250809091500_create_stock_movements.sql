@@ -0,0 +1,20 @@
+-- +goose Up
+CREATE TABLE IF NOT EXISTS stock_movements (
+  id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+  product_id UUID NOT NULL REFERENCES products(id) ON DELETE CASCADE,
+  delta INTEGER NOT NULL,
+  balance INTEGER NOT NULL,
+  reason VARCHAR(20) NOT NULL CHECK (reason IN ('sale', 'restock', 'adjustment', 'return')),
+  reference_id VARCHAR(100),
+  actor VARCHAR(100),
+  created_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+CREATE INDEX IF NOT EXISTS idx_stock_movements_product_id ON stock_movements(product_id, created_at DESC);
+CREATE INDEX IF NOT EXISTS idx_stock_movements_reference_id ON stock_movements(reference_id);
+
+-- saldo de apertura para que el ledger cuadre con el stock actual
+INSERT INTO stock_movements (product_id, delta, balance, reason, reference_id, actor, created_at)
+SELECT id, stock, stock, 'adjustment', 'opening-balance', 'system', created_at FROM products WHERE stock <> 0;
+
+-- +goose Down
+DROP TABLE IF EXISTS stock_movements;
@@ -0,0 +1,41 @@
+-- +goose Up
+CREATE TABLE IF NOT EXISTS warehouses (
+  id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+  code VARCHAR(20) UNIQUE NOT NULL,
+  name VARCHAR(100) NOT NULL,
+  priority INTEGER NOT NULL DEFAULT 100,
+  latitude DOUBLE PRECISION,
+  longitude DOUBLE PRECISION,
+  active BOOLEAN NOT NULL DEFAULT true,
+  created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+  updated_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+CREATE TABLE IF NOT EXISTS product_stock (
+  product_id UUID NOT NULL REFERENCES products(id) ON DELETE CASCADE,
+  warehouse_id UUID NOT NULL REFERENCES warehouses(id),
+  quantity INTEGER NOT NULL CHECK (quantity >= 0),
+  updated_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+  PRIMARY KEY (product_id, warehouse_id)
+);
+CREATE INDEX IF NOT EXISTS idx_product_stock_warehouse_id ON product_stock(warehouse_id);
+
+ALTER TABLE stock_movements ADD COLUMN warehouse_id UUID REFERENCES warehouses(id);
+ALTER TABLE stock_movements DROP CONSTRAINT IF EXISTS stock_movements_reason_check;
+ALTER TABLE stock_movements ADD CONSTRAINT stock_movements_reason_check
+  CHECK (reason IN ('sale', 'restock', 'adjustment', 'return', 'transfer'));
+
+-- almacén por defecto con todo el stock existente
+INSERT INTO warehouses (code, name, priority) VALUES ('MAIN', 'Main warehouse', 0);
+INSERT INTO product_stock (product_id, warehouse_id, quantity)
+SELECT p.id, w.id, p.stock FROM products p CROSS JOIN warehouses w WHERE w.code = 'MAIN' AND p.stock > 0;
+UPDATE stock_movements SET warehouse_id = (SELECT id FROM warehouses WHERE code = 'MAIN');
+
+-- +goose Down
+ALTER TABLE stock_movements DROP CONSTRAINT IF EXISTS stock_movements_reason_check;
+DELETE FROM stock_movements WHERE reason = 'transfer';
+ALTER TABLE stock_movements ADD CONSTRAINT stock_movements_reason_check
+  CHECK (reason IN ('sale', 'restock', 'adjustment', 'return'));
+ALTER TABLE stock_movements DROP COLUMN IF EXISTS warehouse_id;
+DROP TABLE IF EXISTS product_stock;
+DROP TABLE IF EXISTS warehouses;
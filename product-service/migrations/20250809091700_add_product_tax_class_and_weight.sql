@@ -0,0 +1,8 @@
+-- +goose Up
+-- order-service calcula impuestos por tax_class y envío por peso
+ALTER TABLE products ADD COLUMN IF NOT EXISTS tax_class VARCHAR(32) NOT NULL DEFAULT 'standard';
+ALTER TABLE products ADD COLUMN IF NOT EXISTS weight_grams INTEGER NOT NULL DEFAULT 0 CHECK (weight_grams >= 0);
+
+-- +goose Down
+ALTER TABLE products DROP COLUMN IF EXISTS weight_grams;
+ALTER TABLE products DROP COLUMN IF EXISTS tax_class;
@@ -0,0 +1,8 @@
+-- +goose Up
+-- los productos existentes no tienen SKU; order-service lo copia en los pedidos
+ALTER TABLE products ADD COLUMN IF NOT EXISTS sku VARCHAR(64);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_products_sku ON products(sku) WHERE sku IS NOT NULL;
+
+-- +goose Down
+DROP INDEX IF EXISTS idx_products_sku;
+ALTER TABLE products DROP COLUMN IF EXISTS sku;
@@ -0,0 +1,7 @@
+-- +goose Up
+-- un cambio de stock con referencia se aplica una vez por producto y motivo
+-- (ver applyStock): se busca por estos tres datos antes de cada cambio
+CREATE INDEX IF NOT EXISTS idx_stock_movements_reference ON stock_movements(product_id, reason, reference_id) WHERE reference_id IS NOT NULL;
+
+-- +goose Down
+DROP INDEX IF EXISTS idx_stock_movements_reference;
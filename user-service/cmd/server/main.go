@@ -2,64 +2,120 @@ package main
 
 import (
 	"context"
-	"log"
-	"net"
+	"fmt"
+	"log/slog"
+	"net/http"
 	"os"
-	"os/signal"
-	"syscall"
-	"time"
 
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"go.opentelemetry.io/contrib/instrumentation/google.golang.org/grpc/otelgrpc"
 	"google.golang.org/grpc"
+	grpchealth "google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
 	"google.golang.org/grpc/reflection"
 
+	dbpkg "github.com/huntercenter1/backend-test/platform/db"
+	"github.com/huntercenter1/backend-test/platform/health"
+	"github.com/huntercenter1/backend-test/platform/logging"
+	"github.com/huntercenter1/backend-test/platform/metrics"
+	"github.com/huntercenter1/backend-test/platform/migrate"
+	"github.com/huntercenter1/backend-test/platform/ratelimit"
+	"github.com/huntercenter1/backend-test/platform/server"
+	"github.com/huntercenter1/backend-test/platform/tracing"
 	userpb "github.com/huntercenter1/backend-test/proto"
-	dbpkg "github.com/huntercenter1/backend-test/user-service/internal/db"
+
+	"github.com/huntercenter1/backend-test/user-service/internal/config"
 	"github.com/huntercenter1/backend-test/user-service/internal/repo"
 	"github.com/huntercenter1/backend-test/user-service/internal/service"
 	grpcsvr "github.com/huntercenter1/backend-test/user-service/internal/transport/grpc"
 )
 
 func main() {
-	if err := dbpkg.Migrate(os.Getenv("DB_DSN"), os.Getenv("MIGRATIONS_DIR")); err != nil {
-		log.Fatalf("migrate: %v", err)
+	if len(os.Args) > 1 && os.Args[1] == "migrate" { os.Exit(runMigrate(os.Args[2:])) }
+
+	cfg, printOnly, err := config.Load(os.Args[1:])
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "invalid configuration:\n%v\n", err)
+		os.Exit(2)
+	}
+	if printOnly { cfg.Print(os.Stdout); return }
+
+	logger := logging.New("user-service", cfg.Log.Level)
+	shutdownTracing, err := tracing.Init(context.Background(), "user-service")
+	if err != nil { fatal("tracing", err) }
+	defer func() { _ = shutdownTracing(context.Background()) }()
+
+	// con migrations.auto=false las migraciones se lanzan aparte (`migrate up`)
+	if cfg.Migrations.Auto {
+		if err := migrate.Up(context.Background(), cfg.DB.DSN, cfg.Migrations); err != nil { fatal("migrate", err) }
 	}
-	db, err := dbpkg.New(context.Background())
+	db, err := dbpkg.New(context.Background(), cfg.DB)
 	if err != nil {
-		log.Fatalf("db connect: %v", err)
+		fatal("db connect", err)
 	}
 	defer func() { _ = db.Close() }()
+	// no servimos con un esquema más antiguo que el que espera el binario
+	if err := migrate.Check(context.Background(), db.DB, cfg.Migrations.Dir); err != nil { fatal("schema check", err) }
 
 	r := repo.NewUserRepo(db)
 	svc := service.New(r)
 	h := grpcsvr.NewServer(svc)
 
-	addr := getenv("APP_PORT", ":50051")
-	lis, err := net.Listen("tcp", addr)
-	if err != nil { log.Fatalf("listen: %v", err) }
-
-	s := grpc.NewServer()
+	chain := grpcsvr.Chain{
+		Logger:         logger,
+		DefaultTimeout: cfg.GRPC.DefaultTimeout,
+		MaxTimeout:     cfg.GRPC.MaxTimeout,
+		Metrics:        metrics.UnaryServerInterceptor(),
+		// Auth: sin autenticación por ahora; el hook es grpcsvr.AuthFunc
+	}
+	if cfg.RateLimit.Enabled {
+		policy, _ := cfg.RateLimit.Policy() // ya validada en config.Load
+		policy.Exempt = []string{"/grpc.health.v1.Health/"}
+		chain.Unary = append(chain.Unary, ratelimit.New(ratelimit.NewMemoryStore(), policy, logger).UnaryServerInterceptor())
+	}
+	s := grpc.NewServer(append(chain.ServerOptions(), grpc.StatsHandler(otelgrpc.NewServerHandler()))...)
 	userpb.RegisterUserServiceServer(s, h)
 
+	// grpc.health.v1 refleja el resultado de las mismas comprobaciones que /readyz
+	checker := health.NewChecker(cfg.Health.Timeout, health.DB(db.DB), health.Migrations(db.DB, cfg.Migrations.Dir))
+	hs := grpchealth.NewServer()
+	healthpb.RegisterHealthServer(s, hs)
+
 	// SIEMPRE habilitar reflection para debug
 	reflection.Register(s)
-	log.Println("gRPC reflection enabled")
-
-	go func() {
-		log.Printf("user-service gRPC listening on %s", addr)
-		if err := s.Serve(lis); err != nil { log.Fatalf("grpc serve: %v", err) }
-	}()
-
-	quit := make(chan os.Signal, 1)
-	signal.Notify(quit, os.Interrupt, syscall.SIGTERM)
-	<-quit
-	log.Println("shutting down gRPC server...")
-	stopped := make(chan struct{})
-	go func() { s.GracefulStop(); close(stopped) }()
-	select {
-	case <-stopped:
-	case <-time.After(10 * time.Second):
-		s.Stop()
+	logger.Info("gRPC reflection enabled")
+
+	// métricas y probes en un listener HTTP aparte: el puerto gRPC no habla HTTP/1
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", promhttp.Handler())
+	mux.Handle("/livez", health.LivezHandler())
+	mux.Handle("/readyz", health.ReadyzHandler(checker))
+
+	// se paran en orden inverso: primero health pasa a NOT_SERVING para que
+	// los clientes dejen de enviarnos tráfico, después métricas y gRPC
+	err = server.Run(context.Background(), logger, cfg.GRPC.ShutdownTimeout,
+		server.GRPC("grpc", cfg.GRPC.Addr, s),
+		server.HTTP("metrics", &http.Server{Addr: cfg.Metrics.Addr, Handler: mux}),
+		health.WatchServer(hs, checker, cfg.Health.Interval, userpb.UserService_ServiceDesc.ServiceName),
+	)
+	if err != nil { fatal("server", err) }
+}
+
+// runMigrate atiende `user-service migrate <cmd> [flags]`; los flags son
+// los mismos que los del servidor (-config, -db.dsn...).
+func runMigrate(args []string) int {
+	if len(args) == 0 { fmt.Fprintln(os.Stderr, "usage: user-service", migrate.Usage); return 2 }
+	cfg, _, err := config.Load(args[1:])
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "invalid configuration:\n%v\n", err)
+		return 2
+	}
+	logging.New("user-service", cfg.Log.Level)
+	if err := migrate.Command(context.Background(), cfg.DB.DSN, cfg.Migrations, args[0], os.Stdout); err != nil {
+		slog.Error("migrate", "command", args[0], "error", err)
+		return 1
 	}
+	return 0
 }
 
-func getenv(k, d string) string { v := os.Getenv(k); if v == "" { return d }; return v }
+func fatal(msg string, err error) { slog.Error(msg, "error", err); os.Exit(1) }
@@ -0,0 +1,90 @@
+package config
+
+import (
+	"errors"
+	"io"
+	"time"
+
+	platformcfg "github.com/huntercenter1/backend-test/platform/config"
+	"github.com/huntercenter1/backend-test/platform/db"
+	"github.com/huntercenter1/backend-test/platform/logging"
+	"github.com/huntercenter1/backend-test/platform/migrate"
+	"github.com/huntercenter1/backend-test/platform/ratelimit"
+)
+
+type Config struct {
+	Env string `key:"env" env:"APP_ENV"`
+
+	GRPC struct {
+		Addr            string        `key:"addr" env:"APP_PORT"`
+		ShutdownTimeout time.Duration `key:"shutdown_timeout" env:"GRPC_SHUTDOWN_TIMEOUT"`
+		// DefaultTimeout es el deadline de las llamadas que llegan sin uno y
+		// MaxTimeout el máximo que se acepta del cliente.
+		DefaultTimeout time.Duration `key:"default_timeout" env:"GRPC_DEFAULT_TIMEOUT"`
+		MaxTimeout     time.Duration `key:"max_timeout" env:"GRPC_MAX_TIMEOUT"`
+	} `key:"grpc"`
+
+	// Metrics es el listener HTTP de /metrics, /livez y /readyz.
+	Metrics struct {
+		Addr string `key:"addr" env:"METRICS_ADDR"`
+	} `key:"metrics"`
+
+	Health struct {
+		Interval time.Duration `key:"interval" env:"HEALTH_INTERVAL"`
+		Timeout  time.Duration `key:"timeout" env:"HEALTH_TIMEOUT"`
+	} `key:"health"`
+
+	DB db.Config `key:"db"`
+
+	Migrations migrate.Config `key:"migrations"`
+
+	Log logging.Config `key:"log"`
+
+	RateLimit ratelimit.Config `key:"rate_limit"`
+}
+
+func Default() Config {
+	var c Config
+	c.Env = "local"
+	c.GRPC.Addr = ":50051"
+	c.GRPC.ShutdownTimeout = 10 * time.Second
+	c.GRPC.DefaultTimeout = 10 * time.Second
+	c.GRPC.MaxTimeout = 30 * time.Second
+	c.Metrics.Addr = ":9091"
+	c.Health.Interval = 10 * time.Second
+	c.Health.Timeout = 2 * time.Second
+	c.DB = db.DefaultConfig()
+	c.Migrations = migrate.DefaultConfig()
+	c.Log.Level = "info"
+	c.RateLimit = ratelimit.Config{
+		Enabled: true,
+		Default: "600/m",
+		// ValidateUser lo llama order-service en cada pedido, siempre desde las mismas IPs
+		Routes: "/user.UserService/AuthenticateUser=10/m,/user.UserService/CreateUser=20/m,/user.UserService/ValidateUser=6000/m",
+	}
+	return c
+}
+
+// Load parte de Default y aplica fichero, entorno y flags; después valida.
+func Load(args []string) (Config, bool, error) {
+	c := Default()
+	printOnly, err := platformcfg.Load(&c, args)
+	if err != nil { return c, false, err }
+	return c, printOnly, c.Validate()
+}
+
+// Print escribe la configuración efectiva con los secretos ocultos.
+func (c Config) Print(w io.Writer) { platformcfg.Print(w, &c) }
+
+func (c Config) Validate() error {
+	errs := []error{c.DB.Validate(), c.Migrations.Validate(), c.Log.Validate(), c.RateLimit.Validate()}
+	if c.GRPC.Addr == "" { errs = append(errs, errors.New("grpc.addr is required (env APP_PORT)")) }
+	if c.GRPC.ShutdownTimeout <= 0 { errs = append(errs, errors.New("grpc.shutdown_timeout must be > 0")) }
+	if c.GRPC.DefaultTimeout <= 0 { errs = append(errs, errors.New("grpc.default_timeout must be > 0")) }
+	if c.GRPC.MaxTimeout < c.GRPC.DefaultTimeout { errs = append(errs, errors.New("grpc.max_timeout must be >= grpc.default_timeout")) }
+	if c.Metrics.Addr == "" { errs = append(errs, errors.New("metrics.addr is required (env METRICS_ADDR)")) }
+	if c.Metrics.Addr != "" && c.Metrics.Addr == c.GRPC.Addr { errs = append(errs, errors.New("metrics.addr must differ from grpc.addr")) }
+	if c.Health.Interval <= 0 { errs = append(errs, errors.New("health.interval must be > 0")) }
+	if c.Health.Timeout <= 0 { errs = append(errs, errors.New("health.timeout must be > 0")) }
+	return errors.Join(errs...)
+}
@@ -0,0 +1,39 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestLoadFileEnvAndFlags(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "user.yaml")
+	data := "grpc:\n  addr: \":6000\"\nhealth:\n  interval: 30s\ndb:\n  dsn: postgres://u:secret@db/users_db\n"
+	if err := os.WriteFile(file, []byte(data), 0o600); err != nil { t.Fatal(err) }
+	t.Setenv("CONFIG_FILE", file)
+	t.Setenv("APP_PORT", ":6001")
+	cfg, _, err := Load([]string{"-metrics.addr", ":9999"})
+	if err != nil { t.Fatal(err) }
+	if cfg.GRPC.Addr != ":6001" || cfg.Metrics.Addr != ":9999" || cfg.Health.Interval != 30*time.Second {
+		t.Fatalf("unexpected config: %+v", cfg)
+	}
+
+	var b strings.Builder
+	cfg.Print(&b)
+	if strings.Contains(b.String(), "secret") { t.Fatalf("password leaked:\n%s", b.String()) }
+}
+
+func TestValidateListeners(t *testing.T) {
+	t.Setenv("DB_DSN", "postgres://u:p@db/users_db")
+	_, _, err := Load([]string{"-grpc.addr", ":9091"})
+	if err == nil || !strings.Contains(err.Error(), "metrics.addr must differ from grpc.addr") { t.Fatalf("err=%v", err) }
+}
+
+func TestValidateTimeouts(t *testing.T) {
+	t.Setenv("DB_DSN", "postgres://u:p@db/users_db")
+	t.Setenv("GRPC_MAX_TIMEOUT", "5s")
+	_, _, err := Load([]string{"-grpc.default_timeout", "10s"})
+	if err == nil || !strings.Contains(err.Error(), "grpc.max_timeout must be >= grpc.default_timeout") { t.Fatalf("err=%v", err) }
+}
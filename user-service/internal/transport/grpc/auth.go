@@ -0,0 +1,44 @@
+package grpcsvr
+
+import (
+	"context"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// AuthFunc es el punto de enganche para autenticar llamadas. Recibe el
+// método completo (/user.UserService/GetUser) para poder dejar pasar los
+// públicos y devuelve el contexto con la identidad del llamante. Un error
+// sin código gRPC se devuelve como Unauthenticated.
+type AuthFunc func(ctx context.Context, fullMethod string) (context.Context, error)
+
+// UnaryAuth aplica fn a cada llamada; con fn nil no hace nada.
+func UnaryAuth(fn AuthFunc) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		if fn == nil { return handler(ctx, req) }
+		ctx, err := authenticate(ctx, fn, info.FullMethod)
+		if err != nil { return nil, err }
+		return handler(ctx, req)
+	}
+}
+
+func StreamAuth(fn AuthFunc) grpc.StreamServerInterceptor {
+	return func(srv any, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		if fn == nil { return handler(srv, ss) }
+		ctx, err := authenticate(ss.Context(), fn, info.FullMethod)
+		if err != nil { return err }
+		return handler(srv, &serverStream{ServerStream: ss, ctx: ctx})
+	}
+}
+
+func authenticate(ctx context.Context, fn AuthFunc, method string) (context.Context, error) {
+	out, err := fn(ctx, method)
+	if err != nil {
+		if _, ok := status.FromError(err); ok { return nil, err }
+		return nil, status.Error(codes.Unauthenticated, err.Error())
+	}
+	if out == nil { out = ctx }
+	return out, nil
+}
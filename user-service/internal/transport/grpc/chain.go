@@ -0,0 +1,47 @@
+package grpcsvr
+
+import (
+	"log/slog"
+	"time"
+
+	"google.golang.org/grpc"
+)
+
+// Chain describe la cadena de interceptores del servidor.
+type Chain struct {
+	Logger *slog.Logger
+	// DefaultTimeout se aplica a las llamadas unarias sin deadline y
+	// MaxTimeout es el máximo que se respeta del cliente (0 = sin límite).
+	DefaultTimeout time.Duration
+	MaxTimeout     time.Duration
+	// Metrics va justo después de recovery para contar todas las llamadas,
+	// también las que rechazan auth o la validación.
+	Metrics grpc.UnaryServerInterceptor
+	// Auth es opcional; ver AuthFunc.
+	Auth AuthFunc
+	// Unary y Stream van entre la autenticación y la validación
+	// (métricas, rate limit...).
+	Unary  []grpc.UnaryServerInterceptor
+	Stream []grpc.StreamServerInterceptor
+}
+
+// ServerOptions monta la cadena en este orden: logging (ve el código final,
+// también el de un panic), recovery, métricas, deadline, auth, los
+// interceptores de Unary/Stream y por último la validación del mensaje,
+// justo antes del handler.
+func (c Chain) ServerOptions() []grpc.ServerOption {
+	unary := []grpc.UnaryServerInterceptor{UnaryLogging(c.Logger), UnaryRecovery(c.Logger)}
+	if c.Metrics != nil { unary = append(unary, c.Metrics) }
+	unary = append(unary, UnaryDeadline(c.DefaultTimeout, c.MaxTimeout), UnaryAuth(c.Auth))
+	unary = append(append(unary, c.Unary...), UnaryValidation())
+
+	stream := []grpc.StreamServerInterceptor{
+		StreamLogging(c.Logger),
+		StreamRecovery(c.Logger),
+		StreamDeadline(c.MaxTimeout),
+		StreamAuth(c.Auth),
+	}
+	stream = append(append(stream, c.Stream...), StreamValidation())
+
+	return []grpc.ServerOption{grpc.ChainUnaryInterceptor(unary...), grpc.ChainStreamInterceptor(stream...)}
+}
@@ -0,0 +1,214 @@
+package grpcsvr
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"log/slog"
+	"net"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	grpchealth "google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+
+	userpb "github.com/huntercenter1/backend-test/proto"
+	"github.com/huntercenter1/backend-test/user-service/internal/models"
+)
+
+const testUserID = "0b7e2a52-7c4e-4a4b-9a53-2f0c1f5f4d11"
+
+// fakeSvc implementa service.UserService; get decide qué hace GetUser.
+type fakeSvc struct {
+	mu       sync.Mutex
+	get      func(ctx context.Context, id string) (*models.User, error)
+	deadline time.Time
+}
+
+func (f *fakeSvc) Create(ctx context.Context, username, email, password string) (*models.User, error) {
+	return &models.User{ID: testUserID, Username: username, Email: email}, nil
+}
+func (f *fakeSvc) Get(ctx context.Context, id string) (*models.User, error) {
+	f.mu.Lock(); f.deadline, _ = ctx.Deadline(); f.mu.Unlock()
+	if f.get != nil { return f.get(ctx, id) }
+	return &models.User{ID: id}, nil
+}
+func (f *fakeSvc) Update(ctx context.Context, id, username, email, password string) (*models.User, error) {
+	return &models.User{ID: id}, nil
+}
+func (f *fakeSvc) Delete(ctx context.Context, id string) error { return nil }
+func (f *fakeSvc) Authenticate(ctx context.Context, username, password string) (string, error) {
+	return testUserID, nil
+}
+func (f *fakeSvc) Validate(ctx context.Context, id string) (bool, error) { return true, nil }
+
+// start levanta el servidor con la cadena sobre bufconn y devuelve un
+// cliente de user.UserService y otro de grpc.health.v1.
+func start(t *testing.T, c Chain, svc *fakeSvc) (userpb.UserServiceClient, healthpb.HealthClient) {
+	t.Helper()
+	if c.Logger == nil { c.Logger = slog.New(slog.NewJSONHandler(&bytes.Buffer{}, nil)) }
+	lis := bufconn.Listen(1 << 20)
+	s := grpc.NewServer(c.ServerOptions()...)
+	userpb.RegisterUserServiceServer(s, NewServer(svc))
+	healthpb.RegisterHealthServer(s, grpchealth.NewServer())
+	go func() { _ = s.Serve(lis) }()
+	t.Cleanup(s.Stop)
+
+	conn, err := grpc.NewClient("passthrough:///bufnet",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) { return lis.DialContext(ctx) }),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+	)
+	if err != nil { t.Fatal(err) }
+	t.Cleanup(func() { _ = conn.Close() })
+	return userpb.NewUserServiceClient(conn), healthpb.NewHealthClient(conn)
+}
+
+func TestChainRecoversPanic(t *testing.T) {
+	var buf bytes.Buffer
+	svc := &fakeSvc{get: func(context.Context, string) (*models.User, error) { panic("boom") }}
+	uc, _ := start(t, Chain{Logger: slog.New(slog.NewJSONHandler(&buf, nil)), DefaultTimeout: time.Second}, svc)
+
+	_, err := uc.GetUser(context.Background(), &userpb.GetUserRequest{Id: testUserID})
+	if status.Code(err) != codes.Internal { t.Fatalf("err=%v, want Internal", err) }
+	if strings.Contains(status.Convert(err).Message(), "boom") { t.Fatalf("panic value leaked to client: %v", err) }
+	if !strings.Contains(buf.String(), `"msg":"grpc panic"`) || !strings.Contains(buf.String(), `"code":"Internal"`) {
+		t.Fatalf("panic not logged:\n%s", buf.String())
+	}
+
+	// el servidor sigue atendiendo
+	svc.get = nil
+	if _, err := uc.GetUser(context.Background(), &userpb.GetUserRequest{Id: testUserID}); err != nil { t.Fatal(err) }
+}
+
+func TestChainValidatesRequests(t *testing.T) {
+	uc, _ := start(t, Chain{DefaultTimeout: time.Second}, &fakeSvc{})
+	ctx := context.Background()
+
+	cases := []struct {
+		name  string
+		call  func() error
+		field string
+	}{
+		{"empty id", func() error { _, err := uc.GetUser(ctx, &userpb.GetUserRequest{}); return err }, "id"},
+		{"bad uuid", func() error { _, err := uc.DeleteUser(ctx, &userpb.DeleteUserRequest{Id: "42"}); return err }, "id"},
+		{"bad user_id", func() error { _, err := uc.ValidateUser(ctx, &userpb.ValidateUserRequest{UserId: "not-a-uuid"}); return err }, "user_id"},
+		{"bad email", func() error {
+			_, err := uc.CreateUser(ctx, &userpb.CreateUserRequest{Username: "u", Email: "nope", Password: "p"}); return err
+		}, "email"},
+		{"named email", func() error {
+			_, err := uc.UpdateUser(ctx, &userpb.UpdateUserRequest{Id: testUserID, Email: "A <a@b.com>"}); return err
+		}, "email"},
+		{"missing password", func() error {
+			_, err := uc.AuthenticateUser(ctx, &userpb.AuthRequest{Username: "u"}); return err
+		}, "password"},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			st := status.Convert(tc.call())
+			if st.Code() != codes.InvalidArgument { t.Fatalf("code=%s (%s), want InvalidArgument", st.Code(), st.Message()) }
+			var fields []string
+			for _, d := range st.Details() {
+				if br, ok := d.(*errdetails.BadRequest); ok {
+					for _, v := range br.GetFieldViolations() { fields = append(fields, v.GetField()) }
+				}
+			}
+			if len(fields) != 1 || fields[0] != tc.field { t.Fatalf("violations=%v, want [%s]", fields, tc.field) }
+		})
+	}
+
+	if _, err := uc.CreateUser(ctx, &userpb.CreateUserRequest{Username: "u", Email: "u@example.com", Password: "p"}); err != nil { t.Fatal(err) }
+	// en Update el email vacío significa "no cambiar"
+	if _, err := uc.UpdateUser(ctx, &userpb.UpdateUserRequest{Id: testUserID, Username: "x"}); err != nil { t.Fatal(err) }
+}
+
+func TestChainDeadlines(t *testing.T) {
+	svc := &fakeSvc{}
+	uc, _ := start(t, Chain{DefaultTimeout: 2 * time.Second, MaxTimeout: 5 * time.Second}, svc)
+	req := &userpb.GetUserRequest{Id: testUserID}
+
+	check := func(ctx context.Context, want time.Duration) {
+		t.Helper()
+		before := time.Now()
+		if _, err := uc.GetUser(ctx, req); err != nil { t.Fatal(err) }
+		svc.mu.Lock(); got := svc.deadline.Sub(before); svc.mu.Unlock()
+		if got <= 0 || got > want+time.Second/2 || got < want-time.Second/2 { t.Fatalf("handler deadline in %v, want ~%v", got, want) }
+	}
+	check(context.Background(), 2*time.Second) // sin deadline: el por defecto
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Hour)
+	defer cancel()
+	check(ctx, 5*time.Second) // recortado al máximo
+
+	ctx, cancel = context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+	check(ctx, 3*time.Second) // dentro del rango: el del cliente
+}
+
+func TestChainAuthHook(t *testing.T) {
+	type ctxKey struct{}
+	var seen any
+	svc := &fakeSvc{get: func(ctx context.Context, id string) (*models.User, error) { seen = ctx.Value(ctxKey{}); return &models.User{ID: id}, nil }}
+	auth := func(ctx context.Context, method string) (context.Context, error) {
+		md, _ := metadata.FromIncomingContext(ctx)
+		if v := md.Get("authorization"); len(v) == 1 && v[0] == "Bearer good" { return context.WithValue(ctx, ctxKey{}, "alice"), nil }
+		if method == "/user.UserService/ValidateUser" { return nil, status.Error(codes.PermissionDenied, "forbidden") }
+		return nil, errors.New("missing token")
+	}
+	uc, hc := start(t, Chain{DefaultTimeout: time.Second, Auth: auth}, svc)
+	req := &userpb.GetUserRequest{Id: testUserID}
+
+	_, err := uc.GetUser(context.Background(), req)
+	if status.Code(err) != codes.Unauthenticated { t.Fatalf("err=%v, want Unauthenticated", err) }
+	_, err = uc.ValidateUser(context.Background(), &userpb.ValidateUserRequest{UserId: testUserID})
+	if status.Code(err) != codes.PermissionDenied { t.Fatalf("err=%v, want PermissionDenied from hook", err) }
+
+	ctx := metadata.AppendToOutgoingContext(context.Background(), "authorization", "Bearer good")
+	if _, err := uc.GetUser(ctx, req); err != nil { t.Fatal(err) }
+	if seen != "alice" { t.Fatalf("identity in handler = %v, want alice", seen) }
+
+	// los streams pasan por el mismo hook
+	w, err := hc.Watch(context.Background(), &healthpb.HealthCheckRequest{})
+	if err != nil { t.Fatal(err) }
+	if _, err := w.Recv(); status.Code(err) != codes.Unauthenticated { t.Fatalf("stream err=%v, want Unauthenticated", err) }
+}
+
+func TestChainStreamLogging(t *testing.T) {
+	var buf syncBuffer
+	_, hc := start(t, Chain{Logger: slog.New(slog.NewJSONHandler(&buf, nil)), DefaultTimeout: time.Second}, &fakeSvc{})
+
+	ctx, cancel := context.WithCancel(metadata.AppendToOutgoingContext(context.Background(), "x-request-id", "watch-1"))
+	w, err := hc.Watch(ctx, &healthpb.HealthCheckRequest{})
+	if err != nil { t.Fatal(err) }
+	resp, err := w.Recv()
+	if err != nil { t.Fatal(err) }
+	if resp.GetStatus() != healthpb.HealthCheckResponse_SERVING { t.Fatalf("status=%s", resp.GetStatus()) }
+	md, err := w.Header()
+	if err != nil { t.Fatal(err) }
+	if v := md.Get("x-request-id"); len(v) != 1 || v[0] != "watch-1" { t.Fatalf("x-request-id header = %v", v) }
+	cancel()
+
+	deadline := time.Now().Add(2 * time.Second)
+	for !strings.Contains(buf.String(), `"msg":"grpc stream"`) {
+		if time.Now().After(deadline) { t.Fatalf("stream not logged:\n%s", buf.String()) }
+		time.Sleep(10 * time.Millisecond)
+	}
+	if !strings.Contains(buf.String(), `"method":"/grpc.health.v1.Health/Watch"`) { t.Fatalf("method missing:\n%s", buf.String()) }
+}
+
+// syncBuffer: el log del stream se escribe desde la goroutine del servidor.
+type syncBuffer struct {
+	mu sync.Mutex
+	b  bytes.Buffer
+}
+
+func (s *syncBuffer) Write(p []byte) (int, error) { s.mu.Lock(); defer s.mu.Unlock(); return s.b.Write(p) }
+func (s *syncBuffer) String() string             { s.mu.Lock(); defer s.mu.Unlock(); return s.b.String() }
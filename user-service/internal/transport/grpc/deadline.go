@@ -0,0 +1,50 @@
+package grpcsvr
+
+import (
+	"context"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// UnaryDeadline pone un deadline de def a las llamadas que llegan sin
+// grpc-timeout y recorta a max los que piden más. Una llamada cuyo deadline
+// ya pasó se rechaza con DeadlineExceeded sin llegar al handler.
+func UnaryDeadline(def, max time.Duration) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		ctx, cancel, err := deadline(ctx, def, max)
+		if err != nil { return nil, err }
+		defer cancel()
+		return handler(ctx, req)
+	}
+}
+
+// StreamDeadline no pone deadline por defecto (los streams como
+// grpc.health.v1.Health/Watch viven lo que dure la conexión); sólo recorta a
+// max el que pida el cliente y rechaza los vencidos.
+func StreamDeadline(max time.Duration) grpc.StreamServerInterceptor {
+	return func(srv any, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		ctx, cancel, err := deadline(ss.Context(), 0, max)
+		if err != nil { return err }
+		defer cancel()
+		return handler(srv, &serverStream{ServerStream: ss, ctx: ctx})
+	}
+}
+
+func deadline(ctx context.Context, def, max time.Duration) (context.Context, context.CancelFunc, error) {
+	d, ok := ctx.Deadline()
+	if !ok {
+		if def <= 0 { return ctx, func() {}, nil }
+		ctx, cancel := context.WithTimeout(ctx, def)
+		return ctx, cancel, nil
+	}
+	left := time.Until(d)
+	if left <= 0 { return ctx, func() {}, status.Error(codes.DeadlineExceeded, "deadline already exceeded") }
+	if max > 0 && left > max {
+		ctx, cancel := context.WithTimeout(ctx, max)
+		return ctx, cancel, nil
+	}
+	return ctx, func() {}, nil
+}
@@ -0,0 +1,103 @@
+package grpcsvr
+
+import (
+	"context"
+	"log/slog"
+	"runtime/debug"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+
+	"github.com/huntercenter1/backend-test/platform/logging"
+)
+
+const requestIDKey = "x-request-id"
+
+// UnaryLogging toma el x-request-id de la metadata entrante (o genera uno),
+// lo deja en el contexto y en la cabecera de respuesta, y registra una línea
+// por llamada con método, código y duración.
+func UnaryLogging(l *slog.Logger) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		start := time.Now()
+		ctx = withRequestID(ctx)
+		_ = grpc.SetHeader(ctx, metadata.Pairs(requestIDKey, logging.RequestID(ctx)))
+
+		resp, err := handler(ctx, req)
+		logCall(ctx, l, "grpc request", info.FullMethod, start, err)
+		return resp, err
+	}
+}
+
+// StreamLogging es UnaryLogging para streams: la línea se escribe al cerrarse
+// el stream, con su duración total.
+func StreamLogging(l *slog.Logger) grpc.StreamServerInterceptor {
+	return func(srv any, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		start := time.Now()
+		ctx := withRequestID(ss.Context())
+		_ = ss.SetHeader(metadata.Pairs(requestIDKey, logging.RequestID(ctx)))
+
+		err := handler(srv, &serverStream{ServerStream: ss, ctx: ctx})
+		logCall(ctx, l, "grpc stream", info.FullMethod, start, err)
+		return err
+	}
+}
+
+// UnaryRecovery convierte un panic del handler en codes.Internal (sin
+// filtrar el detalle al cliente) y lo registra con el stack.
+func UnaryRecovery(l *slog.Logger) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (resp any, err error) {
+		defer func() {
+			if r := recover(); r != nil { err = recovered(ctx, l, info.FullMethod, r) }
+		}()
+		return handler(ctx, req)
+	}
+}
+
+func StreamRecovery(l *slog.Logger) grpc.StreamServerInterceptor {
+	return func(srv any, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) (err error) {
+		defer func() {
+			if r := recover(); r != nil { err = recovered(ss.Context(), l, info.FullMethod, r) }
+		}()
+		return handler(srv, ss)
+	}
+}
+
+func recovered(ctx context.Context, l *slog.Logger, method string, r any) error {
+	l.ErrorContext(ctx, "grpc panic", "method", method, "panic", r, "stack", string(debug.Stack()))
+	return status.Error(codes.Internal, "internal error")
+}
+
+func withRequestID(ctx context.Context) context.Context {
+	var id string
+	if md, ok := metadata.FromIncomingContext(ctx); ok {
+		if v := md.Get(requestIDKey); len(v) > 0 { id = v[0] }
+	}
+	if !logging.ValidRequestID(id) { id = logging.NewRequestID() }
+	return logging.WithRequestID(ctx, id)
+}
+
+func logCall(ctx context.Context, l *slog.Logger, msg, method string, start time.Time, err error) {
+	code := status.Code(err)
+	level := slog.LevelInfo
+	switch code {
+	case codes.OK, codes.NotFound, codes.AlreadyExists, codes.InvalidArgument, codes.Unauthenticated, codes.Canceled:
+	case codes.Internal, codes.Unknown, codes.DataLoss, codes.Unavailable:
+		level = slog.LevelError
+	default:
+		level = slog.LevelWarn
+	}
+	attrs := []any{"method", method, "code", code.String(), "duration_ms", float64(time.Since(start).Microseconds()) / 1000}
+	if err != nil { attrs = append(attrs, "error", err.Error()) }
+	l.Log(ctx, level, msg, attrs...)
+}
+
+// serverStream permite a los interceptores de stream sustituir el contexto.
+type serverStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (s *serverStream) Context() context.Context { return s.ctx }
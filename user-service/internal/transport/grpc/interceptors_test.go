@@ -0,0 +1,32 @@
+package grpcsvr
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"log/slog"
+	"testing"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+
+	"github.com/huntercenter1/backend-test/platform/logging"
+)
+
+func TestUnaryLoggingUsesIncomingRequestID(t *testing.T) {
+	var buf bytes.Buffer
+	l := slog.New(slog.NewJSONHandler(&buf, nil))
+	ctx := metadata.NewIncomingContext(context.Background(), metadata.Pairs("x-request-id", "req-7"))
+
+	var seen string
+	_, err := UnaryLogging(l)(ctx, nil, &grpc.UnaryServerInfo{FullMethod: "/user.UserService/ValidateUser"},
+		func(ctx context.Context, req any) (any, error) { seen = logging.RequestID(ctx); return nil, nil })
+	if err != nil { t.Fatal(err) }
+	if seen != "req-7" { t.Fatalf("request id in handler = %q, want req-7", seen) }
+
+	var entry map[string]any
+	if err := json.Unmarshal(buf.Bytes(), &entry); err != nil { t.Fatalf("log line: %v", err) }
+	if entry["method"] != "/user.UserService/ValidateUser" || entry["code"] != "OK" {
+		t.Fatalf("unexpected log entry: %v", entry)
+	}
+}
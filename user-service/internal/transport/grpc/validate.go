@@ -0,0 +1,98 @@
+package grpcsvr
+
+import (
+	"context"
+	"fmt"
+	"net/mail"
+	"strings"
+
+	"github.com/google/uuid"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	userpb "github.com/huntercenter1/backend-test/proto"
+)
+
+// violations acumula los campos inválidos de un mensaje.
+type violations []*errdetails.BadRequest_FieldViolation
+
+func (v *violations) add(field, desc string) {
+	*v = append(*v, &errdetails.BadRequest_FieldViolation{Field: field, Description: desc})
+}
+
+func (v *violations) required(field, value string) {
+	if strings.TrimSpace(value) == "" { v.add(field, "is required") }
+}
+
+func (v *violations) uuid(field, value string) {
+	if strings.TrimSpace(value) == "" { v.add(field, "is required"); return }
+	if _, err := uuid.Parse(value); err != nil || len(value) != 36 { v.add(field, "must be a valid UUID") }
+}
+
+func (v *violations) email(field, value string) {
+	value = strings.TrimSpace(value)
+	if value == "" { v.add(field, "is required"); return }
+	// net/mail acepta "Nombre <a@b>"; aquí sólo vale la dirección desnuda
+	a, err := mail.ParseAddress(value)
+	if err != nil || a.Address != value || !strings.Contains(value[strings.LastIndex(value, "@"):], ".") {
+		v.add(field, "must be a valid email address")
+	}
+}
+
+// Validate comprueba un mensaje de entrada de user.UserService antes de
+// llegar al servicio: ids presentes y con formato UUID, email con formato
+// válido y campos obligatorios. Los mensajes desconocidos pasan sin validar.
+// El error es InvalidArgument con el detalle por campo en errdetails.BadRequest.
+func Validate(req any) error {
+	var v violations
+	switch m := req.(type) {
+	case *userpb.CreateUserRequest:
+		v.required("username", m.GetUsername())
+		v.email("email", m.GetEmail())
+		v.required("password", m.GetPassword())
+	case *userpb.GetUserRequest:
+		v.uuid("id", m.GetId())
+	case *userpb.UpdateUserRequest:
+		v.uuid("id", m.GetId())
+		// en Update los campos vacíos no se tocan
+		if strings.TrimSpace(m.GetEmail()) != "" { v.email("email", m.GetEmail()) }
+	case *userpb.DeleteUserRequest:
+		v.uuid("id", m.GetId())
+	case *userpb.AuthRequest:
+		v.required("username", m.GetUsername())
+		v.required("password", m.GetPassword())
+	case *userpb.ValidateUserRequest:
+		v.uuid("user_id", m.GetUserId())
+	}
+	if len(v) == 0 { return nil }
+
+	msgs := make([]string, len(v))
+	for i, f := range v { msgs[i] = fmt.Sprintf("%s %s", f.Field, f.Description) }
+	st := status.New(codes.InvalidArgument, "invalid request: "+strings.Join(msgs, "; "))
+	if d, err := st.WithDetails(&errdetails.BadRequest{FieldViolations: v}); err == nil { st = d }
+	return st.Err()
+}
+
+// UnaryValidation rechaza con InvalidArgument los mensajes que no pasan Validate.
+func UnaryValidation() grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		if err := Validate(req); err != nil { return nil, err }
+		return handler(ctx, req)
+	}
+}
+
+// StreamValidation valida cada mensaje recibido del cliente.
+func StreamValidation() grpc.StreamServerInterceptor {
+	return func(srv any, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		return handler(srv, &validatingStream{ss})
+	}
+}
+
+type validatingStream struct{ grpc.ServerStream }
+
+func (s *validatingStream) RecvMsg(m any) error {
+	if err := s.ServerStream.RecvMsg(m); err != nil { return err }
+	return Validate(m)
+}